@@ -25,6 +25,7 @@ type FakeRuleService struct {
 	AuthorizeAccessInFolderFunc               func(context.Context, identity.Requester, models.Namespaced) error
 	AuthorizeRuleChangesFunc                  func(context.Context, identity.Requester, *store.GroupDelta) error
 	CanReadAllRulesFunc                       func(context.Context, identity.Requester) (bool, error)
+	AuthorizeRecordingTargetFunc              func(context.Context, identity.Requester, string) error
 
 	Calls []Call
 }
@@ -108,3 +109,11 @@ func (s *FakeRuleService) CanReadAllRules(ctx context.Context, user identity.Req
 	}
 	return false, nil
 }
+
+func (s *FakeRuleService) AuthorizeRecordingTarget(ctx context.Context, user identity.Requester, datasourceUID string) error {
+	s.Calls = append(s.Calls, Call{"AuthorizeRecordingTarget", []interface{}{ctx, user, datasourceUID}})
+	if s.AuthorizeRecordingTargetFunc != nil {
+		return s.AuthorizeRecordingTargetFunc(ctx, user, datasourceUID)
+	}
+	return nil
+}
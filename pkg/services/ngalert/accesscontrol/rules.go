@@ -111,6 +111,14 @@ func (r *RuleService) AuthorizeDatasourceAccessForRuleGroup(ctx context.Context,
 	})
 }
 
+// AuthorizeRecordingTarget checks that user is allowed to write to the data source that recording rules write to.
+func (r *RuleService) AuthorizeRecordingTarget(ctx context.Context, user identity.Requester, datasourceUID string) error {
+	eval := accesscontrol.EvalPermission(datasources.ActionWrite, datasources.ScopeProvider.GetResourceScopeUID(datasourceUID))
+	return r.HasAccessOrError(ctx, user, eval, func() string {
+		return fmt.Sprintf("write to data source '%s'", datasourceUID)
+	})
+}
+
 // HasAccessToRuleGroup checks that the identity.Requester has permissions to all rules, which means that it has permissions to:
 // - ("folders:read") read folders which contain the rules
 // - ("alert.rules:read") read alert rules in the folders
@@ -47,6 +47,13 @@ type RuleAccessControlService interface {
 	AuthorizeDatasourceAccessForRule(ctx context.Context, user identity.Requester, rule *models.AlertRule) error
 	AuthorizeDatasourceAccessForRuleGroup(ctx context.Context, user identity.Requester, rules models.RulesGroup) error
 	AuthorizeAccessInFolder(ctx context.Context, user identity.Requester, namespaced models.Namespaced) error
+	AuthorizeRecordingTarget(ctx context.Context, user identity.Requester, datasourceUID string) error
+}
+
+// Scheduler provides the status of scheduled rules and evaluates them on demand.
+type Scheduler interface {
+	StatusReader
+	RuleScheduler
 }
 
 // API handlers.
@@ -64,13 +71,15 @@ type API struct {
 	DataProxy            *datasourceproxy.DataSourceProxyService
 	MultiOrgAlertmanager *notifier.MultiOrgAlertmanager
 	StateManager         *state.Manager
-	Scheduler            StatusReader
+	Scheduler            Scheduler
+	Backfiller           RuleBackfiller
 	AccessControl        ac.AccessControl
 	Policies             *provisioning.NotificationPolicyService
 	ReceiverService      *notifier.ReceiverService
 	ContactPointService  *provisioning.ContactPointService
 	Templates            *provisioning.TemplateService
 	MuteTimings          *provisioning.MuteTimingService
+	LookupTables         *provisioning.LookupTableService
 	AlertRules           *provisioning.AlertRuleService
 	AlertsRouter         *sender.AlertsRouter
 	EvaluatorFactory     eval.EvaluatorFactory
@@ -138,6 +147,9 @@ func (api *API) RegisterAPIEndpoints(m *metrics.API) {
 			amRefresher:        api.MultiOrgAlertmanager,
 			featureManager:     api.FeatureManager,
 			userService:        api.UserService,
+			scheduler:          api.Scheduler,
+			backfiller:         api.Backfiller,
+			datasourceCache:    api.DatasourceCache,
 		},
 	), m)
 	api.RegisterTestingApiEndpoints(NewTestingApi(
@@ -153,6 +165,8 @@ func (api *API) RegisterAPIEndpoints(m *metrics.API) {
 			appUrl:          api.AppUrl,
 			tracer:          api.Tracer,
 			folderService:   api.RuleStore,
+			notifications:   api.MultiOrgAlertmanager,
+			preview:         api.MultiOrgAlertmanager,
 		}), m)
 	api.RegisterConfigurationApiEndpoints(NewConfiguration(
 		&ConfigSrv{
@@ -171,6 +185,7 @@ func (api *API) RegisterAPIEndpoints(m *metrics.API) {
 		templates:           api.Templates,
 		muteTimings:         api.MuteTimings,
 		alertRules:          api.AlertRules,
+		lookupTables:        api.LookupTables,
 		// XXX: Used to flag recording rules, remove when FT is removed
 		featureManager: api.FeatureManager,
 	}), m)
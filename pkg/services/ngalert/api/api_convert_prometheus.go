@@ -279,23 +279,7 @@ func (srv *ConvertPrometheusSrv) getOrCreateNamespace(c *contextmodel.ReqContext
 func (srv *ConvertPrometheusSrv) convertToGrafanaRuleGroup(c *contextmodel.ReqContext, ds *datasources.DataSource, namespaceUID string, promGroup apimodels.PrometheusRuleGroup, logger log.Logger) (*models.AlertRuleGroup, error) {
 	logger.Info("Converting Prometheus rules to Grafana rules", "rules", len(promGroup.Rules), "folder_uid", namespaceUID, "datasource_uid", ds.UID, "datasource_type", ds.Type)
 
-	rules := make([]prom.PrometheusRule, len(promGroup.Rules))
-	for i, r := range promGroup.Rules {
-		rules[i] = prom.PrometheusRule{
-			Alert:         r.Alert,
-			Expr:          r.Expr,
-			For:           r.For,
-			KeepFiringFor: r.KeepFiringFor,
-			Labels:        r.Labels,
-			Annotations:   r.Annotations,
-			Record:        r.Record,
-		}
-	}
-	group := prom.PrometheusRuleGroup{
-		Name:     promGroup.Name,
-		Interval: promGroup.Interval,
-		Rules:    rules,
-	}
+	group := prometheusRuleGroupFromApi(promGroup)
 
 	pauseRecordingRules, err := parseBooleanHeader(c.Req.Header.Get(recordingRulesPausedHeader), recordingRulesPausedHeader)
 	if err != nil {
@@ -334,6 +318,26 @@ func (srv *ConvertPrometheusSrv) convertToGrafanaRuleGroup(c *contextmodel.ReqCo
 	return grafanaGroup, nil
 }
 
+func prometheusRuleGroupFromApi(promGroup apimodels.PrometheusRuleGroup) prom.PrometheusRuleGroup {
+	rules := make([]prom.PrometheusRule, len(promGroup.Rules))
+	for i, r := range promGroup.Rules {
+		rules[i] = prom.PrometheusRule{
+			Alert:         r.Alert,
+			Expr:          r.Expr,
+			For:           r.For,
+			KeepFiringFor: r.KeepFiringFor,
+			Labels:        r.Labels,
+			Annotations:   r.Annotations,
+			Record:        r.Record,
+		}
+	}
+	return prom.PrometheusRuleGroup{
+		Name:     promGroup.Name,
+		Interval: promGroup.Interval,
+		Rules:    rules,
+	}
+}
+
 // parseBooleanHeader parses a boolean header value, returning an error if the header
 // is present but invalid. If the header is not present, returns (false, nil).
 func parseBooleanHeader(header string, headerName string) (bool, error) {
@@ -382,6 +386,12 @@ func grafanaRuleGroupToPrometheus(group string, rules []models.AlertRule) (apimo
 		if err := yaml.Unmarshal([]byte(promDefinition), &r); err != nil {
 			return apimodels.PrometheusRuleGroup{}, fmt.Errorf("failed to unmarshal Prometheus rule definition of the rule with UID %s: %w", rule.UID, err)
 		}
+		// keep_firing_for can be changed in Grafana after the rule is imported, so the rule is the source of truth.
+		r.KeepFiringFor = nil
+		if rule.KeepFiringFor > 0 {
+			keepFiringFor := prommodel.Duration(rule.KeepFiringFor)
+			r.KeepFiringFor = &keepFiringFor
+		}
 		promGroup.Rules[i] = r
 	}
 
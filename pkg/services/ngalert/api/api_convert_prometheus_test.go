@@ -735,6 +735,7 @@ func createConvertPrometheusSrv(t *testing.T, opts ...convertPrometheusSrvOption
 		log.New("test"),
 		&provisioning.NotificationSettingsValidatorProviderFake{},
 		&acfakes.FakeRuleService{},
+		dsCache,
 	)
 
 	cfg := &setting.UnifiedAlertingSettings{
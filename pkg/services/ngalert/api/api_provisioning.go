@@ -32,6 +32,7 @@ type ProvisioningSrv struct {
 	templates           TemplateService
 	muteTimings         MuteTimingService
 	alertRules          AlertRuleService
+	lookupTables        LookupTableService
 	folderSvc           folder.Service
 
 	// XXX: Used to flag recording rules, remove when FT is removed
@@ -52,6 +53,11 @@ type TemplateService interface {
 	DeleteTemplate(ctx context.Context, orgID int64, nameOrUid string, provenance definitions.Provenance, version string) error
 }
 
+type LookupTableService interface {
+	GetLookupTables(ctx context.Context, orgID int64) (definitions.LookupTables, error)
+	UpdateLookupTables(ctx context.Context, orgID int64, tables definitions.LookupTables) error
+}
+
 type NotificationPolicyService interface {
 	GetPolicyTree(ctx context.Context, orgID int64) (definitions.Route, string, error)
 	UpdatePolicyTree(ctx context.Context, orgID int64, tree definitions.Route, p alerting_models.Provenance, version string) (definitions.Route, string, error)
@@ -242,6 +248,22 @@ func (srv *ProvisioningSrv) RouteDeleteTemplate(c *contextmodel.ReqContext, name
 	return response.JSON(http.StatusNoContent, nil)
 }
 
+func (srv *ProvisioningSrv) RouteGetLookupTables(c *contextmodel.ReqContext) response.Response {
+	tables, err := srv.lookupTables.GetLookupTables(c.Req.Context(), c.SignedInUser.GetOrgID())
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "", err)
+	}
+	return response.JSON(http.StatusOK, tables)
+}
+
+func (srv *ProvisioningSrv) RoutePutLookupTables(c *contextmodel.ReqContext, tables definitions.LookupTables) response.Response {
+	err := srv.lookupTables.UpdateLookupTables(c.Req.Context(), c.SignedInUser.GetOrgID(), tables)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "", err)
+	}
+	return response.JSON(http.StatusAccepted, tables)
+}
+
 func (srv *ProvisioningSrv) RouteGetMuteTiming(c *contextmodel.ReqContext, name string) response.Response {
 	timing, err := srv.muteTimings.GetMuteTiming(c.Req.Context(), name, c.SignedInUser.GetOrgID())
 	if err != nil {
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
 	"github.com/prometheus/common/model"
 
 	"github.com/grafana/grafana/pkg/api/apierrors"
@@ -19,13 +20,16 @@ import (
 	"github.com/grafana/grafana/pkg/infra/log"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
 	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	authz "github.com/grafana/grafana/pkg/services/ngalert/accesscontrol"
 	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/backtesting"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
 	"github.com/grafana/grafana/pkg/services/ngalert/notifier"
 	"github.com/grafana/grafana/pkg/services/ngalert/provisioning"
+	"github.com/grafana/grafana/pkg/services/ngalert/schedule"
 	"github.com/grafana/grafana/pkg/services/ngalert/store"
 	"github.com/grafana/grafana/pkg/services/quota"
 	"github.com/grafana/grafana/pkg/services/user"
@@ -46,6 +50,22 @@ type AMRefresher interface {
 	ApplyConfig(ctx context.Context, orgId int64, dbConfig *ngmodels.AlertConfiguration) error
 }
 
+// RuleScheduler evaluates rules regardless of their schedule, and records evaluations of rules in debug mode.
+type RuleScheduler interface {
+	EvaluateNow(key ngmodels.AlertRuleKey) error
+	SetRuleDebug(key ngmodels.AlertRuleKey, evaluations int)
+	RuleEvaluationTraces(key ngmodels.AlertRuleKey) ([]schedule.EvaluationTrace, bool)
+}
+
+// RuleBackfiller backfills the series of recording rules over historical ranges.
+type RuleBackfiller interface {
+	Start(ctx context.Context, rule *ngmodels.AlertRule, from, to time.Time) (backtesting.BackfillJob, error)
+	Get(ctx context.Context, orgID int64, id string) (backtesting.BackfillJob, error)
+	List(ctx context.Context, orgID int64, ruleUID string) ([]backtesting.BackfillJob, error)
+	Cancel(ctx context.Context, orgID int64, id string) (backtesting.BackfillJob, error)
+	Resume(ctx context.Context, orgID int64, id string) (backtesting.BackfillJob, error)
+}
+
 type RulerSrv struct {
 	xactManager        provisioning.TransactionManager
 	provenanceStore    provisioning.ProvisioningStore
@@ -57,9 +77,12 @@ type RulerSrv struct {
 	authz              RuleAccessControlService
 	userService        user.Service
 
-	amConfigStore  AMConfigStore
-	amRefresher    AMRefresher
-	featureManager featuremgmt.FeatureToggles
+	amConfigStore   AMConfigStore
+	amRefresher     AMRefresher
+	featureManager  featuremgmt.FeatureToggles
+	scheduler       RuleScheduler
+	backfiller      RuleBackfiller
+	datasourceCache datasources.CacheService
 }
 
 var (
@@ -360,6 +383,295 @@ func (srv RulerSrv) RouteGetRuleVersionsByUID(c *contextmodel.ReqContext, ruleUI
 	return response.JSON(http.StatusOK, result)
 }
 
+// RoutePostRuleEvaluation evaluates the rule with the given UID now, regardless of its schedule.
+// Returns http.StatusConflict if the rule is paused or is not evaluated by this instance.
+func (srv RulerSrv) RoutePostRuleEvaluation(c *contextmodel.ReqContext, ruleUID string) response.Response {
+	ctx := c.Req.Context()
+	rule, err := srv.getAuthorizedRuleByUid(ctx, c, ruleUID)
+	if err != nil {
+		if errors.Is(err, ngmodels.ErrAlertRuleNotFound) {
+			return response.Empty(http.StatusNotFound)
+		}
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to get rule by UID", err)
+	}
+	// evaluating the rule out of schedule changes the state of its alerts, so the user must be able to update the rule
+	if err := srv.authorizeRuleUpdate(ctx, c, &rule); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to authorize rule evaluation", err)
+	}
+
+	if err := srv.scheduler.EvaluateNow(rule.GetKey()); err != nil {
+		if errors.Is(err, schedule.ErrRulePaused) || errors.Is(err, schedule.ErrRuleNotScheduled) {
+			return ErrResp(http.StatusConflict, err, "")
+		}
+		return ErrResp(http.StatusInternalServerError, err, "failed to evaluate rule")
+	}
+	return response.JSON(http.StatusAccepted, util.DynMap{"message": "rule evaluation requested"})
+}
+
+// RouteGetRuleDebug returns the last evaluations of the rule with the given UID recorded in debug mode by this instance.
+func (srv RulerSrv) RouteGetRuleDebug(c *contextmodel.ReqContext, ruleUID string) response.Response {
+	rule, err := srv.getAuthorizedRuleByUid(c.Req.Context(), c, ruleUID)
+	if err != nil {
+		if errors.Is(err, ngmodels.ErrAlertRuleNotFound) {
+			return response.Empty(http.StatusNotFound)
+		}
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to get rule by UID", err)
+	}
+
+	traces, enabled := srv.scheduler.RuleEvaluationTraces(rule.GetKey())
+	result := apimodels.GettableRuleDebug{
+		Enabled:     enabled,
+		Evaluations: make([]apimodels.RuleEvaluationTrace, 0, len(traces)),
+	}
+	for _, t := range traces {
+		result.Evaluations = append(result.Evaluations, toRuleEvaluationTrace(t))
+	}
+	return response.JSON(http.StatusOK, result)
+}
+
+// RoutePostRuleDebug enables or disables debug mode of the rule with the given UID on this instance.
+// Only the instance that evaluates the rule records its evaluations.
+func (srv RulerSrv) RoutePostRuleDebug(c *contextmodel.ReqContext, debug apimodels.PostableRuleDebug, ruleUID string) response.Response {
+	if debug.Evaluations < 0 || debug.Evaluations > schedule.MaxDebugEvaluations {
+		return ErrResp(http.StatusBadRequest, fmt.Errorf("the number of evaluations must be between 0 and %d", schedule.MaxDebugEvaluations), "")
+	}
+	ctx := c.Req.Context()
+	rule, err := srv.getAuthorizedRuleByUid(ctx, c, ruleUID)
+	if err != nil {
+		if errors.Is(err, ngmodels.ErrAlertRuleNotFound) {
+			return response.Empty(http.StatusNotFound)
+		}
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to get rule by UID", err)
+	}
+	// the recorded evaluations expose the data returned by the queries, so the user must be able to update the rule
+	if err := srv.authorizeRuleUpdate(ctx, c, &rule); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to authorize rule debug", err)
+	}
+
+	if !debug.Enabled {
+		srv.scheduler.SetRuleDebug(rule.GetKey(), 0)
+		return response.JSON(http.StatusAccepted, util.DynMap{"message": "rule debug mode disabled"})
+	}
+	evaluations := debug.Evaluations
+	if evaluations == 0 {
+		evaluations = schedule.DefaultDebugEvaluations
+	}
+	srv.scheduler.SetRuleDebug(rule.GetKey(), evaluations)
+	return response.JSON(http.StatusAccepted, util.DynMap{"message": "rule debug mode enabled"})
+}
+
+// RoutePostRuleBackfill starts a backfill of the recording rule with the given UID over the given range.
+// The backfill runs in the background. Its progress is returned by RouteGetRuleBackfill.
+func (srv RulerSrv) RoutePostRuleBackfill(c *contextmodel.ReqContext, cmd apimodels.PostableRuleBackfill, ruleUID string) response.Response {
+	if srv.backfiller == nil {
+		return ErrResp(http.StatusNotFound, errors.New("recording rules are not enabled"), "")
+	}
+	ctx := c.Req.Context()
+	rule, err := srv.getAuthorizedRuleByUid(ctx, c, ruleUID)
+	if err != nil {
+		return backfillErrorResponse(err, "failed to get rule by UID")
+	}
+	// the backfill queries the data sources of the rule and writes its series, so the user must be able to update the rule
+	if err := srv.authorizeRuleUpdate(ctx, c, &rule); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to authorize rule backfill", err)
+	}
+	if rule.Record != nil && rule.Record.TargetDatasourceUID != "" {
+		if err := srv.authz.AuthorizeRecordingTarget(ctx, c.SignedInUser, rule.Record.TargetDatasourceUID); err != nil {
+			return response.ErrOrFallback(http.StatusInternalServerError, "failed to authorize rule backfill", err)
+		}
+	}
+
+	job, err := srv.backfiller.Start(ctx, &rule, cmd.From, cmd.To)
+	if err != nil {
+		return backfillErrorResponse(err, "failed to start backfill")
+	}
+	return response.JSON(http.StatusAccepted, toGettableRuleBackfill(job))
+}
+
+// RouteGetRuleBackfills returns the backfills of the recording rule with the given UID, the most recent first.
+func (srv RulerSrv) RouteGetRuleBackfills(c *contextmodel.ReqContext, ruleUID string) response.Response {
+	if srv.backfiller == nil {
+		return ErrResp(http.StatusNotFound, errors.New("recording rules are not enabled"), "")
+	}
+	ctx := c.Req.Context()
+	rule, err := srv.getAuthorizedRuleByUid(ctx, c, ruleUID)
+	if err != nil {
+		return backfillErrorResponse(err, "failed to get rule by UID")
+	}
+	jobs, err := srv.backfiller.List(ctx, rule.OrgID, rule.UID)
+	if err != nil {
+		return backfillErrorResponse(err, "failed to list backfills")
+	}
+	result := make(apimodels.GettableRuleBackfills, 0, len(jobs))
+	for _, job := range jobs {
+		result = append(result, toGettableRuleBackfill(job))
+	}
+	return response.JSON(http.StatusOK, result)
+}
+
+// RouteGetRuleBackfill returns the backfill with the given ID of the recording rule with the given UID.
+func (srv RulerSrv) RouteGetRuleBackfill(c *contextmodel.ReqContext, ruleUID string, backfillID string) response.Response {
+	job, err := srv.getRuleBackfill(c, ruleUID, backfillID, false)
+	if err != nil {
+		return backfillErrorResponse(err, "failed to get backfill")
+	}
+	return response.JSON(http.StatusOK, toGettableRuleBackfill(job))
+}
+
+// RoutePostRuleBackfillCancel cancels the backfill with the given ID of the recording rule with the given UID.
+func (srv RulerSrv) RoutePostRuleBackfillCancel(c *contextmodel.ReqContext, ruleUID string, backfillID string) response.Response {
+	job, err := srv.getRuleBackfill(c, ruleUID, backfillID, true)
+	if err == nil {
+		job, err = srv.backfiller.Cancel(c.Req.Context(), job.OrgID, job.ID)
+	}
+	if err != nil {
+		return backfillErrorResponse(err, "failed to cancel backfill")
+	}
+	return response.JSON(http.StatusAccepted, toGettableRuleBackfill(job))
+}
+
+// RoutePostRuleBackfillResume resumes the failed or canceled backfill with the given ID of the recording rule with the given UID.
+func (srv RulerSrv) RoutePostRuleBackfillResume(c *contextmodel.ReqContext, ruleUID string, backfillID string) response.Response {
+	job, err := srv.getRuleBackfill(c, ruleUID, backfillID, true)
+	if err == nil {
+		job, err = srv.backfiller.Resume(c.Req.Context(), job.OrgID, job.ID)
+	}
+	if err != nil {
+		return backfillErrorResponse(err, "failed to resume backfill")
+	}
+	return response.JSON(http.StatusAccepted, toGettableRuleBackfill(job))
+}
+
+// getRuleBackfill returns the backfill of the rule if the user can read the rule, or update it if update is true.
+func (srv RulerSrv) getRuleBackfill(c *contextmodel.ReqContext, ruleUID string, backfillID string, update bool) (backtesting.BackfillJob, error) {
+	if srv.backfiller == nil {
+		return backtesting.BackfillJob{}, backtesting.ErrBackfillNotFound
+	}
+	ctx := c.Req.Context()
+	rule, err := srv.getAuthorizedRuleByUid(ctx, c, ruleUID)
+	if err != nil {
+		return backtesting.BackfillJob{}, err
+	}
+	if update {
+		if err := srv.authorizeRuleUpdate(ctx, c, &rule); err != nil {
+			return backtesting.BackfillJob{}, err
+		}
+	}
+	job, err := srv.backfiller.Get(ctx, rule.OrgID, backfillID)
+	if err != nil {
+		return backtesting.BackfillJob{}, err
+	}
+	if job.RuleUID != rule.UID {
+		return backtesting.BackfillJob{}, backtesting.ErrBackfillNotFound
+	}
+	return job, nil
+}
+
+func backfillErrorResponse(err error, msg string) response.Response {
+	switch {
+	case errors.Is(err, ngmodels.ErrAlertRuleNotFound), errors.Is(err, backtesting.ErrBackfillNotFound):
+		return response.Empty(http.StatusNotFound)
+	case errors.Is(err, backtesting.ErrInvalidInputData):
+		return ErrResp(http.StatusBadRequest, err, "")
+	case errors.Is(err, backtesting.ErrBackfillConflict):
+		return ErrResp(http.StatusConflict, err, "")
+	}
+	return response.ErrOrFallback(http.StatusInternalServerError, msg, err)
+}
+
+// recordingTargetValidator returns a function that checks that the target data source of a recording rule is
+// a Prometheus data source the user is allowed to write to.
+func (srv RulerSrv) recordingTargetValidator(c *contextmodel.ReqContext) func(datasourceUID string) error {
+	return func(datasourceUID string) error {
+		ctx := c.Req.Context()
+		ds, err := srv.datasourceCache.GetDatasourceByUID(ctx, datasourceUID, c.SignedInUser, false)
+		if err != nil {
+			return fmt.Errorf("failed to get target data source '%s': %w", datasourceUID, err)
+		}
+		if ds.Type != datasources.DS_PROMETHEUS {
+			return fmt.Errorf("target data source '%s' must be of type %s, got %s", datasourceUID, datasources.DS_PROMETHEUS, ds.Type)
+		}
+		return srv.authz.AuthorizeRecordingTarget(ctx, c.SignedInUser, datasourceUID)
+	}
+}
+
+// authorizeRuleUpdate checks that the user is allowed to update the rule without changing it.
+func (srv RulerSrv) authorizeRuleUpdate(ctx context.Context, c *contextmodel.ReqContext, rule *ngmodels.AlertRule) error {
+	delta, err := srv.ruleUpdateDelta(ctx, rule, rule)
+	if err != nil {
+		return err
+	}
+	delta.Update = append(delta.Update, store.RuleDelta{Existing: rule, New: rule})
+	return srv.authz.AuthorizeRuleChanges(ctx, c.SignedInUser, delta)
+}
+
+// RoutePostRulePause pauses the rule with the given UID until the given time. The scheduler resumes the rule when the time comes.
+// The change is recorded in the versions of the rule, and the state of the rule is reset with the reason models.StateReasonPausedUntil.
+func (srv RulerSrv) RoutePostRulePause(c *contextmodel.ReqContext, pause apimodels.PostableRulePause, ruleUID string) response.Response {
+	if !pause.Until.After(time.Now()) {
+		return ErrResp(http.StatusBadRequest, errors.New("the time until which the rule is paused must be in the future"), "")
+	}
+	until := pause.Until.UTC()
+
+	var delta *store.GroupDelta
+	err := srv.xactManager.InTransaction(c.Req.Context(), func(ctx context.Context) error {
+		rule, err := srv.getAuthorizedRuleByUid(ctx, c, ruleUID)
+		if err != nil {
+			return err
+		}
+		paused := rule.Copy()
+		paused.IsPaused = true
+		paused.Metadata.PausedUntil = &until
+
+		delta, err = srv.ruleUpdateDelta(ctx, &rule, paused)
+		if err != nil {
+			return err
+		}
+		if delta.IsEmpty() {
+			return nil
+		}
+		if err := srv.authz.AuthorizeRuleChanges(ctx, c.SignedInUser, delta); err != nil {
+			return err
+		}
+		if err := verifyProvisionedRulesNotAffected(ctx, srv.provenanceStore, rule.OrgID, delta); err != nil {
+			return err
+		}
+		return srv.store.UpdateAlertRules(ctx, ngmodels.NewUserUID(c.SignedInUser), []ngmodels.UpdateRule{{
+			Existing: &rule,
+			New:      *paused,
+		}})
+	})
+	if err != nil {
+		if errors.As(err, &errutil.Error{}) {
+			return response.Err(err)
+		} else if errors.Is(err, ngmodels.ErrAlertRuleNotFound) {
+			return response.Empty(http.StatusNotFound)
+		} else if errors.Is(err, errProvisionedResource) {
+			return ErrResp(http.StatusBadRequest, err, "failed to pause rule")
+		} else if errors.Is(err, store.ErrOptimisticLock) {
+			return ErrResp(http.StatusConflict, err, "")
+		}
+		return ErrResp(http.StatusInternalServerError, err, "failed to pause rule")
+	}
+	return changesToResponse(delta)
+}
+
+// ruleUpdateDelta returns the changes of the rule group caused by the update of a single rule.
+func (srv RulerSrv) ruleUpdateDelta(ctx context.Context, existing *ngmodels.AlertRule, updated *ngmodels.AlertRule) (*store.GroupDelta, error) {
+	group, err := srv.store.GetAlertRulesGroupByRuleUID(ctx, &ngmodels.GetAlertRulesGroupByRuleUIDQuery{UID: existing.UID, OrgID: existing.OrgID})
+	if err != nil {
+		return nil, err
+	}
+	delta := &store.GroupDelta{
+		GroupKey:       existing.GetGroupKey(),
+		AffectedGroups: map[ngmodels.AlertRuleGroupKey]ngmodels.RulesGroup{existing.GetGroupKey(): group},
+	}
+	if diff := existing.Diff(updated, store.AlertRuleFieldsToIgnoreInDiff[:]...); len(diff) > 0 {
+		delta.Update = append(delta.Update, store.RuleDelta{Existing: existing, New: updated, Diff: diff})
+	}
+	return delta, nil
+}
+
 func (srv RulerSrv) RoutePostNameRulesConfig(c *contextmodel.ReqContext, ruleGroupConfig apimodels.PostableRuleGroupConfig, namespaceUID string) response.Response {
 	namespace, err := srv.store.GetNamespaceByUID(c.Req.Context(), namespaceUID, c.SignedInUser.GetOrgID(), c.SignedInUser)
 	if err != nil {
@@ -370,7 +682,9 @@ func (srv RulerSrv) RoutePostNameRulesConfig(c *contextmodel.ReqContext, ruleGro
 		return ErrResp(http.StatusBadRequest, err, "")
 	}
 
-	rules, err := ValidateRuleGroup(&ruleGroupConfig, c.SignedInUser.GetOrgID(), namespace.UID, RuleLimitsFromConfig(srv.cfg, srv.featureManager))
+	limits := RuleLimitsFromConfig(srv.cfg, srv.featureManager)
+	limits.ValidateRecordingTarget = srv.recordingTargetValidator(c)
+	rules, err := ValidateRuleGroup(&ruleGroupConfig, c.SignedInUser.GetOrgID(), namespace.UID, limits)
 	if err != nil {
 		return ErrResp(http.StatusBadRequest, err, "")
 	}
@@ -615,9 +929,69 @@ func toGettableExtendedRuleNode(r ngmodels.AlertRule, provenanceRecords map[stri
 		Annotations: r.Annotations,
 		Labels:      r.Labels,
 	}
+	if r.KeepFiringFor > 0 {
+		keepFiringFor := model.Duration(r.KeepFiringFor)
+		gettableExtendedRuleNode.ApiRuleNode.KeepFiringFor = &keepFiringFor
+	}
 	return gettableExtendedRuleNode
 }
 
+func toGettableRuleBackfill(job backtesting.BackfillJob) apimodels.GettableRuleBackfill {
+	return apimodels.GettableRuleBackfill{
+		ID:              job.ID,
+		RuleUID:         job.RuleUID,
+		From:            job.From,
+		To:              job.To,
+		IntervalSeconds: job.IntervalSeconds,
+		Status:          string(job.Status),
+		Evaluations:     job.Evaluations,
+		Completed:       job.Completed,
+		Failed:          job.Failed,
+		Error:           job.Error,
+		CreatedAt:       job.CreatedAt,
+		UpdatedAt:       job.UpdatedAt,
+	}
+}
+
+func toRuleEvaluationTrace(t schedule.EvaluationTrace) apimodels.RuleEvaluationTrace {
+	result := apimodels.RuleEvaluationTrace{
+		ScheduledAt: t.ScheduledAt,
+		Duration:    t.Duration.String(),
+	}
+	if t.Error != nil {
+		result.Error = t.Error.Error()
+	}
+	for _, sent := range t.SentAlerts {
+		alerts := make(amv2.PostableAlerts, 0, len(sent.PostableAlerts))
+		for i := range sent.PostableAlerts {
+			alerts = append(alerts, &sent.PostableAlerts[i])
+		}
+		result.SentAlerts = append(result.SentAlerts, alerts)
+	}
+	if len(t.Responses) > 0 {
+		result.Responses = make(map[string]apimodels.RuleQueryTrace, len(t.Responses))
+		for refID, resp := range t.Responses {
+			query := apimodels.RuleQueryTrace{Frames: resp.Frames}
+			if resp.Error != nil {
+				query.Error = resp.Error.Error()
+			}
+			result.Responses[refID] = query
+		}
+	}
+	if len(t.Results) > 0 {
+		frame := t.Results.AsDataFrame()
+		result.Results = &frame
+	}
+	for _, transition := range t.Transitions {
+		result.Transitions = append(result.Transitions, apimodels.RuleStateTransition{
+			Labels:        transition.Labels,
+			PreviousState: transition.PreviousFormatted(),
+			State:         transition.Formatted(),
+		})
+	}
+	return result
+}
+
 func toNamespaceErrorResponse(err error) response.Response {
 	if errors.Is(err, ngmodels.ErrCannotEditNamespace) {
 		return ErrResp(http.StatusForbidden, err, err.Error())
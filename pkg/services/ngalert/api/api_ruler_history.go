@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/infra/log"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
 )
 
@@ -17,6 +19,11 @@ type Historian interface {
 	Query(ctx context.Context, query models.HistoryQuery) (*data.Frame, error)
 }
 
+// HistoryAggregator is implemented by state history backends that can aggregate state history.
+type HistoryAggregator interface {
+	Aggregate(ctx context.Context, query models.HistoryAggregationQuery) (*data.Frame, error)
+}
+
 type HistorySrv struct {
 	logger log.Logger
 	hist   Historian
@@ -39,20 +46,73 @@ func (srv *HistorySrv) RouteQueryStateHistory(c *contextmodel.ReqContext) respon
 		}
 	}
 
+	previousState, err := parseStateFilter(c.Query("previousState"))
+	if err != nil {
+		return ErrResp(http.StatusBadRequest, err, "invalid previousState")
+	}
+	currentState, err := parseStateFilter(c.Query("currentState"))
+	if err != nil {
+		return ErrResp(http.StatusBadRequest, err, "invalid currentState")
+	}
+
 	query := models.HistoryQuery{
-		RuleUID:      ruleUID,
-		OrgID:        c.SignedInUser.GetOrgID(),
-		DashboardUID: dashUID,
-		PanelID:      panelID,
-		SignedInUser: c.SignedInUser,
-		From:         time.Unix(from, 0),
-		To:           time.Unix(to, 0),
-		Limit:        limit,
-		Labels:       labels,
+		RuleUID:       ruleUID,
+		OrgID:         c.SignedInUser.GetOrgID(),
+		DashboardUID:  dashUID,
+		PanelID:       panelID,
+		SignedInUser:  c.SignedInUser,
+		From:          time.Unix(from, 0),
+		To:            time.Unix(to, 0),
+		Limit:         limit,
+		Labels:        labels,
+		PreviousState: previousState,
+		CurrentState:  currentState,
+		Reason:        c.Query("reason"),
+		ContinueToken: c.Query("continueToken"),
+	}
+
+	if aggregation := c.Query("aggregation"); aggregation != "" {
+		return srv.aggregate(c, models.HistoryAggregationQuery{
+			HistoryQuery: query,
+			Type:         models.HistoryAggregationType(aggregation),
+			Interval:     time.Duration(c.QueryInt64("interval")) * time.Second,
+			TopN:         c.QueryInt("topN"),
+		})
 	}
+
 	frame, err := srv.hist.Query(c.Req.Context(), query)
 	if err != nil {
-		return ErrResp(http.StatusInternalServerError, err, "")
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to query state history", err)
 	}
 	return response.JSON(http.StatusOK, frame)
 }
+
+func (srv *HistorySrv) aggregate(c *contextmodel.ReqContext, query models.HistoryAggregationQuery) response.Response {
+	if !query.Type.IsValid() {
+		return ErrResp(http.StatusBadRequest, fmt.Errorf("unknown aggregation %q", query.Type), "")
+	}
+	if query.Interval < 0 || query.TopN < 0 {
+		return ErrResp(http.StatusBadRequest, fmt.Errorf("interval and topN must not be negative"), "")
+	}
+	aggregator, ok := srv.hist.(HistoryAggregator)
+	if !ok {
+		return ErrResp(http.StatusNotImplemented, fmt.Errorf("state history backend does not support aggregations"), "")
+	}
+	frame, err := aggregator.Aggregate(c.Req.Context(), query)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to aggregate state history", err)
+	}
+	return response.JSON(http.StatusOK, frame)
+}
+
+// parseStateFilter returns the canonical name of the state. It returns an empty string if the filter is empty.
+func parseStateFilter(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	st, err := eval.ParseStateString(s)
+	if err != nil {
+		return "", err
+	}
+	return st.String(), nil
+}
@@ -28,8 +28,10 @@ import (
 	"github.com/grafana/grafana/pkg/services/folder"
 	"github.com/grafana/grafana/pkg/services/ngalert/accesscontrol"
 	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/backtesting"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
 	"github.com/grafana/grafana/pkg/services/ngalert/provisioning"
+	"github.com/grafana/grafana/pkg/services/ngalert/schedule"
 	"github.com/grafana/grafana/pkg/services/ngalert/store"
 	"github.com/grafana/grafana/pkg/services/ngalert/tests/fakes"
 	"github.com/grafana/grafana/pkg/services/user"
@@ -573,6 +575,372 @@ func TestRouteGetRuleHistoryByUID(t *testing.T) {
 	})
 }
 
+func TestRoutePostRulePause(t *testing.T) {
+	orgID := rand.Int63()
+	folder := randFolder()
+	ruleStore := fakes.NewRuleStore(t)
+	ruleStore.Folders[orgID] = append(ruleStore.Folders[orgID], folder)
+	groupKey := models.GenerateGroupKey(orgID)
+	groupKey.NamespaceUID = folder.UID
+	gen := models.RuleGen.With(models.RuleGen.WithGroupKey(groupKey), models.RuleGen.WithIsPaused(false))
+	rule := gen.GenerateRef()
+	ruleStore.PutRule(context.Background(), rule)
+
+	perms := createPermissionsForRules([]*models.AlertRule{rule}, orgID)
+	scope := dashboards.ScopeFoldersProvider.GetResourceScopeUID(rule.NamespaceUID)
+	perms[orgID][ac.ActionAlertingRuleUpdate] = []string{scope}
+
+	t.Run("should pause the rule until the given time", func(t *testing.T) {
+		ruleStore.RecordedOps = nil
+		until := time.Now().Add(time.Hour)
+
+		response := createService(ruleStore).RoutePostRulePause(createRequestContextWithPerms(orgID, perms, nil), apimodels.PostableRulePause{Until: until}, rule.UID)
+
+		require.Equal(t, http.StatusAccepted, response.Status())
+		updates := ruleStore.GetRecordedCommands(func(cmd any) (any, bool) {
+			c, ok := cmd.([]models.UpdateRule)
+			return c, ok
+		})
+		require.Len(t, updates, 1)
+		update := updates[0].([]models.UpdateRule)
+		require.Len(t, update, 1)
+		assert.True(t, update[0].New.IsPaused)
+		require.NotNil(t, update[0].New.Metadata.PausedUntil)
+		assert.True(t, until.Equal(*update[0].New.Metadata.PausedUntil))
+	})
+
+	t.Run("should reject time in the past", func(t *testing.T) {
+		ruleStore.RecordedOps = nil
+
+		response := createService(ruleStore).RoutePostRulePause(createRequestContextWithPerms(orgID, perms, nil), apimodels.PostableRulePause{Until: time.Now().Add(-time.Minute)}, rule.UID)
+
+		require.Equal(t, http.StatusBadRequest, response.Status())
+		require.Empty(t, ruleStore.RecordedOps)
+	})
+
+	t.Run("should require permission to update the rule", func(t *testing.T) {
+		ruleStore.RecordedOps = nil
+		readOnly := createPermissionsForRules([]*models.AlertRule{rule}, orgID)
+
+		response := createService(ruleStore).RoutePostRulePause(createRequestContextWithPerms(orgID, readOnly, nil), apimodels.PostableRulePause{Until: time.Now().Add(time.Hour)}, rule.UID)
+
+		require.Equal(t, http.StatusForbidden, response.Status())
+	})
+}
+
+func TestRoutePostRuleEvaluation(t *testing.T) {
+	orgID := rand.Int63()
+	folder := randFolder()
+	ruleStore := fakes.NewRuleStore(t)
+	ruleStore.Folders[orgID] = append(ruleStore.Folders[orgID], folder)
+	groupKey := models.GenerateGroupKey(orgID)
+	groupKey.NamespaceUID = folder.UID
+	rule := models.RuleGen.With(models.RuleGen.WithGroupKey(groupKey)).GenerateRef()
+	ruleStore.PutRule(context.Background(), rule)
+
+	perms := createPermissionsForRules([]*models.AlertRule{rule}, orgID)
+	perms[orgID][ac.ActionAlertingRuleUpdate] = []string{dashboards.ScopeFoldersProvider.GetResourceScopeUID(rule.NamespaceUID)}
+
+	testCases := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{name: "evaluation is requested", expectedStatus: http.StatusAccepted},
+		{name: "rule is paused", err: schedule.ErrRulePaused, expectedStatus: http.StatusConflict},
+		{name: "rule is not scheduled", err: schedule.ErrRuleNotScheduled, expectedStatus: http.StatusConflict},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheduler := &fakeRuleScheduler{err: tc.err}
+			svc := createService(ruleStore)
+			svc.scheduler = scheduler
+
+			response := svc.RoutePostRuleEvaluation(createRequestContextWithPerms(orgID, perms, nil), rule.UID)
+
+			require.Equal(t, tc.expectedStatus, response.Status())
+			require.Equal(t, []models.AlertRuleKey{rule.GetKey()}, scheduler.evaluated)
+		})
+	}
+}
+
+func TestRouteRuleDebug(t *testing.T) {
+	orgID := rand.Int63()
+	folder := randFolder()
+	ruleStore := fakes.NewRuleStore(t)
+	ruleStore.Folders[orgID] = append(ruleStore.Folders[orgID], folder)
+	groupKey := models.GenerateGroupKey(orgID)
+	groupKey.NamespaceUID = folder.UID
+	rule := models.RuleGen.With(models.RuleGen.WithGroupKey(groupKey)).GenerateRef()
+	ruleStore.PutRule(context.Background(), rule)
+
+	readOnly := createPermissionsForRules([]*models.AlertRule{rule}, orgID)
+	perms := createPermissionsForRules([]*models.AlertRule{rule}, orgID)
+	perms[orgID][ac.ActionAlertingRuleUpdate] = []string{dashboards.ScopeFoldersProvider.GetResourceScopeUID(rule.NamespaceUID)}
+
+	t.Run("should enable debug mode with the default number of evaluations", func(t *testing.T) {
+		scheduler := &fakeRuleScheduler{}
+		svc := createService(ruleStore)
+		svc.scheduler = scheduler
+
+		response := svc.RoutePostRuleDebug(createRequestContextWithPerms(orgID, perms, nil), apimodels.PostableRuleDebug{Enabled: true}, rule.UID)
+
+		require.Equal(t, http.StatusAccepted, response.Status())
+		require.Equal(t, schedule.DefaultDebugEvaluations, scheduler.debug[rule.GetKey()])
+	})
+
+	t.Run("should disable debug mode", func(t *testing.T) {
+		scheduler := &fakeRuleScheduler{debug: map[models.AlertRuleKey]int{rule.GetKey(): 5}}
+		svc := createService(ruleStore)
+		svc.scheduler = scheduler
+
+		response := svc.RoutePostRuleDebug(createRequestContextWithPerms(orgID, perms, nil), apimodels.PostableRuleDebug{Enabled: false, Evaluations: 5}, rule.UID)
+
+		require.Equal(t, http.StatusAccepted, response.Status())
+		require.Zero(t, scheduler.debug[rule.GetKey()])
+	})
+
+	t.Run("should return 400 if number of evaluations is out of range", func(t *testing.T) {
+		svc := createService(ruleStore)
+		svc.scheduler = &fakeRuleScheduler{}
+
+		response := svc.RoutePostRuleDebug(createRequestContextWithPerms(orgID, perms, nil), apimodels.PostableRuleDebug{Enabled: true, Evaluations: schedule.MaxDebugEvaluations + 1}, rule.UID)
+
+		require.Equal(t, http.StatusBadRequest, response.Status())
+	})
+
+	t.Run("should return 403 if user cannot update the rule", func(t *testing.T) {
+		scheduler := &fakeRuleScheduler{}
+		svc := createService(ruleStore)
+		svc.scheduler = scheduler
+
+		response := svc.RoutePostRuleDebug(createRequestContextWithPerms(orgID, readOnly, nil), apimodels.PostableRuleDebug{Enabled: true}, rule.UID)
+
+		require.Equal(t, http.StatusForbidden, response.Status())
+		require.Empty(t, scheduler.debug)
+	})
+
+	t.Run("should return recorded evaluations", func(t *testing.T) {
+		scheduledAt := time.Now().UTC().Truncate(time.Second)
+		scheduler := &fakeRuleScheduler{
+			debug: map[models.AlertRuleKey]int{rule.GetKey(): 5},
+			traces: []schedule.EvaluationTrace{
+				{ScheduledAt: scheduledAt, Duration: time.Second, Error: errors.New("test")},
+			},
+		}
+		svc := createService(ruleStore)
+		svc.scheduler = scheduler
+
+		response := svc.RouteGetRuleDebug(createRequestContextWithPerms(orgID, readOnly, nil), rule.UID)
+
+		require.Equal(t, http.StatusOK, response.Status())
+		var result apimodels.GettableRuleDebug
+		require.NoError(t, json.Unmarshal(response.Body(), &result))
+		require.True(t, result.Enabled)
+		require.Len(t, result.Evaluations, 1)
+		assert.Equal(t, scheduledAt, result.Evaluations[0].ScheduledAt)
+		assert.Equal(t, "1s", result.Evaluations[0].Duration)
+		assert.Equal(t, "test", result.Evaluations[0].Error)
+	})
+
+	t.Run("should return disabled if rule is not in debug mode", func(t *testing.T) {
+		svc := createService(ruleStore)
+		svc.scheduler = &fakeRuleScheduler{}
+
+		response := svc.RouteGetRuleDebug(createRequestContextWithPerms(orgID, readOnly, nil), rule.UID)
+
+		require.Equal(t, http.StatusOK, response.Status())
+		var result apimodels.GettableRuleDebug
+		require.NoError(t, json.Unmarshal(response.Body(), &result))
+		require.False(t, result.Enabled)
+		require.Empty(t, result.Evaluations)
+	})
+}
+
+type fakeRuleScheduler struct {
+	err       error
+	evaluated []models.AlertRuleKey
+	debug     map[models.AlertRuleKey]int
+	traces    []schedule.EvaluationTrace
+}
+
+func (f *fakeRuleScheduler) EvaluateNow(key models.AlertRuleKey) error {
+	f.evaluated = append(f.evaluated, key)
+	return f.err
+}
+
+func (f *fakeRuleScheduler) SetRuleDebug(key models.AlertRuleKey, evaluations int) {
+	if f.debug == nil {
+		f.debug = make(map[models.AlertRuleKey]int)
+	}
+	f.debug[key] = evaluations
+}
+
+func (f *fakeRuleScheduler) RuleEvaluationTraces(key models.AlertRuleKey) ([]schedule.EvaluationTrace, bool) {
+	if f.debug[key] == 0 {
+		return nil, false
+	}
+	return f.traces, true
+}
+
+func TestRouteRuleBackfill(t *testing.T) {
+	orgID := rand.Int63()
+	folder := randFolder()
+	ruleStore := fakes.NewRuleStore(t)
+	ruleStore.Folders[orgID] = append(ruleStore.Folders[orgID], folder)
+	groupKey := models.GenerateGroupKey(orgID)
+	groupKey.NamespaceUID = folder.UID
+	rule := models.RuleGen.With(models.RuleGen.WithGroupKey(groupKey), models.RuleGen.WithAllRecordingRules()).GenerateRef()
+	rule.Record.TargetDatasourceUID = ""
+	ruleStore.PutRule(context.Background(), rule)
+
+	readOnly := createPermissionsForRules([]*models.AlertRule{rule}, orgID)
+	perms := createPermissionsForRules([]*models.AlertRule{rule}, orgID)
+	perms[orgID][ac.ActionAlertingRuleUpdate] = []string{dashboards.ScopeFoldersProvider.GetResourceScopeUID(rule.NamespaceUID)}
+
+	from := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	to := from.Add(30 * time.Minute)
+	job := backtesting.BackfillJob{ID: "test", OrgID: orgID, RuleUID: rule.UID, From: from, To: to, Status: backtesting.BackfillRunning}
+
+	t.Run("should start backfill", func(t *testing.T) {
+		backfiller := &fakeRuleBackfiller{jobs: []backtesting.BackfillJob{job}}
+		svc := createService(ruleStore)
+		svc.backfiller = backfiller
+
+		response := svc.RoutePostRuleBackfill(createRequestContextWithPerms(orgID, perms, nil), apimodels.PostableRuleBackfill{From: from, To: to}, rule.UID)
+
+		require.Equal(t, http.StatusAccepted, response.Status())
+		require.Equal(t, []string{rule.UID}, backfiller.started)
+		var result apimodels.GettableRuleBackfill
+		require.NoError(t, json.Unmarshal(response.Body(), &result))
+		assert.Equal(t, "test", result.ID)
+		assert.Equal(t, string(backtesting.BackfillRunning), result.Status)
+	})
+
+	t.Run("should map errors of the backfiller", func(t *testing.T) {
+		testCases := map[error]int{
+			backtesting.ErrInvalidInputData: http.StatusBadRequest,
+			backtesting.ErrBackfillConflict: http.StatusConflict,
+			backtesting.ErrBackfillNotFound: http.StatusNotFound,
+		}
+		for err, status := range testCases {
+			svc := createService(ruleStore)
+			svc.backfiller = &fakeRuleBackfiller{err: err}
+
+			response := svc.RoutePostRuleBackfill(createRequestContextWithPerms(orgID, perms, nil), apimodels.PostableRuleBackfill{From: from, To: to}, rule.UID)
+
+			require.Equalf(t, status, response.Status(), "unexpected status for error %s", err)
+		}
+	})
+
+	t.Run("should return 403 if user cannot update the rule", func(t *testing.T) {
+		backfiller := &fakeRuleBackfiller{}
+		svc := createService(ruleStore)
+		svc.backfiller = backfiller
+
+		response := svc.RoutePostRuleBackfill(createRequestContextWithPerms(orgID, readOnly, nil), apimodels.PostableRuleBackfill{From: from, To: to}, rule.UID)
+		require.Equal(t, http.StatusForbidden, response.Status())
+		response = svc.RoutePostRuleBackfillCancel(createRequestContextWithPerms(orgID, readOnly, nil), rule.UID, job.ID)
+		require.Equal(t, http.StatusForbidden, response.Status())
+		require.Empty(t, backfiller.started)
+	})
+
+	t.Run("should return 404 if recording rules are not enabled", func(t *testing.T) {
+		svc := createService(ruleStore)
+
+		response := svc.RoutePostRuleBackfill(createRequestContextWithPerms(orgID, perms, nil), apimodels.PostableRuleBackfill{From: from, To: to}, rule.UID)
+		require.Equal(t, http.StatusNotFound, response.Status())
+		response = svc.RouteGetRuleBackfill(createRequestContextWithPerms(orgID, readOnly, nil), rule.UID, job.ID)
+		require.Equal(t, http.StatusNotFound, response.Status())
+	})
+
+	t.Run("should list and get backfills of the rule", func(t *testing.T) {
+		other := job
+		other.ID = "other"
+		other.RuleUID = "other-rule"
+		svc := createService(ruleStore)
+		svc.backfiller = &fakeRuleBackfiller{jobs: []backtesting.BackfillJob{job, other}}
+
+		response := svc.RouteGetRuleBackfills(createRequestContextWithPerms(orgID, readOnly, nil), rule.UID)
+		require.Equal(t, http.StatusOK, response.Status())
+		var result apimodels.GettableRuleBackfills
+		require.NoError(t, json.Unmarshal(response.Body(), &result))
+		require.Len(t, result, 1)
+		assert.Equal(t, job.ID, result[0].ID)
+
+		response = svc.RouteGetRuleBackfill(createRequestContextWithPerms(orgID, readOnly, nil), rule.UID, job.ID)
+		require.Equal(t, http.StatusOK, response.Status())
+		// backfills of other rules are not accessible via the rule
+		response = svc.RouteGetRuleBackfill(createRequestContextWithPerms(orgID, readOnly, nil), rule.UID, other.ID)
+		require.Equal(t, http.StatusNotFound, response.Status())
+	})
+
+	t.Run("should cancel and resume backfill", func(t *testing.T) {
+		backfiller := &fakeRuleBackfiller{jobs: []backtesting.BackfillJob{job}}
+		svc := createService(ruleStore)
+		svc.backfiller = backfiller
+
+		response := svc.RoutePostRuleBackfillCancel(createRequestContextWithPerms(orgID, perms, nil), rule.UID, job.ID)
+		require.Equal(t, http.StatusAccepted, response.Status())
+		require.Equal(t, backtesting.BackfillCanceled, backfiller.jobs[0].Status)
+
+		response = svc.RoutePostRuleBackfillResume(createRequestContextWithPerms(orgID, perms, nil), rule.UID, job.ID)
+		require.Equal(t, http.StatusAccepted, response.Status())
+		require.Equal(t, backtesting.BackfillPending, backfiller.jobs[0].Status)
+	})
+}
+
+type fakeRuleBackfiller struct {
+	err     error
+	jobs    []backtesting.BackfillJob
+	started []string
+}
+
+func (f *fakeRuleBackfiller) Start(_ context.Context, rule *models.AlertRule, _, _ time.Time) (backtesting.BackfillJob, error) {
+	if f.err != nil {
+		return backtesting.BackfillJob{}, f.err
+	}
+	f.started = append(f.started, rule.UID)
+	return f.jobs[0], nil
+}
+
+func (f *fakeRuleBackfiller) Get(_ context.Context, orgID int64, id string) (backtesting.BackfillJob, error) {
+	for _, job := range f.jobs {
+		if job.OrgID == orgID && job.ID == id {
+			return job, nil
+		}
+	}
+	return backtesting.BackfillJob{}, backtesting.ErrBackfillNotFound
+}
+
+func (f *fakeRuleBackfiller) List(_ context.Context, orgID int64, ruleUID string) ([]backtesting.BackfillJob, error) {
+	var result []backtesting.BackfillJob
+	for _, job := range f.jobs {
+		if job.OrgID == orgID && job.RuleUID == ruleUID {
+			result = append(result, job)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeRuleBackfiller) Cancel(ctx context.Context, orgID int64, id string) (backtesting.BackfillJob, error) {
+	return f.setStatus(ctx, orgID, id, backtesting.BackfillCanceled)
+}
+
+func (f *fakeRuleBackfiller) Resume(ctx context.Context, orgID int64, id string) (backtesting.BackfillJob, error) {
+	return f.setStatus(ctx, orgID, id, backtesting.BackfillPending)
+}
+
+func (f *fakeRuleBackfiller) setStatus(_ context.Context, orgID int64, id string, status backtesting.BackfillStatus) (backtesting.BackfillJob, error) {
+	for i, job := range f.jobs {
+		if job.OrgID == orgID && job.ID == id {
+			f.jobs[i].Status = status
+			return f.jobs[i], nil
+		}
+	}
+	return backtesting.BackfillJob{}, backtesting.ErrBackfillNotFound
+}
+
 func TestRouteGetRulesConfig(t *testing.T) {
 	gen := models.RuleGen
 	t.Run("fine-grained access is enabled", func(t *testing.T) {
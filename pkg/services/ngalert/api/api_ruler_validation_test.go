@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"path"
 	"strconv"
@@ -400,6 +401,30 @@ func TestValidateRuleNode_NoUID(t *testing.T) {
 				require.Nil(t, alert.Labels)
 			},
 		},
+		{
+			name: "converts keep_firing_for",
+			rule: func() *apimodels.PostableExtendedRuleNode {
+				r := validRule()
+				r.ApiRuleNode.KeepFiringFor = util.Pointer(model.Duration(5 * time.Minute))
+				return &r
+			},
+			assert: func(t *testing.T, api *apimodels.PostableExtendedRuleNode, alert *models.AlertRule) {
+				require.Equal(t, 5*time.Minute, alert.KeepFiringFor)
+			},
+		},
+		{
+			name:   "recording rules ignore keep_firing_for",
+			limits: allowRecording(limits),
+			rule: func() *apimodels.PostableExtendedRuleNode {
+				r := validRule()
+				r.GrafanaManagedAlert.Record = &apimodels.Record{Metric: "some_metric", From: "A"}
+				r.ApiRuleNode.KeepFiringFor = util.Pointer(model.Duration(5 * time.Minute))
+				return &r
+			},
+			assert: func(t *testing.T, api *apimodels.PostableExtendedRuleNode, alert *models.AlertRule) {
+				require.Zero(t, alert.KeepFiringFor)
+			},
+		},
 		{
 			name: "defaults to NoData if NoDataState is empty",
 			rule: func() *apimodels.PostableExtendedRuleNode {
@@ -720,6 +745,21 @@ func TestValidateRuleNodeFailures_NoUID(t *testing.T) {
 			},
 			expErr: "NOTEXIST does not exist",
 		},
+		{
+			name:   "rejects recording rule with unknown metric type",
+			limits: allowRecording(limits),
+			rule: func() *apimodels.PostableExtendedRuleNode {
+				r := validRule()
+				r.GrafanaManagedAlert.Record = &apimodels.Record{Metric: "my_metric", From: "A", Metadata: &apimodels.RecordMetadata{Type: "timer"}}
+				r.GrafanaManagedAlert.Condition = ""
+				r.GrafanaManagedAlert.NoDataState = ""
+				r.GrafanaManagedAlert.ExecErrState = ""
+				r.GrafanaManagedAlert.NotificationSettings = nil
+				r.ApiRuleNode.For = nil
+				return &r
+			},
+			expErr: "metric type for recording rule must be one of",
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -797,6 +837,17 @@ func TestValidateRuleNode_UID(t *testing.T) {
 				require.Equal(t, models.ExecutionErrorState(""), alert.ExecErrState)
 			},
 		},
+		{
+			name: "use -1 KeepFiringFor if it is not specified",
+			rule: func() *apimodels.PostableExtendedRuleNode {
+				r := validRule()
+				r.ApiRuleNode.KeepFiringFor = nil
+				return &r
+			},
+			assert: func(t *testing.T, api *apimodels.PostableExtendedRuleNode, alert *models.AlertRule) {
+				require.Equal(t, time.Duration(-1), alert.KeepFiringFor)
+			},
+		},
 		{
 			name: "use empty Condition and Data if they are empty",
 			rule: func() *apimodels.PostableExtendedRuleNode {
@@ -1122,3 +1173,49 @@ func TestValidateRuleNodeReservedLabels(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateRuleNodeRecordingTarget(t *testing.T) {
+	cfg := config(t)
+	recordingRule := func(target string) *apimodels.PostableExtendedRuleNode {
+		r := validRule()
+		r.GrafanaManagedAlert.Record = &apimodels.Record{Metric: "some_metric", From: "A", TargetDatasourceUID: target}
+		r.GrafanaManagedAlert.Condition = ""
+		return &r
+	}
+
+	t.Run("accepts target that passes validation", func(t *testing.T) {
+		limits := allowRecording(makeLimits(cfg))
+		var validated []string
+		limits.ValidateRecordingTarget = func(datasourceUID string) error {
+			validated = append(validated, datasourceUID)
+			return nil
+		}
+
+		alert, err := validateRuleNode(recordingRule("mimir"), util.GenerateShortUID(), cfg.BaseInterval, rand.Int63(), randFolder().UID, *limits)
+		require.NoError(t, err)
+		require.Equal(t, "mimir", alert.Record.TargetDatasourceUID)
+		require.Equal(t, []string{"mimir"}, validated)
+	})
+
+	t.Run("does not validate empty target", func(t *testing.T) {
+		limits := allowRecording(makeLimits(cfg))
+		limits.ValidateRecordingTarget = func(datasourceUID string) error {
+			return errors.New("unexpected validation")
+		}
+
+		alert, err := validateRuleNode(recordingRule(""), util.GenerateShortUID(), cfg.BaseInterval, rand.Int63(), randFolder().UID, *limits)
+		require.NoError(t, err)
+		require.Empty(t, alert.Record.TargetDatasourceUID)
+	})
+
+	t.Run("rejects target that fails validation", func(t *testing.T) {
+		limits := allowRecording(makeLimits(cfg))
+		limits.ValidateRecordingTarget = func(datasourceUID string) error {
+			return errors.New("user is not allowed to write to data source")
+		}
+
+		_, err := validateRuleNode(recordingRule("mimir"), util.GenerateShortUID(), cfg.BaseInterval, rand.Int63(), randFolder().UID, *limits)
+		require.ErrorIs(t, err, models.ErrAlertRuleFailedValidation)
+		require.ErrorContains(t, err, "not allowed to write")
+	})
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/benbjohnson/clock"
 	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	alertmanager_config "github.com/prometheus/alertmanager/config"
 
 	"github.com/grafana/alerting/models"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
@@ -20,25 +21,42 @@ import (
 	"github.com/grafana/grafana/pkg/apimachinery/identity"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/tracing"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
 	"github.com/grafana/grafana/pkg/services/dashboards"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/folder"
+	"github.com/grafana/grafana/pkg/services/ngalert/accesscontrol"
 	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
 	"github.com/grafana/grafana/pkg/services/ngalert/backtesting"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/prom"
 	"github.com/grafana/grafana/pkg/services/ngalert/state"
 	"github.com/grafana/grafana/pkg/services/ngalert/store"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
 )
 
+// maxBacktestSweepCombinations limits the number of combinations of settings a single backtesting request can replay.
+const maxBacktestSweepCombinations = 100
+
 type folderService interface {
 	GetNamespaceByUID(ctx context.Context, uid string, orgID int64, user identity.Requester) (*folder.Folder, error)
 }
 
+// notificationPolicyProvider provides the notification configuration that is used to simulate notifications during backtesting.
+type notificationPolicyProvider interface {
+	GetAlertmanagerConfiguration(ctx context.Context, org int64, withAutogen bool) (apimodels.GettableUserConfig, error)
+	ListSilences(ctx context.Context, orgID int64, filter []string) ([]*ngmodels.Silence, error)
+}
+
+// notificationPreviewer routes alerts produced by testing a rule and renders the notifications they would cause.
+type notificationPreviewer interface {
+	PreviewNotifications(ctx context.Context, orgID int64, alerts []*amv2.PostableAlert, settings []ngmodels.NotificationSettings, now time.Time) (apimodels.NotificationsPreview, error)
+}
+
 type TestingApiSrv struct {
 	*AlertingProxy
 	DatasourceCache datasources.CacheService
@@ -51,11 +69,15 @@ type TestingApiSrv struct {
 	appUrl          *url.URL
 	tracer          tracing.Tracer
 	folderService   folderService
+	notifications   notificationPolicyProvider
+	preview         notificationPreviewer
 }
 
 // RouteTestGrafanaRuleConfig returns a list of potential alerts for a given rule configuration. This is intended to be
 // as true as possible to what would be generated by the ruler except that the resulting alerts are not filtered to
 // only Resolved / Firing and ready to send.
+// If preview of notifications is requested, the response also describes how the alerts are routed and what
+// notifications would be sent for them.
 func (srv TestingApiSrv) RouteTestGrafanaRuleConfig(c *contextmodel.ReqContext, body apimodels.PostableExtendedRuleNodeExtended) response.Response {
 	folder, err := srv.folderService.GetNamespaceByUID(c.Req.Context(), body.NamespaceUID, c.OrgID, c.SignedInUser)
 	if err != nil {
@@ -120,7 +142,25 @@ func (srv TestingApiSrv) RouteTestGrafanaRuleConfig(c *contextmodel.ReqContext,
 		alerts = append(alerts, state.StateToPostableAlert(alertState, srv.appUrl))
 	}
 
-	return response.JSON(http.StatusOK, alerts)
+	if !body.PreviewNotifications {
+		return response.JSON(http.StatusOK, alerts)
+	}
+
+	if srv.preview == nil {
+		return ErrResp(http.StatusNotImplemented, nil, "Preview of notifications is not available")
+	}
+	if err := srv.authorizeNotificationsRead(c, "preview notifications"); err != nil {
+		return errorToResponse(err)
+	}
+	preview, err := srv.preview.PreviewNotifications(c.Req.Context(), c.SignedInUser.GetOrgID(), alerts, rule.NotificationSettings, now)
+	if err != nil {
+		return errorToResponse(err)
+	}
+	return response.JSON(http.StatusOK, apimodels.TestGrafanaRulePreview{
+		Alerts:        alerts,
+		Routing:       preview.Routing,
+		Notifications: preview.Notifications,
+	})
 }
 
 func (srv TestingApiSrv) RouteTestRuleConfig(c *contextmodel.ReqContext, body apimodels.TestRulePayload, datasourceUID string) response.Response {
@@ -253,7 +293,6 @@ func (srv TestingApiSrv) BacktestAlertRule(c *contextmodel.ReqContext, cmd apimo
 		// ID:             0,
 		// Updated:        time.Time{},
 		// Version:        0,
-		// NamespaceUID:   "",
 		// DashboardUID:   nil,
 		// PanelID:        nil,
 		// RuleGroup:      "",
@@ -270,9 +309,50 @@ func (srv TestingApiSrv) BacktestAlertRule(c *contextmodel.ReqContext, cmd apimo
 		For:             forInterval,
 		Annotations:     cmd.Annotations,
 		Labels:          cmd.Labels,
+		NamespaceUID:    cmd.NamespaceUID,
+	}
+
+	var opts backtesting.TestOptions
+	if cmd.SimulateNotifications {
+		rule.NotificationSettings = NotificationSettingsFromAlertRuleNotificationSettings(cmd.NotificationSettings)
+		if len(rule.NotificationSettings) > 0 {
+			if err := rule.NotificationSettings[0].Validate(); err != nil {
+				return ErrResp(400, err, "Invalid notification settings")
+			}
+		}
+		opts, err = srv.notificationSimulationOptions(c, rule)
+		if err != nil {
+			return errorToResponse(err)
+		}
 	}
 
-	result, err := srv.backtesting.Test(c.Req.Context(), c.SignedInUser, rule, cmd.From, cmd.To)
+	opts.Summary = true
+	for _, alt := range cmd.Alternatives {
+		if alt.For < 0 || alt.KeepFiringFor < 0 {
+			return ErrResp(400, nil, "Bad alternative settings: durations must not be negative")
+		}
+		opts.Alternatives = append(opts.Alternatives, backtesting.Alternative{
+			For:           time.Duration(alt.For),
+			KeepFiringFor: time.Duration(alt.KeepFiringFor),
+		})
+	}
+
+	if cmd.Sweep != nil {
+		if combinations := max(len(cmd.Sweep.Thresholds), 1) * max(len(cmd.Sweep.For), 1); combinations > maxBacktestSweepCombinations {
+			return ErrResp(400, nil, "Sweep defines %d combinations of settings, the maximum is %d", combinations, maxBacktestSweepCombinations)
+		}
+		opts.Sweep = &backtesting.Sweep{
+			Thresholds: cmd.Sweep.Thresholds,
+		}
+		for _, f := range cmd.Sweep.For {
+			if f < 0 {
+				return ErrResp(400, nil, "Bad sweep settings: durations must not be negative")
+			}
+			opts.Sweep.For = append(opts.Sweep.For, time.Duration(f))
+		}
+	}
+
+	result, err := srv.backtesting.TestWithOptions(c.Req.Context(), c.SignedInUser, rule, cmd.From, cmd.To, opts)
 	if err != nil {
 		if errors.Is(err, backtesting.ErrInvalidInputData) {
 			return ErrResp(400, err, "Failed to evaluate")
@@ -286,3 +366,111 @@ func (srv TestingApiSrv) BacktestAlertRule(c *contextmodel.ReqContext, cmd apimo
 	}
 	return response.JSON(http.StatusOK, body)
 }
+
+// BacktestRuleGroup converts the Prometheus rule group to Grafana rules and runs backtesting of the whole group.
+func (srv TestingApiSrv) BacktestRuleGroup(c *contextmodel.ReqContext, cmd apimodels.BacktestGroupConfig) response.Response {
+	if !srv.featureManager.IsEnabled(c.Req.Context(), featuremgmt.FlagAlertingBacktesting) {
+		return ErrResp(http.StatusNotFound, nil, "Backgtesting API is not enabled")
+	}
+
+	if cmd.From.After(cmd.To) {
+		return ErrResp(400, nil, "From cannot be greater than To")
+	}
+
+	ds, err := srv.DatasourceCache.GetDatasourceByUID(c.Req.Context(), cmd.DatasourceUID, c.SignedInUser, c.SkipDSCache)
+	if err != nil {
+		return errorToResponse(err)
+	}
+
+	converter, err := prom.NewConverter(prom.Config{
+		DatasourceUID:   ds.UID,
+		DatasourceType:  ds.Type,
+		DefaultInterval: srv.cfg.DefaultRuleEvaluationInterval,
+	})
+	if err != nil {
+		return ErrResp(400, err, "")
+	}
+	group, err := converter.PrometheusRulesToGrafana(c.SignedInUser.GetOrgID(), "", prometheusRuleGroupFromApi(cmd.Group))
+	if err != nil {
+		return errorToResponse(err)
+	}
+
+	if _, err := validateInterval(time.Duration(group.Interval)*time.Second, srv.cfg.BaseInterval); err != nil {
+		return ErrResp(400, err, "")
+	}
+	for i := range group.Rules {
+		if err := srv.authz.AuthorizeDatasourceAccessForRule(c.Req.Context(), c.SignedInUser, &group.Rules[i]); err != nil {
+			return errorToResponse(err)
+		}
+	}
+
+	result, err := srv.backtesting.TestGroup(c.Req.Context(), c.SignedInUser, group, cmd.From, cmd.To)
+	if err != nil {
+		if errors.Is(err, backtesting.ErrInvalidInputData) {
+			return ErrResp(400, err, "Failed to evaluate")
+		}
+		return ErrResp(500, err, "Failed to evaluate")
+	}
+	return response.JSON(http.StatusOK, result)
+}
+
+// notificationSimulationOptions builds the options of backtesting that make the engine simulate notifications
+// using the current notification policy tree, time intervals and silences of the organization.
+func (srv TestingApiSrv) notificationSimulationOptions(c *contextmodel.ReqContext, rule *ngmodels.AlertRule) (backtesting.TestOptions, error) {
+	ctx := c.Req.Context()
+	if srv.notifications == nil {
+		return backtesting.TestOptions{}, errors.New("simulation of notifications is not available")
+	}
+	if err := srv.authorizeNotificationsRead(c, "simulate notifications"); err != nil {
+		return backtesting.TestOptions{}, err
+	}
+
+	var folderTitle string
+	if rule.NamespaceUID != "" {
+		f, err := srv.folderService.GetNamespaceByUID(ctx, rule.NamespaceUID, c.SignedInUser.GetOrgID(), c.SignedInUser)
+		if err != nil {
+			return backtesting.TestOptions{}, errors.Join(errFolderAccess, err)
+		}
+		folderTitle = f.Fullpath
+	}
+
+	cfg, err := srv.notifications.GetAlertmanagerConfiguration(ctx, c.SignedInUser.GetOrgID(), true)
+	if err != nil {
+		return backtesting.TestOptions{}, err
+	}
+	silences, err := srv.notifications.ListSilences(ctx, c.SignedInUser.GetOrgID(), nil)
+	if err != nil {
+		return backtesting.TestOptions{}, err
+	}
+
+	amConfig := cfg.AlertmanagerConfig
+	timeIntervals := make([]alertmanager_config.TimeInterval, 0, len(amConfig.MuteTimeIntervals)+len(amConfig.TimeIntervals))
+	for _, mt := range amConfig.MuteTimeIntervals {
+		timeIntervals = append(timeIntervals, alertmanager_config.TimeInterval(mt))
+	}
+	timeIntervals = append(timeIntervals, amConfig.TimeIntervals...)
+
+	includeFolder := !srv.cfg.ReservedLabels.IsReservedLabelDisabled(models.FolderTitleLabel)
+	return backtesting.TestOptions{
+		ExtraLabels: state.GetRuleExtraLabels(srv.log, rule, folderTitle, includeFolder),
+		NotificationPolicy: &backtesting.NotificationPolicy{
+			Route:         amConfig.Route,
+			TimeIntervals: timeIntervals,
+			Silences:      silences,
+		},
+	}, nil
+}
+
+// authorizeNotificationsRead checks that the user can read the notification configuration, which is exposed by
+// the simulation and the preview of notifications.
+func (srv TestingApiSrv) authorizeNotificationsRead(c *contextmodel.ReqContext, action string) error {
+	evaluator := ac.EvalPermission(ac.ActionAlertingNotificationsRead)
+	ok, err := srv.ac.Evaluate(c.Req.Context(), c.SignedInUser, evaluator)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return accesscontrol.NewAuthorizationErrorWithPermissions(action, evaluator)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"testing"
@@ -9,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
@@ -240,9 +242,72 @@ func TestRouteTestGrafanaRuleConfig(t *testing.T) {
 
 			evaluator.AssertCalled(t, "Evaluate", mock.Anything, mock.Anything)
 		})
+
+		t.Run("should return preview of notifications if requested", func(t *testing.T) {
+			data1 := models.RuleGen.GenerateQuery()
+			permissions := []ac.Permission{
+				{Action: datasources.ActionQuery, Scope: datasources.ScopeProvider.GetResourceScopeUID(data1.DatasourceUID)},
+			}
+			setup := func(permissions []ac.Permission) (*TestingApiSrv, *fakeNotificationPreviewer, definitions.PostableExtendedRuleNodeExtended) {
+				acm := acMock.New().WithPermissions(permissions)
+				evaluator := &eval_mocks.ConditionEvaluatorMock{}
+				evaluator.EXPECT().Evaluate(mock.Anything, mock.Anything).Return(eval.Results{}, nil)
+				f := randFolder()
+				ruleStore := fakes2.NewRuleStore(t)
+				ruleStore.Folders[rc.OrgID] = []*folder.Folder{f}
+				srv := createTestingApiSrv(t, nil, acm, eval_mocks.NewEvaluatorFactory(evaluator), featuremgmt.WithFeatures(), ruleStore)
+				srv.AlertingProxy = &AlertingProxy{ac: acm}
+				previewer := &fakeNotificationPreviewer{
+					result: definitions.NotificationsPreview{
+						Routing:       []definitions.AlertRoutingPreview{{Labels: map[string]string{"foo": "bar"}}},
+						Notifications: []definitions.NotificationPreview{{Receiver: "test"}},
+					},
+				}
+				srv.preview = previewer
+
+				rule := validRule()
+				rule.GrafanaManagedAlert.Data = ApiAlertQueriesFromAlertQueries([]models.AlertQuery{data1})
+				rule.GrafanaManagedAlert.Condition = data1.RefID
+				return srv, previewer, definitions.PostableExtendedRuleNodeExtended{
+					Rule:                 rule,
+					NamespaceUID:         f.UID,
+					NamespaceTitle:       f.Title,
+					PreviewNotifications: true,
+				}
+			}
+
+			t.Run("if user can read notifications", func(t *testing.T) {
+				srv, previewer, body := setup(append(permissions, ac.Permission{Action: ac.ActionAlertingNotificationsRead}))
+				response := srv.RouteTestGrafanaRuleConfig(rc, body)
+				require.Equal(t, http.StatusOK, response.Status())
+
+				var result definitions.TestGrafanaRulePreview
+				require.NoError(t, json.Unmarshal(response.Body(), &result))
+				require.Equal(t, previewer.result.Routing, result.Routing)
+				require.Equal(t, previewer.result.Notifications, result.Notifications)
+				require.Equal(t, rc.OrgID, previewer.orgID)
+			})
+
+			t.Run("forbidden if user cannot read notifications", func(t *testing.T) {
+				srv, previewer, body := setup(permissions)
+				response := srv.RouteTestGrafanaRuleConfig(rc, body)
+				require.Equal(t, http.StatusForbidden, response.Status())
+				require.Zero(t, previewer.orgID)
+			})
+		})
 	})
 }
 
+type fakeNotificationPreviewer struct {
+	orgID  int64
+	result definitions.NotificationsPreview
+}
+
+func (f *fakeNotificationPreviewer) PreviewNotifications(_ context.Context, orgID int64, _ []*amv2.PostableAlert, _ []models.NotificationSettings, _ time.Time) (definitions.NotificationsPreview, error) {
+	f.orgID = orgID
+	return f.result, nil
+}
+
 func TestRouteEvalQueries(t *testing.T) {
 	t.Run("when fine-grained access is enabled", func(t *testing.T) {
 		rc := &contextmodel.ReqContext{
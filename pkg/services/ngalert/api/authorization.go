@@ -42,11 +42,25 @@ func (api *API) authorize(method, path string) web.Handler {
 		http.MethodGet + "/api/ruler/grafana/api/v1/export/rules":
 		eval = ac.EvalPermission(ac.ActionAlertingRuleRead)
 	case http.MethodGet + "/api/ruler/grafana/api/v1/rule/{RuleUID}",
-		http.MethodGet + "/api/ruler/grafana/api/v1/rule/{RuleUID}/versions":
+		http.MethodGet + "/api/ruler/grafana/api/v1/rule/{RuleUID}/versions",
+		http.MethodGet + "/api/ruler/grafana/api/v1/rule/{RuleUID}/debug",
+		http.MethodGet + "/api/ruler/grafana/api/v1/rule/{RuleUID}/backfill",
+		http.MethodGet + "/api/ruler/grafana/api/v1/rule/{RuleUID}/backfill/{BackfillID}":
 		eval = ac.EvalAll(
 			ac.EvalPermission(ac.ActionAlertingRuleRead),
 			ac.EvalPermission(dashboards.ActionFoldersRead),
 		)
+	case http.MethodPost + "/api/ruler/grafana/api/v1/rule/{RuleUID}/eval",
+		http.MethodPost + "/api/ruler/grafana/api/v1/rule/{RuleUID}/pause",
+		http.MethodPost + "/api/ruler/grafana/api/v1/rule/{RuleUID}/debug",
+		http.MethodPost + "/api/ruler/grafana/api/v1/rule/{RuleUID}/backfill",
+		http.MethodPost + "/api/ruler/grafana/api/v1/rule/{RuleUID}/backfill/{BackfillID}/cancel",
+		http.MethodPost + "/api/ruler/grafana/api/v1/rule/{RuleUID}/backfill/{BackfillID}/resume":
+		// more granular permissions are enforced by the handler via "authorizeRuleChanges"
+		eval = ac.EvalAll(
+			ac.EvalPermission(ac.ActionAlertingRuleUpdate),
+			ac.EvalPermission(dashboards.ActionFoldersRead),
+		)
 	case http.MethodPost + "/api/ruler/grafana/api/v1/rules/{Namespace}/export":
 		scope := dashboards.ScopeFoldersProvider.GetResourceScopeUID(ac.Parameter(":Namespace"))
 		// more granular permissions are enforced by the handler via "authorizeRuleChanges"
@@ -94,7 +108,8 @@ func (api *API) authorize(method, path string) web.Handler {
 		// additional authorization is done in the request handler
 		eval = ac.EvalPermission(ac.ActionAlertingRuleRead)
 	// Grafana Rules Testing Paths
-	case http.MethodPost + "/api/v1/rule/backtest":
+	case http.MethodPost + "/api/v1/rule/backtest",
+		http.MethodPost + "/api/v1/rule/backtest/group":
 		// additional authorization is done in the request handler
 		eval = ac.EvalPermission(ac.ActionAlertingRuleRead)
 	case http.MethodPost + "/api/v1/eval":
@@ -367,6 +382,20 @@ func (api *API) authorize(method, path string) web.Handler {
 			ac.EvalPermission(ac.ActionAlertingNotificationsRead),
 		)
 
+	// Lookup tables are used by templates of alert rules
+	case http.MethodGet + "/api/v1/provisioning/lookup-tables":
+		eval = ac.EvalAny(
+			ac.EvalPermission(ac.ActionAlertingProvisioningRead),
+			ac.EvalPermission(ac.ActionAlertingRulesProvisioningRead),
+			ac.EvalPermission(ac.ActionAlertingProvisioningReadSecrets),
+			ac.EvalPermission(ac.ActionAlertingRuleRead),
+		)
+	case http.MethodPut + "/api/v1/provisioning/lookup-tables":
+		eval = ac.EvalAny(
+			ac.EvalPermission(ac.ActionAlertingProvisioningWrite),
+			ac.EvalPermission(ac.ActionAlertingRulesProvisioningWrite),
+		)
+
 	// Grafana-only Provisioning Write Paths
 	case http.MethodPost + "/api/v1/provisioning/alert-rules":
 		eval = ac.EvalAny(
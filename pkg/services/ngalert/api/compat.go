@@ -29,6 +29,7 @@ func AlertRuleFromProvisionedAlertRule(a definitions.ProvisionedAlertRule) (mode
 		NoDataState:          models.NoDataState(a.NoDataState),          // TODO there must be a validation
 		ExecErrState:         models.ExecutionErrorState(a.ExecErrState), // TODO there must be a validation
 		For:                  time.Duration(a.For),
+		KeepFiringFor:        time.Duration(a.KeepFiringFor),
 		Annotations:          a.Annotations,
 		Labels:               a.Labels,
 		IsPaused:             a.IsPaused,
@@ -53,6 +54,7 @@ func ProvisionedAlertRuleFromAlertRule(rule models.AlertRule, provenance models.
 		RuleGroup:            rule.RuleGroup,
 		Title:                rule.Title,
 		For:                  model.Duration(rule.For),
+		KeepFiringFor:        model.Duration(rule.KeepFiringFor),
 		Condition:            rule.Condition,
 		Data:                 ApiAlertQueriesFromAlertQueries(rule.Data),
 		Updated:              rule.Updated,
@@ -206,6 +208,7 @@ func AlertRuleExportFromAlertRule(rule models.AlertRule) (definitions.AlertRuleE
 		UID:                  rule.UID,
 		Title:                rule.Title,
 		For:                  model.Duration(rule.For),
+		KeepFiringFor:        model.Duration(rule.KeepFiringFor),
 		Condition:            cPtr,
 		Data:                 data,
 		DashboardUID:         rule.DashboardUID,
@@ -219,6 +222,9 @@ func AlertRuleExportFromAlertRule(rule models.AlertRule) (definitions.AlertRuleE
 	if rule.For.Seconds() > 0 {
 		result.ForString = util.Pointer(model.Duration(rule.For).String())
 	}
+	if rule.KeepFiringFor.Seconds() > 0 {
+		result.KeepFiringForString = util.Pointer(model.Duration(rule.KeepFiringFor).String())
+	}
 	if rule.Annotations != nil {
 		result.Annotations = &rule.Annotations
 	}
@@ -433,6 +439,7 @@ func AlertRuleEditorSettingsFromModelEditorSettings(es models.EditorSettings) *d
 func AlertRuleMetadataFromModelMetadata(es models.AlertRuleMetadata) *definitions.AlertRuleMetadata {
 	return &definitions.AlertRuleMetadata{
 		EditorSettings: *AlertRuleEditorSettingsFromModelEditorSettings(es.EditorSettings),
+		PausedUntil:    es.PausedUntil,
 	}
 }
 
@@ -498,30 +505,59 @@ func AlertRuleRecordExportFromRecord(r *models.Record) *definitions.AlertRuleRec
 	if r == nil {
 		return nil
 	}
-	return &definitions.AlertRuleRecordExport{
+	result := &definitions.AlertRuleRecordExport{
 		Metric: r.Metric,
 		From:   r.From,
 	}
+	if r.TargetDatasourceUID != "" {
+		result.TargetDatasourceUID = &r.TargetDatasourceUID
+	}
+	if !r.Metadata.IsEmpty() {
+		result.Metadata = &definitions.AlertRuleRecordMetadataExport{
+			Type: r.Metadata.Type,
+			Unit: r.Metadata.Unit,
+			Help: r.Metadata.Help,
+		}
+	}
+	return result
 }
 
 func ModelRecordFromApiRecord(r *definitions.Record) *models.Record {
 	if r == nil {
 		return nil
 	}
-	return &models.Record{
-		Metric: r.Metric,
-		From:   r.From,
+	result := &models.Record{
+		Metric:              r.Metric,
+		From:                r.From,
+		TargetDatasourceUID: r.TargetDatasourceUID,
 	}
+	if r.Metadata != nil {
+		result.Metadata = models.RecordMetadata{
+			Type: r.Metadata.Type,
+			Unit: r.Metadata.Unit,
+			Help: r.Metadata.Help,
+		}
+	}
+	return result
 }
 
 func ApiRecordFromModelRecord(r *models.Record) *definitions.Record {
 	if r == nil {
 		return nil
 	}
-	return &definitions.Record{
-		Metric: r.Metric,
-		From:   r.From,
+	result := &definitions.Record{
+		Metric:              r.Metric,
+		From:                r.From,
+		TargetDatasourceUID: r.TargetDatasourceUID,
 	}
+	if !r.Metadata.IsEmpty() {
+		result.Metadata = &definitions.RecordMetadata{
+			Type: r.Metadata.Type,
+			Unit: r.Metadata.Unit,
+			Help: r.Metadata.Help,
+		}
+	}
+	return result
 }
 
 func GettableGrafanaReceiverFromReceiver(r *models.Integration, provenance models.Provenance) (definitions.GettableGrafanaReceiver, error) {
@@ -128,3 +128,39 @@ func (f *RulerApiHandler) getService(ctx *contextmodel.ReqContext) (*LotexRuler,
 func (f *RulerApiHandler) handleRouteGetRuleVersionsByUID(ctx *contextmodel.ReqContext, ruleUID string) response.Response {
 	return f.GrafanaRuler.RouteGetRuleVersionsByUID(ctx, ruleUID)
 }
+
+func (f *RulerApiHandler) handleRouteGetRuleDebug(ctx *contextmodel.ReqContext, ruleUID string) response.Response {
+	return f.GrafanaRuler.RouteGetRuleDebug(ctx, ruleUID)
+}
+
+func (f *RulerApiHandler) handleRoutePostRuleDebug(ctx *contextmodel.ReqContext, debug apimodels.PostableRuleDebug, ruleUID string) response.Response {
+	return f.GrafanaRuler.RoutePostRuleDebug(ctx, debug, ruleUID)
+}
+
+func (f *RulerApiHandler) handleRoutePostRuleEvaluation(ctx *contextmodel.ReqContext, ruleUID string) response.Response {
+	return f.GrafanaRuler.RoutePostRuleEvaluation(ctx, ruleUID)
+}
+
+func (f *RulerApiHandler) handleRoutePostRulePause(ctx *contextmodel.ReqContext, pause apimodels.PostableRulePause, ruleUID string) response.Response {
+	return f.GrafanaRuler.RoutePostRulePause(ctx, pause, ruleUID)
+}
+
+func (f *RulerApiHandler) handleRoutePostRuleBackfill(ctx *contextmodel.ReqContext, backfill apimodels.PostableRuleBackfill, ruleUID string) response.Response {
+	return f.GrafanaRuler.RoutePostRuleBackfill(ctx, backfill, ruleUID)
+}
+
+func (f *RulerApiHandler) handleRouteGetRuleBackfills(ctx *contextmodel.ReqContext, ruleUID string) response.Response {
+	return f.GrafanaRuler.RouteGetRuleBackfills(ctx, ruleUID)
+}
+
+func (f *RulerApiHandler) handleRouteGetRuleBackfill(ctx *contextmodel.ReqContext, ruleUID string, backfillID string) response.Response {
+	return f.GrafanaRuler.RouteGetRuleBackfill(ctx, ruleUID, backfillID)
+}
+
+func (f *RulerApiHandler) handleRoutePostRuleBackfillCancel(ctx *contextmodel.ReqContext, ruleUID string, backfillID string) response.Response {
+	return f.GrafanaRuler.RoutePostRuleBackfillCancel(ctx, ruleUID, backfillID)
+}
+
+func (f *RulerApiHandler) handleRoutePostRuleBackfillResume(ctx *contextmodel.ReqContext, ruleUID string, backfillID string) response.Response {
+	return f.GrafanaRuler.RoutePostRuleBackfillResume(ctx, ruleUID, backfillID)
+}
@@ -35,6 +35,7 @@ type ProvisioningApi interface {
 	RouteGetAlertRulesExport(*contextmodel.ReqContext) response.Response
 	RouteGetContactpoints(*contextmodel.ReqContext) response.Response
 	RouteGetContactpointsExport(*contextmodel.ReqContext) response.Response
+	RouteGetLookupTables(*contextmodel.ReqContext) response.Response
 	RouteGetMuteTiming(*contextmodel.ReqContext) response.Response
 	RouteGetMuteTimings(*contextmodel.ReqContext) response.Response
 	RouteGetPolicyTree(*contextmodel.ReqContext) response.Response
@@ -47,6 +48,7 @@ type ProvisioningApi interface {
 	RoutePutAlertRule(*contextmodel.ReqContext) response.Response
 	RoutePutAlertRuleGroup(*contextmodel.ReqContext) response.Response
 	RoutePutContactpoint(*contextmodel.ReqContext) response.Response
+	RoutePutLookupTables(*contextmodel.ReqContext) response.Response
 	RoutePutMuteTiming(*contextmodel.ReqContext) response.Response
 	RoutePutPolicyTree(*contextmodel.ReqContext) response.Response
 	RoutePutTemplate(*contextmodel.ReqContext) response.Response
@@ -121,6 +123,9 @@ func (f *ProvisioningApiHandler) RouteGetContactpoints(ctx *contextmodel.ReqCont
 func (f *ProvisioningApiHandler) RouteGetContactpointsExport(ctx *contextmodel.ReqContext) response.Response {
 	return f.handleRouteGetContactpointsExport(ctx)
 }
+func (f *ProvisioningApiHandler) RouteGetLookupTables(ctx *contextmodel.ReqContext) response.Response {
+	return f.handleRouteGetLookupTables(ctx)
+}
 func (f *ProvisioningApiHandler) RouteGetMuteTiming(ctx *contextmodel.ReqContext) response.Response {
 	// Parse Path Parameters
 	nameParam := web.Params(ctx.Req)[":name"]
@@ -198,6 +203,14 @@ func (f *ProvisioningApiHandler) RoutePutContactpoint(ctx *contextmodel.ReqConte
 	}
 	return f.handleRoutePutContactpoint(ctx, conf, uIDParam)
 }
+func (f *ProvisioningApiHandler) RoutePutLookupTables(ctx *contextmodel.ReqContext) response.Response {
+	// Parse Request Body
+	conf := apimodels.LookupTables{}
+	if err := web.Bind(ctx.Req, &conf); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	return f.handleRoutePutLookupTables(ctx, conf)
+}
 func (f *ProvisioningApiHandler) RoutePutMuteTiming(ctx *contextmodel.ReqContext) response.Response {
 	// Parse Path Parameters
 	nameParam := web.Params(ctx.Req)[":name"]
@@ -412,6 +425,18 @@ func (api *API) RegisterProvisioningApiEndpoints(srv ProvisioningApi, m *metrics
 				m,
 			),
 		)
+		group.Get(
+			toMacaronPath("/api/v1/provisioning/lookup-tables"),
+			requestmeta.SetOwner(requestmeta.TeamAlerting),
+			requestmeta.SetSLOGroup(requestmeta.SLOGroupHighSlow),
+			api.authorize(http.MethodGet, "/api/v1/provisioning/lookup-tables"),
+			metrics.Instrument(
+				http.MethodGet,
+				"/api/v1/provisioning/lookup-tables",
+				api.Hooks.Wrap(srv.RouteGetLookupTables),
+				m,
+			),
+		)
 		group.Get(
 			toMacaronPath("/api/v1/provisioning/mute-timings/{name}"),
 			requestmeta.SetOwner(requestmeta.TeamAlerting),
@@ -556,6 +581,18 @@ func (api *API) RegisterProvisioningApiEndpoints(srv ProvisioningApi, m *metrics
 				m,
 			),
 		)
+		group.Put(
+			toMacaronPath("/api/v1/provisioning/lookup-tables"),
+			requestmeta.SetOwner(requestmeta.TeamAlerting),
+			requestmeta.SetSLOGroup(requestmeta.SLOGroupHighSlow),
+			api.authorize(http.MethodPut, "/api/v1/provisioning/lookup-tables"),
+			metrics.Instrument(
+				http.MethodPut,
+				"/api/v1/provisioning/lookup-tables",
+				api.Hooks.Wrap(srv.RoutePutLookupTables),
+				m,
+			),
+		)
 		group.Put(
 			toMacaronPath("/api/v1/provisioning/mute-timings/{name}"),
 			requestmeta.SetOwner(requestmeta.TeamAlerting),
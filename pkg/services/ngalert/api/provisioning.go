@@ -64,6 +64,14 @@ func (f *ProvisioningApiHandler) handleRouteDeleteTemplate(ctx *contextmodel.Req
 	return f.svc.RouteDeleteTemplate(ctx, name)
 }
 
+func (f *ProvisioningApiHandler) handleRouteGetLookupTables(ctx *contextmodel.ReqContext) response.Response {
+	return f.svc.RouteGetLookupTables(ctx)
+}
+
+func (f *ProvisioningApiHandler) handleRoutePutLookupTables(ctx *contextmodel.ReqContext, tables apimodels.LookupTables) response.Response {
+	return f.svc.RoutePutLookupTables(ctx, tables)
+}
+
 func (f *ProvisioningApiHandler) handleRouteGetMuteTiming(ctx *contextmodel.ReqContext, name string) response.Response {
 	return f.svc.RouteGetMuteTiming(ctx, name)
 }
@@ -153,6 +153,10 @@ func (f fakeRuleAccessControlService) AuthorizeDatasourceAccessForRuleGroup(ctx
 	return nil
 }
 
+func (f fakeRuleAccessControlService) AuthorizeRecordingTarget(ctx context.Context, user identity.Requester, datasourceUID string) error {
+	return nil
+}
+
 type statesReader interface {
 	GetStatesForRuleUID(orgID int64, alertRuleUID string) []*state.State
 }
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
 	"github.com/prometheus/common/model"
 )
 
@@ -32,6 +34,112 @@ import (
 //       403: ForbiddenError
 //       404: description: Not found.
 
+// swagger:route POST /ruler/grafana/api/v1/rule/{RuleUID}/eval ruler RoutePostRuleEvaluation
+//
+// Evaluate the rule now, regardless of its schedule
+//
+//     Responses:
+//       202: Ack
+//       403: ForbiddenError
+//       404: description: Not found.
+//       409: description: The rule is paused or not evaluated by this instance.
+
+// swagger:route POST /ruler/grafana/api/v1/rule/{RuleUID}/pause ruler RoutePostRulePause
+//
+// Pause the rule until the given time, after which it is resumed automatically
+//
+//     Consumes:
+//     - application/json
+//
+//     Responses:
+//       202: UpdateRuleGroupResponse
+//       400: ValidationError
+//       403: ForbiddenError
+//       404: description: Not found.
+
+// swagger:route Get /ruler/grafana/api/v1/rule/{RuleUID}/debug ruler RouteGetRuleDebug
+//
+// Get the last evaluations of the rule recorded in debug mode by this instance
+//
+//     Produces:
+//     - application/json
+//
+//     Responses:
+//       200: GettableRuleDebug
+//       403: ForbiddenError
+//       404: description: Not found.
+
+// swagger:route POST /ruler/grafana/api/v1/rule/{RuleUID}/debug ruler RoutePostRuleDebug
+//
+// Enable or disable debug mode of the rule on this instance
+//
+//     Consumes:
+//     - application/json
+//
+//     Responses:
+//       202: Ack
+//       400: ValidationError
+//       403: ForbiddenError
+//       404: description: Not found.
+
+// swagger:route POST /ruler/grafana/api/v1/rule/{RuleUID}/backfill ruler RoutePostRuleBackfill
+//
+// Backfill the series of the recording rule over a historical range
+//
+//     Consumes:
+//     - application/json
+//
+//     Responses:
+//       202: GettableRuleBackfill
+//       400: ValidationError
+//       403: ForbiddenError
+//       404: description: Not found.
+//       409: description: Another backfill of the rule is in progress.
+
+// swagger:route Get /ruler/grafana/api/v1/rule/{RuleUID}/backfill ruler RouteGetRuleBackfills
+//
+// List backfills of the recording rule, the most recent first
+//
+//     Produces:
+//     - application/json
+//
+//     Responses:
+//       200: GettableRuleBackfills
+//       403: ForbiddenError
+//       404: description: Not found.
+
+// swagger:route Get /ruler/grafana/api/v1/rule/{RuleUID}/backfill/{BackfillID} ruler RouteGetRuleBackfill
+//
+// Get the backfill of the recording rule
+//
+//     Produces:
+//     - application/json
+//
+//     Responses:
+//       200: GettableRuleBackfill
+//       403: ForbiddenError
+//       404: description: Not found.
+
+// swagger:route POST /ruler/grafana/api/v1/rule/{RuleUID}/backfill/{BackfillID}/cancel ruler RoutePostRuleBackfillCancel
+//
+// Cancel the backfill of the recording rule. The series written so far are kept
+//
+//     Responses:
+//       202: GettableRuleBackfill
+//       403: ForbiddenError
+//       404: description: Not found.
+//       409: description: The backfill is finished.
+
+// swagger:route POST /ruler/grafana/api/v1/rule/{RuleUID}/backfill/{BackfillID}/resume ruler RoutePostRuleBackfillResume
+//
+// Resume the failed or canceled backfill of the recording rule from the first evaluation that is not completed
+//
+//     Responses:
+//       202: GettableRuleBackfill
+//       403: ForbiddenError
+//       404: description: Not found.
+//       409: description: The backfill is not failed or canceled, or another backfill of the rule is in progress.
+
 // swagger:route Get /ruler/grafana/api/v1/rules ruler RouteGetGrafanaRulesConfig
 //
 // List rule groups
@@ -231,12 +339,132 @@ type PathGetRulesParams struct {
 	PanelID int64
 }
 
-// swagger:parameters RouteGetRuleByUID RouteGetRuleVersionsByUID
+// swagger:parameters RouteGetRuleByUID RouteGetRuleVersionsByUID RoutePostRuleEvaluation RouteGetRuleDebug RouteGetRuleBackfills
 type PathGetRuleByUIDParams struct {
 	// in: path
 	RuleUID string
 }
 
+// swagger:parameters RoutePostRulePause
+type PostRulePauseParams struct {
+	// in: path
+	RuleUID string
+	// in: body
+	Body PostableRulePause
+}
+
+// swagger:model
+type PostableRulePause struct {
+	// The time when the rule is resumed. It must be in the future.
+	// required: true
+	Until time.Time `json:"until"`
+}
+
+// swagger:parameters RoutePostRuleDebug
+type PostRuleDebugParams struct {
+	// in: path
+	RuleUID string
+	// in: body
+	Body PostableRuleDebug
+}
+
+// swagger:model
+type PostableRuleDebug struct {
+	// Enabled starts or stops recording evaluations of the rule. Evaluations recorded before are discarded.
+	// required: true
+	Enabled bool `json:"enabled"`
+	// Evaluations is the number of last evaluations to record. Defaults to 10, and cannot be greater than 100.
+	Evaluations int `json:"evaluations,omitempty"`
+}
+
+// GettableRuleDebug contains the last evaluations of a rule in debug mode, oldest first.
+// Evaluations are recorded in memory by the instance that evaluates the rule, and are lost when it restarts.
+// swagger:model
+type GettableRuleDebug struct {
+	Enabled     bool                  `json:"enabled"`
+	Evaluations []RuleEvaluationTrace `json:"evaluations"`
+}
+
+// swagger:model
+type RuleEvaluationTrace struct {
+	ScheduledAt time.Time `json:"scheduled_at"`
+	Duration    string    `json:"duration"`
+	Error       string    `json:"error,omitempty"`
+	// Responses are the raw responses of queries and expressions by RefID.
+	Responses map[string]RuleQueryTrace `json:"responses,omitempty"`
+	// Results are the results of the evaluation of the condition.
+	Results     *data.Frame           `json:"results,omitempty"`
+	Transitions []RuleStateTransition `json:"transitions,omitempty"`
+	// SentAlerts are the alerts of every call to the sender.
+	SentAlerts []amv2.PostableAlerts `json:"sent_alerts,omitempty"`
+}
+
+// swagger:model
+type RuleQueryTrace struct {
+	Frames data.Frames `json:"frames,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// swagger:model
+type RuleStateTransition struct {
+	Labels        map[string]string `json:"labels"`
+	PreviousState string            `json:"previous_state"`
+	State         string            `json:"state"`
+}
+
+// swagger:parameters RoutePostRuleBackfill
+type PostRuleBackfillParams struct {
+	// in: path
+	RuleUID string
+	// in: body
+	Body PostableRuleBackfill
+}
+
+// swagger:model
+type PostableRuleBackfill struct {
+	// The start of the range to backfill.
+	// required: true
+	From time.Time `json:"from"`
+	// The end of the range to backfill, exclusive. It must not be in the future, and the range must not have
+	// more than 50000 evaluations of the rule.
+	// required: true
+	To time.Time `json:"to"`
+}
+
+// swagger:parameters RouteGetRuleBackfill RoutePostRuleBackfillCancel RoutePostRuleBackfillResume
+type PathRuleBackfillParams struct {
+	// in: path
+	RuleUID string
+	// in: path
+	BackfillID string
+}
+
+// GettableRuleBackfill is a backfill of a recording rule. The rule is evaluated at every interval step of the range,
+// and the recorded series are written with the timestamps of the evaluations.
+// swagger:model
+type GettableRuleBackfill struct {
+	ID              string    `json:"id"`
+	RuleUID         string    `json:"rule_uid"`
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	IntervalSeconds int64     `json:"interval_seconds"`
+	// Pending backfills are resumed automatically by any instance. Failed and canceled backfills can be resumed via the API.
+	// enum: pending,running,completed,failed,canceled
+	Status string `json:"status"`
+	// The total number of evaluations, of which Completed are done.
+	Evaluations int `json:"evaluations"`
+	Completed   int `json:"completed"`
+	// The number of completed evaluations that failed.
+	Failed int `json:"failed"`
+	// The last error of the backfill.
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// swagger:model
+type GettableRuleBackfills []GettableRuleBackfill
+
 // swagger:model
 type RuleGroupConfigResponse struct {
 	GettableRuleGroupConfig
@@ -471,6 +699,8 @@ const (
 // swagger:model
 type AlertRuleMetadata struct {
 	EditorSettings AlertRuleEditorSettings `json:"editor_settings" yaml:"editor_settings"`
+	// PausedUntil is the time when the paused rule is resumed automatically. It is read-only and can be set only by pausing the rule via the pause API.
+	PausedUntil *time.Time `json:"paused_until,omitempty" yaml:"paused_until,omitempty"`
 }
 
 // swagger:model
@@ -530,9 +760,30 @@ type Record struct {
 	// example: grafana_alerts_ratio
 	Metric string `json:"metric" yaml:"metric"`
 	// Which expression node should be used as the input for the recorded metric.
+	// If the node returns heatmap cells, the metric is written as a native histogram. The sum of observations is not
+	// known, so it is NaN, and histogram_sum and histogram_avg of the recorded metric return NaN.
 	// required: true
 	// example: A
 	From string `json:"from" yaml:"from"`
+	// UID of a Prometheus-compatible data source to write the recorded metric to. If empty, the metric is written to the remote write endpoint configured for recording rules.
+	// example: my-mimir-uid
+	TargetDatasourceUID string `json:"target_datasource_uid,omitempty" yaml:"target_datasource_uid,omitempty"`
+	// Metadata of the recorded metric that is written along with it.
+	Metadata *RecordMetadata `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// swagger:model
+type RecordMetadata struct {
+	// Type of the recorded metric.
+	// enum: counter,gauge,histogram,gaugehistogram,summary,info,stateset,unknown
+	// example: gauge
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+	// Unit of the recorded metric.
+	// example: seconds
+	Unit string `json:"unit,omitempty" yaml:"unit,omitempty"`
+	// Description of the recorded metric.
+	// example: Ratio of firing alerts.
+	Help string `json:"help,omitempty" yaml:"help,omitempty"`
 }
 
 // swagger:model
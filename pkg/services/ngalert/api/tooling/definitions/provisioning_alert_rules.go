@@ -158,6 +158,8 @@ type ProvisionedAlertRule struct {
 	// required: true
 	// swagger:strfmt duration
 	For model.Duration `json:"for"`
+	// swagger:strfmt duration
+	KeepFiringFor model.Duration `json:"keepFiringFor,omitempty"`
 	// example: {"runbook_url": "https://supercoolrunbook.com/page/13"}
 	Annotations map[string]string `json:"annotations,omitempty"`
 	// example: {"team": "sre-team-1"}
@@ -271,7 +273,10 @@ type AlertRuleExport struct {
 	// ForString is used to:
 	// - Only export the for field for HCL if it is non-zero.
 	// - Format the Prometheus model.Duration type properly for HCL.
-	ForString            *string                              `json:"-" yaml:"-" hcl:"for"`
+	ForString     *string        `json:"-" yaml:"-" hcl:"for"`
+	KeepFiringFor model.Duration `json:"keepFiringFor,omitempty" yaml:"keepFiringFor,omitempty"`
+	// KeepFiringForString is used to export the keepFiringFor field for HCL only if it is non-zero.
+	KeepFiringForString  *string                              `json:"-" yaml:"-" hcl:"keep_firing_for"`
 	Annotations          *map[string]string                   `json:"annotations,omitempty" yaml:"annotations,omitempty" hcl:"annotations"`
 	Labels               *map[string]string                   `json:"labels,omitempty" yaml:"labels,omitempty" hcl:"labels"`
 	IsPaused             bool                                 `json:"isPaused" yaml:"isPaused" hcl:"is_paused"`
@@ -308,6 +313,15 @@ type AlertRuleNotificationSettingsExport struct {
 
 // Record is the provisioned export of models.Record.
 type AlertRuleRecordExport struct {
-	Metric string `json:"metric" yaml:"metric" hcl:"metric"`
-	From   string `json:"from" yaml:"from" hcl:"from"`
+	Metric              string                         `json:"metric" yaml:"metric" hcl:"metric"`
+	From                string                         `json:"from" yaml:"from" hcl:"from"`
+	TargetDatasourceUID *string                        `json:"target_datasource_uid,omitempty" yaml:"target_datasource_uid,omitempty" hcl:"target_datasource_uid"`
+	Metadata            *AlertRuleRecordMetadataExport `json:"metadata,omitempty" yaml:"metadata,omitempty" hcl:"metadata,block"`
+}
+
+// AlertRuleRecordMetadataExport is the provisioned export of models.RecordMetadata.
+type AlertRuleRecordMetadataExport struct {
+	Type string `json:"type,omitempty" yaml:"type,omitempty" hcl:"type"`
+	Unit string `json:"unit,omitempty" yaml:"unit,omitempty" hcl:"unit"`
+	Help string `json:"help,omitempty" yaml:"help,omitempty" hcl:"help"`
 }
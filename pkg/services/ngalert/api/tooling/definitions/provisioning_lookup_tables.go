@@ -0,0 +1,29 @@
+package definitions
+
+// swagger:route GET /v1/provisioning/lookup-tables provisioning stable RouteGetLookupTables
+//
+// Get the lookup tables for templates of alert rules.
+//
+//     Responses:
+//       200: LookupTables
+
+// swagger:route PUT /v1/provisioning/lookup-tables provisioning stable RoutePutLookupTables
+//
+// Replace the lookup tables for templates of alert rules.
+//
+//     Consumes:
+//     - application/json
+//
+//     Responses:
+//       202: LookupTables
+//       400: PublicError
+
+// swagger:parameters RoutePutLookupTables
+type LookupTablesPayload struct {
+	// in:body
+	Body LookupTables
+}
+
+// LookupTables are key/value tables, by table name, that templates of alert rules can read with the lookup function.
+// swagger:model
+type LookupTables map[string]map[string]string
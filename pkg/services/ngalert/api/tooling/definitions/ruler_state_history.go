@@ -10,6 +10,9 @@ import "github.com/grafana/grafana-plugin-sdk-go/data"
 // In addition to defined query parameters it accepts filter by labels. The query parameter name must start with 'labels_'
 //   Example: /v1/rules/history?labels_myKey1=myValue1&labels_myKey2=myValue2
 //
+// If the number of transitions exceeds the limit, the token to the next page is returned in the field continueToken of the custom metadata of the frame.
+// If the parameter aggregation is specified, the response contains the aggregation of transitions instead of the transitions.
+//
 //     Produces:
 //     - application/json
 //
@@ -50,4 +53,33 @@ type StateHistoryParams struct {
 	DashboardUID string
 	// Filter by dashboard's panel ID. Requires Dashboard UID to be specified.
 	PanelID int64
+	// Filter by the state the alert instance transitioned from, e.g. Normal.
+	// in:query
+	// required: false
+	PreviousState string `json:"previousState"`
+	// Filter by the state the alert instance transitioned to, e.g. Alerting.
+	// in:query
+	// required: false
+	CurrentState string `json:"currentState"`
+	// Filter by the reason of the state the alert instance transitioned to, e.g. NoData.
+	// in:query
+	// required: false
+	Reason string `json:"reason"`
+	// The token returned with the previous page of results. Other parameters must not change between pages.
+	// in:query
+	// required: false
+	ContinueToken string `json:"continueToken"`
+	// Aggregation of transitions to return instead of the transitions.
+	// in:query
+	// required: false
+	// enum: transitions,flapping,time_in_state
+	Aggregation string `json:"aggregation"`
+	// The width of time buckets of the transitions aggregation in seconds. Defaults to 1 hour.
+	// in:query
+	// required: false
+	Interval int64 `json:"interval"`
+	// The maximum number of rules returned by the flapping aggregation. Defaults to 10.
+	// in:query
+	// required: false
+	TopN int `json:"topN"`
 }
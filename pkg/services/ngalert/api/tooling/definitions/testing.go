@@ -68,6 +68,19 @@ import (
 //     Responses:
 //       200: BacktestResult
 
+// swagger:route Post /v1/rule/backtest/group testing BacktestGroupConfig
+//
+// Test a Prometheus rule group
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Responses:
+//       200: BacktestGroupResult
+
 // swagger:parameters RouteTestReceiverConfig
 type TestReceiverRequest struct {
 	// in:body
@@ -110,6 +123,10 @@ type PostableExtendedRuleNodeExtended struct {
 	NamespaceTitle string `json:"folderTitle"`
 	// example: eval_group_1
 	RuleGroup string `json:"ruleGroup"`
+	// PreviewNotifications makes the response a TestGrafanaRulePreview that, in addition to the alerts, describes how
+	// the alerts are routed by the current notification configuration of the organization and what notifications
+	// the integrations would send.
+	PreviewNotifications bool `json:"preview_notifications,omitempty"`
 }
 
 func (n *PostableExtendedRuleNodeExtended) UnmarshalJSON(b []byte) error {
@@ -120,6 +137,72 @@ func (n *PostableExtendedRuleNodeExtended) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// swagger:model
+type TestGrafanaRulePreview struct {
+	// Alerts are the alerts produced by the rule, the same as the ones returned without preview of notifications.
+	Alerts []*amv2.PostableAlert `json:"alerts"`
+	// Routing describes how each alert is routed, in the same order as Alerts.
+	Routing []AlertRoutingPreview `json:"routing"`
+	// Notifications are the notifications that would be sent for firing alerts that are neither silenced nor muted.
+	Notifications []NotificationPreview `json:"notifications"`
+}
+
+// NotificationsPreview is the result of routing alerts through the notification configuration of an organization.
+type NotificationsPreview struct {
+	Routing       []AlertRoutingPreview
+	Notifications []NotificationPreview
+}
+
+// swagger:model
+type AlertRoutingPreview struct {
+	Labels map[string]string `json:"labels"`
+	// Firing is false if the alert is resolved or normal. Such alerts do not cause notifications.
+	Firing bool `json:"firing"`
+	// Routes are the notification policies that match the alert.
+	Routes []RoutePreview `json:"routes"`
+	// SilencedBy contains IDs of active silences that match the alert.
+	SilencedBy []string `json:"silencedBy"`
+}
+
+// swagger:model
+type RoutePreview struct {
+	// Key identifies the route in the policy tree.
+	Key      string `json:"key"`
+	Receiver string `json:"receiver"`
+	// Autogenerated is true if the route is generated from the notification settings of rules (simplified routing).
+	Autogenerated       bool              `json:"autogenerated"`
+	GroupBy             []string          `json:"groupBy"`
+	GroupLabels         map[string]string `json:"groupLabels"`
+	MuteTimeIntervals   []string          `json:"muteTimeIntervals,omitempty"`
+	ActiveTimeIntervals []string          `json:"activeTimeIntervals,omitempty"`
+	// Muted is true if notifications of the route are currently suppressed by its mute or active time intervals.
+	Muted bool `json:"muted"`
+	// MutedBy contains the mute time intervals that are currently in effect. It is empty if the route is muted
+	// only because none of its active time intervals is in effect.
+	MutedBy []string `json:"mutedBy,omitempty"`
+}
+
+// swagger:model
+type NotificationPreview struct {
+	Receiver    string            `json:"receiver"`
+	GroupKey    string            `json:"groupKey"`
+	GroupLabels map[string]string `json:"groupLabels"`
+	// Firing is the number of firing alerts in the notification.
+	Firing       int                  `json:"firing"`
+	Integrations []IntegrationPreview `json:"integrations"`
+}
+
+// swagger:model
+type IntegrationPreview struct {
+	UID   string `json:"uid"`
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	// Error is set if the title or the body could not be rendered.
+	Error string `json:"error,omitempty"`
+}
+
 // swagger:parameters RouteEvalQueries
 type EvalQueriesRequest struct {
 	// in:body
@@ -230,7 +313,63 @@ type BacktestConfig struct {
 	Annotations map[string]string `json:"annotations,omitempty"`
 
 	NoDataState NoDataState `json:"no_data_state"`
+
+	// NamespaceUID is the UID of the folder the rule would belong to. It is used to build labels the alerts
+	// would have, which is important when simulating notifications.
+	// example: okrd3I0Vz
+	NamespaceUID string `json:"folderUid,omitempty"`
+	// NotificationSettings of the rule. Used only when notifications are simulated.
+	NotificationSettings *AlertRuleNotificationSettings `json:"notification_settings,omitempty"`
+	// SimulateNotifications enables replay of the alerts through the current notification policy tree, mute timings and
+	// silences of the organization. The result is added to the meta of the returned frame.
+	SimulateNotifications bool `json:"simulate_notifications,omitempty"`
+	// Alternatives are settings of the rule for which the summary of the backtesting contains statistics
+	// in addition to the statistics of the actual settings.
+	Alternatives []BacktestAlternative `json:"alternatives,omitempty"`
+	// Sweep makes the backtesting replay the results of evaluations with every combination of the settings.
+	// The data is queried only once. The result is added to the meta of the returned frame.
+	Sweep *BacktestSweep `json:"sweep,omitempty"`
+}
+
+// swagger:model
+type BacktestSweep struct {
+	// Thresholds are values of the threshold expression that is the condition of the rule.
+	Thresholds []float64 `json:"thresholds,omitempty"`
+	// For are pending periods of the rule.
+	For []model.Duration `json:"for,omitempty"`
+}
+
+// swagger:model
+type BacktestAlternative struct {
+	// For is the pending period.
+	For model.Duration `json:"for,omitempty"`
+	// KeepFiringFor is the period during which an alert keeps firing after the condition stops being met.
+	KeepFiringFor model.Duration `json:"keep_firing_for,omitempty"`
 }
 
 // swagger:model
 type BacktestResult data.Frame
+
+// swagger:parameters BacktestGroupConfig
+type BacktestGroupConfigRequest struct {
+	// in:body
+	Body BacktestGroupConfig
+}
+
+// swagger:model
+type BacktestGroupConfig struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	// DatasourceUID is the UID of the Prometheus or Loki data source the rules of the group query.
+	// example: prometheus
+	DatasourceUID string `json:"datasourceUid"`
+	// Group is converted to Grafana rules the same way as it would be converted when it is imported.
+	// Rules are evaluated in the order they are defined, and the series recorded by recording rules are used
+	// by the following alerting rules of the group.
+	Group PrometheusRuleGroup `json:"group"`
+}
+
+// BacktestGroupResult contains a frame per rule of the group in the order of evaluation, followed by a frame with the summary of the group.
+// swagger:model
+type BacktestGroupResult data.Frames
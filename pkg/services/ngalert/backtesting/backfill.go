@@ -0,0 +1,628 @@
+package backtesting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/schedule"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+const (
+	backfillKVNamespace = "alerting"
+	// backfillKVKeyPrefix is the prefix of the keys of backfills. Every backfill is stored in a key of its own,
+	// which is the prefix followed by the ID of the backfill.
+	backfillKVKeyPrefix = "recording_rule_backfill/"
+
+	// backfillCheckpointInterval is how often a running backfill saves its progress. The saved progress also tells
+	// other instances that the backfill is still running.
+	backfillCheckpointInterval = 10 * time.Second
+	// backfillStaleTimeout is the time after which a running backfill that has not saved its progress is considered
+	// abandoned by its instance, for example because the instance crashed, and is resumed by another one.
+	backfillStaleTimeout = 5 * time.Minute
+	// backfillResumeInterval is how often the backfiller looks for interrupted backfills to resume.
+	backfillResumeInterval = time.Minute
+	// backfillRetention is how long finished backfills are kept.
+	backfillRetention = 7 * 24 * time.Hour
+	// backfillUpdateAttempts is the number of times an update of a backfill is attempted if the backfill
+	// is modified concurrently.
+	backfillUpdateAttempts = 3
+	// backfillMaxConsecutiveErrors is the number of consecutive failed evaluations after which a backfill fails.
+	backfillMaxConsecutiveErrors = 10
+	// backfillMaxEvaluations is the maximum number of evaluations of a backfill, about a month of a rule
+	// that is evaluated every minute.
+	backfillMaxEvaluations = 50000
+	// backfillMinStepDuration is the minimum duration of an evaluation of a backfill. Faster evaluations wait
+	// before the next one, so a backfill does not query the data sources faster than 20 times per second.
+	backfillMinStepDuration = 50 * time.Millisecond
+)
+
+var (
+	ErrBackfillNotFound = errors.New("backfill not found")
+	ErrBackfillConflict = errors.New("backfill conflict")
+
+	errBackfillCanceled  = errors.New("backfill canceled")
+	errBackfillStopped   = errors.New("backfiller stopped")
+	errBackfillTakenOver = errors.New("backfill is run by another instance")
+	errBackfillModified  = fmt.Errorf("%w: backfill was modified concurrently", ErrBackfillConflict)
+)
+
+type BackfillStatus string
+
+const (
+	// BackfillPending is the status of a backfill that waits to be resumed by any instance, for example because
+	// the instance that ran it was stopped.
+	BackfillPending   BackfillStatus = "pending"
+	BackfillRunning   BackfillStatus = "running"
+	BackfillCompleted BackfillStatus = "completed"
+	BackfillFailed    BackfillStatus = "failed"
+	BackfillCanceled  BackfillStatus = "canceled"
+)
+
+// IsFinished returns true if the backfill does not run and is not resumed automatically.
+func (s BackfillStatus) IsFinished() bool {
+	return s == BackfillCompleted || s == BackfillFailed || s == BackfillCanceled
+}
+
+// BackfillJob is a backfill of a recording rule over the range [From, To). The rule is evaluated at every interval step
+// of the range, and the recorded series are written with the timestamps of the evaluations.
+type BackfillJob struct {
+	ID              string         `json:"id"`
+	OrgID           int64          `json:"org_id"`
+	RuleUID         string         `json:"rule_uid"`
+	From            time.Time      `json:"from"`
+	To              time.Time      `json:"to"`
+	IntervalSeconds int64          `json:"interval_seconds"`
+	Status          BackfillStatus `json:"status"`
+	// Evaluations is the total number of evaluations, of which Completed are done. A backfill is resumed
+	// from the first evaluation that is not completed.
+	Evaluations int `json:"evaluations"`
+	Completed   int `json:"completed"`
+	// Failed is the number of completed evaluations that failed, and Error is the last error of the backfill.
+	Failed    int       `json:"failed"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Runner identifies the current run of the backfill. A run stops if it finds another runner in the store,
+	// which means that the backfill has been resumed by another instance.
+	Runner string `json:"runner,omitempty"`
+	// Version is incremented every time the backfill is saved. A backfill is saved only if the stored version
+	// is the one it was loaded with, so concurrent updates by different instances do not overwrite each other.
+	Version int64 `json:"version"`
+}
+
+// BackfillWriter writes the series recorded by backfills. Backfilled samples are historical, so they are written
+// directly to the target instead of being batched with the series of the rules that are evaluated, and errors
+// of the target, such as rejected out-of-order samples, are returned instead of being retried.
+type BackfillWriter interface {
+	WriteDatasourceDirect(ctx context.Context, dsUID string, name string, metadata models.RecordMetadata, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error
+}
+
+// BackfillRuleStore provides the rules that are backfilled.
+type BackfillRuleStore interface {
+	GetAlertRuleByUID(ctx context.Context, query *models.GetAlertRuleByUIDQuery) (*models.AlertRule, error)
+}
+
+// Backfiller backfills the series of recording rules over historical ranges. The rules are evaluated the same way
+// as by the scheduler, and the results are written by the recording writer. Backfills run in the background
+// and save their progress in the key/value store, one key per backfill, so they can be resumed after a restart
+// by any instance.
+type Backfiller struct {
+	evalFactory eval.EvaluatorFactory
+	writer      BackfillWriter
+	rules       BackfillRuleStore
+	kv          kvstore.KVStore
+	clock       clock.Clock
+	log         log.Logger
+
+	maxEvaluations  int
+	minStepDuration time.Duration
+
+	// mtx guards the backfills in the store against concurrent updates by this instance, and the fields below.
+	mtx     sync.Mutex
+	running map[string]context.CancelCauseFunc
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+func NewBackfiller(evalFactory eval.EvaluatorFactory, writer BackfillWriter, rules BackfillRuleStore, kv kvstore.KVStore, clock clock.Clock, log log.Logger) *Backfiller {
+	return &Backfiller{
+		evalFactory: evalFactory,
+		writer:      writer,
+		rules:       rules,
+		kv:          kv,
+		clock:       clock,
+		log:         log,
+
+		maxEvaluations:  backfillMaxEvaluations,
+		minStepDuration: backfillMinStepDuration,
+
+		running: make(map[string]context.CancelCauseFunc),
+	}
+}
+
+// Run resumes interrupted backfills until the context is done. Then, it stops the backfills run by this instance,
+// which are resumed later by any instance.
+func (b *Backfiller) Run(ctx context.Context) error {
+	ticker := b.clock.Ticker(backfillResumeInterval)
+	defer ticker.Stop()
+	for {
+		b.resumeInterrupted(ctx)
+		select {
+		case <-ctx.Done():
+			b.mtx.Lock()
+			b.stopped = true
+			for _, cancel := range b.running {
+				cancel(errBackfillStopped)
+			}
+			b.mtx.Unlock()
+			b.wg.Wait()
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Start starts a backfill of the recording rule over the range [from, to), which must be in the past and must not
+// have more than backfillMaxEvaluations evaluations of the rule.
+// Returns ErrBackfillConflict if another backfill of the rule is in progress.
+func (b *Backfiller) Start(ctx context.Context, rule *models.AlertRule, from, to time.Time) (BackfillJob, error) {
+	if rule.Type() != models.RuleTypeRecording {
+		return BackfillJob{}, fmt.Errorf("%w: only recording rules can be backfilled", ErrInvalidInputData)
+	}
+	now := b.clock.Now()
+	if to.After(now) {
+		return BackfillJob{}, fmt.Errorf("%w: the end of the backfill range must not be in the future", ErrInvalidInputData)
+	}
+	evaluations, err := evaluationsCount(from, to, rule.IntervalSeconds)
+	if err != nil {
+		return BackfillJob{}, err
+	}
+	if evaluations > b.maxEvaluations {
+		return BackfillJob{}, fmt.Errorf("%w: the backfill range has %d evaluations of the rule, the maximum is %d", ErrInvalidInputData, evaluations, b.maxEvaluations)
+	}
+	job := BackfillJob{
+		ID:              util.GenerateShortUID(),
+		OrgID:           rule.OrgID,
+		RuleUID:         rule.UID,
+		From:            from.UTC(),
+		To:              to.UTC(),
+		IntervalSeconds: rule.IntervalSeconds,
+		Status:          BackfillRunning,
+		Evaluations:     evaluations,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		Runner:          util.GenerateShortUID(),
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	jobs, err := b.load(ctx, rule.OrgID)
+	if err != nil {
+		return BackfillJob{}, err
+	}
+	if err := checkNoBackfillInProgress(jobs, rule.UID); err != nil {
+		return BackfillJob{}, err
+	}
+	if b.stopped {
+		job.Status = BackfillPending
+	}
+	if err := b.save(ctx, &job); err != nil {
+		return BackfillJob{}, err
+	}
+	b.log.FromContext(ctx).Info("Starting backfill of recording rule", "org_id", job.OrgID, "rule_uid", job.RuleUID, "backfill_id", job.ID, "from", job.From, "to", job.To, "evaluations", job.Evaluations)
+	if job.Status == BackfillRunning {
+		b.startLocked(job)
+	}
+	return job, nil
+}
+
+// Get returns the backfill with the given ID.
+func (b *Backfiller) Get(ctx context.Context, orgID int64, id string) (BackfillJob, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.loadJob(ctx, orgID, id)
+}
+
+// List returns the backfills of the rule, the most recent first.
+func (b *Backfiller) List(ctx context.Context, orgID int64, ruleUID string) ([]BackfillJob, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	jobs, err := b.load(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]BackfillJob, 0)
+	for _, job := range jobs {
+		if job.RuleUID == ruleUID {
+			result = append(result, job)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// Cancel cancels the backfill with the given ID. The series written so far are not deleted.
+// Returns ErrBackfillConflict if the backfill is already finished.
+func (b *Backfiller) Cancel(ctx context.Context, orgID int64, id string) (BackfillJob, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	job, err := b.updateLocked(ctx, orgID, id, func(job *BackfillJob) error {
+		if job.Status.IsFinished() {
+			return fmt.Errorf("%w: backfill is %s", ErrBackfillConflict, job.Status)
+		}
+		job.Status = BackfillCanceled
+		return nil
+	})
+	if err != nil {
+		return BackfillJob{}, err
+	}
+	// A backfill run by another instance stops at its next checkpoint.
+	if cancel, ok := b.running[id]; ok {
+		cancel(errBackfillCanceled)
+	}
+	b.log.FromContext(ctx).Info("Canceled backfill of recording rule", "org_id", job.OrgID, "rule_uid", job.RuleUID, "backfill_id", job.ID)
+	return job, nil
+}
+
+// Resume resumes the failed or canceled backfill with the given ID from the first evaluation that is not completed.
+// Returns ErrBackfillConflict if the backfill is not failed or canceled, or if another backfill of the rule is in progress.
+func (b *Backfiller) Resume(ctx context.Context, orgID int64, id string) (BackfillJob, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	jobs, err := b.load(ctx, orgID)
+	if err != nil {
+		return BackfillJob{}, err
+	}
+	job, ok := jobs[id]
+	if !ok {
+		return BackfillJob{}, ErrBackfillNotFound
+	}
+	if job.Status != BackfillFailed && job.Status != BackfillCanceled {
+		return BackfillJob{}, fmt.Errorf("%w: only failed or canceled backfills can be resumed, backfill is %s", ErrBackfillConflict, job.Status)
+	}
+	if err := checkNoBackfillInProgress(jobs, job.RuleUID); err != nil {
+		return BackfillJob{}, err
+	}
+	job.Status = BackfillRunning
+	if b.stopped {
+		job.Status = BackfillPending
+	}
+	job.Runner = util.GenerateShortUID()
+	job.UpdatedAt = b.clock.Now()
+	if err := b.save(ctx, &job); err != nil {
+		return BackfillJob{}, err
+	}
+	b.log.FromContext(ctx).Info("Resuming backfill of recording rule", "org_id", job.OrgID, "rule_uid", job.RuleUID, "backfill_id", job.ID, "completed", job.Completed, "evaluations", job.Evaluations)
+	if job.Status == BackfillRunning {
+		b.startLocked(job)
+	}
+	return job, nil
+}
+
+// resumeInterrupted resumes the pending backfills, and the running backfills that have not saved their progress
+// for a while. Backfills that finished more than backfillRetention ago are deleted.
+func (b *Backfiller) resumeInterrupted(ctx context.Context) {
+	keys, err := b.kv.Keys(ctx, kvstore.AllOrganizations, backfillKVNamespace, backfillKVKeyPrefix)
+	if err != nil {
+		b.log.Error("Failed to list backfills", "error", err)
+		return
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.stopped {
+		return
+	}
+	for _, key := range keys {
+		id := strings.TrimPrefix(key.Key, backfillKVKeyPrefix)
+		if _, ok := b.running[id]; ok {
+			continue
+		}
+		job, err := b.loadJob(ctx, key.OrgId, id)
+		if err != nil {
+			b.log.Error("Failed to load backfill", "org_id", key.OrgId, "backfill_id", id, "error", err)
+			continue
+		}
+		if job.Status.IsFinished() {
+			if b.clock.Since(job.UpdatedAt) > backfillRetention {
+				if err := kvstore.WithNamespace(b.kv, key.OrgId, backfillKVNamespace).Del(ctx, key.Key); err != nil {
+					b.log.Error("Failed to delete finished backfill", "org_id", key.OrgId, "backfill_id", id, "error", err)
+				}
+			}
+			continue
+		}
+		stale := job.Status == BackfillRunning && b.clock.Since(job.UpdatedAt) > backfillStaleTimeout
+		if job.Status != BackfillPending && !stale {
+			continue
+		}
+		job.Status = BackfillRunning
+		job.Runner = util.GenerateShortUID()
+		job.UpdatedAt = b.clock.Now()
+		// The save fails if another instance resumed the backfill since it was loaded.
+		if err := b.save(ctx, &job); err != nil {
+			b.log.Error("Failed to save resumed backfill", "org_id", key.OrgId, "backfill_id", id, "error", err)
+			continue
+		}
+		b.log.Info("Resuming interrupted backfill of recording rule", "org_id", job.OrgID, "rule_uid", job.RuleUID, "backfill_id", job.ID, "completed", job.Completed, "evaluations", job.Evaluations)
+		b.startLocked(job)
+	}
+}
+
+// startLocked runs the backfill in the background. It must be called with mtx held.
+func (b *Backfiller) startLocked(job BackfillJob) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	b.running[job.ID] = cancel
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.run(ctx, job)
+		b.mtx.Lock()
+		delete(b.running, job.ID)
+		b.mtx.Unlock()
+		cancel(nil)
+	}()
+}
+
+func (b *Backfiller) run(ctx context.Context, job BackfillJob) {
+	logger := b.log.New("org_id", job.OrgID, "rule_uid", job.RuleUID, "backfill_id", job.ID)
+	start := b.clock.Now()
+
+	err := b.backfill(ctx, &job, logger)
+	switch {
+	case err == nil:
+		job.Status = BackfillCompleted
+	case errors.Is(err, errBackfillTakenOver):
+		logger.Info("Backfill is resumed by another instance, stopping")
+		return
+	case errors.Is(err, errBackfillStopped):
+		job.Status = BackfillPending
+	case errors.Is(err, errBackfillCanceled):
+		job.Status = BackfillCanceled
+	default:
+		job.Status = BackfillFailed
+		job.Error = err.Error()
+	}
+
+	// The context of the backfill is done at this point.
+	_, err = b.update(context.Background(), job.OrgID, job.ID, func(stored *BackfillJob) error {
+		if stored.Runner != job.Runner {
+			return errBackfillTakenOver
+		}
+		canceled := stored.Status == BackfillCanceled
+		*stored = job
+		if canceled {
+			stored.Status = BackfillCanceled
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to save backfill", "error", err)
+		return
+	}
+	logger.Info("Backfill of recording rule stopped", "status", job.Status, "completed", job.Completed, "evaluations", job.Evaluations, "failed", job.Failed, "duration", b.clock.Since(start))
+}
+
+// backfill evaluates the rule at every step of the range that is not completed yet, and saves the progress periodically.
+// Evaluations are paced by minStepDuration, and the queries share the limits of the data sources with the scheduler.
+func (b *Backfiller) backfill(ctx context.Context, job *BackfillJob, logger log.Logger) error {
+	rule, err := b.rules.GetAlertRuleByUID(ctx, &models.GetAlertRuleByUIDQuery{OrgID: job.OrgID, UID: job.RuleUID})
+	if err != nil {
+		return fmt.Errorf("failed to get rule: %w", err)
+	}
+	if rule.Type() != models.RuleTypeRecording {
+		return errors.New("the rule is not a recording rule anymore")
+	}
+	ruleCtx := models.WithRuleKey(ctx, rule.GetKey())
+	evaluator, err := b.evalFactory.Create(eval.NewContext(ruleCtx, schedule.SchedulerUserFor(rule.OrgID)), rule.GetEvalCondition().WithSource("backfill"))
+	if err != nil {
+		return fmt.Errorf("failed to create evaluator: %w", err)
+	}
+
+	interval := time.Duration(job.IntervalSeconds) * time.Second
+	lastCheckpoint := b.clock.Now()
+	consecutiveErrors := 0
+	for job.Completed < job.Evaluations {
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		stepStart := b.clock.Now()
+		now := job.From.Add(time.Duration(job.Completed) * interval)
+		if err := b.evaluate(ruleCtx, evaluator, rule, now); err != nil {
+			if ctx.Err() != nil {
+				return context.Cause(ctx)
+			}
+			consecutiveErrors++
+			if consecutiveErrors >= backfillMaxConsecutiveErrors {
+				return fmt.Errorf("%d consecutive evaluations failed: %w", consecutiveErrors, err)
+			}
+			logger.Warn("Failed to backfill evaluation", "time", now, "error", err)
+			job.Failed++
+			job.Error = err.Error()
+		} else {
+			consecutiveErrors = 0
+		}
+		job.Completed++
+
+		if b.clock.Since(lastCheckpoint) >= backfillCheckpointInterval {
+			if err := b.checkpoint(ctx, job); err != nil {
+				return err
+			}
+			lastCheckpoint = b.clock.Now()
+		}
+		if job.Completed < job.Evaluations {
+			if err := b.pace(ctx, stepStart); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pace waits until minStepDuration has passed since the start of the evaluation.
+func (b *Backfiller) pace(ctx context.Context, stepStart time.Time) error {
+	wait := b.minStepDuration - b.clock.Since(stepStart)
+	if wait <= 0 {
+		return nil
+	}
+	timer := b.clock.Timer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timer.C:
+		return nil
+	}
+}
+
+// evaluate evaluates the rule at the given time and writes the recorded series with that timestamp.
+func (b *Backfiller) evaluate(ctx context.Context, evaluator eval.ConditionEvaluator, rule *models.AlertRule, now time.Time) error {
+	resp, err := evaluator.EvaluateRaw(ctx, now)
+	if err != nil {
+		return err
+	}
+	frames, err := recordedFrames(rule, resp)
+	if err != nil || len(frames) == 0 {
+		return err
+	}
+	return b.writer.WriteDatasourceDirect(ctx, rule.Record.TargetDatasourceUID, rule.Record.Metric, rule.Record.Metadata, now, frames, rule.OrgID, rule.Labels)
+}
+
+// checkpoint saves the progress of the backfill. It returns an error if the backfill must stop because it has been
+// canceled or resumed by another instance.
+func (b *Backfiller) checkpoint(ctx context.Context, job *BackfillJob) error {
+	_, err := b.update(ctx, job.OrgID, job.ID, func(stored *BackfillJob) error {
+		if stored.Runner != job.Runner {
+			return errBackfillTakenOver
+		}
+		if stored.Status != BackfillRunning {
+			return errBackfillCanceled
+		}
+		*stored = *job
+		return nil
+	})
+	return err
+}
+
+func (b *Backfiller) update(ctx context.Context, orgID int64, id string, fn func(job *BackfillJob) error) (BackfillJob, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.updateLocked(ctx, orgID, id, fn)
+}
+
+// updateLocked applies the function to the stored backfill and saves it. If the backfill is modified concurrently
+// by another instance, the update is retried with the newly stored backfill. It must be called with mtx held.
+func (b *Backfiller) updateLocked(ctx context.Context, orgID int64, id string, fn func(job *BackfillJob) error) (BackfillJob, error) {
+	var err error
+	for attempt := 0; attempt < backfillUpdateAttempts; attempt++ {
+		var job BackfillJob
+		job, err = b.loadJob(ctx, orgID, id)
+		if err != nil {
+			return BackfillJob{}, err
+		}
+		version := job.Version
+		if err := fn(&job); err != nil {
+			return BackfillJob{}, err
+		}
+		job.Version = version
+		job.UpdatedAt = b.clock.Now()
+		if err = b.save(ctx, &job); err == nil {
+			return job, nil
+		}
+		if !errors.Is(err, errBackfillModified) {
+			return BackfillJob{}, err
+		}
+	}
+	return BackfillJob{}, err
+}
+
+// load returns the backfills of the organization by their ID.
+func (b *Backfiller) load(ctx context.Context, orgID int64) (map[string]BackfillJob, error) {
+	keys, err := b.kv.Keys(ctx, orgID, backfillKVNamespace, backfillKVKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backfills: %w", err)
+	}
+	jobs := make(map[string]BackfillJob, len(keys))
+	for _, key := range keys {
+		job, err := b.loadJob(ctx, orgID, strings.TrimPrefix(key.Key, backfillKVKeyPrefix))
+		if err != nil {
+			// The backfill is deleted after its key has been listed.
+			if errors.Is(err, ErrBackfillNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		jobs[job.ID] = job
+	}
+	return jobs, nil
+}
+
+// loadJob returns the backfill with the given ID, or ErrBackfillNotFound if it does not exist.
+func (b *Backfiller) loadJob(ctx context.Context, orgID int64, id string) (BackfillJob, error) {
+	content, exists, err := kvstore.WithNamespace(b.kv, orgID, backfillKVNamespace).Get(ctx, backfillKVKeyPrefix+id)
+	if err != nil {
+		return BackfillJob{}, fmt.Errorf("failed to read backfill: %w", err)
+	}
+	if !exists {
+		return BackfillJob{}, ErrBackfillNotFound
+	}
+	var job BackfillJob
+	if err := json.Unmarshal([]byte(content), &job); err != nil {
+		return BackfillJob{}, fmt.Errorf("failed to decode backfill: %w", err)
+	}
+	return job, nil
+}
+
+// save stores the backfill and increments its version. A new backfill, whose version is zero, must not exist, and
+// an existing one must have the same version in the store, otherwise errBackfillModified is returned.
+// The key/value store has no conditional writes, so the check does not exclude updates by another instance
+// between the read and the write, but it prevents overwriting any update that was saved before.
+func (b *Backfiller) save(ctx context.Context, job *BackfillJob) error {
+	stored, err := b.loadJob(ctx, job.OrgID, job.ID)
+	switch {
+	case errors.Is(err, ErrBackfillNotFound):
+		if job.Version != 0 {
+			return err
+		}
+	case err != nil:
+		return err
+	case stored.Version != job.Version:
+		return errBackfillModified
+	}
+
+	saved := *job
+	saved.Version++
+	content, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	if err := kvstore.WithNamespace(b.kv, job.OrgID, backfillKVNamespace).Set(ctx, backfillKVKeyPrefix+job.ID, string(content)); err != nil {
+		return fmt.Errorf("failed to save backfill: %w", err)
+	}
+	job.Version = saved.Version
+	return nil
+}
+
+func checkNoBackfillInProgress(jobs map[string]BackfillJob, ruleUID string) error {
+	for _, job := range jobs {
+		if job.RuleUID == ruleUID && !job.Status.IsFinished() {
+			return fmt.Errorf("%w: backfill %s of the rule is in progress", ErrBackfillConflict, job.ID)
+		}
+	}
+	return nil
+}
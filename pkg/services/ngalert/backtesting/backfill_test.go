@@ -0,0 +1,303 @@
+package backtesting
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval/eval_mocks"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests/fakes"
+	"github.com/grafana/grafana/pkg/services/ngalert/writer"
+)
+
+func TestBackfiller(t *testing.T) {
+	ctx := context.Background()
+	from := time.Unix(0, 0).UTC()
+	to := from.Add(5 * time.Minute)
+	const evaluations = 5
+
+	gen := models.RuleGen
+	rule := gen.With(gen.WithOrgID(1), gen.WithAllRecordingRules(), gen.WithIntervalSeconds(60)).GenerateRef()
+	rule.Record.Metadata = models.RecordMetadata{Type: "gauge"}
+	rules := fakes.NewRuleStore(t)
+	rules.PutRule(ctx, rule)
+
+	evaluator := &fakeRawEvaluator{
+		evalRawCallback: func(now time.Time) *backend.QueryDataResponse {
+			frame := data.NewFrame("",
+				data.NewField("T", nil, []time.Time{now}),
+				data.NewField("value", data.Labels{"job": "api"}, []float64{1}),
+			)
+			frame.SetMeta(&data.FrameMeta{Type: data.FrameTypeNumericWide, TypeVersion: data.FrameTypeVersion{0, 1}})
+			return &backend.QueryDataResponse{
+				Responses: backend.Responses{rule.Record.From: backend.DataResponse{Frames: data.Frames{frame}}},
+			}
+		},
+	}
+
+	// recordingWriter records the timestamps of writes. If release is not nil, every write waits for a value from it.
+	type recordingWriter struct {
+		mtx     sync.Mutex
+		written []time.Time
+		release chan struct{}
+		err     error
+	}
+	newWriter := func(w *recordingWriter) writer.FakeWriter {
+		return writer.FakeWriter{WriteFunc: func(ctx context.Context, _ string, name string, metadata models.RecordMetadata, t time.Time, _ data.Frames, _ int64, _ map[string]string) error {
+			require.Equal(t, rule.Record.Metric, name)
+			require.Equal(t, rule.Record.Metadata, metadata)
+			if w.release != nil {
+				select {
+				case <-w.release:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			w.mtx.Lock()
+			defer w.mtx.Unlock()
+			w.written = append(w.written, t)
+			return w.err
+		}}
+	}
+	steps := func(from time.Time, n int) []time.Time {
+		result := make([]time.Time, 0, n)
+		for i := 0; i < n; i++ {
+			result = append(result, from.Add(time.Duration(i)*time.Minute))
+		}
+		return result
+	}
+
+	setup := func(kv kvstore.KVStore, w *recordingWriter) *Backfiller {
+		clk := clock.NewMock()
+		clk.Set(from.Add(time.Hour))
+		b := NewBackfiller(eval_mocks.NewEvaluatorFactory(evaluator), newWriter(w), rules, kv, clk, log.NewNopLogger())
+		b.minStepDuration = 0
+		return b
+	}
+	waitIdle := func(b *Backfiller) {
+		require.Eventually(t, func() bool {
+			b.mtx.Lock()
+			defer b.mtx.Unlock()
+			return len(b.running) == 0
+		}, time.Second, time.Millisecond)
+	}
+
+	t.Run("writes recorded series at every step of the range", func(t *testing.T) {
+		w := &recordingWriter{}
+		b := setup(fakes.NewFakeKVStore(t), w)
+
+		job, err := b.Start(ctx, rule, from, to)
+		require.NoError(t, err)
+		require.Equal(t, evaluations, job.Evaluations)
+		waitIdle(b)
+
+		job, err = b.Get(ctx, rule.OrgID, job.ID)
+		require.NoError(t, err)
+		require.Equal(t, BackfillCompleted, job.Status)
+		require.Equal(t, evaluations, job.Completed)
+		require.Zero(t, job.Failed)
+		require.Equal(t, steps(from, evaluations), w.written)
+	})
+
+	t.Run("rejects invalid ranges and rules", func(t *testing.T) {
+		b := setup(fakes.NewFakeKVStore(t), &recordingWriter{})
+
+		_, err := b.Start(ctx, rule, from, from.Add(2*time.Hour))
+		require.ErrorIs(t, err, ErrInvalidInputData)
+		_, err = b.Start(ctx, rule, to, from)
+		require.ErrorIs(t, err, ErrInvalidInputData)
+		_, err = b.Start(ctx, gen.With(gen.WithOrgID(1)).GenerateRef(), from, to)
+		require.ErrorIs(t, err, ErrInvalidInputData)
+	})
+
+	t.Run("resumes canceled backfill from the first evaluation that is not completed", func(t *testing.T) {
+		w := &recordingWriter{release: make(chan struct{}, evaluations)}
+		b := setup(fakes.NewFakeKVStore(t), w)
+		w.release <- struct{}{}
+
+		job, err := b.Start(ctx, rule, from, to)
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			w.mtx.Lock()
+			defer w.mtx.Unlock()
+			return len(w.written) == 1
+		}, time.Second, time.Millisecond)
+
+		_, err = b.Start(ctx, rule, from, to)
+		require.ErrorIs(t, err, ErrBackfillConflict)
+		_, err = b.Resume(ctx, rule.OrgID, job.ID)
+		require.ErrorIs(t, err, ErrBackfillConflict)
+
+		_, err = b.Cancel(ctx, rule.OrgID, job.ID)
+		require.NoError(t, err)
+		waitIdle(b)
+		job, err = b.Get(ctx, rule.OrgID, job.ID)
+		require.NoError(t, err)
+		require.Equal(t, BackfillCanceled, job.Status)
+		require.Equal(t, 1, job.Completed)
+
+		for i := 1; i < evaluations; i++ {
+			w.release <- struct{}{}
+		}
+		_, err = b.Resume(ctx, rule.OrgID, job.ID)
+		require.NoError(t, err)
+		waitIdle(b)
+		job, err = b.Get(ctx, rule.OrgID, job.ID)
+		require.NoError(t, err)
+		require.Equal(t, BackfillCompleted, job.Status)
+		require.Equal(t, steps(from, evaluations), w.written)
+
+		_, err = b.Cancel(ctx, rule.OrgID, job.ID)
+		require.ErrorIs(t, err, ErrBackfillConflict)
+	})
+
+	t.Run("backfill stopped by shutdown is resumed by another instance", func(t *testing.T) {
+		kv := fakes.NewFakeKVStore(t)
+		stopped := &recordingWriter{release: make(chan struct{}, 2)}
+		b := setup(kv, stopped)
+		stopped.release <- struct{}{}
+		stopped.release <- struct{}{}
+
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error)
+		go func() {
+			done <- b.Run(runCtx)
+		}()
+		job, err := b.Start(ctx, rule, from, to)
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			stopped.mtx.Lock()
+			defer stopped.mtx.Unlock()
+			return len(stopped.written) == 2
+		}, time.Second, time.Millisecond)
+		cancel()
+		require.NoError(t, <-done)
+
+		job, err = b.Get(ctx, rule.OrgID, job.ID)
+		require.NoError(t, err)
+		require.Equal(t, BackfillPending, job.Status)
+		require.Equal(t, 2, job.Completed)
+
+		resumed := &recordingWriter{}
+		other := setup(kv, resumed)
+		other.resumeInterrupted(ctx)
+		waitIdle(other)
+
+		job, err = other.Get(ctx, rule.OrgID, job.ID)
+		require.NoError(t, err)
+		require.Equal(t, BackfillCompleted, job.Status)
+		require.Equal(t, steps(from.Add(2*time.Minute), evaluations-2), resumed.written)
+	})
+
+	t.Run("fails after consecutive failed evaluations", func(t *testing.T) {
+		w := &recordingWriter{err: errors.New("write failed")}
+		b := setup(fakes.NewFakeKVStore(t), w)
+
+		job, err := b.Start(ctx, rule, from, from.Add(time.Duration(backfillMaxConsecutiveErrors+1)*time.Minute))
+		require.NoError(t, err)
+		waitIdle(b)
+
+		job, err = b.Get(ctx, rule.OrgID, job.ID)
+		require.NoError(t, err)
+		require.Equal(t, BackfillFailed, job.Status)
+		require.Equal(t, backfillMaxConsecutiveErrors-1, job.Completed)
+		require.Equal(t, backfillMaxConsecutiveErrors-1, job.Failed)
+		require.Contains(t, job.Error, "write failed")
+	})
+
+	t.Run("stores every backfill in a key of its own", func(t *testing.T) {
+		kv := fakes.NewFakeKVStore(t)
+		b := setup(kv, &recordingWriter{})
+
+		first, err := b.Start(ctx, rule, from, to)
+		require.NoError(t, err)
+		waitIdle(b)
+		second, err := b.Start(ctx, rule, from, to)
+		require.NoError(t, err)
+		waitIdle(b)
+
+		keys, err := kv.Keys(ctx, rule.OrgID, backfillKVNamespace, backfillKVKeyPrefix)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{backfillKVKeyPrefix + first.ID, backfillKVKeyPrefix + second.ID}, []string{keys[0].Key, keys[1].Key})
+		jobs, err := b.List(ctx, rule.OrgID, rule.UID)
+		require.NoError(t, err)
+		require.Len(t, jobs, 2)
+	})
+
+	t.Run("does not save backfill modified since it was loaded", func(t *testing.T) {
+		b := setup(fakes.NewFakeKVStore(t), &recordingWriter{})
+		job := BackfillJob{ID: "test", OrgID: rule.OrgID, RuleUID: rule.UID, Status: BackfillFailed}
+		require.NoError(t, b.save(ctx, &job))
+		stale := job
+
+		job.Completed = 1
+		require.NoError(t, b.save(ctx, &job))
+		require.Equal(t, int64(2), job.Version)
+		stale.Completed = 2
+		require.ErrorIs(t, b.save(ctx, &stale), ErrBackfillConflict)
+
+		stored, err := b.Get(ctx, rule.OrgID, job.ID)
+		require.NoError(t, err)
+		require.Equal(t, 1, stored.Completed)
+	})
+
+	t.Run("deletes finished backfills after the retention", func(t *testing.T) {
+		b := setup(fakes.NewFakeKVStore(t), &recordingWriter{})
+		clk := b.clock.(*clock.Mock)
+		job, err := b.Start(ctx, rule, from, to)
+		require.NoError(t, err)
+		waitIdle(b)
+
+		clk.Add(backfillRetention)
+		b.resumeInterrupted(ctx)
+		_, err = b.Get(ctx, rule.OrgID, job.ID)
+		require.NoError(t, err)
+
+		clk.Add(time.Second)
+		b.resumeInterrupted(ctx)
+		_, err = b.Get(ctx, rule.OrgID, job.ID)
+		require.ErrorIs(t, err, ErrBackfillNotFound)
+	})
+
+	t.Run("rejects ranges with too many evaluations", func(t *testing.T) {
+		b := setup(fakes.NewFakeKVStore(t), &recordingWriter{})
+		b.maxEvaluations = evaluations - 1
+
+		_, err := b.Start(ctx, rule, from, to)
+		require.ErrorIs(t, err, ErrInvalidInputData)
+	})
+
+	t.Run("waits the minimum step duration between evaluations", func(t *testing.T) {
+		w := &recordingWriter{}
+		b := setup(fakes.NewFakeKVStore(t), w)
+		b.minStepDuration = time.Second
+		clk := b.clock.(*clock.Mock)
+		written := func() int {
+			w.mtx.Lock()
+			defer w.mtx.Unlock()
+			return len(w.written)
+		}
+
+		_, err := b.Start(ctx, rule, from, to)
+		require.NoError(t, err)
+		require.Eventually(t, func() bool { return written() == 1 }, time.Second, time.Millisecond)
+		require.Never(t, func() bool { return written() > 1 }, 50*time.Millisecond, time.Millisecond)
+
+		require.Eventually(t, func() bool {
+			clk.Add(b.minStepDuration)
+			return written() == evaluations
+		}, time.Second, 10*time.Millisecond)
+		waitIdle(b)
+		require.Equal(t, steps(from, evaluations), w.written)
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/benbjohnson/clock"
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 
@@ -42,11 +43,37 @@ type stateManager interface {
 type Engine struct {
 	evalFactory        eval.EvaluatorFactory
 	createStateManager func() stateManager
+	appUrl             *url.URL
+}
+
+// TestOptions contains optional settings of a backtesting run.
+type TestOptions struct {
+	// ExtraLabels are added to every alert instance, the same way the scheduler adds built-in labels.
+	ExtraLabels data.Labels
+	// NotificationPolicy, if set, makes the engine replay the alerts that would have been sent to the Alertmanager
+	// through the notification policy tree. The result is stored in the meta of the resulting frame.
+	NotificationPolicy *NotificationPolicy
+	// Summary, if set, makes the engine calculate statistics of the alert instances. They are stored in the meta of the resulting frame.
+	Summary bool
+	// Alternatives are settings of the rule for which the engine calculates statistics in addition to the actual ones.
+	// They are calculated only if Summary is set.
+	Alternatives []Alternative
+	// Sweep, if set, makes the engine replay the results of evaluations with every combination of the settings it defines.
+	// The data is queried only once.
+	Sweep *Sweep
+}
+
+// ResultMeta contains additional information about a backtesting run. It is stored as custom meta of the resulting frame.
+type ResultMeta struct {
+	Summary       *Summary             `json:"summary,omitempty"`
+	Notifications *NotificationsResult `json:"notifications,omitempty"`
+	Sweep         *SweepResult         `json:"sweep,omitempty"`
 }
 
 func NewEngine(appUrl *url.URL, evalFactory eval.EvaluatorFactory, tracer tracing.Tracer) *Engine {
 	return &Engine{
 		evalFactory: evalFactory,
+		appUrl:      appUrl,
 		createStateManager: func() stateManager {
 			cfg := state.ManagerCfg{
 				Metrics:       nil,
@@ -64,16 +91,24 @@ func NewEngine(appUrl *url.URL, evalFactory eval.EvaluatorFactory, tracer tracin
 }
 
 func (e *Engine) Test(ctx context.Context, user identity.Requester, rule *models.AlertRule, from, to time.Time) (*data.Frame, error) {
+	return e.TestWithOptions(ctx, user, rule, from, to, TestOptions{})
+}
+
+// TestWithOptions runs backtesting of the rule in the interval [from, to) and returns a frame with state of every alert instance at each evaluation.
+func (e *Engine) TestWithOptions(ctx context.Context, user identity.Requester, rule *models.AlertRule, from, to time.Time, opts TestOptions) (*data.Frame, error) {
 	ruleCtx := models.WithRuleKey(ctx, rule.GetKey())
 	logger := logger.FromContext(ctx)
 
-	if !from.Before(to) {
-		return nil, fmt.Errorf("%w: invalid interval of the backtesting [%d,%d]", ErrInvalidInputData, from.Unix(), to.Unix())
+	length, err := evaluationsCount(from, to, rule.IntervalSeconds)
+	if err != nil {
+		return nil, err
 	}
-	if to.Sub(from).Seconds() < float64(rule.IntervalSeconds) {
-		return nil, fmt.Errorf("%w: interval of the backtesting [%d,%d] is less than evaluation interval [%ds]", ErrInvalidInputData, from.Unix(), to.Unix(), rule.IntervalSeconds)
+
+	if opts.Sweep != nil && len(opts.Sweep.Thresholds) > 0 {
+		if _, err := thresholdFromRule(rule); err != nil {
+			return nil, errors.Join(ErrInvalidInputData, err)
+		}
 	}
-	length := int(to.Sub(from).Seconds()) / int(rule.IntervalSeconds)
 
 	stateManager := e.createStateManager()
 
@@ -85,51 +120,128 @@ func (e *Engine) Test(ctx context.Context, user identity.Requester, rule *models
 		return nil, errors.Join(ErrInvalidInputData, err)
 	}
 
-	logger.Info("Start testing alert rule", "from", from, "to", to, "interval", rule.IntervalSeconds, "evaluations", length)
+	var simulator *notificationSimulator
+	var send state.Sender
+	if opts.NotificationPolicy != nil {
+		simulator, err = newNotificationSimulator(*opts.NotificationPolicy)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidInputData, err)
+		}
+		send = func(_ context.Context, toSend state.StateTransitions) {
+			alerts := make([]*amv2.PostableAlert, 0, len(toSend))
+			for _, t := range toSend {
+				alerts = append(alerts, state.StateToPostableAlert(t, e.appUrl))
+			}
+			simulator.Put(alerts)
+		}
+	}
+
+	logger.Info("Start testing alert rule", "from", from, "to", to, "interval", rule.IntervalSeconds, "evaluations", length, "simulateNotifications", simulator != nil)
 
 	start := time.Now()
 
-	tsField := data.NewField("Time", nil, make([]time.Time, length))
-	valueFields := make(map[data.Fingerprint]*data.Field)
+	frame := newStateFrameBuilder(length)
+	summary := newSummaryBuilder(length)
+	var evaluations []recordedEvaluation
 
 	err = evaluator.Eval(ruleCtx, from, time.Duration(rule.IntervalSeconds)*time.Second, length, func(idx int, currentTime time.Time, results eval.Results) error {
 		if idx >= length {
 			logger.Info("Unexpected evaluation. Skipping", "from", from, "to", to, "interval", rule.IntervalSeconds, "evaluationTime", currentTime, "evaluationIndex", idx, "expectedEvaluations", length)
 			return nil
 		}
-		states := stateManager.ProcessEvalResults(ruleCtx, currentTime, rule, results, nil, nil)
-		tsField.Set(idx, currentTime)
-		for _, s := range states {
-			field, ok := valueFields[s.CacheID]
-			if !ok {
-				field = data.NewField("", s.Labels, make([]*string, length))
-				valueFields[s.CacheID] = field
-			}
-			if s.State.State != eval.NoData { // set nil if NoData
-				value := s.State.State.String()
-				if s.StateReason != "" {
-					value += " (" + s.StateReason + ")"
-				}
-				field.Set(idx, &value)
-				continue
-			}
+		if simulator != nil {
+			simulator.Advance(currentTime)
+		}
+		states := stateManager.ProcessEvalResults(ruleCtx, currentTime, rule, results, opts.ExtraLabels, send)
+		frame.Add(idx, currentTime, states)
+		summary.Add(idx, currentTime, states)
+		if opts.Sweep != nil {
+			evaluations = append(evaluations, recordedEvaluation{time: currentTime, results: results})
 		}
 		return nil
 	})
-	fields := make([]*data.Field, 0, len(valueFields)+1)
-	fields = append(fields, tsField)
-	for _, f := range valueFields {
-		fields = append(fields, f)
-	}
-	result := data.NewFrame("Testing results", fields...)
-
 	if err != nil {
 		return nil, err
 	}
+	result := frame.Frame("Testing results")
+	var meta ResultMeta
+	if opts.Summary {
+		meta.Summary = summary.Summary(to, opts.Alternatives)
+	}
+	if simulator != nil {
+		meta.Notifications = simulator.Finish(to)
+	}
+	if opts.Sweep != nil {
+		meta.Sweep, err = e.sweep(ruleCtx, rule, evaluations, *opts.Sweep, opts.ExtraLabels, to)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if meta != (ResultMeta{}) {
+		result.SetMeta(&data.FrameMeta{
+			Custom: meta,
+		})
+	}
 	logger.Info("Rule testing finished successfully", "duration", time.Since(start))
 	return result, nil
 }
 
+// evaluationsCount returns the number of evaluations with the given interval that fit into the range [from, to).
+func evaluationsCount(from, to time.Time, intervalSeconds int64) (int, error) {
+	if !from.Before(to) {
+		return 0, fmt.Errorf("%w: invalid interval of the backtesting [%d,%d]", ErrInvalidInputData, from.Unix(), to.Unix())
+	}
+	if intervalSeconds <= 0 {
+		return 0, fmt.Errorf("%w: evaluation interval must be positive", ErrInvalidInputData)
+	}
+	if to.Sub(from).Seconds() < float64(intervalSeconds) {
+		return 0, fmt.Errorf("%w: interval of the backtesting [%d,%d] is less than evaluation interval [%ds]", ErrInvalidInputData, from.Unix(), to.Unix(), intervalSeconds)
+	}
+	return int(to.Sub(from).Seconds()) / int(intervalSeconds), nil
+}
+
+// stateFrameBuilder collects states of alert instances at every evaluation into a frame that has a field per alert instance.
+type stateFrameBuilder struct {
+	length      int
+	tsField     *data.Field
+	valueFields map[data.Fingerprint]*data.Field
+}
+
+func newStateFrameBuilder(length int) *stateFrameBuilder {
+	return &stateFrameBuilder{
+		length:      length,
+		tsField:     data.NewField("Time", nil, make([]time.Time, length)),
+		valueFields: make(map[data.Fingerprint]*data.Field),
+	}
+}
+
+func (b *stateFrameBuilder) Add(idx int, now time.Time, states state.StateTransitions) {
+	b.tsField.Set(idx, now)
+	for _, s := range states {
+		field, ok := b.valueFields[s.CacheID]
+		if !ok {
+			field = data.NewField("", s.Labels, make([]*string, b.length))
+			b.valueFields[s.CacheID] = field
+		}
+		if s.State.State != eval.NoData { // set nil if NoData
+			value := s.State.State.String()
+			if s.StateReason != "" {
+				value += " (" + s.StateReason + ")"
+			}
+			field.Set(idx, &value)
+		}
+	}
+}
+
+func (b *stateFrameBuilder) Frame(name string) *data.Frame {
+	fields := make([]*data.Field, 0, len(b.valueFields)+1)
+	fields = append(fields, b.tsField)
+	for _, f := range b.valueFields {
+		fields = append(fields, f)
+	}
+	return data.NewFrame(name, fields...)
+}
+
 func newBacktestingEvaluator(ctx context.Context, evalFactory eval.EvaluatorFactory, user identity.Requester, condition models.Condition, reader eval.AlertingResultsReader) (backtestingEvaluator, error) {
 	for _, q := range condition.Data {
 		if q.DatasourceUID == "__data__" || q.QueryType == "__data__" {
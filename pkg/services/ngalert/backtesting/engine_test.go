@@ -9,11 +9,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 
 	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval/eval_mocks"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
@@ -345,6 +347,62 @@ func TestEvaluatorTest(t *testing.T) {
 		}
 	})
 
+	t.Run("should simulate notifications if notification policy is specified", func(t *testing.T) {
+		from := time.Unix(0, 0)
+		to := from.Add(5 * ruleInterval)
+
+		labels := models.GenerateAlertLabels(rand.Intn(5)+1, "test-")
+		manager.stateCallback = func(now time.Time) []state.StateTransition {
+			return []state.StateTransition{
+				{
+					State: &state.State{
+						CacheID:  labels.Fingerprint(),
+						Labels:   labels,
+						State:    eval.Alerting,
+						StartsAt: from,
+						EndsAt:   now.Add(4 * ruleInterval),
+					},
+				},
+			}
+		}
+
+		groupWait := model.Duration(0)
+		opts := TestOptions{
+			NotificationPolicy: &NotificationPolicy{
+				Route: &definitions.Route{
+					Receiver:  "test-receiver",
+					GroupWait: &groupWait,
+				},
+			},
+		}
+		frame, err := engine.TestWithOptions(context.Background(), nil, rule, from, to, opts)
+		require.NoError(t, err)
+		require.NotNil(t, frame.Meta)
+
+		meta, ok := frame.Meta.Custom.(ResultMeta)
+		require.True(t, ok)
+		require.NotNil(t, meta.Notifications)
+		require.Equal(t, 1, meta.Notifications.Total)
+		require.Equal(t, map[string]int{"test-receiver": 1}, meta.Notifications.Receivers)
+
+		t.Run("should not add notifications if policy is not specified", func(t *testing.T) {
+			frame, err := engine.Test(context.Background(), nil, rule, from, to)
+			require.NoError(t, err)
+			require.Nil(t, frame.Meta)
+		})
+
+		t.Run("should add summary if it is requested", func(t *testing.T) {
+			frame, err := engine.TestWithOptions(context.Background(), nil, rule, from, to, TestOptions{Summary: true})
+			require.NoError(t, err)
+			require.NotNil(t, frame.Meta)
+			meta, ok := frame.Meta.Custom.(ResultMeta)
+			require.True(t, ok)
+			require.Nil(t, meta.Notifications)
+			require.NotNil(t, meta.Summary)
+			require.Len(t, meta.Summary.Series, 1)
+		})
+	})
+
 	t.Run("should fail", func(t *testing.T) {
 		manager.stateCallback = func(now time.Time) []state.StateTransition {
 			return nil
@@ -386,8 +444,12 @@ type fakeStateManager struct {
 	stateCallback func(now time.Time) []state.StateTransition
 }
 
-func (f *fakeStateManager) ProcessEvalResults(_ context.Context, evaluatedAt time.Time, _ *models.AlertRule, _ eval.Results, _ data.Labels, _ state.Sender) state.StateTransitions {
-	return f.stateCallback(evaluatedAt)
+func (f *fakeStateManager) ProcessEvalResults(ctx context.Context, evaluatedAt time.Time, _ *models.AlertRule, _ eval.Results, _ data.Labels, send state.Sender) state.StateTransitions {
+	transitions := f.stateCallback(evaluatedAt)
+	if send != nil {
+		send(ctx, transitions)
+	}
+	return transitions
 }
 
 func (f *fakeStateManager) GetStatesForRuleUID(orgID int64, alertRuleUID string) []*state.State {
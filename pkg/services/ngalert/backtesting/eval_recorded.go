@@ -0,0 +1,271 @@
+package backtesting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/writer"
+)
+
+// recordedMetrics contains metrics recorded by recording rules of a group during backtesting.
+// The data source does not have the series that recording rules would have written, and therefore
+// the rules that follow a recording rule in the group read recorded series from here.
+type recordedMetrics struct {
+	// metrics are names of the metrics recorded by the rules that are already added to the timeline.
+	metrics map[string]struct{}
+	// points are the series recorded at the current evaluation.
+	points map[string][]writer.Point
+}
+
+func newRecordedMetrics() *recordedMetrics {
+	return &recordedMetrics{
+		metrics: make(map[string]struct{}),
+		points:  make(map[string][]writer.Point),
+	}
+}
+
+// Register marks the metric as recorded by a rule of the group.
+func (m *recordedMetrics) Register(metric string) {
+	m.metrics[metric] = struct{}{}
+}
+
+// IsRecorded returns true if the metric is recorded by a rule that precedes the current one.
+func (m *recordedMetrics) IsRecorded(metric string) bool {
+	_, ok := m.metrics[metric]
+	return ok
+}
+
+// Reset drops all points recorded at the previous evaluation.
+// Like in Prometheus, a series that is not recorded at the current evaluation becomes stale.
+func (m *recordedMetrics) Reset() {
+	clear(m.points)
+}
+
+func (m *recordedMetrics) Set(metric string, points []writer.Point) {
+	m.points[metric] = points
+}
+
+func (m *recordedMetrics) Get(metric string) []writer.Point {
+	return m.points[metric]
+}
+
+// ReferencedBy returns sorted names of the recorded metrics that data queries of the rule select.
+// Queries that are not valid PromQL are ignored.
+func (m *recordedMetrics) ReferencedBy(rule *models.AlertRule) []string {
+	var result []string
+	seen := map[string]struct{}{}
+	for _, q := range rule.Data {
+		if isExpr, _ := q.IsExpression(); isExpr {
+			continue
+		}
+		query, err := q.GetQuery()
+		if err != nil {
+			continue
+		}
+		expr, err := parser.ParseExpr(query)
+		if err != nil {
+			continue
+		}
+		parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+			vs, ok := node.(*parser.VectorSelector)
+			if !ok {
+				return nil
+			}
+			name := metricName(vs)
+			if _, ok := seen[name]; ok || !m.IsRecorded(name) {
+				return nil
+			}
+			seen[name] = struct{}{}
+			result = append(result, name)
+			return nil
+		})
+	}
+	sort.Strings(result)
+	return result
+}
+
+// recordedEvaluator evaluates an alert rule imported from Prometheus against the series recorded by the preceding
+// rules of the group. It supports queries that select a recorded metric and optionally compare it with a number,
+// e.g. `job:errors:rate5m{job="api"} > 0.1`.
+type recordedEvaluator struct {
+	refID    string
+	metric   string
+	matchers []*labels.Matcher
+	// op is the comparison operator. If it is zero, every selected series is returned.
+	op    parser.ItemType
+	value float64
+	// swapped is true if the number is the left-hand operand of the comparison.
+	swapped bool
+
+	recorded *recordedMetrics
+}
+
+func newRecordedEvaluator(rule *models.AlertRule, recorded *recordedMetrics) (*recordedEvaluator, error) {
+	// Prometheus rules are converted to a query and expressions that make every returned series alerting.
+	// This is what lets the evaluator skip the expressions.
+	if !rule.ImportedFromPrometheus() {
+		return nil, errors.New("only rules imported from Prometheus are supported")
+	}
+	var query *models.AlertQuery
+	for i := range rule.Data {
+		if isExpr, _ := rule.Data[i].IsExpression(); isExpr {
+			continue
+		}
+		if query != nil {
+			return nil, errors.New("the rule has more than one data query")
+		}
+		query = &rule.Data[i]
+	}
+	if query == nil {
+		return nil, errors.New("the rule does not have data queries")
+	}
+	q, err := query.GetQuery()
+	if err != nil {
+		return nil, err
+	}
+	expr, err := parser.ParseExpr(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query: %w", err)
+	}
+
+	result := &recordedEvaluator{
+		refID:    query.RefID,
+		recorded: recorded,
+	}
+	var vs *parser.VectorSelector
+	switch e := unwrapParens(expr).(type) {
+	case *parser.VectorSelector:
+		vs = e
+	case *parser.BinaryExpr:
+		if !e.Op.IsComparisonOperator() || e.ReturnBool {
+			return nil, fmt.Errorf("unsupported binary expression: %s", e.Op)
+		}
+		lhs, rhs := unwrapParens(e.LHS), unwrapParens(e.RHS)
+		if n, ok := lhs.(*parser.NumberLiteral); ok {
+			result.swapped = true
+			result.value = n.Val
+			vs, _ = rhs.(*parser.VectorSelector)
+		} else if n, ok := rhs.(*parser.NumberLiteral); ok {
+			result.value = n.Val
+			vs, _ = lhs.(*parser.VectorSelector)
+		}
+		result.op = e.Op
+	}
+	if vs == nil {
+		return nil, errors.New("the query must select a recorded metric and optionally compare it with a number")
+	}
+	if vs.OriginalOffset != 0 || vs.Timestamp != nil || vs.StartOrEnd != 0 {
+		return nil, errors.New("modifiers of a vector selector are not supported")
+	}
+	result.metric = metricName(vs)
+	if !recorded.IsRecorded(result.metric) {
+		return nil, fmt.Errorf("metric %s is not recorded by the preceding rules of the group", result.metric)
+	}
+	result.matchers = vs.LabelMatchers
+	return result, nil
+}
+
+func (d *recordedEvaluator) Eval(_ context.Context, from time.Time, interval time.Duration, evaluations int, callback callbackFunc) error {
+	for idx, now := 0, from; idx < evaluations; idx, now = idx+1, now.Add(interval) {
+		err := callback(idx, now, d.evaluate(now))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *recordedEvaluator) evaluate(now time.Time) eval.Results {
+	var results eval.Results
+	for _, p := range d.recorded.Get(d.metric) {
+		if !d.matches(p) {
+			continue
+		}
+		value := p.Metric.V
+		lbls := data.Labels(p.Labels).Copy()
+		results = append(results, eval.Result{
+			Instance: lbls,
+			State:    eval.Alerting,
+			Values: map[string]eval.NumberValueCapture{
+				d.refID: {
+					Var:    d.refID,
+					Labels: lbls,
+					Value:  &value,
+				},
+			},
+			EvaluatedAt: now,
+		})
+	}
+	if len(results) == 0 {
+		return eval.Results{{State: eval.NoData, EvaluatedAt: now}}
+	}
+	return results
+}
+
+func (d *recordedEvaluator) matches(p writer.Point) bool {
+	for _, m := range d.matchers {
+		value := p.Labels[m.Name]
+		if m.Name == labels.MetricName {
+			value = d.metric
+		}
+		if !m.Matches(value) {
+			return false
+		}
+	}
+	if d.op == 0 {
+		return true
+	}
+	if d.swapped {
+		return compare(d.op, d.value, p.Metric.V)
+	}
+	return compare(d.op, p.Metric.V, d.value)
+}
+
+func compare(op parser.ItemType, lhs, rhs float64) bool {
+	switch op {
+	case parser.EQLC:
+		return lhs == rhs
+	case parser.NEQ:
+		return lhs != rhs
+	case parser.GTR:
+		return lhs > rhs
+	case parser.LSS:
+		return lhs < rhs
+	case parser.GTE:
+		return lhs >= rhs
+	case parser.LTE:
+		return lhs <= rhs
+	}
+	return false
+}
+
+func metricName(vs *parser.VectorSelector) string {
+	if vs.Name != "" {
+		return vs.Name
+	}
+	for _, m := range vs.LabelMatchers {
+		if m.Name == labels.MetricName && m.Type == labels.MatchEqual {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+func unwrapParens(expr parser.Expr) parser.Expr {
+	for {
+		p, ok := expr.(*parser.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = p.Expr
+	}
+}
@@ -0,0 +1,138 @@
+package backtesting
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/writer"
+)
+
+func TestNewRecordedEvaluator(t *testing.T) {
+	recorded := newRecordedMetrics()
+	recorded.Register("job:errors:sum")
+
+	testCases := []struct {
+		name     string
+		expr     string
+		imported bool
+		error    bool
+	}{
+		{name: "selector", expr: `job:errors:sum`, imported: true},
+		{name: "selector with matchers", expr: `job:errors:sum{job="api"}`, imported: true},
+		{name: "comparison with number", expr: `job:errors:sum > 5`, imported: true},
+		{name: "number compared with selector", expr: `5 < job:errors:sum`, imported: true},
+		{name: "parentheses", expr: `(job:errors:sum) >= (5)`, imported: true},
+		{name: "rule not imported from Prometheus", expr: `job:errors:sum`, error: true},
+		{name: "metric is not recorded", expr: `job:requests:sum > 5`, imported: true, error: true},
+		{name: "function", expr: `rate(job:errors:sum[5m])`, imported: true, error: true},
+		{name: "bool comparison", expr: `job:errors:sum > bool 5`, imported: true, error: true},
+		{name: "arithmetic", expr: `job:errors:sum + 5`, imported: true, error: true},
+		{name: "offset", expr: `job:errors:sum offset 5m`, imported: true, error: true},
+		{name: "invalid query", expr: `job:errors:sum >`, imported: true, error: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newRecordedEvaluator(recordedTestRule(tc.expr, tc.imported), recorded)
+			if tc.error {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRecordedEvaluator_Eval(t *testing.T) {
+	recorded := newRecordedMetrics()
+	recorded.Register("job:errors:sum")
+	now := time.Now()
+
+	recorded.Set("job:errors:sum", []writer.Point{
+		{Name: "job:errors:sum", Labels: map[string]string{"job": "api"}, Metric: writer.Metric{T: now, V: 10}},
+		{Name: "job:errors:sum", Labels: map[string]string{"job": "web"}, Metric: writer.Metric{T: now, V: 1}},
+	})
+
+	evaluate := func(t *testing.T, expr string) eval.Results {
+		t.Helper()
+		evaluator, err := newRecordedEvaluator(recordedTestRule(expr, true), recorded)
+		require.NoError(t, err)
+		var result eval.Results
+		err = evaluator.Eval(context.Background(), now, time.Minute, 1, func(_ int, _ time.Time, results eval.Results) error {
+			result = results
+			return nil
+		})
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("every selected series is alerting", func(t *testing.T) {
+		results := evaluate(t, `job:errors:sum`)
+		require.Len(t, results, 2)
+		for _, r := range results {
+			require.Equal(t, eval.Alerting, r.State)
+			require.Equal(t, now, r.EvaluatedAt)
+		}
+	})
+
+	t.Run("series are filtered by matchers and comparison", func(t *testing.T) {
+		for _, expr := range []string{`job:errors:sum > 5`, `5 < job:errors:sum`, `job:errors:sum{job="api"}`, `{__name__="job:errors:sum", job=~"a.*"}`} {
+			t.Run(expr, func(t *testing.T) {
+				results := evaluate(t, expr)
+				require.Len(t, results, 1)
+				require.Equal(t, "api", results[0].Instance["job"])
+				require.Equal(t, 10.0, *results[0].Values["A"].Value)
+			})
+		}
+	})
+
+	t.Run("no data if nothing is selected", func(t *testing.T) {
+		results := evaluate(t, `job:errors:sum > 100`)
+		require.Len(t, results, 1)
+		require.Equal(t, eval.NoData, results[0].State)
+	})
+
+	t.Run("no data if nothing is recorded", func(t *testing.T) {
+		recorded.Reset()
+		results := evaluate(t, `job:errors:sum`)
+		require.Len(t, results, 1)
+		require.Equal(t, eval.NoData, results[0].State)
+	})
+}
+
+func TestRecordedMetrics_ReferencedBy(t *testing.T) {
+	recorded := newRecordedMetrics()
+	recorded.Register("job:errors:sum")
+	recorded.Register("job:requests:sum")
+
+	rule := recordedTestRule(`rate(job:errors:sum[5m]) / job:requests:sum > job:errors:sum or up`, true)
+	require.Equal(t, []string{"job:errors:sum", "job:requests:sum"}, recorded.ReferencedBy(rule))
+
+	rule = recordedTestRule(`up == 0`, true)
+	require.Empty(t, recorded.ReferencedBy(rule))
+}
+
+func recordedTestRule(expr string, imported bool) *models.AlertRule {
+	rule := &models.AlertRule{
+		Condition: "A",
+		Data: []models.AlertQuery{
+			{
+				RefID:         "A",
+				DatasourceUID: "prometheus",
+				Model:         []byte(fmt.Sprintf(`{"expr": %q}`, expr)),
+			},
+		},
+	}
+	if imported {
+		rule.Metadata.PrometheusStyleRule = &models.PrometheusStyleRule{
+			OriginalRuleDefinition: fmt.Sprintf("alert: test\nexpr: %s\n", expr),
+		}
+	}
+	return rule
+}
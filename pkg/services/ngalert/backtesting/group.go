@@ -0,0 +1,342 @@
+package backtesting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/schedule"
+	"github.com/grafana/grafana/pkg/services/ngalert/writer"
+)
+
+const (
+	// GroupSummaryFrameName is the name of the frame with the summary of a rule group backtesting.
+	GroupSummaryFrameName = "Group summary"
+
+	sourceDatasource = "datasource"
+	sourceRecorded   = "recorded"
+)
+
+// ruleSummary contains aggregated results of backtesting of a single rule of a group.
+type ruleSummary struct {
+	// Source is where the data of the rule comes from: the data source or the series recorded by the group.
+	Source string
+	// Series is the number of distinct alert instances or recorded series.
+	Series int
+	// Alerting is the number of evaluations at which at least one alert instance was alerting.
+	Alerting int
+	// Errors is the number of evaluations that resulted in an error.
+	Errors int
+	// Warning explains why the rule queries the data source although it selects metrics recorded by the group.
+	// It is also added as a notice to the frame of the rule.
+	Warning string
+}
+
+// groupRuleRunner evaluates a single rule of a group at the timestamps of the shared timeline.
+type groupRuleRunner interface {
+	Eval(ctx context.Context, idx int, now time.Time) error
+	Frame() *data.Frame
+	Summary() ruleSummary
+}
+
+// TestGroup runs backtesting of all rules of the group in the interval [from, to).
+// The rules share the timeline and are evaluated at every timestamp in the order of RuleGroupIndex, the same way
+// as the scheduler evaluates a group. The series produced by recording rules are available to the rules that follow
+// them in the group. It returns a frame per rule, in the order of evaluation, followed by the summary frame.
+func (e *Engine) TestGroup(ctx context.Context, user identity.Requester, group *models.AlertRuleGroup, from, to time.Time) (data.Frames, error) {
+	logger := logger.FromContext(ctx)
+	if group == nil || len(group.Rules) == 0 {
+		return nil, fmt.Errorf("%w: rule group must have at least one rule", ErrInvalidInputData)
+	}
+
+	rules := make(models.RulesGroup, 0, len(group.Rules))
+	for i := range group.Rules {
+		rules = append(rules, &group.Rules[i])
+	}
+	rules.SortByGroupIndex()
+
+	intervalSeconds := group.Interval
+	if intervalSeconds == 0 {
+		intervalSeconds = rules[0].IntervalSeconds
+	}
+	length, err := evaluationsCount(from, to, intervalSeconds)
+	if err != nil {
+		return nil, err
+	}
+	interval := time.Duration(intervalSeconds) * time.Second
+
+	stateManager := e.createStateManager()
+	recorded := newRecordedMetrics()
+	runners := make([]groupRuleRunner, 0, len(rules))
+	for _, rule := range rules {
+		var runner groupRuleRunner
+		if rule.Type() == models.RuleTypeRecording {
+			runner, err = e.newRecordingRuleRunner(ctx, user, rule, recorded, length)
+		} else {
+			runner, err = e.newAlertRuleRunner(ctx, user, rule, stateManager, recorded, interval, length)
+		}
+		if err != nil {
+			return nil, errors.Join(ErrInvalidInputData, fmt.Errorf("failed to create evaluator for rule %s: %w", rule.UID, err))
+		}
+		if rule.Type() == models.RuleTypeRecording {
+			recorded.Register(rule.Record.Metric)
+		}
+		runners = append(runners, runner)
+	}
+
+	logger.Info("Start testing rule group", "group", group.Title, "rules", len(rules), "from", from, "to", to, "interval", intervalSeconds, "evaluations", length)
+	start := time.Now()
+
+	for idx := 0; idx < length; idx++ {
+		now := from.Add(time.Duration(idx) * interval)
+		recorded.Reset()
+		for i, runner := range runners {
+			if err := runner.Eval(models.WithRuleKey(ctx, rules[i].GetKey()), idx, now); err != nil {
+				return nil, fmt.Errorf("failed to evaluate rule %s: %w", rules[i].UID, err)
+			}
+		}
+	}
+
+	result := make(data.Frames, 0, len(runners)+1)
+	summary := make([]ruleSummary, 0, len(runners))
+	for _, runner := range runners {
+		result = append(result, runner.Frame())
+		summary = append(summary, runner.Summary())
+	}
+	result = append(result, groupSummaryFrame(rules, summary))
+
+	logger.Info("Rule group testing finished successfully", "group", group.Title, "duration", time.Since(start))
+	return result, nil
+}
+
+func groupSummaryFrame(rules models.RulesGroup, summary []ruleSummary) *data.Frame {
+	uids := make([]string, 0, len(rules))
+	titles := make([]string, 0, len(rules))
+	types := make([]string, 0, len(rules))
+	sources := make([]string, 0, len(rules))
+	series := make([]int64, 0, len(rules))
+	alerting := make([]int64, 0, len(rules))
+	errs := make([]int64, 0, len(rules))
+	warnings := make([]string, 0, len(rules))
+	for i, rule := range rules {
+		uids = append(uids, rule.UID)
+		titles = append(titles, rule.Title)
+		types = append(types, string(rule.Type()))
+		sources = append(sources, summary[i].Source)
+		series = append(series, int64(summary[i].Series))
+		alerting = append(alerting, int64(summary[i].Alerting))
+		errs = append(errs, int64(summary[i].Errors))
+		warnings = append(warnings, summary[i].Warning)
+	}
+	return data.NewFrame(GroupSummaryFrameName,
+		data.NewField("UID", nil, uids),
+		data.NewField("Title", nil, titles),
+		data.NewField("Type", nil, types),
+		data.NewField("Source", nil, sources),
+		data.NewField("Series", nil, series),
+		data.NewField("Alerting", nil, alerting),
+		data.NewField("Errors", nil, errs),
+		data.NewField("Warning", nil, warnings),
+	)
+}
+
+// alertRuleRunner evaluates an alert rule of the group and passes the results to the state manager.
+type alertRuleRunner struct {
+	rule         *models.AlertRule
+	evaluator    backtestingEvaluator
+	interval     time.Duration
+	stateManager stateManager
+	frame        *stateFrameBuilder
+	summary      ruleSummary
+}
+
+func (e *Engine) newAlertRuleRunner(ctx context.Context, user identity.Requester, rule *models.AlertRule, stateManager stateManager, recorded *recordedMetrics, interval time.Duration, length int) (*alertRuleRunner, error) {
+	runner := &alertRuleRunner{
+		rule:         rule,
+		interval:     interval,
+		stateManager: stateManager,
+		frame:        newStateFrameBuilder(length),
+		summary: ruleSummary{
+			Source: sourceDatasource,
+		},
+	}
+	if metrics := recorded.ReferencedBy(rule); len(metrics) > 0 {
+		evaluator, err := newRecordedEvaluator(rule, recorded)
+		if err == nil {
+			runner.evaluator = evaluator
+			runner.summary.Source = sourceRecorded
+			return runner, nil
+		}
+		runner.summary.Warning = fmt.Sprintf("The rule queries metrics recorded by the group (%s) but cannot be evaluated against the recorded series: %s. The data source is queried instead.", strings.Join(metrics, ", "), err)
+	}
+	evaluator, err := backtestingEvaluatorFactory(models.WithRuleKey(ctx, rule.GetKey()), e.evalFactory, user, rule.GetEvalCondition().WithSource("backtesting"), &schedule.AlertingResultsFromRuleState{
+		Manager: stateManager,
+		Rule:    rule,
+	})
+	if err != nil {
+		return nil, err
+	}
+	runner.evaluator = evaluator
+	return runner, nil
+}
+
+func (r *alertRuleRunner) Eval(ctx context.Context, idx int, now time.Time) error {
+	return r.evaluator.Eval(ctx, now, r.interval, 1, func(_ int, now time.Time, results eval.Results) error {
+		states := r.stateManager.ProcessEvalResults(ctx, now, r.rule, results, nil, nil)
+		r.frame.Add(idx, now, states)
+		var alerting, failed bool
+		for _, s := range states {
+			alerting = alerting || s.State.State == eval.Alerting
+			failed = failed || s.State.State == eval.Error
+		}
+		if alerting {
+			r.summary.Alerting++
+		}
+		if failed {
+			r.summary.Errors++
+		}
+		return nil
+	})
+}
+
+func (r *alertRuleRunner) Frame() *data.Frame {
+	frame := r.frame.Frame(r.rule.Title)
+	frame.RefID = r.rule.UID
+	if r.summary.Warning != "" {
+		frame.AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     r.summary.Warning,
+		})
+	}
+	return frame
+}
+
+func (r *alertRuleRunner) Summary() ruleSummary {
+	s := r.summary
+	s.Series = len(r.frame.valueFields)
+	return s
+}
+
+// recordingRuleRunner evaluates a recording rule of the group and makes the result available to the following rules.
+type recordingRuleRunner struct {
+	rule      *models.AlertRule
+	evaluator eval.ConditionEvaluator
+	recorded  *recordedMetrics
+	frame     *seriesFrameBuilder
+	summary   ruleSummary
+}
+
+func (e *Engine) newRecordingRuleRunner(ctx context.Context, user identity.Requester, rule *models.AlertRule, recorded *recordedMetrics, length int) (*recordingRuleRunner, error) {
+	evaluator, err := e.evalFactory.Create(eval.NewContext(models.WithRuleKey(ctx, rule.GetKey()), user), rule.GetEvalCondition().WithSource("backtesting"))
+	if err != nil {
+		return nil, err
+	}
+	return &recordingRuleRunner{
+		rule:      rule,
+		evaluator: evaluator,
+		recorded:  recorded,
+		frame:     newSeriesFrameBuilder(length),
+		summary: ruleSummary{
+			Source: sourceDatasource,
+		},
+	}, nil
+}
+
+func (r *recordingRuleRunner) Eval(ctx context.Context, idx int, now time.Time) error {
+	r.frame.SetTime(idx, now)
+	resp, err := r.evaluator.EvaluateRaw(ctx, now)
+	if err != nil {
+		return err
+	}
+	frames, err := recordedFrames(r.rule, resp)
+	if err != nil {
+		r.summary.Errors++
+		return nil
+	}
+	if len(frames) == 0 {
+		return nil
+	}
+	points, err := writer.PointsFromFrames(r.rule.Record.Metric, now, frames, r.rule.Labels)
+	if err != nil {
+		r.summary.Errors++
+		return nil
+	}
+	r.recorded.Set(r.rule.Record.Metric, points)
+	for _, p := range points {
+		r.frame.Add(idx, p)
+	}
+	return nil
+}
+
+// recordedFrames returns the frames of the series recorded by the rule from the response of its query.
+// It returns no frames if the query returned no data.
+func recordedFrames(rule *models.AlertRule, resp *backend.QueryDataResponse) (data.Frames, error) {
+	if err := eval.FindConditionError(resp, rule.Record.From); err != nil {
+		return nil, err
+	}
+	target, ok := resp.Responses[rule.Record.From]
+	if !ok || eval.IsNoData(target) {
+		return nil, nil
+	}
+	return target.Frames, nil
+}
+
+func (r *recordingRuleRunner) Frame() *data.Frame {
+	frame := r.frame.Frame(r.rule.Title)
+	frame.RefID = r.rule.UID
+	return frame
+}
+
+func (r *recordingRuleRunner) Summary() ruleSummary {
+	s := r.summary
+	s.Series = len(r.frame.valueFields)
+	return s
+}
+
+// seriesFrameBuilder collects values of the recorded series at every evaluation into a frame that has a field per series.
+type seriesFrameBuilder struct {
+	length      int
+	tsField     *data.Field
+	valueFields map[data.Fingerprint]*data.Field
+}
+
+func newSeriesFrameBuilder(length int) *seriesFrameBuilder {
+	return &seriesFrameBuilder{
+		length:      length,
+		tsField:     data.NewField("Time", nil, make([]time.Time, length)),
+		valueFields: make(map[data.Fingerprint]*data.Field),
+	}
+}
+
+func (b *seriesFrameBuilder) SetTime(idx int, now time.Time) {
+	b.tsField.Set(idx, now)
+}
+
+func (b *seriesFrameBuilder) Add(idx int, p writer.Point) {
+	lbls := data.Labels(p.Labels)
+	fp := lbls.Fingerprint()
+	field, ok := b.valueFields[fp]
+	if !ok {
+		field = data.NewField(p.Name, lbls.Copy(), make([]*float64, b.length))
+		b.valueFields[fp] = field
+	}
+	value := p.Metric.V
+	field.Set(idx, &value)
+}
+
+func (b *seriesFrameBuilder) Frame(name string) *data.Frame {
+	fields := make([]*data.Field, 0, len(b.valueFields)+1)
+	fields = append(fields, b.tsField)
+	for _, f := range b.valueFields {
+		fields = append(fields, f)
+	}
+	return data.NewFrame(name, fields...)
+}
@@ -0,0 +1,177 @@
+package backtesting
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval/eval_mocks"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/prom"
+)
+
+func TestEngine_TestGroup(t *testing.T) {
+	from := time.Unix(0, 0)
+	interval := time.Minute
+
+	converter, err := prom.NewConverter(prom.Config{
+		DatasourceUID:   "prometheus",
+		DatasourceType:  datasources.DS_PROMETHEUS,
+		DefaultInterval: interval,
+	})
+	require.NoError(t, err)
+	group, err := converter.PrometheusRulesToGrafana(1, "folder", prom.PrometheusRuleGroup{
+		Name:     "test",
+		Interval: model.Duration(interval),
+		Rules: []prom.PrometheusRule{
+			{Record: "job:errors:sum", Expr: `sum by (job) (errors_total)`},
+			{Alert: "HighErrors", Expr: `job:errors:sum > 2`},
+			{Alert: "InstanceDown", Expr: `up == 0`},
+			{Alert: "ErrorsGrowing", Expr: `rate(job:errors:sum[5m]) > 1`},
+		},
+	})
+	require.NoError(t, err)
+
+	// the recording rule returns the index of the evaluation for the job "api" and 0 for the job "web".
+	recordingEvaluator := &fakeRawEvaluator{
+		evalRawCallback: func(now time.Time) *backend.QueryDataResponse {
+			value := float64(now.Sub(from) / interval)
+			frame := data.NewFrame("",
+				data.NewField("T", nil, []time.Time{now}),
+				data.NewField("value", data.Labels{"job": "api"}, []float64{value}),
+				data.NewField("value", data.Labels{"job": "web"}, []float64{0}),
+			)
+			frame.SetMeta(&data.FrameMeta{
+				Type:        data.FrameTypeNumericWide,
+				TypeVersion: data.FrameTypeVersion{0, 1},
+			})
+			return &backend.QueryDataResponse{
+				Responses: backend.Responses{
+					"query": backend.DataResponse{Frames: data.Frames{frame}},
+				},
+			}
+		},
+	}
+	var queried []string
+	backtestingEvaluatorFactory = func(ctx context.Context, evalFactory eval.EvaluatorFactory, user identity.Requester, condition models.Condition, r eval.AlertingResultsReader) (backtestingEvaluator, error) {
+		queried = append(queried, condition.Metadata["Uid"])
+		return &fakeBacktestingEvaluator{
+			evalCallback: func(now time.Time) (eval.Results, error) {
+				return eval.Results{{State: eval.Normal, EvaluatedAt: now}}, nil
+			},
+		}, nil
+	}
+	t.Cleanup(func() {
+		backtestingEvaluatorFactory = newBacktestingEvaluator
+	})
+
+	engine := NewEngine(&url.URL{}, eval_mocks.NewEvaluatorFactory(recordingEvaluator), tracing.InitializeTracerForTest())
+
+	evaluations := 6
+	frames, err := engine.TestGroup(context.Background(), nil, group, from, from.Add(time.Duration(evaluations)*interval))
+	require.NoError(t, err)
+	require.Len(t, frames, len(group.Rules)+1)
+
+	for i, rule := range group.Rules {
+		require.Equal(t, rule.UID, frames[i].RefID)
+		require.Equal(t, rule.Title, frames[i].Name)
+		require.Equal(t, evaluations, frames[i].Rows())
+	}
+
+	t.Run("recording rule frame should contain recorded series", func(t *testing.T) {
+		frame := frames[0]
+		require.Len(t, frame.Fields, 3)
+		for _, f := range frame.Fields[1:] {
+			require.Equal(t, "job:errors:sum", f.Name)
+		}
+	})
+
+	t.Run("alert rule should be evaluated against recorded series", func(t *testing.T) {
+		frame := frames[1]
+		var field *data.Field
+		for _, f := range frame.Fields {
+			if f.Labels["job"] == "api" {
+				field = f
+			}
+		}
+		require.NotNil(t, field)
+		for i := 0; i < evaluations; i++ {
+			if i <= 2 {
+				require.Nil(t, field.At(i))
+				continue
+			}
+			require.Equal(t, eval.Alerting.String(), *field.At(i).(*string))
+		}
+		require.NotContains(t, queried, group.Rules[1].UID)
+	})
+
+	t.Run("should query data source if recorded series cannot be used", func(t *testing.T) {
+		require.Equal(t, []string{group.Rules[2].UID, group.Rules[3].UID}, queried)
+	})
+
+	t.Run("should report the data source fallback in the frame of the rule", func(t *testing.T) {
+		require.Nil(t, frames[1].Meta)
+		require.Nil(t, frames[2].Meta)
+		require.NotNil(t, frames[3].Meta)
+		require.Len(t, frames[3].Meta.Notices, 1)
+		require.Equal(t, data.NoticeSeverityWarning, frames[3].Meta.Notices[0].Severity)
+		require.Contains(t, frames[3].Meta.Notices[0].Text, "job:errors:sum")
+	})
+
+	t.Run("summary frame", func(t *testing.T) {
+		summary := frames[len(frames)-1]
+		require.Equal(t, GroupSummaryFrameName, summary.Name)
+		require.Equal(t, len(group.Rules), summary.Rows())
+
+		source, _ := summary.FieldByName("Source")
+		require.Equal(t, sourceDatasource, source.At(0))
+		require.Equal(t, sourceRecorded, source.At(1))
+		require.Equal(t, sourceDatasource, source.At(2))
+		require.Equal(t, sourceDatasource, source.At(3))
+
+		series, _ := summary.FieldByName("Series")
+		require.EqualValues(t, 2, series.At(0))
+
+		alerting, _ := summary.FieldByName("Alerting")
+		require.EqualValues(t, 3, alerting.At(1))
+		require.EqualValues(t, 0, alerting.At(2))
+
+		warning, _ := summary.FieldByName("Warning")
+		require.Empty(t, warning.At(1))
+		require.Empty(t, warning.At(2))
+		require.Contains(t, warning.At(3), "job:errors:sum")
+	})
+
+	t.Run("should fail", func(t *testing.T) {
+		t.Run("when group is empty", func(t *testing.T) {
+			_, err := engine.TestGroup(context.Background(), nil, &models.AlertRuleGroup{}, from, from.Add(time.Hour))
+			require.ErrorIs(t, err, ErrInvalidInputData)
+		})
+		t.Run("when to-from < interval", func(t *testing.T) {
+			_, err := engine.TestGroup(context.Background(), nil, group, from, from.Add(interval-time.Second))
+			require.ErrorIs(t, err, ErrInvalidInputData)
+		})
+	})
+}
+
+type fakeRawEvaluator struct {
+	evalRawCallback func(now time.Time) *backend.QueryDataResponse
+}
+
+func (f *fakeRawEvaluator) EvaluateRaw(_ context.Context, now time.Time) (*backend.QueryDataResponse, error) {
+	return f.evalRawCallback(now), nil
+}
+
+func (f *fakeRawEvaluator) Evaluate(_ context.Context, now time.Time) (eval.Results, error) {
+	return nil, nil
+}
@@ -0,0 +1,414 @@
+package backtesting
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// NotificationPolicy is the notification configuration of an organization that is used to simulate
+// what notifications the alerts produced during backtesting would have caused.
+type NotificationPolicy struct {
+	// Route is the root of the notification policy tree. It is expected to include autogenerated routes.
+	Route *definitions.Route
+	// TimeIntervals contains all time intervals that can be referenced by the routes as mute or active time intervals.
+	TimeIntervals []config.TimeInterval
+	// Silences contains silences that might be active during the backtesting window. Expired silences are
+	// taken into account as long as they overlap with the window.
+	Silences []*models.Silence
+}
+
+// Notification describes a single notification that would have been sent to a receiver.
+type Notification struct {
+	Time        time.Time         `json:"time"`
+	Receiver    string            `json:"receiver"`
+	GroupKey    string            `json:"groupKey"`
+	GroupLabels map[string]string `json:"groupLabels"`
+	Firing      int               `json:"firing"`
+	Resolved    int               `json:"resolved"`
+}
+
+// NotificationGroup summarizes notifications sent for a single aggregation group.
+type NotificationGroup struct {
+	GroupKey          string            `json:"groupKey"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	Notifications     int               `json:"notifications"`
+	FirstNotification time.Time         `json:"firstNotification"`
+	LastNotification  time.Time         `json:"lastNotification"`
+}
+
+// NotificationsResult is the result of simulation of notification routing.
+type NotificationsResult struct {
+	// Timeline contains all notifications ordered by time.
+	Timeline []Notification `json:"timeline"`
+	// Groups contains aggregation groups that sent at least one notification.
+	Groups []NotificationGroup `json:"groups"`
+	// Receivers contains the number of notifications per receiver.
+	Receivers map[string]int `json:"receivers"`
+	// Total is the total number of notifications.
+	Total int `json:"total"`
+	// Muted is the number of group flushes that were suppressed by mute or active time intervals.
+	Muted int `json:"muted"`
+	// Silenced is the number of group flushes that were suppressed because all alerts were silenced.
+	Silenced int `json:"silenced"`
+}
+
+type simulatedAlert struct {
+	labels   model.LabelSet
+	startsAt time.Time
+	endsAt   time.Time
+}
+
+func (a simulatedAlert) resolvedAt(t time.Time) bool {
+	return !a.endsAt.IsZero() && !a.endsAt.After(t)
+}
+
+// notificationLogEntry mirrors the Alertmanager's notification log entry that is used to deduplicate notifications.
+type notificationLogEntry struct {
+	firing    map[model.Fingerprint]struct{}
+	resolved  map[model.Fingerprint]struct{}
+	timestamp time.Time
+}
+
+type aggregationGroup struct {
+	key         string
+	route       *dispatch.Route
+	groupLabels model.LabelSet
+	alerts      map[model.Fingerprint]simulatedAlert
+	next        time.Time
+	log         *notificationLogEntry
+}
+
+type silenceMatcher struct {
+	startsAt time.Time
+	endsAt   time.Time
+	matchers labels.Matchers
+}
+
+func (s silenceMatcher) mutes(ls model.LabelSet, t time.Time) bool {
+	if t.Before(s.startsAt) || !t.Before(s.endsAt) {
+		return false
+	}
+	for _, m := range s.matchers {
+		if !m.Matches(string(ls[model.LabelName(m.Name)])) {
+			return false
+		}
+	}
+	return true
+}
+
+// notificationSimulator replays alerts through a notification policy tree the same way the Alertmanager dispatcher does,
+// i.e. it groups alerts, waits for group_wait, group_interval and repeat_interval, and suppresses muted and silenced groups.
+type notificationSimulator struct {
+	route     *dispatch.Route
+	intervals map[string][]timeinterval.TimeInterval
+	silences  []silenceMatcher
+	groups    map[string]*aggregationGroup
+	now       time.Time
+	result    *NotificationsResult
+	stats     map[string]*NotificationGroup
+}
+
+func newNotificationSimulator(policy NotificationPolicy) (*notificationSimulator, error) {
+	if policy.Route == nil {
+		return nil, errors.New("notification policy tree must not be empty")
+	}
+	intervals := make(map[string][]timeinterval.TimeInterval, len(policy.TimeIntervals))
+	for _, ti := range policy.TimeIntervals {
+		intervals[ti.Name] = ti.TimeIntervals
+	}
+	silences := make([]silenceMatcher, 0, len(policy.Silences))
+	for _, s := range policy.Silences {
+		m, err := newSilenceMatcher(s)
+		if err != nil {
+			return nil, err
+		}
+		silences = append(silences, m)
+	}
+	return &notificationSimulator{
+		route:     dispatch.NewRoute(policy.Route.AsAMRoute(), nil),
+		intervals: intervals,
+		silences:  silences,
+		groups:    make(map[string]*aggregationGroup),
+		result: &NotificationsResult{
+			Timeline:  []Notification{},
+			Groups:    []NotificationGroup{},
+			Receivers: make(map[string]int),
+		},
+		stats: make(map[string]*NotificationGroup),
+	}, nil
+}
+
+func newSilenceMatcher(s *models.Silence) (silenceMatcher, error) {
+	if s == nil || s.StartsAt == nil || s.EndsAt == nil {
+		return silenceMatcher{}, errors.New("silence must have start and end time")
+	}
+	result := silenceMatcher{
+		startsAt: time.Time(*s.StartsAt),
+		endsAt:   time.Time(*s.EndsAt),
+		matchers: make(labels.Matchers, 0, len(s.Matchers)),
+	}
+	for _, m := range s.Matchers {
+		if m == nil || m.Name == nil || m.Value == nil {
+			continue
+		}
+		isEqual := m.IsEqual == nil || *m.IsEqual
+		isRegex := m.IsRegex != nil && *m.IsRegex
+		t := labels.MatchEqual
+		switch {
+		case isRegex && isEqual:
+			t = labels.MatchRegexp
+		case isRegex:
+			t = labels.MatchNotRegexp
+		case !isEqual:
+			t = labels.MatchNotEqual
+		}
+		matcher, err := labels.NewMatcher(t, *m.Name, *m.Value)
+		if err != nil {
+			return silenceMatcher{}, fmt.Errorf("invalid silence matcher: %w", err)
+		}
+		result.matchers = append(result.matchers, matcher)
+	}
+	return result, nil
+}
+
+// Advance moves the clock of the simulator to the given time and flushes all groups that are due.
+func (s *notificationSimulator) Advance(now time.Time) {
+	s.flushUntil(now)
+	s.now = now
+}
+
+// Put adds alerts that were sent to the Alertmanager at the current time of the simulator.
+func (s *notificationSimulator) Put(alerts []*amv2.PostableAlert) {
+	now := s.now
+	for _, alert := range alerts {
+		ls := make(model.LabelSet, len(alert.Labels))
+		for k, v := range alert.Labels {
+			ls[model.LabelName(k)] = model.LabelValue(v)
+		}
+		a := simulatedAlert{
+			labels:   ls,
+			startsAt: time.Time(alert.StartsAt),
+			endsAt:   time.Time(alert.EndsAt),
+		}
+		fp := ls.Fingerprint()
+		for _, route := range s.route.Match(ls) {
+			groupLabels := getGroupLabels(ls, route)
+			key := fmt.Sprintf("%s:%s", route.Key(), groupLabels)
+			group, ok := s.groups[key]
+			if !ok {
+				group = &aggregationGroup{
+					key:         key,
+					route:       route,
+					groupLabels: groupLabels,
+					alerts:      make(map[model.Fingerprint]simulatedAlert),
+					next:        now.Add(route.RouteOpts.GroupWait),
+				}
+				s.groups[key] = group
+			}
+			if existing, ok := group.alerts[fp]; ok && !existing.resolvedAt(now) {
+				a.startsAt = existing.startsAt
+			}
+			group.alerts[fp] = a
+		}
+	}
+}
+
+// Finish flushes all groups that are due before the given time and returns the result of the simulation.
+func (s *notificationSimulator) Finish(to time.Time) *NotificationsResult {
+	s.flushUntil(to)
+	keys := make([]string, 0, len(s.stats))
+	for key := range s.stats {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s.result.Groups = append(s.result.Groups, *s.stats[key])
+	}
+	return s.result
+}
+
+func (s *notificationSimulator) flushUntil(t time.Time) {
+	for {
+		var next *aggregationGroup
+		for _, g := range s.groups {
+			if g.next.After(t) {
+				continue
+			}
+			if next == nil || g.next.Before(next.next) || (g.next.Equal(next.next) && g.key < next.key) {
+				next = g
+			}
+		}
+		if next == nil {
+			return
+		}
+		s.flush(next, next.next)
+	}
+}
+
+func (s *notificationSimulator) flush(g *aggregationGroup, at time.Time) {
+	g.next = at.Add(g.route.RouteOpts.GroupInterval)
+
+	firing := make(map[model.Fingerprint]struct{})
+	resolved := make(map[model.Fingerprint]struct{})
+	for fp, a := range g.alerts {
+		if s.silenced(a.labels, at) {
+			continue
+		}
+		if a.resolvedAt(at) {
+			resolved[fp] = struct{}{}
+		} else {
+			firing[fp] = struct{}{}
+		}
+	}
+
+	defer func() {
+		// Alertmanager removes resolved alerts from the group after the flush, and destroys empty groups.
+		for fp, a := range g.alerts {
+			if a.resolvedAt(at) {
+				delete(g.alerts, fp)
+			}
+		}
+		if len(g.alerts) == 0 {
+			delete(s.groups, g.key)
+		}
+	}()
+
+	if len(g.alerts) == 0 {
+		return
+	}
+	if s.muted(g.route, at) {
+		s.result.Muted++
+		return
+	}
+	if len(firing) == 0 && len(resolved) == 0 {
+		s.result.Silenced++
+		return
+	}
+	if !needsUpdate(g.log, firing, resolved, g.route.RouteOpts.RepeatInterval, at) {
+		return
+	}
+	g.log = &notificationLogEntry{
+		firing:    firing,
+		resolved:  resolved,
+		timestamp: at,
+	}
+	s.notify(g, at, len(firing), len(resolved))
+}
+
+func (s *notificationSimulator) notify(g *aggregationGroup, at time.Time, firing, resolved int) {
+	receiver := g.route.RouteOpts.Receiver
+	groupLabels := make(map[string]string, len(g.groupLabels))
+	for k, v := range g.groupLabels {
+		groupLabels[string(k)] = string(v)
+	}
+	s.result.Timeline = append(s.result.Timeline, Notification{
+		Time:        at,
+		Receiver:    receiver,
+		GroupKey:    g.key,
+		GroupLabels: groupLabels,
+		Firing:      firing,
+		Resolved:    resolved,
+	})
+	s.result.Receivers[receiver]++
+	s.result.Total++
+
+	stat, ok := s.stats[g.key]
+	if !ok {
+		stat = &NotificationGroup{
+			GroupKey:          g.key,
+			Receiver:          receiver,
+			GroupLabels:       groupLabels,
+			FirstNotification: at,
+		}
+		s.stats[g.key] = stat
+	}
+	stat.Notifications++
+	stat.LastNotification = at
+}
+
+func (s *notificationSimulator) muted(route *dispatch.Route, at time.Time) bool {
+	for _, name := range route.RouteOpts.MuteTimeIntervals {
+		if s.inInterval(name, at) {
+			return true
+		}
+	}
+	if len(route.RouteOpts.ActiveTimeIntervals) == 0 {
+		return false
+	}
+	for _, name := range route.RouteOpts.ActiveTimeIntervals {
+		if s.inInterval(name, at) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *notificationSimulator) inInterval(name string, at time.Time) bool {
+	for _, ti := range s.intervals[name] {
+		if ti.ContainsTime(at.UTC()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *notificationSimulator) silenced(ls model.LabelSet, at time.Time) bool {
+	for _, silence := range s.silences {
+		if silence.mutes(ls, at) {
+			return true
+		}
+	}
+	return false
+}
+
+// needsUpdate follows the logic of the Alertmanager's deduplication stage with send_resolved enabled.
+func needsUpdate(entry *notificationLogEntry, firing, resolved map[model.Fingerprint]struct{}, repeat time.Duration, now time.Time) bool {
+	if entry == nil {
+		return len(firing) > 0
+	}
+	if !isSubset(firing, entry.firing) {
+		return true
+	}
+	if len(firing) == 0 {
+		return len(entry.firing) > 0
+	}
+	if !isSubset(resolved, entry.resolved) {
+		return true
+	}
+	return entry.timestamp.Before(now.Add(-repeat))
+}
+
+func isSubset(subset, set map[model.Fingerprint]struct{}) bool {
+	for fp := range subset {
+		if _, ok := set[fp]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func getGroupLabels(ls model.LabelSet, route *dispatch.Route) model.LabelSet {
+	if route.RouteOpts.GroupByAll {
+		return ls.Clone()
+	}
+	result := model.LabelSet{}
+	for ln, lv := range ls {
+		if _, ok := route.RouteOpts.GroupBy[ln]; ok {
+			result[ln] = lv
+		}
+	}
+	return result
+}
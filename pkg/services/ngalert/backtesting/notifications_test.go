@@ -0,0 +1,192 @@
+package backtesting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+func TestNotificationSimulator(t *testing.T) {
+	groupWait := model.Duration(30 * time.Second)
+	groupInterval := model.Duration(5 * time.Minute)
+	repeatInterval := model.Duration(1 * time.Hour)
+	route := func(mutations ...func(r *definitions.Route)) *definitions.Route {
+		r := &definitions.Route{
+			Receiver:       "default",
+			GroupByStr:     []string{model.AlertNameLabel},
+			GroupWait:      &groupWait,
+			GroupInterval:  &groupInterval,
+			RepeatInterval: &repeatInterval,
+		}
+		for _, m := range mutations {
+			m(r)
+		}
+		return r
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	alert := func(name string, instance string, startsAt, endsAt time.Time) *amv2.PostableAlert {
+		return &amv2.PostableAlert{
+			StartsAt: strfmt.DateTime(startsAt),
+			EndsAt:   strfmt.DateTime(endsAt),
+			Alert: amv2.Alert{
+				Labels: amv2.LabelSet{model.AlertNameLabel: name, "instance": instance},
+			},
+		}
+	}
+	// replay sends the alert every minute until resolvedAt and then sends the resolved alert once.
+	replay := func(s *notificationSimulator, a func(startsAt, endsAt time.Time) *amv2.PostableAlert, from, resolvedAt, to time.Time) *NotificationsResult {
+		for now := from; now.Before(to); now = now.Add(time.Minute) {
+			s.Advance(now)
+			if now.Before(resolvedAt) {
+				s.Put([]*amv2.PostableAlert{a(from, now.Add(4*time.Minute))})
+			} else if now.Equal(resolvedAt) {
+				s.Put([]*amv2.PostableAlert{a(from, now)})
+			}
+		}
+		return s.Finish(to)
+	}
+
+	t.Run("should notify after group wait, on resolve and after repeat interval", func(t *testing.T) {
+		s, err := newNotificationSimulator(NotificationPolicy{Route: route()})
+		require.NoError(t, err)
+
+		resolvedAt := start.Add(90 * time.Minute)
+		result := replay(s, func(startsAt, endsAt time.Time) *amv2.PostableAlert {
+			return alert("test", "1", startsAt, endsAt)
+		}, start, resolvedAt, start.Add(2*time.Hour))
+
+		require.Equal(t, 3, result.Total)
+		require.Equal(t, map[string]int{"default": 3}, result.Receivers)
+		require.Len(t, result.Groups, 1)
+		require.Equal(t, map[string]string{model.AlertNameLabel: "test"}, result.Groups[0].GroupLabels)
+
+		first := result.Timeline[0]
+		require.Equal(t, start.Add(time.Duration(groupWait)), first.Time)
+		require.Equal(t, 1, first.Firing)
+		require.Equal(t, 0, first.Resolved)
+
+		// repeat interval is aligned with group interval
+		repeat := result.Timeline[1]
+		require.Equal(t, 1, repeat.Firing)
+		require.False(t, repeat.Time.Before(first.Time.Add(time.Duration(repeatInterval))))
+
+		last := result.Timeline[2]
+		require.Equal(t, 0, last.Firing)
+		require.Equal(t, 1, last.Resolved)
+		require.False(t, last.Time.Before(resolvedAt))
+	})
+
+	t.Run("should group alerts by labels", func(t *testing.T) {
+		s, err := newNotificationSimulator(NotificationPolicy{Route: route()})
+		require.NoError(t, err)
+
+		s.Advance(start)
+		s.Put([]*amv2.PostableAlert{
+			alert("test", "1", start, start.Add(time.Hour)),
+			alert("test", "2", start, start.Add(time.Hour)),
+			alert("test-2", "1", start, start.Add(time.Hour)),
+		})
+		result := s.Finish(start.Add(time.Minute))
+
+		require.Equal(t, 2, result.Total)
+		require.Len(t, result.Groups, 2)
+		for _, n := range result.Timeline {
+			if n.GroupLabels[model.AlertNameLabel] == "test" {
+				require.Equal(t, 2, n.Firing)
+			} else {
+				require.Equal(t, 1, n.Firing)
+			}
+		}
+	})
+
+	t.Run("should route to matching child policy", func(t *testing.T) {
+		matcher, err := labels.NewMatcher(labels.MatchEqual, "instance", "2")
+		require.NoError(t, err)
+		s, err := newNotificationSimulator(NotificationPolicy{Route: route(func(r *definitions.Route) {
+			r.Routes = []*definitions.Route{
+				{
+					Receiver:       "team",
+					ObjectMatchers: definitions.ObjectMatchers{matcher},
+				},
+			}
+		})})
+		require.NoError(t, err)
+
+		s.Advance(start)
+		s.Put([]*amv2.PostableAlert{
+			alert("test", "1", start, start.Add(time.Hour)),
+			alert("test", "2", start, start.Add(time.Hour)),
+		})
+		result := s.Finish(start.Add(time.Minute))
+
+		require.Equal(t, map[string]int{"default": 1, "team": 1}, result.Receivers)
+	})
+
+	t.Run("should not notify when muted by time interval", func(t *testing.T) {
+		always := config.TimeInterval{
+			Name: "always",
+			TimeIntervals: []timeinterval.TimeInterval{
+				{Times: []timeinterval.TimeRange{{StartMinute: 0, EndMinute: 24 * 60}}},
+			},
+		}
+		s, err := newNotificationSimulator(NotificationPolicy{
+			Route: route(func(r *definitions.Route) {
+				r.MuteTimeIntervals = []string{always.Name}
+			}),
+			TimeIntervals: []config.TimeInterval{always},
+		})
+		require.NoError(t, err)
+
+		s.Advance(start)
+		s.Put([]*amv2.PostableAlert{alert("test", "1", start, start.Add(time.Hour))})
+		result := s.Finish(start.Add(30 * time.Minute))
+
+		require.Zero(t, result.Total)
+		require.Positive(t, result.Muted)
+	})
+
+	t.Run("should not notify when silenced", func(t *testing.T) {
+		silenceStart := strfmt.DateTime(start)
+		silenceEnd := strfmt.DateTime(start.Add(10 * time.Minute))
+		s, err := newNotificationSimulator(NotificationPolicy{
+			Route: route(),
+			Silences: []*models.Silence{
+				{
+					ID: util.Pointer(util.GenerateShortUID()),
+					Silence: amv2.Silence{
+						StartsAt: &silenceStart,
+						EndsAt:   &silenceEnd,
+						Matchers: amv2.Matchers{
+							{Name: util.Pointer("instance"), Value: util.Pointer("1"), IsEqual: util.Pointer(true), IsRegex: util.Pointer(false)},
+						},
+					},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		result := replay(s, func(startsAt, endsAt time.Time) *amv2.PostableAlert {
+			return alert("test", "1", startsAt, endsAt)
+		}, start, start.Add(time.Hour), start.Add(20*time.Minute))
+
+		require.Equal(t, 1, result.Total)
+		require.Positive(t, result.Silenced)
+		require.False(t, result.Timeline[0].Time.Before(time.Time(silenceEnd)))
+	})
+
+	t.Run("should fail if route is empty", func(t *testing.T) {
+		_, err := newNotificationSimulator(NotificationPolicy{})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,240 @@
+package backtesting
+
+import (
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+)
+
+// Alternative is a set of settings of the rule that backtesting uses to calculate alternative statistics.
+type Alternative struct {
+	// For is the pending period of the rule.
+	For time.Duration
+	// KeepFiringFor is the period during which an alert keeps firing after the condition stops being met.
+	KeepFiringFor time.Duration
+}
+
+// Stats contains statistics of firing alerts.
+type Stats struct {
+	// Firings is the number of times alerts started firing, i.e. transitioned to Alerting (from Pending if the rule has a pending period).
+	Firings int `json:"firings"`
+	// Flaps is the number of times the state of alerts changed.
+	Flaps int `json:"flaps"`
+	// FiringTime is the total time alerts were firing.
+	FiringTime model.Duration `json:"firingTime"`
+	// MeanFiringDuration is the average duration of a single firing.
+	MeanFiringDuration model.Duration `json:"meanFiringDuration"`
+	// MaxFiringDuration is the duration of the longest firing.
+	MaxFiringDuration model.Duration `json:"maxFiringDuration"`
+}
+
+func (s *Stats) add(other Stats) {
+	s.Firings += other.Firings
+	s.Flaps += other.Flaps
+	s.FiringTime += other.FiringTime
+	if other.MaxFiringDuration > s.MaxFiringDuration {
+		s.MaxFiringDuration = other.MaxFiringDuration
+	}
+	s.MeanFiringDuration = 0
+	if s.Firings > 0 {
+		s.MeanFiringDuration = s.FiringTime / model.Duration(s.Firings)
+	}
+}
+
+// SeriesSummary contains statistics of a single alert instance.
+type SeriesSummary struct {
+	Labels data.Labels `json:"labels"`
+	Stats
+}
+
+// AlternativeSummary contains statistics that the rule would have with different settings.
+type AlternativeSummary struct {
+	For           model.Duration `json:"for"`
+	KeepFiringFor model.Duration `json:"keepFiringFor"`
+	Total         Stats          `json:"total"`
+}
+
+// Summary contains statistics of a backtesting run.
+type Summary struct {
+	// Series contains statistics of every alert instance. Instances that fired longer come first.
+	Series []SeriesSummary `json:"series"`
+	// Total contains statistics of all alert instances.
+	Total Stats `json:"total"`
+	// Alternatives contains statistics the rule would have with different pending and keep firing periods.
+	Alternatives []AlternativeSummary `json:"alternatives,omitempty"`
+}
+
+// summaryBuilder collects states of alert instances at every evaluation and calculates statistics.
+type summaryBuilder struct {
+	times  []time.Time
+	series map[data.Fingerprint]*seriesStates
+}
+
+type seriesStates struct {
+	labels data.Labels
+	states []eval.State
+	// seen is false for evaluations at which the instance did not exist or had no data.
+	seen []bool
+	// keepFiring is true for evaluations at which the instance was Alerting only because of the keep firing period.
+	keepFiring []bool
+}
+
+func newSummaryBuilder(length int) *summaryBuilder {
+	return &summaryBuilder{
+		times:  make([]time.Time, length),
+		series: make(map[data.Fingerprint]*seriesStates),
+	}
+}
+
+func (b *summaryBuilder) Add(idx int, now time.Time, states state.StateTransitions) {
+	b.times[idx] = now
+	for _, s := range states {
+		series, ok := b.series[s.CacheID]
+		if !ok {
+			series = &seriesStates{
+				labels:     s.Labels,
+				states:     make([]eval.State, len(b.times)),
+				seen:       make([]bool, len(b.times)),
+				keepFiring: make([]bool, len(b.times)),
+			}
+			b.series[s.CacheID] = series
+		}
+		if s.State.State != eval.NoData {
+			series.states[idx] = s.State.State
+			series.seen[idx] = true
+			series.keepFiring[idx] = s.State.State == eval.Alerting && s.StateReason == models.StateReasonKeepFiring
+		}
+	}
+}
+
+// Summary calculates statistics for the evaluations that were added so far. Alerts that are still firing at the end of
+// backtesting are considered resolved at the time end.
+func (b *summaryBuilder) Summary(end time.Time, alternatives []Alternative) *Summary {
+	result := &Summary{
+		Series: make([]SeriesSummary, 0, len(b.series)),
+	}
+	for _, series := range b.series {
+		acc := newStatsAccumulator()
+		for idx, t := range b.times {
+			s := eval.Normal
+			if series.seen[idx] {
+				s = series.states[idx]
+			}
+			acc.observe(t, s)
+		}
+		stats := acc.finish(end)
+		result.Total.add(stats)
+		result.Series = append(result.Series, SeriesSummary{
+			Labels: series.labels,
+			Stats:  stats,
+		})
+	}
+	sort.Slice(result.Series, func(i, j int) bool {
+		if result.Series[i].FiringTime != result.Series[j].FiringTime {
+			return result.Series[i].FiringTime > result.Series[j].FiringTime
+		}
+		return result.Series[i].Labels.String() < result.Series[j].Labels.String()
+	})
+
+	for _, alt := range alternatives {
+		summary := AlternativeSummary{
+			For:           model.Duration(alt.For),
+			KeepFiringFor: model.Duration(alt.KeepFiringFor),
+		}
+		for _, series := range b.series {
+			summary.Total.add(b.simulate(series, alt, end))
+		}
+		result.Alternatives = append(result.Alternatives, summary)
+	}
+	return result
+}
+
+// simulate replays the series with different settings. The condition of the rule is considered met if the alert
+// was either Pending or Alerting, unless it was Alerting only because of the actual keep firing period.
+func (b *summaryBuilder) simulate(series *seriesStates, alt Alternative, end time.Time) Stats {
+	acc := newStatsAccumulator()
+	current := eval.Normal
+	var activeSince, inactiveSince time.Time
+	for idx, t := range b.times {
+		active := series.seen[idx] && !series.keepFiring[idx] && (series.states[idx] == eval.Pending || series.states[idx] == eval.Alerting)
+		switch {
+		case active && current == eval.Normal:
+			activeSince = t
+			current = eval.Pending
+			if alt.For <= 0 {
+				current = eval.Alerting
+			}
+		case active && current == eval.Pending:
+			if t.Sub(activeSince) >= alt.For {
+				current = eval.Alerting
+			}
+		case active && current == eval.Alerting:
+			inactiveSince = time.Time{}
+		case !active && current == eval.Pending:
+			current = eval.Normal
+		case !active && current == eval.Alerting:
+			if inactiveSince.IsZero() {
+				inactiveSince = t
+			}
+			if t.Sub(inactiveSince) >= alt.KeepFiringFor {
+				current = eval.Normal
+				inactiveSince = time.Time{}
+			}
+		}
+		acc.observe(t, current)
+	}
+	return acc.finish(end)
+}
+
+// statsAccumulator calculates statistics of a single alert instance from its consecutive states.
+type statsAccumulator struct {
+	stats       Stats
+	started     bool
+	previous    eval.State
+	firingSince time.Time
+}
+
+func newStatsAccumulator() *statsAccumulator {
+	return &statsAccumulator{}
+}
+
+func (a *statsAccumulator) observe(t time.Time, s eval.State) {
+	if a.started && s != a.previous {
+		a.stats.Flaps++
+	}
+	firing := s == eval.Alerting
+	wasFiring := a.started && a.previous == eval.Alerting
+	switch {
+	case firing && !wasFiring:
+		a.firingSince = t
+		a.stats.Firings++
+	case !firing && wasFiring:
+		a.resolve(t)
+	}
+	a.started = true
+	a.previous = s
+}
+
+func (a *statsAccumulator) resolve(t time.Time) {
+	d := model.Duration(t.Sub(a.firingSince))
+	a.stats.FiringTime += d
+	if d > a.stats.MaxFiringDuration {
+		a.stats.MaxFiringDuration = d
+	}
+}
+
+func (a *statsAccumulator) finish(end time.Time) Stats {
+	if a.started && a.previous == eval.Alerting {
+		a.resolve(end)
+	}
+	if a.stats.Firings > 0 {
+		a.stats.MeanFiringDuration = a.stats.FiringTime / model.Duration(a.stats.Firings)
+	}
+	return a.stats
+}
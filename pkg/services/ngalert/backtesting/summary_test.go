@@ -0,0 +1,121 @@
+package backtesting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+)
+
+func TestSummaryBuilder(t *testing.T) {
+	from := time.Unix(0, 0)
+	interval := time.Minute
+	n, p, a := eval.Normal, eval.Pending, eval.Alerting
+
+	// the rule has a pending period of 1 minute
+	flapping := data.Labels{"series": "flapping"}
+	flappingStates := []eval.State{n, p, a, a, n, p, a, a, a, n}
+	firing := data.Labels{"series": "firing"}
+	firingStates := []eval.State{a, a, a, a, a, a, a, a, a, a}
+
+	b := newSummaryBuilder(len(flappingStates))
+	for i := range flappingStates {
+		b.Add(i, from.Add(time.Duration(i)*interval), state.StateTransitions{
+			{State: &state.State{CacheID: flapping.Fingerprint(), Labels: flapping, State: flappingStates[i]}},
+			{State: &state.State{CacheID: firing.Fingerprint(), Labels: firing, State: firingStates[i]}},
+		})
+	}
+	end := from.Add(time.Duration(len(flappingStates)) * interval)
+
+	minutes := func(m int) model.Duration {
+		return model.Duration(time.Duration(m) * time.Minute)
+	}
+
+	summary := b.Summary(end, []Alternative{
+		{},
+		{For: 2 * time.Minute},
+		{For: time.Minute, KeepFiringFor: 2 * time.Minute},
+	})
+
+	t.Run("should calculate statistics per series", func(t *testing.T) {
+		require.Len(t, summary.Series, 2)
+
+		require.Equal(t, firing, summary.Series[0].Labels)
+		require.Equal(t, Stats{
+			Firings:            1,
+			Flaps:              0,
+			FiringTime:         minutes(10),
+			MeanFiringDuration: minutes(10),
+			MaxFiringDuration:  minutes(10),
+		}, summary.Series[0].Stats)
+
+		require.Equal(t, flapping, summary.Series[1].Labels)
+		require.Equal(t, Stats{
+			Firings:            2,
+			Flaps:              6,
+			FiringTime:         minutes(5),
+			MeanFiringDuration: model.Duration(150 * time.Second),
+			MaxFiringDuration:  minutes(3),
+		}, summary.Series[1].Stats)
+	})
+
+	t.Run("should calculate total statistics", func(t *testing.T) {
+		require.Equal(t, Stats{
+			Firings:            3,
+			Flaps:              6,
+			FiringTime:         minutes(15),
+			MeanFiringDuration: minutes(5),
+			MaxFiringDuration:  minutes(10),
+		}, summary.Total)
+	})
+
+	t.Run("should calculate statistics with alternative settings", func(t *testing.T) {
+		require.Len(t, summary.Alternatives, 3)
+
+		noPending := summary.Alternatives[0]
+		require.Equal(t, 3, noPending.Total.Firings)
+		require.Equal(t, minutes(17), noPending.Total.FiringTime)
+
+		longerPending := summary.Alternatives[1]
+		require.Equal(t, minutes(2), longerPending.For)
+		require.Equal(t, 3, longerPending.Total.Firings)
+		require.Equal(t, minutes(11), longerPending.Total.FiringTime)
+
+		keepFiring := summary.Alternatives[2]
+		require.Equal(t, minutes(2), keepFiring.KeepFiringFor)
+		require.Equal(t, 2, keepFiring.Total.Firings)
+		require.Equal(t, minutes(18), keepFiring.Total.FiringTime)
+		require.Equal(t, minutes(10), keepFiring.Total.MaxFiringDuration)
+	})
+}
+
+func TestSummaryBuilderKeepFiring(t *testing.T) {
+	from := time.Unix(0, 0)
+	interval := time.Minute
+	lbls := data.Labels{"series": "keep-firing"}
+
+	// the condition is met for 2 minutes, and the rule keeps firing for 3 more minutes
+	states := []eval.State{eval.Alerting, eval.Alerting, eval.Alerting, eval.Alerting, eval.Alerting, eval.Normal}
+	reasons := []string{"", "", models.StateReasonKeepFiring, models.StateReasonKeepFiring, models.StateReasonKeepFiring, ""}
+
+	b := newSummaryBuilder(len(states))
+	for i := range states {
+		b.Add(i, from.Add(time.Duration(i)*interval), state.StateTransitions{
+			{State: &state.State{CacheID: lbls.Fingerprint(), Labels: lbls, State: states[i], StateReason: reasons[i]}},
+		})
+	}
+	end := from.Add(time.Duration(len(states)) * interval)
+
+	summary := b.Summary(end, []Alternative{{}})
+
+	require.Equal(t, model.Duration(5*time.Minute), summary.Total.FiringTime)
+	require.Len(t, summary.Alternatives, 1)
+	require.Equal(t, 1, summary.Alternatives[0].Total.Firings)
+	require.Equal(t, model.Duration(2*time.Minute), summary.Alternatives[0].Total.FiringTime)
+}
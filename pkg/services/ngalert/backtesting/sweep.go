@@ -0,0 +1,193 @@
+package backtesting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// Sweep defines values of the rule settings that are tested in all combinations.
+type Sweep struct {
+	// Thresholds are values of the threshold expression that is the condition of the rule.
+	// If empty, the current value is used.
+	Thresholds []float64
+	// For are pending periods of the rule. If empty, the current pending period is used.
+	For []time.Duration
+}
+
+// SweepCell contains statistics of the rule with a single combination of settings.
+type SweepCell struct {
+	// Alerts is the number of alert instances that fired at least once.
+	Alerts int `json:"alerts"`
+	// Firings is the number of times alert instances started firing.
+	Firings int `json:"firings"`
+	// FiringTime is the total time alert instances were firing.
+	FiringTime model.Duration `json:"firingTime"`
+}
+
+// SweepResult contains statistics for every combination of the settings defined by the Sweep.
+type SweepResult struct {
+	Thresholds []float64        `json:"thresholds,omitempty"`
+	For        []model.Duration `json:"for"`
+	// Matrix is indexed by the index of the threshold and then by the index of the pending period.
+	Matrix [][]SweepCell `json:"matrix"`
+}
+
+// recordedEvaluation is the result of a single evaluation of a rule that is stored to be replayed with different settings.
+type recordedEvaluation struct {
+	time    time.Time
+	results eval.Results
+}
+
+// thresholdCondition is a threshold expression with a single comparison with a number.
+type thresholdCondition struct {
+	// input is the RefID of the node the threshold expression compares.
+	input         string
+	evaluatorType expr.ThresholdType
+	value         float64
+}
+
+// thresholdFromRule returns the threshold expression that is the condition of the rule.
+func thresholdFromRule(rule *models.AlertRule) (*thresholdCondition, error) {
+	for _, q := range rule.Data {
+		if q.RefID != rule.Condition {
+			continue
+		}
+		if isExpr, _ := q.IsExpression(); !isExpr {
+			return nil, errors.New("condition of the rule is not an expression")
+		}
+		var m struct {
+			Type expr.QueryType `json:"type"`
+			expr.ThresholdQuery
+		}
+		if err := json.Unmarshal(q.Model, &m); err != nil {
+			return nil, fmt.Errorf("failed to parse condition of the rule: %w", err)
+		}
+		if m.Type != expr.QueryTypeThreshold {
+			return nil, errors.New("condition of the rule is not a threshold expression")
+		}
+		if len(m.Conditions) != 1 {
+			return nil, errors.New("threshold expression must have exactly one condition")
+		}
+		c := m.Conditions[0]
+		if c.UnloadEvaluator != nil {
+			return nil, errors.New("threshold expressions with recovery threshold are not supported")
+		}
+		if c.Evaluator.Type != expr.ThresholdIsAbove && c.Evaluator.Type != expr.ThresholdIsBelow {
+			return nil, fmt.Errorf("threshold type %s is not supported, only %s and %s are supported", c.Evaluator.Type, expr.ThresholdIsAbove, expr.ThresholdIsBelow)
+		}
+		if len(c.Evaluator.Params) != 1 {
+			return nil, errors.New("threshold must have exactly one parameter")
+		}
+		return &thresholdCondition{
+			input:         m.Expression,
+			evaluatorType: c.Evaluator.Type,
+			value:         c.Evaluator.Params[0],
+		}, nil
+	}
+	return nil, fmt.Errorf("condition %s of the rule is not found", rule.Condition)
+}
+
+// apply re-evaluates the results with a different threshold value. It uses the values of the input of the threshold
+// expression that the evaluator captured. Results without the captured value, e.g. NoData or Error, are not changed.
+func (c *thresholdCondition) apply(results eval.Results, threshold float64) eval.Results {
+	applied := make(eval.Results, 0, len(results))
+	for _, r := range results {
+		capture, ok := r.Values[c.input]
+		if !ok || capture.Value == nil || (r.State != eval.Normal && r.State != eval.Alerting) {
+			applied = append(applied, r)
+			continue
+		}
+		var firing bool
+		switch c.evaluatorType {
+		case expr.ThresholdIsAbove:
+			firing = *capture.Value > threshold
+		case expr.ThresholdIsBelow:
+			firing = *capture.Value < threshold
+		}
+		r.State = eval.Normal
+		if firing {
+			r.State = eval.Alerting
+		}
+		applied = append(applied, r)
+	}
+	return applied
+}
+
+// sweep replays the recorded evaluations for every combination of the settings defined by the Sweep.
+func (e *Engine) sweep(ctx context.Context, rule *models.AlertRule, evaluations []recordedEvaluation, sweep Sweep, extraLabels data.Labels, end time.Time) (*SweepResult, error) {
+	var threshold *thresholdCondition
+	thresholds := sweep.Thresholds
+	if len(thresholds) > 0 {
+		var err error
+		threshold, err = thresholdFromRule(rule)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidInputData, err)
+		}
+	}
+	fors := sweep.For
+	if len(fors) == 0 {
+		fors = []time.Duration{rule.For}
+	}
+
+	result := &SweepResult{
+		Thresholds: thresholds,
+		For:        make([]model.Duration, 0, len(fors)),
+		Matrix:     make([][]SweepCell, 0, max(len(thresholds), 1)),
+	}
+	for _, f := range fors {
+		result.For = append(result.For, model.Duration(f))
+	}
+
+	replay := func(threshold func(eval.Results) eval.Results) []SweepCell {
+		row := make([]SweepCell, 0, len(fors))
+		results := make([]eval.Results, 0, len(evaluations))
+		for _, ev := range evaluations {
+			results = append(results, threshold(ev.results))
+		}
+		for _, f := range fors {
+			r := rule.Copy()
+			r.For = f
+			stateManager := e.createStateManager()
+			summary := newSummaryBuilder(len(evaluations))
+			for idx, ev := range evaluations {
+				states := stateManager.ProcessEvalResults(ctx, ev.time, r, results[idx], extraLabels, nil)
+				summary.Add(idx, ev.time, states)
+			}
+			s := summary.Summary(end, nil)
+			cell := SweepCell{
+				Firings:    s.Total.Firings,
+				FiringTime: s.Total.FiringTime,
+			}
+			for _, series := range s.Series {
+				if series.Firings > 0 {
+					cell.Alerts++
+				}
+			}
+			row = append(row, cell)
+		}
+		return row
+	}
+
+	if threshold == nil {
+		result.Matrix = append(result.Matrix, replay(func(results eval.Results) eval.Results {
+			return results
+		}))
+		return result, nil
+	}
+	for _, t := range thresholds {
+		result.Matrix = append(result.Matrix, replay(func(results eval.Results) eval.Results {
+			return threshold.apply(results, t)
+		}))
+	}
+	return result, nil
+}
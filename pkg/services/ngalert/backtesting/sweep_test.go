@@ -0,0 +1,203 @@
+package backtesting
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestThresholdFromRule(t *testing.T) {
+	testCases := []struct {
+		name      string
+		condition string
+		expected  *thresholdCondition
+		error     bool
+	}{
+		{
+			name:      "greater than",
+			condition: `{"type": "threshold", "expression": "B", "conditions": [{"evaluator": {"type": "gt", "params": [5]}}]}`,
+			expected:  &thresholdCondition{input: "B", evaluatorType: expr.ThresholdIsAbove, value: 5},
+		},
+		{
+			name:      "less than",
+			condition: `{"type": "threshold", "expression": "B", "conditions": [{"evaluator": {"type": "lt", "params": [1.5]}}]}`,
+			expected:  &thresholdCondition{input: "B", evaluatorType: expr.ThresholdIsBelow, value: 1.5},
+		},
+		{
+			name:      "range",
+			condition: `{"type": "threshold", "expression": "B", "conditions": [{"evaluator": {"type": "within_range", "params": [1, 5]}}]}`,
+			error:     true,
+		},
+		{
+			name:      "recovery threshold",
+			condition: `{"type": "threshold", "expression": "B", "conditions": [{"evaluator": {"type": "gt", "params": [5]}, "unloadEvaluator": {"type": "lt", "params": [2]}}]}`,
+			error:     true,
+		},
+		{
+			name:      "not a threshold",
+			condition: `{"type": "math", "expression": "$B > 5"}`,
+			error:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			threshold, err := thresholdFromRule(sweepTestRule(tc.condition))
+			if tc.error {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, threshold)
+		})
+	}
+
+	t.Run("should fail if condition is a data query", func(t *testing.T) {
+		rule := sweepTestRule(`{"type": "threshold", "expression": "B", "conditions": [{"evaluator": {"type": "gt", "params": [5]}}]}`)
+		rule.Condition = "A"
+		_, err := thresholdFromRule(rule)
+		require.Error(t, err)
+	})
+}
+
+func TestThresholdCondition_Apply(t *testing.T) {
+	threshold := &thresholdCondition{input: "B", evaluatorType: expr.ThresholdIsAbove, value: 5}
+	value := func(v float64) *float64 {
+		return &v
+	}
+	results := eval.Results{
+		{State: eval.Normal, Instance: data.Labels{"series": "1"}, Values: map[string]eval.NumberValueCapture{"B": {Var: "B", Value: value(3)}}},
+		{State: eval.Alerting, Instance: data.Labels{"series": "2"}, Values: map[string]eval.NumberValueCapture{"B": {Var: "B", Value: value(10)}}},
+		{State: eval.NoData, Instance: data.Labels{"series": "3"}},
+		{State: eval.Error, Instance: data.Labels{"series": "4"}, Values: map[string]eval.NumberValueCapture{"B": {Var: "B", Value: value(10)}}},
+	}
+
+	applied := threshold.apply(results, 1)
+	require.Equal(t, eval.Alerting, applied[0].State)
+	require.Equal(t, eval.Alerting, applied[1].State)
+	require.Equal(t, eval.NoData, applied[2].State)
+	require.Equal(t, eval.Error, applied[3].State)
+
+	applied = threshold.apply(results, 20)
+	require.Equal(t, eval.Normal, applied[0].State)
+	require.Equal(t, eval.Normal, applied[1].State)
+
+	// original results are not changed
+	require.Equal(t, eval.Normal, results[0].State)
+	require.Equal(t, eval.Alerting, results[1].State)
+}
+
+func TestEngine_Sweep(t *testing.T) {
+	from := time.Unix(0, 0)
+	interval := time.Minute
+	evaluations := 10
+
+	// the value of the series is the index of the evaluation
+	queries := 0
+	backtestingEvaluatorFactory = func(ctx context.Context, evalFactory eval.EvaluatorFactory, user identity.Requester, condition models.Condition, r eval.AlertingResultsReader) (backtestingEvaluator, error) {
+		return &fakeBacktestingEvaluator{
+			evalCallback: func(now time.Time) (eval.Results, error) {
+				queries++
+				value := float64(now.Sub(from) / interval)
+				state := eval.Normal
+				if value > 5 {
+					state = eval.Alerting
+				}
+				return eval.Results{{
+					State:       state,
+					Instance:    data.Labels{"series": "1"},
+					EvaluatedAt: now,
+					Values:      map[string]eval.NumberValueCapture{"B": {Var: "B", Value: &value}},
+				}}, nil
+			},
+		}, nil
+	}
+	t.Cleanup(func() {
+		backtestingEvaluatorFactory = newBacktestingEvaluator
+	})
+
+	engine := NewEngine(&url.URL{}, nil, tracing.InitializeTracerForTest())
+	rule := sweepTestRule(`{"type": "threshold", "expression": "B", "conditions": [{"evaluator": {"type": "gt", "params": [5]}}]}`)
+
+	frame, err := engine.TestWithOptions(context.Background(), nil, rule, from, from.Add(time.Duration(evaluations)*interval), TestOptions{
+		Sweep: &Sweep{
+			Thresholds: []float64{2, 5, 8},
+			For:        []time.Duration{0, 2 * time.Minute},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, evaluations, queries, "data must be queried only once")
+
+	meta, ok := frame.Meta.Custom.(ResultMeta)
+	require.True(t, ok)
+	require.NotNil(t, meta.Sweep)
+	require.Equal(t, []float64{2, 5, 8}, meta.Sweep.Thresholds)
+	require.Equal(t, []model.Duration{0, model.Duration(2 * time.Minute)}, meta.Sweep.For)
+
+	minutes := func(m int) model.Duration {
+		return model.Duration(time.Duration(m) * time.Minute)
+	}
+	require.Equal(t, [][]SweepCell{
+		{{Alerts: 1, Firings: 1, FiringTime: minutes(7)}, {Alerts: 1, Firings: 1, FiringTime: minutes(5)}},
+		{{Alerts: 1, Firings: 1, FiringTime: minutes(4)}, {Alerts: 1, Firings: 1, FiringTime: minutes(2)}},
+		{{Alerts: 1, Firings: 1, FiringTime: minutes(1)}, {Alerts: 0, Firings: 0, FiringTime: 0}},
+	}, meta.Sweep.Matrix)
+
+	t.Run("should use current settings if sweep does not define them", func(t *testing.T) {
+		frame, err := engine.TestWithOptions(context.Background(), nil, rule, from, from.Add(time.Duration(evaluations)*interval), TestOptions{
+			Sweep: &Sweep{},
+		})
+		require.NoError(t, err)
+		meta := frame.Meta.Custom.(ResultMeta)
+		require.Equal(t, [][]SweepCell{{{Alerts: 1, Firings: 1, FiringTime: minutes(4)}}}, meta.Sweep.Matrix)
+	})
+
+	t.Run("should fail if condition is not a threshold", func(t *testing.T) {
+		rule := sweepTestRule(`{"type": "math", "expression": "$B > 5"}`)
+		_, err := engine.TestWithOptions(context.Background(), nil, rule, from, from.Add(time.Duration(evaluations)*interval), TestOptions{
+			Sweep: &Sweep{Thresholds: []float64{1}},
+		})
+		require.ErrorIs(t, err, ErrInvalidInputData)
+	})
+}
+
+func sweepTestRule(condition string) *models.AlertRule {
+	return &models.AlertRule{
+		OrgID:           1,
+		UID:             "test",
+		Title:           "test",
+		Condition:       "C",
+		IntervalSeconds: 60,
+		NoDataState:     models.NoData,
+		ExecErrState:    models.ErrorErrState,
+		Data: []models.AlertQuery{
+			{
+				RefID:         "A",
+				DatasourceUID: "prometheus",
+				Model:         json.RawMessage(`{"expr": "up"}`),
+			},
+			{
+				RefID:         "B",
+				DatasourceUID: expr.DatasourceUID,
+				Model:         json.RawMessage(`{"type": "reduce", "expression": "A", "reducer": "last"}`),
+			},
+			{
+				RefID:         "C",
+				DatasourceUID: expr.DatasourceUID,
+				Model:         json.RawMessage(condition),
+			},
+		},
+	}
+}
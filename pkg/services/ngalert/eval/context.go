@@ -19,6 +19,8 @@ type EvaluationContext struct {
 	Ctx                   context.Context
 	User                  identity.Requester
 	AlertingResultsReader AlertingResultsReader
+	// SharedQueries, if set, is used to share results of datasource queries with other conditions evaluated at the same time.
+	SharedQueries *SharedQueries
 }
 
 func NewContext(ctx context.Context, user identity.Requester) EvaluationContext {
@@ -35,3 +37,9 @@ func NewContextWithPreviousResults(ctx context.Context, user identity.Requester,
 		AlertingResultsReader: reader,
 	}
 }
+
+// WithSharedQueries returns a copy of the context that shares results of datasource queries via the given SharedQueries.
+func (c EvaluationContext) WithSharedQueries(shared *SharedQueries) EvaluationContext {
+	c.SharedQueries = shared
+	return c
+}
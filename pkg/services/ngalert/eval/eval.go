@@ -26,6 +26,14 @@ import (
 
 var logger = log.New("ngalert.eval")
 
+var (
+	// ErrDatasourceThrottled is returned when an evaluation is skipped because it waited too long for a free slot
+	// of the concurrency limit of a datasource.
+	ErrDatasourceThrottled = errors.New("evaluation skipped because the concurrency limit of the datasource was reached")
+	// ErrDatasourceQueryTimeout is returned when an evaluation does not complete within the query timeout of a datasource.
+	ErrDatasourceQueryTimeout = errors.New("evaluation exceeded the query timeout of the datasource")
+)
+
 type EvaluatorFactory interface {
 	// Create builds an evaluator pipeline ready to evaluate a rule's query
 	Create(ctx EvaluationContext, condition models.Condition) (ConditionEvaluator, error)
@@ -192,7 +200,8 @@ func (evalResults Results) HasNonRetryableErrors() bool {
 }
 
 // IsNonRetryableError indicates whether an error is considered persistent and not worth performing evaluation retries.
-// Currently it is true if err is `&invalidEvalResultFormatError` or `ErrSeriesMustBeWide`
+// Currently it is true if err is `&invalidEvalResultFormatError`, `ErrSeriesMustBeWide` or `ErrDatasourceThrottled`.
+// Throttled evaluations are not retried because a retry would queue again for the datasource that is already busy.
 func IsNonRetryableError(err error) bool {
 	var nonRetryableError *invalidEvalResultFormatError
 	if errors.As(err, &nonRetryableError) {
@@ -201,6 +210,9 @@ func IsNonRetryableError(err error) bool {
 	if errors.Is(err, expr.ErrSeriesMustBeWide) {
 		return true
 	}
+	if errors.Is(err, ErrDatasourceThrottled) {
+		return true
+	}
 	return false
 }
 
@@ -834,14 +846,17 @@ func (e *evaluatorImpl) Create(ctx EvaluationContext, condition models.Condition
 	if err != nil {
 		return nil, err
 	}
-	return e.create(condition, req)
+	return e.create(condition, req, ctx.SharedQueries)
 }
 
-func (e *evaluatorImpl) create(condition models.Condition, req *expr.Request) (ConditionEvaluator, error) {
+func (e *evaluatorImpl) create(condition models.Condition, req *expr.Request, shared *SharedQueries) (ConditionEvaluator, error) {
 	pipeline, err := e.expressionService.BuildPipeline(req)
 	if err != nil {
 		return nil, err
 	}
+	if shared != nil {
+		pipeline = withSharedQueries(pipeline, sharedQueryKeys(req, condition), shared)
+	}
 	conditions := make([]string, 0, len(pipeline))
 	for _, node := range pipeline {
 		if node.RefID() == condition.Condition {
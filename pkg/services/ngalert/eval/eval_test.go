@@ -1276,6 +1276,16 @@ func TestResults_HasNonRetryableErrors(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			name: "with throttled evaluation",
+			eval: Results{
+				{
+					State: Error,
+					Error: fmt.Errorf("%w: datasource ds", ErrDatasourceThrottled),
+				},
+			},
+			expected: true,
+		},
 		{
 			name: "with retryable errors",
 			eval: Results{
@@ -0,0 +1,206 @@
+package eval
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// SharedQueries deduplicates datasource queries of conditions that are evaluated at the same time.
+// Identical queries, i.e. queries with the same model and time range against the same datasource of the same organization,
+// are executed once and the result is shared by all conditions that run them.
+// Only successful results are shared. If a query fails, conditions that wait for it execute the query themselves.
+// Each condition gets its own copy of the shared result, so it can modify the frames without affecting other conditions.
+type SharedQueries struct {
+	mtx     sync.Mutex
+	entries map[sharedQueryKey]*sharedQuery
+
+	hits   prometheus.Counter
+	misses prometheus.Counter
+}
+
+type sharedQueryKey struct {
+	orgID         int64
+	datasourceUID string
+	refID         string
+	queryType     string
+	from          time.Duration
+	to            time.Duration
+	model         string
+	// now is the evaluation time in nanoseconds. Queries are shared only between evaluations of the same time.
+	now int64
+}
+
+type sharedQuery struct {
+	done    chan struct{}
+	results mathexp.Results
+	err     error
+}
+
+func NewSharedQueries(hits, misses prometheus.Counter) *SharedQueries {
+	return &SharedQueries{
+		entries: make(map[sharedQueryKey]*sharedQuery),
+		hits:    hits,
+		misses:  misses,
+	}
+}
+
+// Expire removes results of queries that were executed for evaluations before the given time.
+func (s *SharedQueries) Expire(before time.Time) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for key := range s.entries {
+		if key.now < before.UnixNano() {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// execute returns the result of the query identified by the key. If the query is not executed yet, it runs fn.
+// If the query is being executed by another condition, it waits for the result.
+func (s *SharedQueries) execute(ctx context.Context, key sharedQueryKey, fn func() (mathexp.Results, error)) (mathexp.Results, error) {
+	s.mtx.Lock()
+	q, ok := s.entries[key]
+	if !ok {
+		q = &sharedQuery{done: make(chan struct{})}
+		s.entries[key] = q
+	}
+	s.mtx.Unlock()
+
+	if !ok {
+		s.misses.Inc()
+		q.results, q.err = fn()
+		if q.err != nil {
+			s.mtx.Lock()
+			if s.entries[key] == q {
+				delete(s.entries, key)
+			}
+			s.mtx.Unlock()
+		}
+		close(q.done)
+		if q.err != nil {
+			return q.results, q.err
+		}
+		return copyResults(q.results), nil
+	}
+
+	select {
+	case <-q.done:
+	case <-ctx.Done():
+		return mathexp.Results{}, ctx.Err()
+	}
+	if q.err != nil {
+		// the error could be specific to the condition that executed the query, for example, its context was canceled.
+		s.misses.Inc()
+		return fn()
+	}
+	s.hits.Inc()
+	return copyResults(q.results), nil
+}
+
+// copyResults returns a deep copy of the results. Values of unknown types are not copied.
+func copyResults(results mathexp.Results) mathexp.Results {
+	if results.Values == nil {
+		return results
+	}
+	values := make(mathexp.Values, 0, len(results.Values))
+	for _, v := range results.Values {
+		switch v := v.(type) {
+		case mathexp.Series:
+			values = append(values, mathexp.Series{Frame: copyFrame(v.Frame)})
+		case mathexp.Number:
+			values = append(values, mathexp.Number{Frame: copyFrame(v.Frame)})
+		case mathexp.NoData:
+			values = append(values, mathexp.NoData{Frame: copyFrame(v.Frame)})
+		case mathexp.TableData:
+			values = append(values, mathexp.TableData{Frame: copyFrame(v.Frame)})
+		default:
+			values = append(values, v)
+		}
+	}
+	results.Values = values
+	return results
+}
+
+// copyFrame returns a copy of the frame with its own fields, labels and metadata.
+func copyFrame(frame *data.Frame) *data.Frame {
+	if frame == nil {
+		return nil
+	}
+	result := frame.EmptyCopy()
+	if frame.Meta != nil {
+		meta := *frame.Meta
+		meta.Notices = slices.Clone(frame.Meta.Notices)
+		result.Meta = &meta
+	}
+	for i := 0; i < frame.Rows(); i++ {
+		result.AppendRow(frame.RowCopy(i)...)
+	}
+	return result
+}
+
+// sharedQueryKeys returns keys of datasource queries of the request indexed by RefID.
+func sharedQueryKeys(req *expr.Request, condition models.Condition) map[string]sharedQueryKey {
+	timeRanges := make(map[string]models.RelativeTimeRange, len(condition.Data))
+	for _, q := range condition.Data {
+		timeRanges[q.RefID] = q.RelativeTimeRange
+	}
+	keys := make(map[string]sharedQueryKey, len(req.Queries))
+	for _, q := range req.Queries {
+		if q.DataSource == nil || expr.NodeTypeFromDatasourceUID(q.DataSource.UID) != expr.TypeDatasourceNode {
+			continue
+		}
+		tr, ok := timeRanges[q.RefID]
+		if !ok {
+			continue
+		}
+		keys[q.RefID] = sharedQueryKey{
+			orgID:         req.OrgId,
+			datasourceUID: q.DataSource.UID,
+			refID:         q.RefID,
+			queryType:     q.QueryType,
+			from:          time.Duration(tr.From),
+			to:            time.Duration(tr.To),
+			model:         string(q.JSON),
+		}
+	}
+	return keys
+}
+
+// sharedQueryNode is a datasource node of the pipeline that executes its query via SharedQueries.
+type sharedQueryNode struct {
+	expr.Node
+	key    sharedQueryKey
+	shared *SharedQueries
+}
+
+func (n *sharedQueryNode) Execute(ctx context.Context, now time.Time, vars mathexp.Vars, s *expr.Service) (mathexp.Results, error) {
+	key := n.key
+	key.now = now.UnixNano()
+	return n.shared.execute(ctx, key, func() (mathexp.Results, error) {
+		return n.Node.Execute(ctx, now, vars, s)
+	})
+}
+
+// withSharedQueries replaces datasource nodes of the pipeline with nodes that execute queries via SharedQueries.
+func withSharedQueries(pipeline expr.DataPipeline, keys map[string]sharedQueryKey, shared *SharedQueries) expr.DataPipeline {
+	for i, node := range pipeline {
+		if node.NodeType() != expr.TypeDatasourceNode {
+			continue
+		}
+		key, ok := keys[node.RefID()]
+		if !ok {
+			continue
+		}
+		pipeline[i] = &sharedQueryNode{Node: node, key: key, shared: shared}
+	}
+	return pipeline
+}
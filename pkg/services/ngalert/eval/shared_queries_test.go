@@ -0,0 +1,220 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+)
+
+func newTestSharedQueries() *SharedQueries {
+	return NewSharedQueries(prometheus.NewCounter(prometheus.CounterOpts{Name: "hits"}), prometheus.NewCounter(prometheus.CounterOpts{Name: "misses"}))
+}
+
+func TestSharedQueries(t *testing.T) {
+	now := time.Now()
+	key := sharedQueryKey{orgID: 1, datasourceUID: "ds", refID: "A", model: `{"expr":"up"}`, now: now.UnixNano()}
+
+	t.Run("should execute identical queries once", func(t *testing.T) {
+		shared := newTestSharedQueries()
+		calls := 0
+		fn := func() (mathexp.Results, error) {
+			calls++
+			return mathexp.Results{Values: mathexp.Values{mathexp.NewNoData()}}, nil
+		}
+
+		for i := 0; i < 3; i++ {
+			res, err := shared.execute(context.Background(), key, fn)
+			require.NoError(t, err)
+			require.Len(t, res.Values, 1)
+		}
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, 2.0, testutil.ToFloat64(shared.hits))
+		assert.Equal(t, 1.0, testutil.ToFloat64(shared.misses))
+	})
+
+	t.Run("should give each condition its own copy of the result", func(t *testing.T) {
+		shared := newTestSharedQueries()
+		fn := func() (mathexp.Results, error) {
+			n := mathexp.NewNumber("A", data.Labels{"job": "api"})
+			v := 1.0
+			n.SetValue(&v)
+			return mathexp.Results{Values: mathexp.Values{n}}, nil
+		}
+
+		first, err := shared.execute(context.Background(), key, fn)
+		require.NoError(t, err)
+		// modify the result the same way the evaluation of the condition does.
+		first.Values[0].AsDataFrame().SetMeta(&data.FrameMeta{Custom: "modified"})
+		first.Values[0].GetLabels()["job"] = "modified"
+		other := 2.0
+		first.Values[0].AsDataFrame().Fields[0].Set(0, &other)
+
+		second, err := shared.execute(context.Background(), key, fn)
+		require.NoError(t, err)
+		require.Len(t, second.Values, 1)
+		frame := second.Values[0].AsDataFrame()
+		require.NotSame(t, first.Values[0].AsDataFrame(), frame)
+		assert.Nil(t, frame.Meta)
+		assert.Equal(t, data.Labels{"job": "api"}, second.Values[0].GetLabels())
+		num, ok := second.Values[0].(mathexp.Number)
+		require.True(t, ok)
+		assert.Equal(t, 1.0, *num.GetFloat64Value())
+	})
+
+	t.Run("should execute different queries separately", func(t *testing.T) {
+		shared := newTestSharedQueries()
+		calls := 0
+		fn := func() (mathexp.Results, error) {
+			calls++
+			return mathexp.Results{}, nil
+		}
+		other := []func(k sharedQueryKey) sharedQueryKey{
+			func(k sharedQueryKey) sharedQueryKey { k.orgID = 2; return k },
+			func(k sharedQueryKey) sharedQueryKey { k.datasourceUID = "other"; return k },
+			func(k sharedQueryKey) sharedQueryKey { k.model = `{"expr":"down"}`; return k },
+			func(k sharedQueryKey) sharedQueryKey { k.from = time.Hour; return k },
+			func(k sharedQueryKey) sharedQueryKey { k.now = now.Add(time.Second).UnixNano(); return k },
+		}
+
+		_, err := shared.execute(context.Background(), key, fn)
+		require.NoError(t, err)
+		for _, change := range other {
+			_, err := shared.execute(context.Background(), change(key), fn)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, len(other)+1, calls)
+		assert.Equal(t, 0.0, testutil.ToFloat64(shared.hits))
+	})
+
+	t.Run("should not share failed results", func(t *testing.T) {
+		shared := newTestSharedQueries()
+		calls := 0
+		expectedErr := errors.New("test")
+		fn := func() (mathexp.Results, error) {
+			calls++
+			return mathexp.Results{}, expectedErr
+		}
+
+		_, err := shared.execute(context.Background(), key, fn)
+		require.ErrorIs(t, err, expectedErr)
+		_, err = shared.execute(context.Background(), key, fn)
+		require.ErrorIs(t, err, expectedErr)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("should wait for the query that is being executed", func(t *testing.T) {
+		shared := newTestSharedQueries()
+		started := make(chan struct{})
+		release := make(chan struct{})
+		calls := 0
+		fn := func() (mathexp.Results, error) {
+			calls++
+			close(started)
+			<-release
+			return mathexp.Results{Values: mathexp.Values{mathexp.NewNoData()}}, nil
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := shared.execute(context.Background(), key, fn)
+			assert.NoError(t, err)
+		}()
+		<-started
+
+		var res mathexp.Results
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			res, err = shared.execute(context.Background(), key, fn)
+			assert.NoError(t, err)
+		}()
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, 1, calls)
+		assert.Len(t, res.Values, 1)
+	})
+
+	t.Run("should return when context is canceled while waiting", func(t *testing.T) {
+		shared := newTestSharedQueries()
+		started := make(chan struct{})
+		release := make(chan struct{})
+		defer close(release)
+		go func() {
+			_, _ = shared.execute(context.Background(), key, func() (mathexp.Results, error) {
+				close(started)
+				<-release
+				return mathexp.Results{}, nil
+			})
+		}()
+		<-started
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := shared.execute(ctx, key, func() (mathexp.Results, error) {
+			t.Fatal("query should not be executed")
+			return mathexp.Results{}, nil
+		})
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("Expire should remove results of older evaluations", func(t *testing.T) {
+		shared := newTestSharedQueries()
+		fn := func() (mathexp.Results, error) {
+			return mathexp.Results{}, nil
+		}
+		older := key
+		older.now = now.Add(-time.Minute).UnixNano()
+		_, _ = shared.execute(context.Background(), older, fn)
+		_, _ = shared.execute(context.Background(), key, fn)
+
+		shared.Expire(now)
+		require.Len(t, shared.entries, 1)
+		require.Contains(t, shared.entries, key)
+	})
+}
+
+type fakePipelineNode struct {
+	expr.Node
+	refID    string
+	nodeType expr.NodeType
+}
+
+func (n *fakePipelineNode) RefID() string {
+	return n.refID
+}
+
+func (n *fakePipelineNode) NodeType() expr.NodeType {
+	return n.nodeType
+}
+
+func TestWithSharedQueries(t *testing.T) {
+	shared := newTestSharedQueries()
+	ds := &fakePipelineNode{refID: "A", nodeType: expr.TypeDatasourceNode}
+	cmd := &fakePipelineNode{refID: "B", nodeType: expr.TypeCMDNode}
+	unknown := &fakePipelineNode{refID: "C", nodeType: expr.TypeDatasourceNode}
+
+	pipeline := withSharedQueries(expr.DataPipeline{ds, cmd, unknown}, map[string]sharedQueryKey{"A": {refID: "A"}}, shared)
+
+	require.Len(t, pipeline, 3)
+	wrapped, ok := pipeline[0].(*sharedQueryNode)
+	require.True(t, ok)
+	assert.Equal(t, "A", wrapped.RefID())
+	assert.Same(t, shared, wrapped.shared)
+	assert.Same(t, cmd, pipeline[1])
+	assert.Same(t, unknown, pipeline[2])
+}
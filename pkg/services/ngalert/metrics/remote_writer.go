@@ -6,8 +6,14 @@ import (
 )
 
 type RemoteWriter struct {
-	WritesTotal   *prometheus.CounterVec
-	WriteDuration *prometheus.HistogramVec
+	WritesTotal             *prometheus.CounterVec
+	WriteDuration           *prometheus.HistogramVec
+	WALBatches              *prometheus.GaugeVec
+	WALSizeBytes            *prometheus.GaugeVec
+	WALOldestSampleAge      *prometheus.GaugeVec
+	WALDroppedBatchesTotal  *prometheus.CounterVec
+	WALReplayedBatchesTotal *prometheus.CounterVec
+	BatchWrites             prometheus.Histogram
 }
 
 func NewRemoteWriterMetrics(r prometheus.Registerer) *RemoteWriter {
@@ -26,5 +32,42 @@ func NewRemoteWriterMetrics(r prometheus.Registerer) *RemoteWriter {
 				Help:      "Histogram of remote write durations.",
 				Buckets:   prometheus.DefBuckets,
 			}, []string{"org", "backend"}),
+		WALBatches: promauto.With(r).NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "remote_writer_wal_batches",
+			Help:      "The number of batches buffered in the write-ahead log waiting to be replayed.",
+		}, []string{"target"}),
+		WALSizeBytes: promauto.With(r).NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "remote_writer_wal_size_bytes",
+			Help:      "The size on disk of the batches buffered in the write-ahead log.",
+		}, []string{"target"}),
+		WALOldestSampleAge: promauto.With(r).NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "remote_writer_wal_oldest_sample_age_seconds",
+			Help:      "The age of the oldest sample buffered in the write-ahead log, or zero if it is empty.",
+		}, []string{"target"}),
+		WALDroppedBatchesTotal: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "remote_writer_wal_dropped_batches_total",
+			Help:      "The total number of batches dropped from the write-ahead log without being written.",
+		}, []string{"target", "reason"}),
+		WALReplayedBatchesTotal: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "remote_writer_wal_replayed_batches_total",
+			Help:      "The total number of batches replayed from the write-ahead log.",
+		}, []string{"target"}),
+		BatchWrites: promauto.With(r).NewHistogram(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "remote_writer_batch_writes",
+			Help:      "Histogram of the number of rule writes coalesced into one remote write request.",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 7),
+		}),
 	}
 }
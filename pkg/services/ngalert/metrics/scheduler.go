@@ -32,6 +32,19 @@ type Scheduler struct {
 	Ticker                              *ticker.Metrics
 	EvaluationMissed                    *prometheus.CounterVec
 	SimplifiedEditorRules               *prometheus.GaugeVec
+	SharedQueryHits                     prometheus.Counter
+	SharedQueryMisses                   prometheus.Counter
+	DatasourceQueueLength               *prometheus.GaugeVec
+	DatasourceQueueDuration             *prometheus.HistogramVec
+	DatasourceEvaluationsLimited        *prometheus.CounterVec
+	TickLoad                            prometheus.Histogram
+	TickLoadImbalance                   prometheus.Gauge
+	JitterRebalancedRules               prometheus.Counter
+	ShardOwnedRules                     prometheus.Gauge
+	ShardMembers                        prometheus.Gauge
+	ShardHandOffs                       *prometheus.CounterVec
+	CatchUpMissed                       *prometheus.CounterVec
+	CatchUpRecovered                    *prometheus.CounterVec
 }
 
 func NewSchedulerMetrics(r prometheus.Registerer) *Scheduler {
@@ -192,5 +205,117 @@ func NewSchedulerMetrics(r prometheus.Registerer) *Scheduler {
 			},
 			[]string{"org", "setting"},
 		),
+		SharedQueryHits: promauto.With(r).NewCounter(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "rule_shared_query_hits_total",
+				Help:      "The total number of datasource queries that used the result of an identical query of another rule evaluated in the same tick.",
+			},
+		),
+		SharedQueryMisses: promauto.With(r).NewCounter(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "rule_shared_query_misses_total",
+				Help:      "The total number of datasource queries that were executed because the result of an identical query was not available.",
+			},
+		),
+		DatasourceQueueLength: promauto.With(r).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "rule_evaluations_queued",
+				Help:      "The number of rule evaluations waiting for the concurrency limit of a datasource.",
+			},
+			[]string{"datasource_uid"},
+		),
+		DatasourceQueueDuration: promauto.With(r).NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "rule_evaluation_queue_duration_seconds",
+				Help:      "The time rule evaluations waited for the concurrency limit of a datasource.",
+				Buckets:   []float64{.01, .1, .5, 1, 5, 10, 15, 30, 60, 120},
+			},
+			[]string{"datasource_uid"},
+		),
+		DatasourceEvaluationsLimited: promauto.With(r).NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "rule_evaluations_limited_total",
+				Help:      "The total number of rule evaluations that were skipped or timed out because of the limits of a datasource.",
+			},
+			[]string{"datasource_uid", "reason"},
+		),
+		TickLoad: promauto.With(r).NewHistogram(
+			prometheus.HistogramOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "schedule_tick_load_seconds",
+				Help:      "The sum of the last evaluation durations of the rules scheduled in a tick. Observed only with load-aware jitter.",
+				Buckets:   []float64{.1, .5, 1, 5, 10, 30, 60, 120, 300, 600, 1800},
+			},
+		),
+		TickLoadImbalance: promauto.With(r).NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "schedule_tick_load_imbalance_ratio",
+				Help:      "The ratio between the highest and the average planned load of a tick after the last rebalance of load-aware jitter.",
+			},
+		),
+		JitterRebalancedRules: promauto.With(r).NewCounter(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "schedule_jitter_rebalanced_rules_total",
+				Help:      "The total number of rules that were moved to another tick by load-aware jitter.",
+			},
+		),
+		ShardOwnedRules: promauto.With(r).NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "schedule_owned_alert_rules",
+				Help:      "The number of alert rules evaluated by this instance when evaluation is sharded across the cluster.",
+			},
+		),
+		ShardMembers: promauto.With(r).NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "schedule_shard_members",
+				Help:      "The number of cluster members the evaluation of alert rules is sharded across.",
+			},
+		),
+		ShardHandOffs: promauto.With(r).NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "schedule_rule_handoffs_total",
+				Help:      "The total number of alert rules whose evaluation moved from or to this instance.",
+			},
+			[]string{"direction"},
+		),
+		CatchUpMissed: promauto.With(r).NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "schedule_catch_up_missed_evaluations_total",
+				Help:      "The total number of evaluations of alert rules that did not happen at their scheduled time, detected by the catch-up.",
+			},
+			[]string{"org"},
+		),
+		CatchUpRecovered: promauto.With(r).NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "schedule_catch_up_recovered_evaluations_total",
+				Help:      "The total number of missed evaluations of alert rules that were performed later at their original timestamp.",
+			},
+			[]string{"org"},
+		),
 	}
 }
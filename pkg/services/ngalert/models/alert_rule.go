@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"hash/fnv"
 	"maps"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -170,6 +171,10 @@ const (
 	StateReasonUpdated       = "Updated"
 	StateReasonRuleDeleted   = "RuleDeleted"
 	StateReasonKeepLast      = "KeepLast"
+	StateReasonKeepFiring    = "KeepFiring"
+	StateReasonThrottled     = "Throttled"
+	StateReasonQueryTimeout  = "QueryTimeout"
+	StateReasonPausedUntil   = "PausedUntil"
 )
 
 func ConcatReasons(reasons ...string) string {
@@ -288,7 +293,9 @@ type AlertRule struct {
 	ExecErrState    ExecutionErrorState
 	// ideally this field should have been apimodels.ApiDuration
 	// but this is currently not possible because of circular dependencies
-	For                  time.Duration
+	For time.Duration
+	// KeepFiringFor is how long an alert keeps firing after the condition stops being met.
+	KeepFiringFor        time.Duration
 	Annotations          map[string]string
 	Labels               map[string]string
 	IsPaused             bool
@@ -299,6 +306,8 @@ type AlertRule struct {
 type AlertRuleMetadata struct {
 	EditorSettings      EditorSettings       `json:"editor_settings"`
 	PrometheusStyleRule *PrometheusStyleRule `json:"prometheus_style_rule,omitempty"`
+	// PausedUntil is the time when a paused rule is resumed automatically. It is set only if IsPaused is true.
+	PausedUntil *time.Time `json:"paused_until,omitempty"`
 }
 
 type EditorSettings struct {
@@ -384,6 +393,16 @@ func (alertRule *AlertRule) GetPanelID() int64 {
 	return -1
 }
 
+// GetDatasourceUID returns the UID of the datasource of the first query that is not an expression, or "".
+func (alertRule *AlertRule) GetDatasourceUID() string {
+	for _, q := range alertRule.Data {
+		if isExpr, _ := q.IsExpression(); !isExpr {
+			return q.DatasourceUID
+		}
+	}
+	return ""
+}
+
 type LabelOption func(map[string]string)
 
 func WithoutInternalLabels() LabelOption {
@@ -632,6 +651,10 @@ func (alertRule *AlertRule) ValidateAlertRule(cfg setting.UnifiedAlertingSetting
 		return fmt.Errorf("%w: field `for` cannot be negative", ErrAlertRuleFailedValidation)
 	}
 
+	if alertRule.KeepFiringFor < 0 {
+		return fmt.Errorf("%w: field `keep_firing_for` cannot be negative", ErrAlertRuleFailedValidation)
+	}
+
 	if len(alertRule.Labels) > 0 {
 		for label := range alertRule.Labels {
 			if _, ok := LabelsUserCannotSpecify[label]; ok {
@@ -671,6 +694,9 @@ func validateRecordingRuleFields(rule *AlertRule) error {
 	if !prommodels.IsValidMetricName(metricName) {
 		return fmt.Errorf("%w: %s", ErrAlertRuleFailedValidation, "metric name for recording rule must be a valid Prometheus metric name")
 	}
+	if err := rule.Record.Metadata.Validate(); err != nil {
+		return fmt.Errorf("%w: %s", ErrAlertRuleFailedValidation, err.Error())
+	}
 
 	ClearRecordingRuleIgnoredFields(rule)
 
@@ -703,6 +729,11 @@ func (alertRule *AlertRule) Type() RuleType {
 	return RuleTypeAlerting
 }
 
+// PauseExpired returns true if the rule is paused until a time that is not after now.
+func (alertRule *AlertRule) PauseExpired(now time.Time) bool {
+	return alertRule.IsPaused && alertRule.Metadata.PausedUntil != nil && !now.Before(*alertRule.Metadata.PausedUntil)
+}
+
 // Copy creates and returns a deep copy of the AlertRule instance, duplicating all fields and nested data structures.
 func (alertRule *AlertRule) Copy() *AlertRule {
 	if alertRule == nil {
@@ -725,6 +756,7 @@ func (alertRule *AlertRule) Copy() *AlertRule {
 		NoDataState:     alertRule.NoDataState,
 		ExecErrState:    alertRule.ExecErrState,
 		For:             alertRule.For,
+		KeepFiringFor:   alertRule.KeepFiringFor,
 		Record:          alertRule.Record,
 		IsPaused:        alertRule.IsPaused,
 		Metadata:        alertRule.Metadata,
@@ -767,8 +799,10 @@ func (alertRule *AlertRule) Copy() *AlertRule {
 
 	if alertRule.Record != nil {
 		result.Record = &Record{
-			From:   alertRule.Record.From,
-			Metric: alertRule.Record.Metric,
+			From:                alertRule.Record.From,
+			Metric:              alertRule.Record.Metric,
+			TargetDatasourceUID: alertRule.Record.TargetDatasourceUID,
+			Metadata:            alertRule.Record.Metadata,
 		}
 	}
 
@@ -777,6 +811,11 @@ func (alertRule *AlertRule) Copy() *AlertRule {
 		result.Metadata.PrometheusStyleRule = &prometheusStyleRule
 	}
 
+	if alertRule.Metadata.PausedUntil != nil {
+		pausedUntil := *alertRule.Metadata.PausedUntil
+		result.Metadata.PausedUntil = &pausedUntil
+	}
+
 	for _, s := range alertRule.NotificationSettings {
 		result.NotificationSettings = append(result.NotificationSettings, CopyNotificationSettings(s))
 	}
@@ -789,6 +828,7 @@ func ClearRecordingRuleIgnoredFields(rule *AlertRule) {
 	rule.ExecErrState = ""
 	rule.Condition = ""
 	rule.For = 0
+	rule.KeepFiringFor = 0
 	rule.NotificationSettings = nil
 }
 
@@ -906,7 +946,7 @@ func (c Condition) IsValid() bool {
 // PatchPartialAlertRule patches `ruleToPatch` by `existingRule` following the rule that if a field of `ruleToPatch` is empty or has the default value, it is populated by the value of the corresponding field from `existingRule`.
 // There are several exceptions:
 //  1. Following fields are not patched and therefore will be ignored: AlertRule.ID, AlertRule.OrgID, AlertRule.Updated, AlertRule.Version,
-//     AlertRule.UID, AlertRule.DashboardUID, AlertRule.PanelID, AlertRule.Annotations, AlertRule.Labels, AlertRule.Metadata (except for EditorSettings)
+//     AlertRule.UID, AlertRule.DashboardUID, AlertRule.PanelID, AlertRule.Annotations, AlertRule.Labels, AlertRule.Metadata (except for EditorSettings and PausedUntil)
 //  2. There are fields that are patched together:
 //     - AlertRule.Condition and AlertRule.Data
 //
@@ -937,12 +977,19 @@ func PatchPartialAlertRule(existingRule *AlertRule, ruleToPatch *AlertRuleWithOp
 	if ruleToPatch.For == -1 {
 		ruleToPatch.For = existingRule.For
 	}
+	if ruleToPatch.KeepFiringFor == -1 {
+		ruleToPatch.KeepFiringFor = existingRule.KeepFiringFor
+	}
 	if !ruleToPatch.HasPause {
 		ruleToPatch.IsPaused = existingRule.IsPaused
 	}
 	if !ruleToPatch.HasEditorSettings {
 		ruleToPatch.Metadata.EditorSettings = existingRule.Metadata.EditorSettings
 	}
+	if ruleToPatch.IsPaused && existingRule.IsPaused {
+		// the rule stays paused, so it is still resumed at the time it was paused until
+		ruleToPatch.Metadata.PausedUntil = existingRule.Metadata.PausedUntil
+	}
 
 	if ruleToPatch.GUID == "" {
 		ruleToPatch.GUID = existingRule.GUID
@@ -1010,7 +1057,35 @@ type Record struct {
 	// Metric indicates a metric name to send results to.
 	Metric string
 	// From contains a query RefID, indicating which expression node is the output of the recording rule.
+	// Heatmap cells are recorded as native histograms whose sum of observations is NaN, because it is not known.
 	From string
+	// TargetDatasourceUID is the UID of a Prometheus-compatible data source to write the results to.
+	// If empty, the results are written to the remote write endpoint configured for recording rules.
+	TargetDatasourceUID string
+	// Metadata describes the recorded metric. It is written along with the series.
+	Metadata RecordMetadata
+}
+
+// RecordMetricTypes are the types a recorded metric can have, as defined by OpenMetrics.
+var RecordMetricTypes = []string{"counter", "gauge", "histogram", "gaugehistogram", "summary", "info", "stateset", "unknown"}
+
+// RecordMetadata is the metadata of a recorded metric.
+type RecordMetadata struct {
+	// Type is one of RecordMetricTypes. If empty, the type is unknown.
+	Type string
+	Unit string
+	Help string
+}
+
+func (m RecordMetadata) IsEmpty() bool {
+	return m == RecordMetadata{}
+}
+
+func (m RecordMetadata) Validate() error {
+	if m.Type != "" && !slices.Contains(RecordMetricTypes, m.Type) {
+		return fmt.Errorf("metric type for recording rule must be one of %s", strings.Join(RecordMetricTypes, ", "))
+	}
+	return nil
 }
 
 func (r *Record) Fingerprint() data.Fingerprint {
@@ -1025,6 +1100,15 @@ func (r *Record) Fingerprint() data.Fingerprint {
 
 	writeString(r.Metric)
 	writeString(r.From)
+	// keep fingerprints of rules without a target unchanged
+	if r.TargetDatasourceUID != "" {
+		writeString(r.TargetDatasourceUID)
+	}
+	if !r.Metadata.IsEmpty() {
+		writeString(r.Metadata.Type)
+		writeString(r.Metadata.Unit)
+		writeString(r.Metadata.Help)
+	}
 	return data.Fingerprint(h.Sum64())
 }
 
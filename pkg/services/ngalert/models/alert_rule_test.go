@@ -253,6 +253,12 @@ func TestPatchPartialAlertRule(t *testing.T) {
 					r.For = -1
 				},
 			},
+			{
+				name: "KeepFiringFor is -1",
+				mutator: func(r *AlertRuleWithOptionals) {
+					r.KeepFiringFor = -1
+				},
+			},
 			{
 				name: "IsPaused did not come in request",
 				mutator: func(r *AlertRuleWithOptionals) {
@@ -270,6 +276,7 @@ func TestPatchPartialAlertRule(t *testing.T) {
 
 		gen := RuleGen.With(
 			RuleMuts.WithFor(time.Duration(rand.Int63n(1000)+1)),
+			RuleMuts.WithKeepFiringFor(time.Duration(rand.Int63n(1000)+1)),
 			RuleMuts.WithEditorSettingsSimplifiedQueryAndExpressionsSection(true),
 		)
 
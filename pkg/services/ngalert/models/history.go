@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/grafana/grafana/pkg/apimachinery/identity"
@@ -17,4 +18,72 @@ type HistoryQuery struct {
 	To           time.Time
 	Limit        int
 	SignedInUser identity.Requester
+	// PreviousState filters transitions by the state the alert instance transitioned from, e.g. "Normal".
+	PreviousState string
+	// CurrentState filters transitions by the state the alert instance transitioned to, e.g. "Alerting".
+	CurrentState string
+	// Reason filters transitions by the reason of the state the alert instance transitioned to, e.g. "NoData".
+	Reason string
+	// ContinueToken is the token returned with the previous page of results. It is empty for the first page.
+	ContinueToken string
+}
+
+// HistoryQueryResultMeta is the custom metadata of the frame returned by a state history query.
+type HistoryQueryResultMeta struct {
+	// ContinueToken is the token to request the next page of results. It is empty if there are no more results.
+	ContinueToken string `json:"continueToken,omitempty"`
+}
+
+// HistoryAggregationType is the type of aggregation of state history.
+type HistoryAggregationType string
+
+const (
+	// HistoryAggregationTransitions counts state transitions per rule in time buckets.
+	HistoryAggregationTransitions HistoryAggregationType = "transitions"
+	// HistoryAggregationFlapping returns rules whose alert instances changed between firing and not firing most often.
+	HistoryAggregationFlapping HistoryAggregationType = "flapping"
+	// HistoryAggregationTimeInState sums the time alert instances of every rule spent in every state.
+	HistoryAggregationTimeInState HistoryAggregationType = "time_in_state"
+)
+
+func (t HistoryAggregationType) IsValid() bool {
+	switch t {
+	case HistoryAggregationTransitions, HistoryAggregationFlapping, HistoryAggregationTimeInState:
+		return true
+	}
+	return false
+}
+
+// HistoryAggregationQuery represents a query for aggregated alert state history.
+// Pagination fields of the embedded HistoryQuery are ignored.
+type HistoryAggregationQuery struct {
+	HistoryQuery
+	Type HistoryAggregationType
+	// Interval is the width of time buckets of the transitions aggregation.
+	Interval time.Duration
+	// TopN is the maximum number of rules returned by the flapping aggregation.
+	TopN int
+}
+
+// StateHistoryEntry is a single state transition of an alert instance that is stored in the database.
+type StateHistoryEntry struct {
+	ID             int64
+	OrgID          int64
+	RuleUID        string
+	RuleID         int64
+	RuleTitle      string
+	RuleGroup      string
+	NamespaceUID   string
+	DashboardUID   string
+	PanelID        int64
+	Condition      string
+	Fingerprint    string
+	Labels         map[string]string
+	Values         json.RawMessage
+	PreviousState  string
+	PreviousReason string
+	CurrentState   string
+	CurrentReason  string
+	Error          string
+	Timestamp      time.Time
 }
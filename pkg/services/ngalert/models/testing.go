@@ -73,6 +73,7 @@ func (g *AlertRuleGenerator) Generate() AlertRule {
 
 	interval := (rand.Int63n(6) + 1) * 10
 	forInterval := time.Duration(interval*rand.Int63n(6)) * time.Second
+	keepFiringFor := time.Duration(interval*rand.Int63n(6)) * time.Second
 
 	var annotations map[string]string = nil
 	if rand.Int63()%2 == 0 {
@@ -122,6 +123,7 @@ func (g *AlertRuleGenerator) Generate() AlertRule {
 		NoDataState:          randNoDataState(),
 		ExecErrState:         randErrState(),
 		For:                  forInterval,
+		KeepFiringFor:        keepFiringFor,
 		Annotations:          annotations,
 		Labels:               labels,
 		NotificationSettings: ns,
@@ -337,6 +339,12 @@ func (a *AlertRuleMutators) WithFor(duration time.Duration) AlertRuleMutator {
 	}
 }
 
+func (a *AlertRuleMutators) WithKeepFiringFor(duration time.Duration) AlertRuleMutator {
+	return func(rule *AlertRule) {
+		rule.KeepFiringFor = duration
+	}
+}
+
 func (a *AlertRuleMutators) WithForNTimes(timesOfInterval int64) AlertRuleMutator {
 	return func(rule *AlertRule) {
 		rule.For = time.Duration(rule.IntervalSeconds*timesOfInterval) * time.Second
@@ -555,6 +563,15 @@ func (a *AlertRuleMutators) WithRecordFrom(from string) AlertRuleMutator {
 	}
 }
 
+func (a *AlertRuleMutators) WithRecordTarget(datasourceUID string) AlertRuleMutator {
+	return func(rule *AlertRule) {
+		if rule.Record == nil {
+			rule.Record = &Record{}
+		}
+		rule.Record.TargetDatasourceUID = datasourceUID
+	}
+}
+
 func (a *AlertRuleMutators) WithUpdatedBy(uid *UserUID) AlertRuleMutator {
 	return func(r *AlertRule) {
 		r.UpdatedBy = uid
@@ -1342,6 +1359,7 @@ func ConvertToRecordingRule(rule *AlertRule) {
 	rule.NoDataState = ""
 	rule.ExecErrState = ""
 	rule.For = 0
+	rule.KeepFiringFor = 0
 	rule.NotificationSettings = nil
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"time"
 
 	"github.com/benbjohnson/clock"
@@ -30,6 +31,7 @@ import (
 	ac "github.com/grafana/grafana/pkg/services/ngalert/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/ngalert/api"
 	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/backtesting"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	"github.com/grafana/grafana/pkg/services/ngalert/image"
 	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
@@ -141,6 +143,7 @@ type AlertNG struct {
 	renderService       rendering.Service
 	ImageService        image.ImageService
 	RecordingWriter     schedule.RecordingWriter
+	backfiller          *backtesting.Backfiller
 	schedule            schedule.ScheduleService
 	stateManager        *state.Manager
 	folderService       folder.Service
@@ -374,7 +377,7 @@ func (ng *AlertNG) init() error {
 		// Force-disable the feature if the feature toggle is not on - sets us up for feature toggle removal.
 		ng.Cfg.UnifiedAlerting.RecordingRules.Enabled = false
 	}
-	recordingWriter, err := createRecordingWriter(ng.FeatureToggles, ng.Cfg.UnifiedAlerting.RecordingRules, ng.httpClientProvider, clk, ng.Metrics.GetRemoteWriterMetrics())
+	recordingWriter, err := createRecordingWriter(ng.FeatureToggles, ng.Cfg.UnifiedAlerting.RecordingRules, ng.Cfg.DataPath, ng.httpClientProvider, ng.DataSourceService, clk, ng.Metrics.GetRemoteWriterMetrics())
 	if err != nil {
 		return fmt.Errorf("failed to initialize recording writer: %w", err)
 	}
@@ -397,11 +400,40 @@ func (ng *AlertNG) init() error {
 		Log:                  log.New("ngalert.scheduler"),
 		RecordingWriter:      ng.RecordingWriter,
 	}
+	// Rules of a group can use results of recording rules that precede them in the group.
+	schedCfg.SequentialGroupEvaluation = ng.FeatureToggles.IsEnabledGlobally(featuremgmt.FlagAlertingSequentialGroupEvaluation)
+	schedCfg.DatasourceLimits = schedule.DatasourceLimitsFrom(ng.Cfg.UnifiedAlerting)
+	schedCfg.CatchUpMaxLookback = ng.Cfg.UnifiedAlerting.CatchUpMaxLookback
+	schedCfg.RuleResumer = ng.store
+	// In high availability mode, every rule is evaluated by only one instance of the cluster.
+	if ng.FeatureToggles.IsEnabledGlobally(featuremgmt.FlagAlertingRuleEvaluationSharding) {
+		if membership := ng.MultiOrgAlertmanager.ClusterMembership(); membership != nil {
+			schedCfg.ClusterMembership = membership
+		} else {
+			ng.Log.Warn("Sharding of rule evaluation is enabled but high availability is not configured, all rules are evaluated by this instance")
+		}
+	}
+	// Identical queries of rules evaluated in the same tick are executed once. Datasource nodes cannot be replaced
+	// when queries are grouped by datasource, so the two features are mutually exclusive.
+	if ng.FeatureToggles.IsEnabledGlobally(featuremgmt.FlagAlertingSharedQueries) {
+		if ng.FeatureToggles.IsEnabledGlobally(featuremgmt.FlagSseGroupByDatasource) {
+			ng.Log.Warn("Sharing of rule queries is not supported when queries are grouped by datasource, it will be disabled")
+		} else {
+			schedulerMetrics := ng.Metrics.GetSchedulerMetrics()
+			schedCfg.SharedQueries = eval.NewSharedQueries(schedulerMetrics.SharedQueryHits, schedulerMetrics.SharedQueryMisses)
+		}
+	}
 
 	// There are a set of feature toggles available that act as short-circuits for common configurations.
 	// If any are set, override the config accordingly.
 	ApplyStateHistoryFeatureToggles(&ng.Cfg.UnifiedAlerting.StateHistory, ng.FeatureToggles, ng.Log)
-	history, err := configureHistorianBackend(initCtx, ng.Cfg.UnifiedAlerting.StateHistory, ng.annotationsRepo, ng.dashboardService, ng.store, ng.Metrics.GetHistorianMetrics(), ng.Log, ng.tracer, ac.NewRuleService(ng.accesscontrol))
+	// The Prometheus state history backend writes series using the remote write of recording rules, if it is enabled.
+	var pointsWriter historian.PointsWriter
+	if w, ok := ng.RecordingWriter.(*writer.DatasourceWriter); ok && w.DefaultWriter() != nil {
+		pointsWriter = w.DefaultWriter()
+	}
+	stateHistorySection := ng.Cfg.SectionWithEnvOverrides("unified_alerting.state_history")
+	history, err := configureHistorianBackend(initCtx, ng.Cfg.UnifiedAlerting.StateHistory, stateHistorySection, ng.annotationsRepo, ng.dashboardService, ng.store, ng.store, pointsWriter, ng.Metrics.GetHistorianMetrics(), ng.Log, ng.tracer, ac.NewRuleService(ng.accesscontrol))
 	if err != nil {
 		return err
 	}
@@ -416,6 +448,7 @@ func (ng *AlertNG) init() error {
 		Images:                         ng.ImageService,
 		Clock:                          clk,
 		Historian:                      history,
+		HistoryReader:                  configureHistoryReader(ng.Cfg.UnifiedAlerting.StateHistory, stateHistorySection, history, schedule.NewDatasourceQuerier(evalFactory)),
 		ApplyNoDataAndErrorToAllStates: ng.FeatureToggles.IsEnabledGlobally(featuremgmt.FlagAlertingNoDataErrorExecution),
 		MaxStateSaveConcurrency:        ng.Cfg.UnifiedAlerting.MaxStateSaveConcurrency,
 		StatePeriodicSaveBatchSize:     ng.Cfg.UnifiedAlerting.StatePeriodicSaveBatchSize,
@@ -424,9 +457,19 @@ func (ng *AlertNG) init() error {
 		Log:                            log.New("ngalert.state.manager"),
 		ResolvedRetention:              ng.Cfg.UnifiedAlerting.ResolvedAlertRetention,
 	}
+	lookupTableService := provisioning.NewLookupTableService(ng.KVStore, clk, ng.Log)
+	stateManagerCfg.LookupTables = lookupTableService
+	// Templates of annotations and labels can execute queries with the query function.
+	if ng.FeatureToggles.IsEnabledGlobally(featuremgmt.FlagAlertingQueryInTemplates) {
+		stateManagerCfg.TemplateQuerier = schedule.NewTemplateQuerierProvider(evalFactory)
+		stateManagerCfg.TemplateQueryTimeout = ng.Cfg.UnifiedAlerting.EvaluationTimeout
+	}
 	statePersister := initStatePersister(ng.Cfg.UnifiedAlerting, stateManagerCfg, ng.FeatureToggles)
 	stateManager := state.NewManager(stateManagerCfg, statePersister)
 	scheduler := schedule.NewScheduler(schedCfg, stateManager)
+	if backfillWriter, ok := recordingWriter.(backtesting.BackfillWriter); ok && ng.Cfg.UnifiedAlerting.RecordingRules.Enabled {
+		ng.backfiller = backtesting.NewBackfiller(scheduler.LimitEvaluations(evalFactory), backfillWriter, ng.store, ng.KVStore, clk, log.New("ngalert.backfiller"))
+	}
 
 	// if it is required to include folder title to the alerts, we need to subscribe to changes of alert title
 	if !ng.Cfg.UnifiedAlerting.ReservedLabels.IsReservedLabelDisabled(models.FolderTitleLabel) {
@@ -469,7 +512,13 @@ func (ng *AlertNG) init() error {
 		int64(ng.Cfg.UnifiedAlerting.DefaultRuleEvaluationInterval.Seconds()),
 		int64(ng.Cfg.UnifiedAlerting.BaseInterval.Seconds()),
 		ng.Cfg.UnifiedAlerting.RulesPerRuleGroupLimit, ng.Log, notifier.NewNotificationSettingsValidationService(ng.store),
-		ac.NewRuleService(ng.accesscontrol))
+		ac.NewRuleService(ng.accesscontrol), ng.DataSourceCache)
+
+	// A nil backfiller must not be assigned to the interface, the API checks it to tell whether backfills are available.
+	var backfiller api.RuleBackfiller
+	if ng.backfiller != nil {
+		backfiller = ng.backfiller
+	}
 
 	ng.Api = &api.API{
 		Cfg:                  ng.Cfg,
@@ -486,11 +535,13 @@ func (ng *AlertNG) init() error {
 		MultiOrgAlertmanager: ng.MultiOrgAlertmanager,
 		StateManager:         ng.stateManager,
 		Scheduler:            scheduler,
+		Backfiller:           backfiller,
 		AccessControl:        ng.accesscontrol,
 		Policies:             policyService,
 		ReceiverService:      receiverService,
 		ContactPointService:  contactPointService,
 		Templates:            templateService,
+		LookupTables:         lookupTableService,
 		MuteTimings:          muteTimingService,
 		AlertRules:           alertRuleService,
 		AlertsRouter:         alertsRouter,
@@ -603,6 +654,16 @@ func (ng *AlertNG) Run(ctx context.Context) error {
 	children.Go(func() error {
 		return ng.AlertsRouter.Run(subCtx)
 	})
+	if w, ok := ng.RecordingWriter.(*writer.DatasourceWriter); ok {
+		children.Go(func() error {
+			return w.Run(subCtx)
+		})
+	}
+	if ng.backfiller != nil {
+		children.Go(func() error {
+			return ng.backfiller.Run(subCtx)
+		})
+	}
 
 	if ng.Cfg.UnifiedAlerting.ExecuteAlerts {
 		// Only Warm() the state manager if we are actually executing alerts.
@@ -646,7 +707,9 @@ type Historian interface {
 	state.Historian
 }
 
-func configureHistorianBackend(ctx context.Context, cfg setting.UnifiedAlertingStateHistorySettings, ar annotations.Repository, ds dashboards.DashboardService, rs historian.RuleStore, met *metrics.Historian, l log.Logger, tracer tracing.Tracer, ac historian.AccessControl) (Historian, error) {
+// configureHistorianBackend creates the state history backend. Options of backends that are not part of the state
+// history settings are read from section.
+func configureHistorianBackend(ctx context.Context, cfg setting.UnifiedAlertingStateHistorySettings, section *setting.DynamicSection, ar annotations.Repository, ds dashboards.DashboardService, rs historian.RuleStore, hs historian.StateHistoryStore, pw historian.PointsWriter, met *metrics.Historian, l log.Logger, tracer tracing.Tracer, ac historian.AccessControl) (Historian, error) {
 	if !cfg.Enabled {
 		met.Info.WithLabelValues("noop").Set(0)
 		return historian.NewNopHistorian(), nil
@@ -661,7 +724,7 @@ func configureHistorianBackend(ctx context.Context, cfg setting.UnifiedAlertingS
 	if backend == historian.BackendTypeMultiple {
 		primaryCfg := cfg
 		primaryCfg.Backend = cfg.MultiPrimary
-		primary, err := configureHistorianBackend(ctx, primaryCfg, ar, ds, rs, met, l, tracer, ac)
+		primary, err := configureHistorianBackend(ctx, primaryCfg, section, ar, ds, rs, hs, pw, met, l, tracer, ac)
 		if err != nil {
 			return nil, fmt.Errorf("multi-backend target \"%s\" was misconfigured: %w", cfg.MultiPrimary, err)
 		}
@@ -670,7 +733,7 @@ func configureHistorianBackend(ctx context.Context, cfg setting.UnifiedAlertingS
 		for _, b := range cfg.MultiSecondaries {
 			secCfg := cfg
 			secCfg.Backend = b
-			sec, err := configureHistorianBackend(ctx, secCfg, ar, ds, rs, met, l, tracer, ac)
+			sec, err := configureHistorianBackend(ctx, secCfg, section, ar, ds, rs, hs, pw, met, l, tracer, ac)
 			if err != nil {
 				return nil, fmt.Errorf("multi-backend target \"%s\" was miconfigured: %w", b, err)
 			}
@@ -701,10 +764,57 @@ func configureHistorianBackend(ctx context.Context, cfg setting.UnifiedAlertingS
 		}
 		return backend, nil
 	}
+	if backend == historian.BackendTypeSQL {
+		scfg, err := historian.NewSQLConfig(section)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SQL state history configuration: %w", err)
+		}
+		sqlBackendLogger := log.New("ngalert.state.historian", "backend", "sql")
+		return historian.NewSQLBackend(sqlBackendLogger, scfg, hs, rs, met, ac), nil
+	}
+	if backend == historian.BackendTypePrometheus {
+		if pw == nil {
+			return nil, fmt.Errorf("prometheus state history backend requires remote write of recording rules to be enabled")
+		}
+		prometheusBackendLogger := log.New("ngalert.state.historian", "backend", "prometheus")
+		return historian.NewPrometheusBackend(prometheusBackendLogger, pw, met), nil
+	}
 
 	return nil, fmt.Errorf("unrecognized state history backend: %s", backend)
 }
 
+// configureHistoryReader returns a reader that restores alert instances from the state history on startup,
+// if the backend that serves state history queries supports it. Otherwise, it returns nil.
+// The Prometheus backend only writes series, so they are read with the querier from the data source set in the
+// prometheus_datasource_uid option of section, which should be the one the remote write endpoint writes to.
+// History is read with the identity of the scheduler.
+func configureHistoryReader(cfg setting.UnifiedAlertingStateHistorySettings, section *setting.DynamicSection, history Historian, querier historian.PrometheusQuerier) state.InstanceReader {
+	if !cfg.Enabled {
+		return nil
+	}
+	backend, err := historian.ParseBackendType(cfg.Backend)
+	if err != nil {
+		return nil
+	}
+	if backend == historian.BackendTypeMultiple {
+		backend, err = historian.ParseBackendType(cfg.MultiPrimary)
+		if err != nil {
+			return nil
+		}
+	}
+	if backend == historian.BackendTypePrometheus {
+		datasourceUID := section.Key("prometheus_datasource_uid").MustString("")
+		if datasourceUID == "" {
+			return nil
+		}
+		return historian.NewPrometheusInstanceReader(querier, datasourceUID, log.New("ngalert.state.historian.reader"))
+	}
+	if backend != historian.BackendTypeLoki && backend != historian.BackendTypeSQL {
+		return nil
+	}
+	return historian.NewInstanceReader(history, historian.DefaultInstanceReaderLookback, schedule.SchedulerUserFor, log.New("ngalert.state.historian.reader"))
+}
+
 // ApplyStateHistoryFeatureToggles edits state history configuration to comply with currently active feature toggles.
 func ApplyStateHistoryFeatureToggles(cfg *setting.UnifiedAlertingStateHistorySettings, ft featuremgmt.FeatureToggles, logger log.Logger) {
 	backend, _ := historian.ParseBackendType(cfg.Backend)
@@ -754,12 +864,39 @@ func createRemoteAlertmanager(cfg remote.AlertmanagerConfig, kvstore kvstore.KVS
 	return remote.NewAlertmanager(cfg, notifier.NewFileStore(cfg.OrgID, kvstore), decryptFn, autogenFn, m, tracer)
 }
 
-func createRecordingWriter(featureToggles featuremgmt.FeatureToggles, settings setting.RecordingRuleSettings, httpClientProvider httpclient.Provider, clock clock.Clock, m *metrics.RemoteWriter) (schedule.RecordingWriter, error) {
+func createRecordingWriter(featureToggles featuremgmt.FeatureToggles, settings setting.RecordingRuleSettings, dataPath string, httpClientProvider httpclient.Provider, datasourceService datasources.DataSourceService, clock clock.Clock, m *metrics.RemoteWriter) (schedule.RecordingWriter, error) {
 	logger := log.New("ngalert.writer")
 
+	// Recording rules without a target data source are written to the remote write endpoint, if it is enabled.
+	var defaultWriter *writer.PrometheusWriter
 	if settings.Enabled {
-		return writer.NewPrometheusWriter(settings, httpClientProvider, clock, logger, m)
+		var err error
+		defaultWriter, err = writer.NewPrometheusWriter(settings, httpClientProvider, clock, logger, m)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return writer.NoopWriter{}, nil
+	w := writer.NewDatasourceWriter(settings, defaultWriter, datasourceService, httpClientProvider, clock, logger, m)
+	// Points that fail to be written are buffered on disk and replayed, so that short outages of the target do not
+	// leave gaps in recorded series.
+	if settings.WALEnabled {
+		err := w.EnableWAL(writer.WALConfig{
+			Dir:     filepath.Join(dataPath, "alerting", "recording-rules-wal"),
+			MaxAge:  settings.WALMaxAge,
+			MaxSize: settings.WALMaxSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open write-ahead log: %w", err)
+		}
+	}
+	// With many recording rules, writing the series of every evaluation in its own request results in lots of tiny
+	// requests, so series of rules evaluated at about the same time are written together.
+	if settings.BatchingEnabled {
+		w.EnableBatching(writer.BatchConfig{
+			FlushInterval: settings.BatchFlushInterval,
+			MaxSeries:     settings.BatchMaxSeries,
+		})
+	}
+	return w, nil
 }
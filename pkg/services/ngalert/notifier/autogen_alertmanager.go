@@ -200,6 +200,49 @@ func RemoveAutogenConfigIfExists(route *definitions.Route) {
 	})
 }
 
+// AddNotificationSettingsToRoute adds autogenerated routes for the given notification settings to the route unless
+// they already exist. It is used to route alerts of rules that are not saved yet, and therefore whose notification
+// settings are not part of the autogenerated configuration.
+func AddNotificationSettingsToRoute(route *definitions.Route, settings ...models.NotificationSettings) error {
+	if route == nil {
+		return errors.New("route does not exist")
+	}
+	if len(settings) == 0 {
+		return nil
+	}
+	bySettings := make(map[data.Fingerprint]models.NotificationSettings, len(settings))
+	for _, s := range settings {
+		bySettings[s.Fingerprint()] = s
+	}
+	generated, err := generateRouteFromSettings(route.Receiver, bySettings)
+	if err != nil {
+		return fmt.Errorf("failed to create autogenerated route: %w", err)
+	}
+	for _, r := range route.Routes {
+		if isAutogeneratedRoot(r) {
+			mergeRoutes(r, generated.Route)
+			return nil
+		}
+	}
+	return generated.addToRoute(route)
+}
+
+// mergeRoutes adds the child routes of src to dst recursively. Routes are considered the same if they have the same matchers.
+func mergeRoutes(dst, src *definitions.Route) {
+	for _, child := range src.Routes {
+		idx := slices.IndexFunc(dst.Routes, func(r *definitions.Route) bool {
+			return slices.EqualFunc(r.ObjectMatchers, child.ObjectMatchers, func(a, b *labels.Matcher) bool {
+				return a.String() == b.String()
+			})
+		})
+		if idx < 0 {
+			dst.Routes = append(dst.Routes, child)
+			continue
+		}
+		mergeRoutes(dst.Routes[idx], child)
+	}
+}
+
 // isAutogeneratedRoot returns true if the route is the root of an autogenerated route.
 func isAutogeneratedRoot(route *definitions.Route) bool {
 	return len(route.ObjectMatchers) == 1 && route.ObjectMatchers[0].Name == models.AutogeneratedRouteLabel
@@ -406,6 +406,44 @@ func (moa *MultiOrgAlertmanager) StopAndWait() {
 	}
 }
 
+// ClusterMembership provides the members of the high availability cluster of Grafana instances.
+type ClusterMembership interface {
+	// Members returns the names of the active members of the cluster.
+	Members() []string
+	// Self returns the name of the current instance.
+	Self() string
+}
+
+// ClusterMembership returns the membership of the high availability cluster, or nil if high availability is not configured.
+func (moa *MultiOrgAlertmanager) ClusterMembership() ClusterMembership {
+	switch p := moa.peer.(type) {
+	case *redisPeer:
+		return p
+	case *alertingCluster.Peer:
+		return memberlistMembership{peer: p}
+	default:
+		return nil
+	}
+}
+
+// memberlistMembership provides the members of the gossip cluster.
+type memberlistMembership struct {
+	peer *alertingCluster.Peer
+}
+
+func (m memberlistMembership) Members() []string {
+	nodes := m.peer.Peers()
+	members := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		members = append(members, n.Name)
+	}
+	return members
+}
+
+func (m memberlistMembership) Self() string {
+	return m.peer.Name()
+}
+
 // AlertmanagerFor returns the Alertmanager instance for the organization provided.
 // When the organization does not have an active Alertmanager, it returns a ErrNoAlertmanagerForOrg.
 // When the Alertmanager of the organization is not ready, it returns a ErrAlertmanagerNotReady.
@@ -0,0 +1,388 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	alertingTemplates "github.com/grafana/alerting/templates"
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/notifier/channels_config"
+)
+
+const (
+	previewTemplateName      = "__rule_preview__"
+	previewTitleTemplateName = "__rule_preview_title__"
+	previewBodyTemplateName  = "__rule_preview_body__"
+)
+
+var (
+	// titleSettings and bodySettings are the names of the settings that contain the templates of the title and the body
+	// of a notification, in the order of preference. Integrations use different names for them.
+	titleSettings = []string{"title", "subject", "summary"}
+	bodySettings  = []string{"message", "text", "details", "description", "body"}
+)
+
+// templateTester renders templates using the templates of an organization.
+type templateTester interface {
+	TestTemplate(ctx context.Context, c definitions.TestTemplatesConfigBodyParams) (*TestTemplatesResults, error)
+}
+
+// PreviewNotifications routes the alerts through the current notification configuration of the organization, and
+// renders the notifications that the integrations would send for them at the given time. Notification settings of a
+// rule that is not saved yet can be provided to route the alerts via the autogenerated routes.
+func (moa *MultiOrgAlertmanager) PreviewNotifications(ctx context.Context, orgID int64, alerts []*amv2.PostableAlert, settings []models.NotificationSettings, now time.Time) (definitions.NotificationsPreview, error) {
+	cfg, err := moa.GetAlertmanagerConfiguration(ctx, orgID, true)
+	if err != nil {
+		return definitions.NotificationsPreview{}, err
+	}
+	amConfig := cfg.AlertmanagerConfig
+	if len(settings) > 0 {
+		validator := NewNotificationSettingsValidator(&amConfig)
+		for _, s := range settings {
+			if err := validator.Validate(s); err != nil {
+				return definitions.NotificationsPreview{}, errors.Join(models.ErrAlertRuleFailedValidation, err)
+			}
+		}
+		if err := AddNotificationSettingsToRoute(amConfig.Route, settings...); err != nil {
+			return definitions.NotificationsPreview{}, err
+		}
+	}
+
+	silences, err := moa.ListSilences(ctx, orgID, nil)
+	if err != nil {
+		return definitions.NotificationsPreview{}, err
+	}
+	am, err := moa.AlertmanagerFor(orgID)
+	if err != nil {
+		return definitions.NotificationsPreview{}, err
+	}
+	return previewNotifications(ctx, am, amConfig, silences, alerts, now)
+}
+
+// previewGroup is an aggregation group of the alerts that would be sent in a single notification.
+type previewGroup struct {
+	key         string
+	route       *dispatch.Route
+	groupLabels model.LabelSet
+	alerts      []*amv2.PostableAlert
+}
+
+func previewNotifications(ctx context.Context, tester templateTester, cfg definitions.GettableApiAlertingConfig, silences []*models.Silence, alerts []*amv2.PostableAlert, now time.Time) (definitions.NotificationsPreview, error) {
+	if cfg.Route == nil {
+		return definitions.NotificationsPreview{}, errors.New("notification policy tree must not be empty")
+	}
+	root := dispatch.NewRoute(cfg.Route.AsAMRoute(), nil)
+
+	intervals := make(map[string][]timeinterval.TimeInterval, len(cfg.MuteTimeIntervals)+len(cfg.TimeIntervals))
+	for _, mt := range cfg.MuteTimeIntervals {
+		intervals[mt.Name] = mt.TimeIntervals
+	}
+	for _, ti := range cfg.TimeIntervals {
+		intervals[ti.Name] = ti.TimeIntervals
+	}
+
+	result := definitions.NotificationsPreview{
+		Routing:       make([]definitions.AlertRoutingPreview, 0, len(alerts)),
+		Notifications: []definitions.NotificationPreview{},
+	}
+	groups := make(map[string]*previewGroup)
+	for _, alert := range alerts {
+		ls := make(model.LabelSet, len(alert.Labels))
+		for k, v := range alert.Labels {
+			ls[model.LabelName(k)] = model.LabelValue(v)
+		}
+		silencedBy, err := activeSilencesFor(silences, ls, now)
+		if err != nil {
+			return definitions.NotificationsPreview{}, err
+		}
+		routing := definitions.AlertRoutingPreview{
+			Labels:     alert.Labels,
+			Firing:     time.Time(alert.EndsAt).IsZero() || time.Time(alert.EndsAt).After(now),
+			Routes:     []definitions.RoutePreview{},
+			SilencedBy: silencedBy,
+		}
+		for _, route := range root.Match(ls) {
+			mutedBy, muted := mutedAt(route, intervals, now)
+			groupLabels := getGroupLabels(ls, route)
+			routing.Routes = append(routing.Routes, definitions.RoutePreview{
+				Key:                 route.Key(),
+				Receiver:            route.RouteOpts.Receiver,
+				Autogenerated:       isAutogeneratedAMRoute(route),
+				GroupBy:             groupByNames(route),
+				GroupLabels:         toStringMap(groupLabels),
+				MuteTimeIntervals:   route.RouteOpts.MuteTimeIntervals,
+				ActiveTimeIntervals: route.RouteOpts.ActiveTimeIntervals,
+				Muted:               muted,
+				MutedBy:             mutedBy,
+			})
+			if !routing.Firing || muted || len(silencedBy) > 0 {
+				continue
+			}
+			key := fmt.Sprintf("%s:%s", route.Key(), groupLabels)
+			group, ok := groups[key]
+			if !ok {
+				group = &previewGroup{key: key, route: route, groupLabels: groupLabels}
+				groups[key] = group
+			}
+			group.alerts = append(group.alerts, alert)
+		}
+		result.Routing = append(result.Routing, routing)
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	receivers := make(map[string]*definitions.GettableApiReceiver, len(cfg.Receivers))
+	for _, r := range cfg.Receivers {
+		receivers[r.Name] = r
+	}
+	for _, key := range keys {
+		n, err := renderNotification(ctx, tester, receivers[groups[key].route.RouteOpts.Receiver], groups[key])
+		if err != nil {
+			return definitions.NotificationsPreview{}, err
+		}
+		result.Notifications = append(result.Notifications, n)
+	}
+	return result, nil
+}
+
+func renderNotification(ctx context.Context, tester templateTester, receiver *definitions.GettableApiReceiver, group *previewGroup) (definitions.NotificationPreview, error) {
+	n := definitions.NotificationPreview{
+		Receiver:     group.route.RouteOpts.Receiver,
+		GroupKey:     group.key,
+		GroupLabels:  toStringMap(group.groupLabels),
+		Firing:       len(group.alerts),
+		Integrations: []definitions.IntegrationPreview{},
+	}
+	if receiver == nil {
+		return n, nil
+	}
+	for _, integration := range receiver.GrafanaManagedReceivers {
+		p := definitions.IntegrationPreview{
+			UID:  integration.UID,
+			Name: integration.Name,
+			Type: integration.Type,
+		}
+		title, body, err := integrationTemplates(integration.Type, integration.Settings)
+		if err != nil {
+			p.Error = err.Error()
+			n.Integrations = append(n.Integrations, p)
+			continue
+		}
+		// TestTemplate adds default labels and annotations to the alerts, so it must not modify the alerts of the preview.
+		alerts := make([]*amv2.PostableAlert, 0, len(group.alerts))
+		for _, a := range group.alerts {
+			alerts = append(alerts, copyPostableAlert(a))
+		}
+		res, err := tester.TestTemplate(ctx, definitions.TestTemplatesConfigBodyParams{
+			Alerts: alerts,
+			Template: fmt.Sprintf(`{{ define "%s" }}%s{{ end }}{{ define "%s" }}%s{{ end }}`,
+				previewTitleTemplateName, title, previewBodyTemplateName, body),
+			Name: previewTemplateName,
+		})
+		if err != nil {
+			return definitions.NotificationPreview{}, fmt.Errorf("failed to render notification for integration %s: %w", integration.UID, err)
+		}
+		for _, r := range res.Results {
+			switch r.Name {
+			case previewTitleTemplateName:
+				p.Title = r.Text
+			case previewBodyTemplateName:
+				p.Body = r.Text
+			}
+		}
+		errs := make([]string, 0, len(res.Errors))
+		for _, e := range res.Errors {
+			errs = append(errs, e.Error)
+		}
+		p.Error = strings.Join(errs, "; ")
+		n.Integrations = append(n.Integrations, p)
+	}
+	return n, nil
+}
+
+// integrationTemplates returns the templates of the title and the body of notifications sent by an integration.
+// Settings that are not configured fall back to the defaults of the integration.
+func integrationTemplates(integrationType string, raw definitions.RawMessage) (string, string, error) {
+	settings := map[string]any{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &settings); err != nil {
+			return "", "", fmt.Errorf("failed to parse settings: %w", err)
+		}
+	}
+	var options []channels_config.NotifierOption
+	if plugin, err := channels_config.ConfigForIntegrationType(integrationType); err == nil {
+		options = plugin.Options
+	}
+	title := templateSetting(options, settings, titleSettings, alertingTemplates.DefaultMessageTitleEmbed)
+	body := templateSetting(options, settings, bodySettings, alertingTemplates.DefaultMessageEmbed)
+	return title, body, nil
+}
+
+func templateSetting(options []channels_config.NotifierOption, settings map[string]any, names []string, fallback string) string {
+	for _, name := range names {
+		for _, option := range options {
+			if option.PropertyName != name {
+				continue
+			}
+			if v, ok := settings[name].(string); ok && v != "" {
+				return v
+			}
+			if strings.Contains(option.Placeholder, "{{") {
+				return option.Placeholder
+			}
+			return fallback
+		}
+	}
+	return fallback
+}
+
+// activeSilencesFor returns IDs of the silences that are active at the given time and match the labels.
+func activeSilencesFor(silences []*models.Silence, ls model.LabelSet, now time.Time) ([]string, error) {
+	result := []string{}
+	for _, s := range silences {
+		if s == nil || s.ID == nil || s.StartsAt == nil || s.EndsAt == nil {
+			continue
+		}
+		if now.Before(time.Time(*s.StartsAt)) || !now.Before(time.Time(*s.EndsAt)) {
+			continue
+		}
+		matches, err := silenceMatches(s, ls)
+		if err != nil {
+			return nil, err
+		}
+		if matches {
+			result = append(result, *s.ID)
+		}
+	}
+	return result, nil
+}
+
+func silenceMatches(s *models.Silence, ls model.LabelSet) (bool, error) {
+	for _, m := range s.Matchers {
+		if m == nil || m.Name == nil || m.Value == nil {
+			continue
+		}
+		isEqual := m.IsEqual == nil || *m.IsEqual
+		isRegex := m.IsRegex != nil && *m.IsRegex
+		t := labels.MatchEqual
+		switch {
+		case isRegex && isEqual:
+			t = labels.MatchRegexp
+		case isRegex:
+			t = labels.MatchNotRegexp
+		case !isEqual:
+			t = labels.MatchNotEqual
+		}
+		matcher, err := labels.NewMatcher(t, *m.Name, *m.Value)
+		if err != nil {
+			return false, fmt.Errorf("invalid matcher of silence %s: %w", *s.ID, err)
+		}
+		if !matcher.Matches(string(ls[model.LabelName(*m.Name)])) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// mutedAt returns the mute time intervals of the route that are in effect at the given time, and whether notifications
+// of the route are muted either by them or because none of the active time intervals of the route is in effect.
+func mutedAt(route *dispatch.Route, intervals map[string][]timeinterval.TimeInterval, at time.Time) ([]string, bool) {
+	inEffect := func(name string) bool {
+		for _, ti := range intervals[name] {
+			if ti.ContainsTime(at.UTC()) {
+				return true
+			}
+		}
+		return false
+	}
+	var mutedBy []string
+	for _, name := range route.RouteOpts.MuteTimeIntervals {
+		if inEffect(name) {
+			mutedBy = append(mutedBy, name)
+		}
+	}
+	if len(mutedBy) > 0 {
+		return mutedBy, true
+	}
+	if len(route.RouteOpts.ActiveTimeIntervals) == 0 {
+		return nil, false
+	}
+	for _, name := range route.RouteOpts.ActiveTimeIntervals {
+		if inEffect(name) {
+			return nil, false
+		}
+	}
+	return nil, true
+}
+
+func isAutogeneratedAMRoute(route *dispatch.Route) bool {
+	for r := route; r != nil; r = r.Parent {
+		for _, m := range r.Matchers {
+			if m.Name == models.AutogeneratedRouteLabel {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func getGroupLabels(ls model.LabelSet, route *dispatch.Route) model.LabelSet {
+	if route.RouteOpts.GroupByAll {
+		return ls.Clone()
+	}
+	result := model.LabelSet{}
+	for ln, lv := range ls {
+		if _, ok := route.RouteOpts.GroupBy[ln]; ok {
+			result[ln] = lv
+		}
+	}
+	return result
+}
+
+func groupByNames(route *dispatch.Route) []string {
+	if route.RouteOpts.GroupByAll {
+		return []string{models.GroupByAll}
+	}
+	result := make([]string, 0, len(route.RouteOpts.GroupBy))
+	for ln := range route.RouteOpts.GroupBy {
+		result = append(result, string(ln))
+	}
+	sort.Strings(result)
+	return result
+}
+
+func toStringMap(ls model.LabelSet) map[string]string {
+	result := make(map[string]string, len(ls))
+	for k, v := range ls {
+		result[string(k)] = string(v)
+	}
+	return result
+}
+
+func copyPostableAlert(a *amv2.PostableAlert) *amv2.PostableAlert {
+	c := *a
+	c.Labels = make(amv2.LabelSet, len(a.Labels))
+	for k, v := range a.Labels {
+		c.Labels[k] = v
+	}
+	c.Annotations = make(amv2.LabelSet, len(a.Annotations))
+	for k, v := range a.Annotations {
+		c.Annotations[k] = v
+	}
+	return &c
+}
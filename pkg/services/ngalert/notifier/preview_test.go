@@ -0,0 +1,285 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	alertingNotify "github.com/grafana/alerting/notify"
+	alertingTemplates "github.com/grafana/alerting/templates"
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+type fakeTemplateTester struct {
+	calls []definitions.TestTemplatesConfigBodyParams
+	err   error
+}
+
+func (f *fakeTemplateTester) TestTemplate(_ context.Context, c definitions.TestTemplatesConfigBodyParams) (*TestTemplatesResults, error) {
+	f.calls = append(f.calls, c)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &TestTemplatesResults{
+		Results: []alertingNotify.TestTemplatesResult{
+			{Name: previewTitleTemplateName, Text: "title"},
+			{Name: previewBodyTemplateName, Text: "body"},
+		},
+	}, nil
+}
+
+func TestPreviewNotifications(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	alert := func(name, instance string, endsAt time.Time) *amv2.PostableAlert {
+		return &amv2.PostableAlert{
+			StartsAt: strfmt.DateTime(now),
+			EndsAt:   strfmt.DateTime(endsAt),
+			Alert: amv2.Alert{
+				Labels: amv2.LabelSet{model.AlertNameLabel: name, "instance": instance},
+			},
+		}
+	}
+	always := config.TimeInterval{
+		Name: "always",
+		TimeIntervals: []timeinterval.TimeInterval{
+			{Times: []timeinterval.TimeRange{{StartMinute: 0, EndMinute: 24 * 60}}},
+		},
+	}
+	teamMatcher, err := labels.NewMatcher(labels.MatchEqual, "instance", "team")
+	require.NoError(t, err)
+	mutedMatcher, err := labels.NewMatcher(labels.MatchEqual, "instance", "muted")
+	require.NoError(t, err)
+	amConfig := func() definitions.GettableApiAlertingConfig {
+		return definitions.GettableApiAlertingConfig{
+			Config: definitions.Config{
+				Route: &definitions.Route{
+					Receiver:   "default",
+					GroupByStr: []string{model.AlertNameLabel},
+					Routes: []*definitions.Route{
+						{Receiver: "team", ObjectMatchers: definitions.ObjectMatchers{teamMatcher}},
+						{Receiver: "team", ObjectMatchers: definitions.ObjectMatchers{mutedMatcher}, MuteTimeIntervals: []string{always.Name}},
+					},
+				},
+				TimeIntervals: []config.TimeInterval{always},
+			},
+			Receivers: []*definitions.GettableApiReceiver{
+				{
+					Receiver: config.Receiver{Name: "default"},
+					GettableGrafanaReceivers: definitions.GettableGrafanaReceivers{GrafanaManagedReceivers: []*definitions.GettableGrafanaReceiver{
+						{UID: "email-uid", Name: "default", Type: "email"},
+					}},
+				},
+				{
+					Receiver: config.Receiver{Name: "team"},
+					GettableGrafanaReceivers: definitions.GettableGrafanaReceivers{GrafanaManagedReceivers: []*definitions.GettableGrafanaReceiver{
+						{UID: "slack-uid", Name: "team", Type: "slack", Settings: definitions.RawMessage(`{"title": "{{ .CommonLabels.alertname }}"}`)},
+						{UID: "webhook-uid", Name: "team", Type: "webhook"},
+					}},
+				},
+			},
+		}
+	}
+
+	t.Run("should group firing alerts and render notifications of all integrations", func(t *testing.T) {
+		tester := &fakeTemplateTester{}
+		alerts := []*amv2.PostableAlert{
+			alert("test", "1", now.Add(time.Minute)),
+			alert("test", "2", now.Add(time.Minute)),
+			alert("test", "team", now.Add(time.Minute)),
+		}
+
+		result, err := previewNotifications(context.Background(), tester, amConfig(), nil, alerts, now)
+		require.NoError(t, err)
+
+		require.Len(t, result.Routing, 3)
+		for i, r := range result.Routing {
+			assert.True(t, r.Firing)
+			assert.Empty(t, r.SilencedBy)
+			require.Len(t, r.Routes, 1)
+			assert.Equal(t, map[string]string{model.AlertNameLabel: "test"}, r.Routes[0].GroupLabels)
+			assert.False(t, r.Routes[0].Muted)
+			assert.False(t, r.Routes[0].Autogenerated)
+			if i < 2 {
+				assert.Equal(t, "default", r.Routes[0].Receiver)
+			} else {
+				assert.Equal(t, "team", r.Routes[0].Receiver)
+			}
+		}
+
+		require.Len(t, result.Notifications, 2)
+		byReceiver := map[string]definitions.NotificationPreview{}
+		for _, n := range result.Notifications {
+			byReceiver[n.Receiver] = n
+		}
+		assert.Equal(t, 2, byReceiver["default"].Firing)
+		require.Len(t, byReceiver["default"].Integrations, 1)
+		assert.Equal(t, definitions.IntegrationPreview{UID: "email-uid", Name: "default", Type: "email", Title: "title", Body: "body"}, byReceiver["default"].Integrations[0])
+		assert.Equal(t, 1, byReceiver["team"].Firing)
+		require.Len(t, byReceiver["team"].Integrations, 2)
+
+		require.Len(t, tester.calls, 3)
+		templates := make([]string, 0, len(tester.calls))
+		for _, c := range tester.calls {
+			assert.Equal(t, previewTemplateName, c.Name)
+			templates = append(templates, c.Template)
+		}
+		assert.Contains(t, templates, `{{ define "__rule_preview_title__" }}{{ .CommonLabels.alertname }}{{ end }}{{ define "__rule_preview_body__" }}{{ template "slack.default.text" . }}{{ end }}`)
+		// The alerts of the preview must not get default labels added by TestTemplate.
+		for _, a := range alerts {
+			assert.NotContains(t, a.Labels, models.FolderTitleLabel)
+		}
+	})
+
+	t.Run("should report mute time intervals and not render muted notifications", func(t *testing.T) {
+		tester := &fakeTemplateTester{}
+		result, err := previewNotifications(context.Background(), tester, amConfig(), nil, []*amv2.PostableAlert{alert("test", "muted", now.Add(time.Minute))}, now)
+		require.NoError(t, err)
+
+		require.Len(t, result.Routing, 1)
+		require.Len(t, result.Routing[0].Routes, 1)
+		route := result.Routing[0].Routes[0]
+		assert.True(t, route.Muted)
+		assert.Equal(t, []string{always.Name}, route.MutedBy)
+		assert.Equal(t, []string{always.Name}, route.MuteTimeIntervals)
+		assert.Empty(t, result.Notifications)
+		assert.Empty(t, tester.calls)
+	})
+
+	t.Run("should report active silences and not render silenced notifications", func(t *testing.T) {
+		active := []strfmt.DateTime{strfmt.DateTime(now.Add(-time.Hour)), strfmt.DateTime(now.Add(time.Hour))}
+		expired := []strfmt.DateTime{strfmt.DateTime(now.Add(-2 * time.Hour)), strfmt.DateTime(now.Add(-time.Hour))}
+		silence := func(id string, period []strfmt.DateTime, value string) *models.Silence {
+			return &models.Silence{
+				ID: util.Pointer(id),
+				Silence: amv2.Silence{
+					StartsAt: &period[0],
+					EndsAt:   &period[1],
+					Matchers: amv2.Matchers{
+						{Name: util.Pointer("instance"), Value: util.Pointer(value), IsEqual: util.Pointer(true), IsRegex: util.Pointer(false)},
+					},
+				},
+			}
+		}
+		silences := []*models.Silence{
+			silence("active", active, "1"),
+			silence("expired", expired, "1"),
+			silence("other", active, "2"),
+		}
+		tester := &fakeTemplateTester{}
+
+		result, err := previewNotifications(context.Background(), tester, amConfig(), silences, []*amv2.PostableAlert{alert("test", "1", now.Add(time.Minute))}, now)
+		require.NoError(t, err)
+
+		require.Len(t, result.Routing, 1)
+		assert.Equal(t, []string{"active"}, result.Routing[0].SilencedBy)
+		assert.Empty(t, result.Notifications)
+	})
+
+	t.Run("should not render notifications for resolved alerts", func(t *testing.T) {
+		tester := &fakeTemplateTester{}
+		result, err := previewNotifications(context.Background(), tester, amConfig(), nil, []*amv2.PostableAlert{alert("test", "1", now)}, now)
+		require.NoError(t, err)
+
+		require.Len(t, result.Routing, 1)
+		assert.False(t, result.Routing[0].Firing)
+		assert.Len(t, result.Routing[0].Routes, 1)
+		assert.Empty(t, result.Notifications)
+	})
+
+	t.Run("should route alerts of unsaved rules via autogenerated routes", func(t *testing.T) {
+		cfg := amConfig()
+		settings := models.NotificationSettings{
+			Receiver:          "team",
+			GroupBy:           []string{model.AlertNameLabel, models.FolderTitleLabel, "instance"},
+			MuteTimeIntervals: []string{always.Name},
+		}
+		require.NoError(t, AddNotificationSettingsToRoute(cfg.Route, settings))
+		a := alert("test", "1", now.Add(time.Minute))
+		for k, v := range settings.ToLabels() {
+			a.Labels[k] = v
+		}
+
+		result, err := previewNotifications(context.Background(), &fakeTemplateTester{}, cfg, nil, []*amv2.PostableAlert{a}, now)
+		require.NoError(t, err)
+
+		require.Len(t, result.Routing[0].Routes, 1)
+		route := result.Routing[0].Routes[0]
+		assert.True(t, route.Autogenerated)
+		assert.Equal(t, "team", route.Receiver)
+		assert.Equal(t, []string{always.Name}, route.MutedBy)
+	})
+
+	t.Run("should render default templates of integrations", func(t *testing.T) {
+		title, body, err := integrationTemplates("slack", nil)
+		require.NoError(t, err)
+		assert.Equal(t, `{{ template "slack.default.title" . }}`, title)
+		assert.Equal(t, `{{ template "slack.default.text" . }}`, body)
+
+		title, body, err = integrationTemplates("unknown", definitions.RawMessage(`{"title": "custom"}`))
+		require.NoError(t, err)
+		assert.Equal(t, alertingTemplates.DefaultMessageTitleEmbed, title)
+		assert.Equal(t, alertingTemplates.DefaultMessageEmbed, body)
+
+		_, _, err = integrationTemplates("slack", definitions.RawMessage(`invalid`))
+		require.Error(t, err)
+	})
+
+	t.Run("should fail if rendering fails", func(t *testing.T) {
+		tester := &fakeTemplateTester{err: errors.New("failed")}
+		_, err := previewNotifications(context.Background(), tester, amConfig(), nil, []*amv2.PostableAlert{alert("test", "1", now.Add(time.Minute))}, now)
+		require.ErrorIs(t, err, tester.err)
+	})
+}
+
+func TestAddNotificationSettingsToRoute(t *testing.T) {
+	settings := func(receiver string, groupBy ...string) models.NotificationSettings {
+		s := models.NewDefaultNotificationSettings(receiver)
+		if len(groupBy) > 0 {
+			s.GroupBy = append([]string{model.AlertNameLabel, models.FolderTitleLabel}, groupBy...)
+		}
+		return s
+	}
+
+	t.Run("should add autogenerated routes if there are none", func(t *testing.T) {
+		route := &definitions.Route{Receiver: "default"}
+		require.NoError(t, AddNotificationSettingsToRoute(route, settings("team", "instance")))
+
+		require.Len(t, route.Routes, 1)
+		require.True(t, isAutogeneratedRoot(route.Routes[0]))
+		require.Len(t, route.Routes[0].Routes, 1)
+		assert.Equal(t, "team", route.Routes[0].Routes[0].Receiver)
+		require.Len(t, route.Routes[0].Routes[0].Routes, 1)
+	})
+
+	t.Run("should merge with existing autogenerated routes", func(t *testing.T) {
+		route := &definitions.Route{Receiver: "default"}
+		require.NoError(t, AddNotificationSettingsToRoute(route, settings("team")))
+		require.NoError(t, AddNotificationSettingsToRoute(route, settings("team", "instance")))
+		require.NoError(t, AddNotificationSettingsToRoute(route, settings("other")))
+		require.NoError(t, AddNotificationSettingsToRoute(route, settings("team", "instance")))
+
+		require.Len(t, route.Routes, 1)
+		root := route.Routes[0]
+		require.Len(t, root.Routes, 2)
+		assert.Equal(t, "team", root.Routes[0].Receiver)
+		assert.Len(t, root.Routes[0].Routes, 1)
+		assert.Equal(t, "other", root.Routes[1].Receiver)
+		assert.Empty(t, root.Routes[1].Routes)
+	})
+
+	t.Run("should fail without route", func(t *testing.T) {
+		require.Error(t, AddNotificationSettingsToRoute(nil, settings("team")))
+	})
+}
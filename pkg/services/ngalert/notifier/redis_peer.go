@@ -410,6 +410,11 @@ func (p *redisPeer) Members() []string {
 	return p.members
 }
 
+// Self returns the name of this peer as it appears in Members.
+func (p *redisPeer) Self() string {
+	return p.withPrefix(p.name)
+}
+
 func (p *redisPeer) WaitReady(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
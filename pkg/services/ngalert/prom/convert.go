@@ -178,6 +178,11 @@ func (p *Converter) convertRule(orgID int64, namespaceUID, group string, rule Pr
 		forInterval = time.Duration(*rule.For)
 	}
 
+	var keepFiringFor time.Duration
+	if rule.KeepFiringFor != nil {
+		keepFiringFor = time.Duration(*rule.KeepFiringFor)
+	}
+
 	var query []models.AlertQuery
 	var title string
 	var isPaused bool
@@ -221,19 +226,20 @@ func (p *Converter) convertRule(orgID int64, namespaceUID, group string, rule Pr
 	}
 
 	result := models.AlertRule{
-		OrgID:        orgID,
-		NamespaceUID: namespaceUID,
-		Title:        title,
-		Data:         query,
-		Condition:    query[len(query)-1].RefID,
-		NoDataState:  p.cfg.NoDataState,
-		ExecErrState: p.cfg.ExecErrState,
-		Annotations:  rule.Annotations,
-		Labels:       labels,
-		For:          forInterval,
-		RuleGroup:    group,
-		IsPaused:     isPaused,
-		Record:       record,
+		OrgID:         orgID,
+		NamespaceUID:  namespaceUID,
+		Title:         title,
+		Data:          query,
+		Condition:     query[len(query)-1].RefID,
+		NoDataState:   p.cfg.NoDataState,
+		ExecErrState:  p.cfg.ExecErrState,
+		Annotations:   rule.Annotations,
+		Labels:        labels,
+		For:           forInterval,
+		KeepFiringFor: keepFiringFor,
+		RuleGroup:     group,
+		IsPaused:      isPaused,
+		Record:        record,
 		Metadata: models.AlertRuleMetadata{
 			PrometheusStyleRule: &models.PrometheusStyleRule{
 				OriginalRuleDefinition: string(originalRuleDefinition),
@@ -53,7 +53,7 @@ func TestPrometheusRulesToGrafana(t *testing.T) {
 			expectError: false,
 		},
 		{
-			name:      "rules with keep_firing_for are not supported",
+			name:      "rule with keep_firing_for",
 			orgID:     1,
 			namespace: "namespaceUID",
 			promGroup: PrometheusRuleGroup{
@@ -67,6 +67,23 @@ func TestPrometheusRulesToGrafana(t *testing.T) {
 					},
 				},
 			},
+			expectError: false,
+		},
+		{
+			name:      "recording rules with keep_firing_for are not supported",
+			orgID:     1,
+			namespace: "namespaceUID",
+			promGroup: PrometheusRuleGroup{
+				Name:     "test-group-1",
+				Interval: prommodel.Duration(1 * time.Minute),
+				Rules: []PrometheusRule{
+					{
+						Record:        "some_metric",
+						Expr:          "sum(up)",
+						KeepFiringFor: util.Pointer(prommodel.Duration(5 * time.Minute)),
+					},
+				},
+			},
 			expectError: true,
 		},
 		{
@@ -146,6 +163,12 @@ func TestPrometheusRulesToGrafana(t *testing.T) {
 				}
 				require.Equal(t, expectedFor, grafanaRule.For, tc.name)
 
+				var expectedKeepFiringFor time.Duration
+				if promRule.KeepFiringFor != nil {
+					expectedKeepFiringFor = time.Duration(*promRule.KeepFiringFor)
+				}
+				require.Equal(t, expectedKeepFiringFor, grafanaRule.KeepFiringFor, tc.name)
+
 				expectedLabels := make(map[string]string, len(promRule.Labels)+1)
 				for k, v := range promRule.Labels {
 					expectedLabels[k] = v
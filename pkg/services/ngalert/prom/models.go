@@ -31,8 +31,8 @@ type PrometheusRule struct {
 }
 
 func (r *PrometheusRule) Validate() error {
-	if r.KeepFiringFor != nil {
-		return ErrPrometheusRuleValidationFailed.Errorf("keep_firing_for is not supported")
+	if r.Record != "" && r.KeepFiringFor != nil {
+		return ErrPrometheusRuleValidationFailed.Errorf("keep_firing_for is not allowed in recording rules")
 	}
 
 	return nil
@@ -10,6 +10,7 @@ import (
 	"github.com/grafana/grafana/pkg/apimachinery/identity"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/folder"
 	"github.com/grafana/grafana/pkg/services/ngalert/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
@@ -28,6 +29,9 @@ type ruleAccessControlService interface {
 	CanReadAllRules(ctx context.Context, user identity.Requester) (bool, error)
 	// CanWriteAllRules returns true if the user has full access to write rules via provisioning API and bypass regular checks
 	CanWriteAllRules(ctx context.Context, user identity.Requester) (bool, error)
+	// AuthorizeRecordingTarget checks that the user is allowed to write to the target data source of recording rules.
+	// It is not bypassed by the permission to write all rules.
+	AuthorizeRecordingTarget(ctx context.Context, user identity.Requester, datasourceUID string) error
 }
 
 var errProvenanceMismatch = errutil.NewBase(errutil.StatusConflict, "alerting.provenanceMismatch").MustTemplate(
@@ -51,6 +55,7 @@ type AlertRuleService struct {
 	log                    log.Logger
 	nsValidatorProvider    NotificationSettingsValidatorProvider
 	authz                  ruleAccessControlService
+	datasourceCache        datasources.CacheService
 }
 
 func NewAlertRuleService(ruleStore RuleStore,
@@ -64,6 +69,7 @@ func NewAlertRuleService(ruleStore RuleStore,
 	log log.Logger,
 	ns NotificationSettingsValidatorProvider,
 	authz RuleAccessControlService,
+	datasourceCache datasources.CacheService,
 ) *AlertRuleService {
 	return &AlertRuleService{
 		defaultIntervalSeconds: defaultIntervalSeconds,
@@ -77,6 +83,7 @@ func NewAlertRuleService(ruleStore RuleStore,
 		log:                    log,
 		nsValidatorProvider:    ns,
 		authz:                  newRuleAccessControlService(authz),
+		datasourceCache:        datasourceCache,
 	}
 }
 
@@ -237,6 +244,11 @@ func (service *AlertRuleService) CreateAlertRule(ctx context.Context, user ident
 			}
 		}
 	}
+	if rule.Record != nil && rule.Record.TargetDatasourceUID != "" {
+		if err := service.validateRecordingTarget(ctx, user, rule.Record.TargetDatasourceUID); err != nil {
+			return models.AlertRule{}, err
+		}
+	}
 	err = service.xact.InTransaction(ctx, func(ctx context.Context) error {
 		ids, err := service.ruleStore.InsertAlertRules(ctx, userUidOrFallback(user), []models.AlertRule{
 			rule,
@@ -447,6 +459,12 @@ func (service *AlertRuleService) ReplaceRuleGroup(ctx context.Context, user iden
 		}
 	}
 
+	for _, target := range delta.NewOrUpdatedRecordingTargets() {
+		if err := service.validateRecordingTarget(ctx, user, target); err != nil {
+			return err
+		}
+	}
+
 	return service.persistDelta(ctx, user, delta, provenance)
 }
 
@@ -678,6 +696,11 @@ func (service *AlertRuleService) UpdateAlertRule(ctx context.Context, user ident
 			}
 		}
 	}
+	if rule.Record != nil && rule.Record.TargetDatasourceUID != "" && (storedRule.Record == nil || storedRule.Record.TargetDatasourceUID != rule.Record.TargetDatasourceUID) {
+		if err := service.validateRecordingTarget(ctx, user, rule.Record.TargetDatasourceUID); err != nil {
+			return models.AlertRule{}, err
+		}
+	}
 	rule.Updated = time.Now()
 	rule.ID = storedRule.ID
 	rule.IntervalSeconds = storedRule.IntervalSeconds
@@ -916,6 +939,19 @@ func withoutNilAlertRules(ptrs []*models.AlertRule) []models.AlertRule {
 	return result
 }
 
+// validateRecordingTarget checks that the target data source of a recording rule is a Prometheus data source
+// that the user is allowed to write to, the same way as the Ruler API does.
+func (service *AlertRuleService) validateRecordingTarget(ctx context.Context, user identity.Requester, datasourceUID string) error {
+	ds, err := service.datasourceCache.GetDatasourceByUID(ctx, datasourceUID, user, false)
+	if err != nil {
+		return errors.Join(models.ErrAlertRuleFailedValidation, fmt.Errorf("failed to get target data source '%s': %w", datasourceUID, err))
+	}
+	if ds.Type != datasources.DS_PROMETHEUS {
+		return errors.Join(models.ErrAlertRuleFailedValidation, fmt.Errorf("target data source '%s' must be of type %s, got %s", datasourceUID, datasources.DS_PROMETHEUS, ds.Type))
+	}
+	return service.authz.AuthorizeRecordingTarget(ctx, user, datasourceUID)
+}
+
 func (service *AlertRuleService) checkGroupLimits(group models.AlertRuleGroup) error {
 	if service.rulesPerRuleGroupLimit > 0 && int64(len(group.Rules)) > service.rulesPerRuleGroupLimit {
 		service.log.Warn("Large rule group was edited. Large groups are discouraged and may be rejected in the future.",
@@ -30,6 +30,8 @@ import (
 	"github.com/grafana/grafana/pkg/infra/tracing"
 	acmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
 	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	dsfakes "github.com/grafana/grafana/pkg/services/datasources/fakes"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/folder"
 	"github.com/grafana/grafana/pkg/services/folder/folderimpl"
@@ -1507,6 +1509,56 @@ func TestReplaceGroup(t *testing.T) {
 		return service, ruleStore, provenanceStore, ac
 	}
 
+	t.Run("validates new target data sources of recording rules", func(t *testing.T) {
+		group := models.AlertRuleGroup{
+			Title:      groupKey.RuleGroup,
+			FolderUID:  groupKey.NamespaceUID,
+			Interval:   groupIntervalSeconds,
+			Provenance: groupProvenance,
+		}
+		for _, rule := range rules {
+			group.Rules = append(group.Rules, *models.CopyRule(rule))
+		}
+		recording := gen.With(gen.WithGroupKey(groupKey), gen.WithIntervalSeconds(groupIntervalSeconds), gen.WithAllRecordingRules())
+
+		testCases := []struct {
+			name        string
+			target      string
+			authzErr    error
+			expectedErr error
+		}{
+			{name: "prometheus data source", target: "prom"},
+			{name: "missing data source", target: "missing", expectedErr: models.ErrAlertRuleFailedValidation},
+			{name: "not a prometheus data source", target: "loki", expectedErr: models.ErrAlertRuleFailedValidation},
+			{name: "not allowed to write", target: "prom", authzErr: accesscontrol.ErrAuthorizationBase.Errorf("test"), expectedErr: accesscontrol.ErrAuthorizationBase},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				service, _, _, ac := initServiceWithData(t)
+				service.datasourceCache = &dsfakes.FakeCacheService{DataSources: []*datasources.DataSource{
+					{UID: "prom", Type: datasources.DS_PROMETHEUS},
+					{UID: "loki", Type: datasources.DS_LOKI},
+				}}
+				ac.CanWriteAllRulesFunc = func(ctx context.Context, user identity.Requester) (bool, error) {
+					return true, nil
+				}
+				ac.AuthorizeRecordingTargetFunc = func(ctx context.Context, user identity.Requester, datasourceUID string) error {
+					return tc.authzErr
+				}
+				g := group
+				g.Rules = append(slices.Clone(group.Rules), *recording.With(gen.WithRecordTarget(tc.target)).Generate())
+
+				err := service.ReplaceRuleGroup(context.Background(), u, g, models.ProvenanceAPI)
+				if tc.expectedErr != nil {
+					require.ErrorIs(t, err, tc.expectedErr)
+					return
+				}
+				require.NoError(t, err)
+				require.Equal(t, "AuthorizeRecordingTarget", ac.Calls[len(ac.Calls)-1].Method)
+			})
+		}
+	})
+
 	t.Run("when user can write all rules", func(t *testing.T) {
 		group := models.AlertRuleGroup{
 			Title:      groupKey.RuleGroup,
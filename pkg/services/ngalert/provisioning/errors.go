@@ -33,6 +33,11 @@ var (
 		contactPointUidExists, errutil.WithPublic(contactPointUidExists),
 	)
 
+	ErrLookupTablesInvalid = errutil.BadRequest("alerting.lookup-tables.invalidFormat").MustTemplate(
+		"Invalid format of the submitted lookup tables.",
+		errutil.WithPublic("Invalid format of the submitted lookup tables: {{.Public.Error}}. Correct the payload and try again."),
+	)
+
 	ErrRouteInvalidFormat = errutil.BadRequest("alerting.notifications.routes.invalidFormat").MustTemplate(
 		"Invalid format of the submitted route.",
 		errutil.WithPublic("Invalid format of the submitted route: {{.Public.Error}}. Correct the payload and try again."),
@@ -51,6 +56,18 @@ func MakeErrTimeIntervalInvalid(err error) error {
 	return ErrTimeIntervalInvalid.Build(data)
 }
 
+// MakeErrLookupTablesInvalid creates an error with the ErrLookupTablesInvalid template
+func MakeErrLookupTablesInvalid(err error) error {
+	data := errutil.TemplateData{
+		Public: map[string]interface{}{
+			"Error": err.Error(),
+		},
+		Error: err,
+	}
+
+	return ErrLookupTablesInvalid.Build(data)
+}
+
 func MakeErrTimeIntervalInUse(usedByRoutes bool, rules []models.AlertRuleKey) error {
 	uids := make([]string, 0, len(rules))
 	for _, key := range rules {
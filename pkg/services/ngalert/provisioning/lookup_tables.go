@@ -0,0 +1,111 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+)
+
+const (
+	lookupTablesKVNamespace = "alerting"
+	lookupTablesKVKey       = "lookup_tables"
+
+	// lookupTablesCacheTTL is the time after which cached lookup tables are read from the database again,
+	// so that changes made on other instances are eventually used.
+	lookupTablesCacheTTL = time.Minute
+)
+
+// LookupTableService manages the key/value lookup tables of organizations that templates of alert rules
+// read with the lookup function. The tables are cached in memory because templates read them on every evaluation.
+type LookupTableService struct {
+	kv    kvstore.KVStore
+	clock clock.Clock
+	log   log.Logger
+
+	mtx   sync.RWMutex
+	cache map[int64]cachedLookupTables
+}
+
+type cachedLookupTables struct {
+	tables   definitions.LookupTables
+	cachedAt time.Time
+}
+
+func NewLookupTableService(kv kvstore.KVStore, clock clock.Clock, log log.Logger) *LookupTableService {
+	return &LookupTableService{
+		kv:    kv,
+		clock: clock,
+		log:   log,
+		cache: make(map[int64]cachedLookupTables),
+	}
+}
+
+// GetLookupTables returns the lookup tables of the organization. The returned tables must not be modified.
+func (s *LookupTableService) GetLookupTables(ctx context.Context, orgID int64) (definitions.LookupTables, error) {
+	s.mtx.RLock()
+	cached, ok := s.cache[orgID]
+	s.mtx.RUnlock()
+	if ok && s.clock.Since(cached.cachedAt) < lookupTablesCacheTTL {
+		return cached.tables, nil
+	}
+
+	content, exists, err := kvstore.WithNamespace(s.kv, orgID, lookupTablesKVNamespace).Get(ctx, lookupTablesKVKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lookup tables: %w", err)
+	}
+	tables := definitions.LookupTables{}
+	if exists {
+		if err := json.Unmarshal([]byte(content), &tables); err != nil {
+			return nil, fmt.Errorf("failed to decode lookup tables: %w", err)
+		}
+	}
+	s.setCache(orgID, tables)
+	return tables, nil
+}
+
+// UpdateLookupTables replaces the lookup tables of the organization.
+func (s *LookupTableService) UpdateLookupTables(ctx context.Context, orgID int64, tables definitions.LookupTables) error {
+	if err := validateLookupTables(tables); err != nil {
+		return MakeErrLookupTablesInvalid(err)
+	}
+	if tables == nil {
+		tables = definitions.LookupTables{}
+	}
+	content, err := json.Marshal(tables)
+	if err != nil {
+		return err
+	}
+	if err := kvstore.WithNamespace(s.kv, orgID, lookupTablesKVNamespace).Set(ctx, lookupTablesKVKey, string(content)); err != nil {
+		return fmt.Errorf("failed to save lookup tables: %w", err)
+	}
+	s.log.FromContext(ctx).Info("Updated lookup tables", "org", orgID, "tables", len(tables))
+	s.setCache(orgID, tables)
+	return nil
+}
+
+func (s *LookupTableService) setCache(orgID int64, tables definitions.LookupTables) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.cache[orgID] = cachedLookupTables{tables: tables, cachedAt: s.clock.Now()}
+}
+
+func validateLookupTables(tables definitions.LookupTables) error {
+	for name, table := range tables {
+		if name == "" {
+			return errors.New("table name cannot be empty")
+		}
+		if _, ok := table[""]; ok {
+			return fmt.Errorf("table %q has an empty key", name)
+		}
+	}
+	return nil
+}
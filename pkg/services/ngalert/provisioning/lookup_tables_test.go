@@ -0,0 +1,75 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests/fakes"
+)
+
+func TestLookupTableService(t *testing.T) {
+	ctx := context.Background()
+	orgID := int64(1)
+
+	setup := func(t *testing.T) (*LookupTableService, *fakes.FakeKVStore, *clock.Mock) {
+		kv := fakes.NewFakeKVStore(t)
+		clk := clock.NewMock()
+		return NewLookupTableService(kv, clk, log.NewNopLogger()), kv, clk
+	}
+
+	t.Run("returns empty tables if there are none", func(t *testing.T) {
+		sut, _, _ := setup(t)
+
+		tables, err := sut.GetLookupTables(ctx, orgID)
+		require.NoError(t, err)
+		require.Empty(t, tables)
+	})
+
+	t.Run("returns updated tables per org", func(t *testing.T) {
+		sut, _, _ := setup(t)
+		expected := definitions.LookupTables{"owners": {"api": "team-a", "db": "team-b"}}
+
+		require.NoError(t, sut.UpdateLookupTables(ctx, orgID, expected))
+
+		tables, err := sut.GetLookupTables(ctx, orgID)
+		require.NoError(t, err)
+		require.Equal(t, expected, tables)
+
+		tables, err = sut.GetLookupTables(ctx, orgID+1)
+		require.NoError(t, err)
+		require.Empty(t, tables)
+	})
+
+	t.Run("reads tables from the store after the cache expires", func(t *testing.T) {
+		sut, kv, clk := setup(t)
+		require.NoError(t, sut.UpdateLookupTables(ctx, orgID, definitions.LookupTables{"owners": {"api": "team-a"}}))
+
+		// Simulate an update by another instance.
+		require.NoError(t, kv.Set(ctx, orgID, lookupTablesKVNamespace, lookupTablesKVKey, `{"owners":{"api":"team-b"}}`))
+
+		tables, err := sut.GetLookupTables(ctx, orgID)
+		require.NoError(t, err)
+		require.Equal(t, "team-a", tables["owners"]["api"])
+
+		clk.Add(lookupTablesCacheTTL + time.Second)
+		tables, err = sut.GetLookupTables(ctx, orgID)
+		require.NoError(t, err)
+		require.Equal(t, "team-b", tables["owners"]["api"])
+	})
+
+	t.Run("rejects invalid tables", func(t *testing.T) {
+		sut, _, _ := setup(t)
+
+		err := sut.UpdateLookupTables(ctx, orgID, definitions.LookupTables{"": {"api": "team-a"}})
+		require.ErrorIs(t, err, ErrLookupTablesInvalid)
+
+		err = sut.UpdateLookupTables(ctx, orgID, definitions.LookupTables{"owners": {"": "team-a"}})
+		require.ErrorIs(t, err, ErrLookupTablesInvalid)
+	})
+}
@@ -113,6 +113,7 @@ type fakeRuleAccessControlService struct {
 	AuthorizeRuleChangesFunc       func(ctx context.Context, user identity.Requester, change *store.GroupDelta) error
 	CanReadAllRulesFunc            func(ctx context.Context, user identity.Requester) (bool, error)
 	CanWriteAllRulesFunc           func(ctx context.Context, user identity.Requester) (bool, error)
+	AuthorizeRecordingTargetFunc   func(ctx context.Context, user identity.Requester, datasourceUID string) error
 }
 
 func (s *fakeRuleAccessControlService) RecordCall(method string, args ...interface{}) {
@@ -167,6 +168,14 @@ func (s *fakeRuleAccessControlService) CanWriteAllRules(ctx context.Context, use
 	return false, nil
 }
 
+func (s *fakeRuleAccessControlService) AuthorizeRecordingTarget(ctx context.Context, user identity.Requester, datasourceUID string) error {
+	s.RecordCall("AuthorizeRecordingTarget", ctx, user, datasourceUID)
+	if s.AuthorizeRecordingTargetFunc != nil {
+		return s.AuthorizeRecordingTargetFunc(ctx, user, datasourceUID)
+	}
+	return nil
+}
+
 type fakeAlertRuleNotificationStore struct {
 	Calls []call
 
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/prometheus/alertmanager/api/v2/models"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -15,6 +16,8 @@ import (
 
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/dashboards"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
@@ -67,6 +70,7 @@ func newRuleFactory(
 	recordingWriter RecordingWriter,
 	evalAppliedHook evalAppliedFunc,
 	stopAppliedHook stopAppliedFunc,
+	debugger *ruleDebugger,
 ) ruleFactoryFunc {
 	return func(ctx context.Context, rule *ngmodels.AlertRule) Rule {
 		if rule.Type() == ngmodels.RuleTypeRecording {
@@ -100,6 +104,7 @@ func newRuleFactory(
 			tracer,
 			evalAppliedHook,
 			stopAppliedHook,
+			debugger,
 		)
 	}
 }
@@ -128,6 +133,9 @@ type alertRule struct {
 	evalAppliedHook evalAppliedFunc
 	stopAppliedHook stopAppliedFunc
 
+	// debugger records evaluations of the rule when it is in debug mode.
+	debugger *ruleDebugger
+
 	metrics *metrics.Scheduler
 	logger  log.Logger
 	tracer  tracing.Tracer
@@ -148,6 +156,7 @@ func newAlertRule(
 	tracer tracing.Tracer,
 	evalAppliedHook func(ngmodels.AlertRuleKey, time.Time),
 	stopAppliedHook func(ngmodels.AlertRuleKey),
+	debugger *ruleDebugger,
 ) *alertRule {
 	ctx, stop := util.WithCancelCause(ngmodels.WithRuleKey(parent, key.AlertRuleKey))
 	return &alertRule{
@@ -165,6 +174,7 @@ func newAlertRule(
 		evalFactory:          evalFactory,
 		evalAppliedHook:      evalAppliedHook,
 		stopAppliedHook:      stopAppliedHook,
+		debugger:             debugger,
 		metrics:              met,
 		logger:               logger.FromContext(ctx),
 		tracer:               tracer,
@@ -274,6 +284,7 @@ func (a *alertRule) Run() error {
 				defer func() {
 					evalDuration.Observe(a.clock.Now().Sub(evalStart).Seconds())
 					a.evalApplied(ctx.scheduledAt)
+					ctx.complete()
 				}()
 
 				for attempt := int64(1); attempt <= a.maxAttempts; attempt++ {
@@ -356,6 +367,9 @@ func (a *alertRule) Run() error {
 				// the evaluation loop is that the rule was deleted.
 				stateTransitions := a.stateManager.DeleteStateByRuleUID(ngmodels.WithRuleKey(ctx, a.key.AlertRuleKey), a.key, ngmodels.StateReasonRuleDeleted)
 				a.expireAndSend(grafanaCtx, stateTransitions)
+			} else if errors.Is(reason, errRuleNotOwned) {
+				// Another instance evaluates the rule now, so it has to continue from the current state.
+				a.stateManager.HandOffStateByRuleUID(ngmodels.WithRuleKey(ctx, a.key.AlertRuleKey), a.key)
 			} else {
 				// Otherwise, just clean up the cache.
 				a.stateManager.ForgetStateByRuleUID(ngmodels.WithRuleKey(ctx, a.key.AlertRuleKey), a.key)
@@ -377,20 +391,43 @@ func (a *alertRule) evaluate(ctx context.Context, e *Evaluation, span trace.Span
 
 	start := a.clock.Now()
 
+	// If the rule is in debug mode, the evaluation is recorded once it is complete, including retried attempts.
+	var evalTrace *EvaluationTrace
+	if ring := a.debugger.ring(a.key.AlertRuleKey); ring != nil {
+		evalTrace = &EvaluationTrace{ScheduledAt: e.scheduledAt}
+		defer ring.add(evalTrace)
+	}
+
 	evalCtx := eval.NewContextWithPreviousResults(ctx, SchedulerUserFor(e.rule.OrgID), a.newLoadedMetricsReader(e.rule))
-	ruleEval, err := a.evalFactory.Create(evalCtx, e.rule.GetEvalCondition().WithSource("scheduler").WithFolder(e.folderTitle))
+	condition := e.rule.GetEvalCondition().WithSource("scheduler").WithFolder(e.folderTitle)
+	ruleEval, err := a.evalFactory.Create(evalCtx, condition)
 	var results eval.Results
 	var dur time.Duration
 	if err != nil {
 		dur = a.clock.Now().Sub(start)
 		logger.Error("Failed to build rule evaluator", "error", err)
 	} else {
-		results, err = ruleEval.Evaluate(ctx, e.scheduledAt)
+		if evalTrace != nil {
+			// Evaluate the raw response to keep the frames returned by every query and expression.
+			var resp *backend.QueryDataResponse
+			resp, err = ruleEval.EvaluateRaw(ctx, e.scheduledAt)
+			if err == nil {
+				evalTrace.Responses = resp.Responses
+				results = eval.EvaluateAlert(resp, condition, e.scheduledAt)
+			}
+		} else {
+			results, err = ruleEval.Evaluate(ctx, e.scheduledAt)
+		}
 		dur = a.clock.Now().Sub(start)
 		if err != nil {
 			logger.Error("Failed to evaluate rule", "error", err, "duration", dur)
 		}
 	}
+	if evalTrace != nil {
+		evalTrace.Duration = dur
+		evalTrace.Error = err
+		evalTrace.Results = results
+	}
 
 	evalAttemptTotal.Inc()
 
@@ -407,7 +444,7 @@ func (a *alertRule) evaluate(ctx context.Context, e *Evaluation, span trace.Span
 		if retry {
 			// The only thing that can return non-nil `err` from ruleEval.Evaluate is the server side expression pipeline.
 			// This includes transport errors such as transient network errors.
-			if err != nil {
+			if err != nil && !eval.IsNonRetryableError(err) {
 				span.SetStatus(codes.Error, "rule evaluation failed")
 				span.RecordError(err)
 				return fmt.Errorf("server side expressions pipeline returned an error: %w", err)
@@ -443,23 +480,34 @@ func (a *alertRule) evaluate(ctx context.Context, e *Evaluation, span trace.Span
 			attribute.Int64("results", int64(len(results))),
 		))
 	}
+	var send state.Sender = func(ctx context.Context, statesToSend state.StateTransitions) {
+		start := a.clock.Now()
+		alerts := a.send(ctx, logger, statesToSend)
+		if evalTrace != nil {
+			evalTrace.SentAlerts = append(evalTrace.SentAlerts, alerts)
+		}
+		span.AddEvent("results sent", trace.WithAttributes(
+			attribute.Int64("alerts_sent", int64(len(alerts.PostableAlerts))),
+		))
+		sendDuration.Observe(a.clock.Now().Sub(start).Seconds())
+	}
+	if e.catchUp {
+		// Alerts of missed evaluations are outdated. They are sent by the scheduled evaluation if they are still relevant.
+		send = nil
+	}
 	start = a.clock.Now()
-	_ = a.stateManager.ProcessEvalResults(
+	transitions := a.stateManager.ProcessEvalResults(
 		ctx,
 		e.scheduledAt,
 		e.rule,
 		results,
 		state.GetRuleExtraLabels(logger, e.rule, e.folderTitle, !a.disableGrafanaFolder),
-		func(ctx context.Context, statesToSend state.StateTransitions) {
-			start := a.clock.Now()
-			alerts := a.send(ctx, logger, statesToSend)
-			span.AddEvent("results sent", trace.WithAttributes(
-				attribute.Int64("alerts_sent", int64(len(alerts.PostableAlerts))),
-			))
-			sendDuration.Observe(a.clock.Now().Sub(start).Seconds())
-		},
+		send,
 	)
 	processDuration.Observe(a.clock.Now().Sub(start).Seconds())
+	if evalTrace != nil {
+		evalTrace.Transitions = transitions
+	}
 
 	return nil
 }
@@ -490,6 +538,9 @@ func (a *alertRule) resetState(ctx context.Context, rule *ngmodels.AlertRule, is
 	reason := ngmodels.StateReasonUpdated
 	if isPaused {
 		reason = ngmodels.StateReasonPaused
+		if rule.Metadata.PausedUntil != nil {
+			reason = ngmodels.StateReasonPausedUntil
+		}
 	}
 	states := a.stateManager.ResetStateByRuleUID(ctx, rule, reason)
 	a.expireAndSend(ctx, states)
@@ -513,6 +564,8 @@ func (a *alertRule) stopApplied() {
 	a.stopAppliedHook(a.key.AlertRuleKey)
 }
 
+// SchedulerUserFor returns the identity of the scheduler in the organization. It can query all datasources, and read
+// all rules, which also allows it to read the state history of the rules when alert instances are restored.
 func SchedulerUserFor(orgID int64) *user.SignedInUser {
 	return &user.SignedInUser{
 		UserID:           -1,
@@ -528,6 +581,12 @@ func SchedulerUserFor(orgID int64) *user.SignedInUser {
 				datasources.ActionRead: []string{
 					datasources.ScopeAll,
 				},
+				accesscontrol.ActionAlertingRuleRead: []string{
+					dashboards.ScopeFoldersProvider.GetResourceAllScope(),
+				},
+				dashboards.ActionFoldersRead: []string{
+					dashboards.ScopeFoldersProvider.GetResourceAllScope(),
+				},
 			},
 		},
 	}
@@ -284,7 +284,7 @@ func blankRuleForTests(ctx context.Context, key models.AlertRuleKeyWithGroup) *a
 		Log:       log.NewNopLogger(),
 	}
 	st := state.NewManager(managerCfg, state.NewNoopPersister())
-	return newAlertRule(ctx, key, nil, false, 0, nil, st, nil, nil, nil, log.NewNopLogger(), nil, nil, nil)
+	return newAlertRule(ctx, key, nil, false, 0, nil, st, nil, nil, nil, log.NewNopLogger(), nil, nil, nil, nil)
 }
 
 func TestRuleRoutine(t *testing.T) {
@@ -813,6 +813,46 @@ func TestRuleRoutine(t *testing.T) {
 
 			require.Len(t, args.PostableAlerts, 1)
 		})
+
+		t.Run("it should not call sender when catching up a missed evaluation", func(t *testing.T) {
+			rule := gen.With(withQueryForState(t, eval.Alerting)).GenerateRef()
+
+			evalAppliedChan := make(chan time.Time)
+
+			sender := NewSyncAlertsSenderMock()
+			sender.EXPECT().Send(mock.Anything, rule.GetKey(), mock.Anything).Return()
+
+			sch, ruleStore, _, _ := createSchedule(evalAppliedChan, sender)
+			ruleStore.PutRule(context.Background(), rule)
+			factory := ruleFactoryFromScheduler(sch)
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			ruleInfo := factory.new(ctx, rule)
+
+			go func() {
+				_ = ruleInfo.Run()
+			}()
+
+			ruleInfo.Eval(&Evaluation{
+				scheduledAt: sch.clock.Now(),
+				rule:        rule,
+				catchUp:     true,
+			})
+
+			waitForTimeChannel(t, evalAppliedChan)
+
+			sender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+			require.NotEmpty(t, sch.stateManager.GetStatesForRuleUID(rule.OrgID, rule.UID))
+
+			ruleInfo.Eval(&Evaluation{
+				scheduledAt: sch.clock.Now().Add(time.Duration(rule.IntervalSeconds) * time.Second),
+				rule:        rule,
+			})
+
+			waitForTimeChannel(t, evalAppliedChan)
+
+			sender.AssertNumberOfCalls(t, "Send", 1)
+		})
 	})
 
 	t.Run("when there are no alerts to send it should not call notifiers", func(t *testing.T) {
@@ -847,7 +887,7 @@ func TestRuleRoutine(t *testing.T) {
 	})
 
 	t.Run("when there are resolved alerts they should keep sending until retention period is over", func(t *testing.T) {
-		rule := gen.With(withQueryForState(t, eval.Normal), models.RuleMuts.WithInterval(time.Second)).GenerateRef()
+		rule := gen.With(withQueryForState(t, eval.Normal), models.RuleMuts.WithInterval(time.Second), models.RuleMuts.WithKeepFiringFor(0)).GenerateRef()
 
 		evalAppliedChan := make(chan time.Time)
 
@@ -904,7 +944,7 @@ func TestRuleRoutine(t *testing.T) {
 }
 
 func ruleFactoryFromScheduler(sch *schedule) ruleFactory {
-	return newRuleFactory(sch.appURL, sch.disableGrafanaFolder, sch.maxAttempts, sch.alertsSender, sch.stateManager, sch.evaluatorFactory, sch.clock, sch.rrCfg, sch.metrics, sch.log, sch.tracer, sch.recordingWriter, sch.evalAppliedFunc, sch.stopAppliedFunc)
+	return newRuleFactory(sch.appURL, sch.disableGrafanaFolder, sch.maxAttempts, sch.alertsSender, sch.stateManager, sch.evaluatorFactory, sch.clock, sch.rrCfg, sch.metrics, sch.log, sch.tracer, sch.recordingWriter, sch.evalAppliedFunc, sch.stopAppliedFunc, sch.debugger)
 }
 
 func stateForRule(rule *models.AlertRule, ts time.Time, evalState eval.State) *state.State {
@@ -0,0 +1,77 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// missedEvaluations returns the timestamps of evaluations that should have happened between the last evaluation and
+// the scheduled one, oldest first, and the total number of missed evaluations. Only evaluations within maxLookback
+// before the scheduled one are returned.
+func missedEvaluations(lastEvaluation, scheduledAt time.Time, interval, maxLookback time.Duration) ([]time.Time, int) {
+	if lastEvaluation.IsZero() || interval <= 0 || !lastEvaluation.Before(scheduledAt) {
+		return nil, 0
+	}
+	total := int((scheduledAt.Sub(lastEvaluation) - 1) / interval)
+	earliest := scheduledAt.Add(-maxLookback)
+	var missed []time.Time
+	for ts := scheduledAt.Add(-interval); ts.After(lastEvaluation) && !ts.Before(earliest); ts = ts.Add(-interval) {
+		missed = append(missed, ts)
+	}
+	slices.Reverse(missed)
+	return missed, total
+}
+
+// missedEvaluationsOf returns the timestamps of evaluations of the rule that should be caught up before the scheduled evaluation.
+// Only alert rules are caught up because their last evaluation time is restored from the state after a restart.
+func (sch *schedule) missedEvaluationsOf(item readyToRunItem) []time.Time {
+	if sch.catchUpMaxLookback <= 0 || item.rule.IsPaused || item.rule.Type() != ngmodels.RuleTypeAlerting {
+		return nil
+	}
+	lastEvaluation := item.ruleRoutine.Status().EvaluationTimestamp
+	interval := time.Duration(item.rule.IntervalSeconds) * time.Second
+	missed, total := missedEvaluations(lastEvaluation, item.scheduledAt, interval, sch.catchUpMaxLookback)
+	if total > 0 {
+		sch.metrics.CatchUpMissed.WithLabelValues(fmt.Sprint(item.rule.OrgID)).Add(float64(total))
+		sch.log.Debug("Detected missed evaluations of the rule", append(item.rule.GetKey().LogContext(), "lastEvaluation", lastEvaluation, "missed", total, "catchUp", len(missed))...)
+	}
+	return missed
+}
+
+// catchUp evaluates the missed evaluations of the rule and then sends the scheduled evaluation.
+func (sch *schedule) catchUp(ctx context.Context, item readyToRunItem, missed []time.Time) {
+	sch.evaluateMissed(ctx, item, missed)
+	if ctx.Err() == nil && item.ruleRoutine.Status().EvaluationTimestamp.Before(item.scheduledAt) {
+		sch.sendEvaluation(item.ruleRoutine, &item.Evaluation)
+	}
+}
+
+// evaluateMissed evaluates the rule at the missed timestamps in order, waiting for each evaluation to complete.
+// Alerts of these evaluations are not sent. It stops if the rule is evaluated at a later time in the meantime,
+// e.g. by the next tick, or if an evaluation does not complete within the interval of the rule.
+func (sch *schedule) evaluateMissed(ctx context.Context, item readyToRunItem, missed []time.Time) {
+	recovered := sch.metrics.CatchUpRecovered.WithLabelValues(fmt.Sprint(item.rule.OrgID))
+	for _, ts := range missed {
+		if !item.ruleRoutine.Status().EvaluationTimestamp.Before(ts) {
+			return
+		}
+		ev := item.Evaluation
+		ev.scheduledAt = ts
+		ev.done = make(chan struct{})
+		ev.catchUp = true
+		if !sch.sendEvaluation(item.ruleRoutine, &ev) {
+			return
+		}
+		if !sch.waitEvaluation(ctx, &ev) {
+			return
+		}
+		// The evaluation could have been dropped in favor of a newer one.
+		if !item.ruleRoutine.Status().EvaluationTimestamp.Before(ts) {
+			recovered.Inc()
+		}
+	}
+}
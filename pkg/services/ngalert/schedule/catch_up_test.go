@@ -0,0 +1,66 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissedEvaluations(t *testing.T) {
+	scheduledAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	interval := time.Minute
+
+	testCases := []struct {
+		name           string
+		lastEvaluation time.Time
+		maxLookback    time.Duration
+		expected       []time.Time
+		expectedTotal  int
+	}{
+		{
+			name:           "never evaluated",
+			lastEvaluation: time.Time{},
+			maxLookback:    time.Hour,
+		},
+		{
+			name:           "evaluated at the previous interval",
+			lastEvaluation: scheduledAt.Add(-interval),
+			maxLookback:    time.Hour,
+		},
+		{
+			name:           "evaluated in the future",
+			lastEvaluation: scheduledAt.Add(interval),
+			maxLookback:    time.Hour,
+		},
+		{
+			name:           "missed evaluations are returned oldest first",
+			lastEvaluation: scheduledAt.Add(-4 * interval),
+			maxLookback:    time.Hour,
+			expected:       []time.Time{scheduledAt.Add(-3 * interval), scheduledAt.Add(-2 * interval), scheduledAt.Add(-interval)},
+			expectedTotal:  3,
+		},
+		{
+			name:           "last evaluation is not aligned with the schedule",
+			lastEvaluation: scheduledAt.Add(-2*interval - 10*time.Second),
+			maxLookback:    time.Hour,
+			expected:       []time.Time{scheduledAt.Add(-2 * interval), scheduledAt.Add(-interval)},
+			expectedTotal:  2,
+		},
+		{
+			name:           "only evaluations within lookback are returned",
+			lastEvaluation: scheduledAt.Add(-10 * interval),
+			maxLookback:    2 * interval,
+			expected:       []time.Time{scheduledAt.Add(-2 * interval), scheduledAt.Add(-interval)},
+			expectedTotal:  9,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			missed, total := missedEvaluations(tc.lastEvaluation, scheduledAt, interval, tc.maxLookback)
+			assert.Equal(t, tc.expected, missed)
+			assert.Equal(t, tc.expectedTotal, total)
+		})
+	}
+}
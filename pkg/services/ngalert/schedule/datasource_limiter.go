@@ -0,0 +1,270 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	limitReasonThrottled    = "throttled"
+	limitReasonQueryTimeout = "query_timeout"
+)
+
+// DatasourceLimit limits evaluations of rules that query a datasource.
+type DatasourceLimit struct {
+	// MaxConcurrentEvaluations is the maximum number of evaluations that query the datasource at the same time. Zero means no limit.
+	MaxConcurrentEvaluations int
+	// MaxQueueTime is how long an evaluation waits for the concurrency limit before it is skipped.
+	// Zero means that it waits until the evaluation is canceled.
+	MaxQueueTime time.Duration
+	// QueryTimeout is the maximum duration of evaluations that query the datasource. Zero means no timeout other than the evaluation timeout.
+	QueryTimeout time.Duration
+}
+
+func (l DatasourceLimit) isZero() bool {
+	return l.MaxConcurrentEvaluations <= 0 && l.QueryTimeout <= 0
+}
+
+// DatasourceLimits configures the limits of datasources by their UID.
+type DatasourceLimits struct {
+	// Default is applied to datasources that do not have their own limit.
+	Default     DatasourceLimit
+	Datasources map[string]DatasourceLimit
+}
+
+// IsEmpty returns true if no datasource is limited.
+func (l DatasourceLimits) IsEmpty() bool {
+	if !l.Default.isZero() {
+		return false
+	}
+	for _, limit := range l.Datasources {
+		if !limit.isZero() {
+			return false
+		}
+	}
+	return true
+}
+
+func (l DatasourceLimits) limitFor(uid string) DatasourceLimit {
+	if limit, ok := l.Datasources[uid]; ok {
+		return limit
+	}
+	return l.Default
+}
+
+// DatasourceLimitsFrom returns the DatasourceLimits configured in the settings.
+func DatasourceLimitsFrom(cfg setting.UnifiedAlertingSettings) DatasourceLimits {
+	toLimit := func(s setting.DatasourceEvaluationLimit) DatasourceLimit {
+		return DatasourceLimit{
+			MaxConcurrentEvaluations: s.MaxConcurrentEvaluations,
+			MaxQueueTime:             s.MaxQueueTime,
+			QueryTimeout:             s.QueryTimeout,
+		}
+	}
+	limits := DatasourceLimits{
+		Default:     toLimit(cfg.DatasourceEvaluationLimits.Default),
+		Datasources: make(map[string]DatasourceLimit, len(cfg.DatasourceEvaluationLimits.Datasources)),
+	}
+	for uid, l := range cfg.DatasourceEvaluationLimits.Datasources {
+		limits.Datasources[uid] = toLimit(l)
+	}
+	return limits
+}
+
+// datasourceLimiter applies DatasourceLimits to evaluations of rules.
+type datasourceLimiter struct {
+	limits  DatasourceLimits
+	clock   clock.Clock
+	metrics *metrics.Scheduler
+
+	mtx   sync.Mutex
+	slots map[string]chan struct{}
+}
+
+func newDatasourceLimiter(limits DatasourceLimits, clk clock.Clock, m *metrics.Scheduler) *datasourceLimiter {
+	return &datasourceLimiter{
+		limits:  limits,
+		clock:   clk,
+		metrics: m,
+		slots:   make(map[string]chan struct{}),
+	}
+}
+
+func (l *datasourceLimiter) semaphore(uid string, size int) chan struct{} {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	sem, ok := l.slots[uid]
+	if !ok {
+		sem = make(chan struct{}, size)
+		l.slots[uid] = sem
+	}
+	return sem
+}
+
+// acquire takes a slot of every datasource with a concurrency limit. Datasources must be sorted to avoid deadlocks
+// between evaluations that query the same datasources. It returns eval.ErrDatasourceThrottled if a slot is not available within MaxQueueTime.
+func (l *datasourceLimiter) acquire(ctx context.Context, uids []string) (func(), error) {
+	var acquired []chan struct{}
+	release := func() {
+		for _, sem := range acquired {
+			<-sem
+		}
+	}
+	for _, uid := range uids {
+		limit := l.limits.limitFor(uid)
+		if limit.MaxConcurrentEvaluations <= 0 {
+			continue
+		}
+		sem := l.semaphore(uid, limit.MaxConcurrentEvaluations)
+		select {
+		case sem <- struct{}{}:
+			acquired = append(acquired, sem)
+			continue
+		default:
+		}
+
+		queueLength := l.metrics.DatasourceQueueLength.WithLabelValues(uid)
+		queueLength.Inc()
+		start := l.clock.Now()
+		ok := wait(ctx, sem, limit.MaxQueueTime)
+		queueLength.Dec()
+		l.metrics.DatasourceQueueDuration.WithLabelValues(uid).Observe(l.clock.Since(start).Seconds())
+		if !ok {
+			release()
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			l.metrics.DatasourceEvaluationsLimited.WithLabelValues(uid, limitReasonThrottled).Inc()
+			return nil, fmt.Errorf("%w: datasource %s, waited %s", eval.ErrDatasourceThrottled, uid, limit.MaxQueueTime)
+		}
+		acquired = append(acquired, sem)
+	}
+	return release, nil
+}
+
+// wait takes a slot of the semaphore. It returns false if the context is canceled or the slot is not available within maxWait.
+func wait(ctx context.Context, sem chan struct{}, maxWait time.Duration) bool {
+	if maxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, maxWait)
+		defer cancel()
+	}
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// queryTimeout returns the smallest query timeout of the datasources and the datasource it belongs to.
+func (l *datasourceLimiter) queryTimeout(uids []string) (time.Duration, string) {
+	var timeout time.Duration
+	var timeoutUID string
+	for _, uid := range uids {
+		limit := l.limits.limitFor(uid)
+		if limit.QueryTimeout > 0 && (timeout == 0 || limit.QueryTimeout < timeout) {
+			timeout = limit.QueryTimeout
+			timeoutUID = uid
+		}
+	}
+	return timeout, timeoutUID
+}
+
+// do runs fn within the limits of the datasources.
+func (l *datasourceLimiter) do(ctx context.Context, uids []string, fn func(ctx context.Context) error) error {
+	release, err := l.acquire(ctx, uids)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	timeout, uid := l.queryTimeout(uids)
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err = fn(timeoutCtx)
+	if ctx.Err() == nil && errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+		l.metrics.DatasourceEvaluationsLimited.WithLabelValues(uid, limitReasonQueryTimeout).Inc()
+		return fmt.Errorf("%w: datasource %s, timeout %s", eval.ErrDatasourceQueryTimeout, uid, timeout)
+	}
+	return err
+}
+
+// datasourceUIDs returns the sorted unique UIDs of datasources queried by the condition.
+func datasourceUIDs(condition ngmodels.Condition) []string {
+	uids := make([]string, 0, len(condition.Data))
+	for _, q := range condition.Data {
+		if expr.NodeTypeFromDatasourceUID(q.DatasourceUID) != expr.TypeDatasourceNode {
+			continue
+		}
+		uids = append(uids, q.DatasourceUID)
+	}
+	slices.Sort(uids)
+	return slices.Compact(uids)
+}
+
+// limitedEvaluatorFactory creates evaluators that apply the limits of the datasources the condition queries.
+type limitedEvaluatorFactory struct {
+	factory eval.EvaluatorFactory
+	limiter *datasourceLimiter
+}
+
+func (f limitedEvaluatorFactory) Create(ctx eval.EvaluationContext, condition ngmodels.Condition) (eval.ConditionEvaluator, error) {
+	evaluator, err := f.factory.Create(ctx, condition)
+	if err != nil {
+		return nil, err
+	}
+	uids := datasourceUIDs(condition)
+	if len(uids) == 0 {
+		return evaluator, nil
+	}
+	return &limitedConditionEvaluator{evaluator: evaluator, limiter: f.limiter, datasourceUIDs: uids}, nil
+}
+
+type limitedConditionEvaluator struct {
+	evaluator      eval.ConditionEvaluator
+	limiter        *datasourceLimiter
+	datasourceUIDs []string
+}
+
+func (e *limitedConditionEvaluator) EvaluateRaw(ctx context.Context, now time.Time) (*backend.QueryDataResponse, error) {
+	var resp *backend.QueryDataResponse
+	err := e.limiter.do(ctx, e.datasourceUIDs, func(ctx context.Context) error {
+		var err error
+		resp, err = e.evaluator.EvaluateRaw(ctx, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (e *limitedConditionEvaluator) Evaluate(ctx context.Context, now time.Time) (eval.Results, error) {
+	var results eval.Results
+	err := e.limiter.do(ctx, e.datasourceUIDs, func(ctx context.Context) error {
+		var err error
+		results, err = e.evaluator.Evaluate(ctx, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
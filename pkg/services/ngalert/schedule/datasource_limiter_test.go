@@ -0,0 +1,173 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval/eval_mocks"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestDatasourceLimiter(t *testing.T) {
+	newLimiter := func(limits DatasourceLimits) *datasourceLimiter {
+		return newDatasourceLimiter(limits, clock.New(), metrics.NewSchedulerMetrics(prometheus.NewPedanticRegistry()))
+	}
+	noop := func(ctx context.Context) error { return nil }
+
+	t.Run("should skip evaluation if datasource is at the concurrency limit for longer than the queue time", func(t *testing.T) {
+		l := newLimiter(DatasourceLimits{
+			Datasources: map[string]DatasourceLimit{"ds": {MaxConcurrentEvaluations: 1, MaxQueueTime: 10 * time.Millisecond}},
+		})
+		release, err := l.acquire(context.Background(), []string{"ds"})
+		require.NoError(t, err)
+
+		err = l.do(context.Background(), []string{"ds"}, noop)
+		require.ErrorIs(t, err, eval.ErrDatasourceThrottled)
+		assert.Equal(t, 1.0, testutil.ToFloat64(l.metrics.DatasourceEvaluationsLimited.WithLabelValues("ds", limitReasonThrottled)))
+		assert.Equal(t, 0.0, testutil.ToFloat64(l.metrics.DatasourceQueueLength.WithLabelValues("ds")))
+
+		release()
+		require.NoError(t, l.do(context.Background(), []string{"ds"}, noop))
+	})
+
+	t.Run("should wait for a free slot", func(t *testing.T) {
+		l := newLimiter(DatasourceLimits{
+			Default: DatasourceLimit{MaxConcurrentEvaluations: 1},
+		})
+		release, err := l.acquire(context.Background(), []string{"ds"})
+		require.NoError(t, err)
+
+		done := make(chan error)
+		go func() {
+			done <- l.do(context.Background(), []string{"ds"}, noop)
+		}()
+		require.Eventually(t, func() bool {
+			return testutil.ToFloat64(l.metrics.DatasourceQueueLength.WithLabelValues("ds")) == 1
+		}, time.Second, time.Millisecond)
+
+		release()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("should release acquired slots if evaluation is skipped", func(t *testing.T) {
+		l := newLimiter(DatasourceLimits{
+			Datasources: map[string]DatasourceLimit{
+				"a": {MaxConcurrentEvaluations: 1},
+				"b": {MaxConcurrentEvaluations: 1, MaxQueueTime: time.Millisecond},
+			},
+		})
+		_, err := l.acquire(context.Background(), []string{"b"})
+		require.NoError(t, err)
+
+		err = l.do(context.Background(), []string{"a", "b"}, noop)
+		require.ErrorIs(t, err, eval.ErrDatasourceThrottled)
+
+		require.NoError(t, l.do(context.Background(), []string{"a"}, noop))
+	})
+
+	t.Run("should return context error if canceled while waiting", func(t *testing.T) {
+		l := newLimiter(DatasourceLimits{
+			Default: DatasourceLimit{MaxConcurrentEvaluations: 1},
+		})
+		_, err := l.acquire(context.Background(), []string{"ds"})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err = l.do(ctx, []string{"ds"}, noop)
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 0.0, testutil.ToFloat64(l.metrics.DatasourceEvaluationsLimited.WithLabelValues("ds", limitReasonThrottled)))
+	})
+
+	t.Run("should apply the smallest query timeout", func(t *testing.T) {
+		l := newLimiter(DatasourceLimits{
+			Default:     DatasourceLimit{QueryTimeout: time.Hour},
+			Datasources: map[string]DatasourceLimit{"slow": {QueryTimeout: 10 * time.Millisecond}},
+		})
+
+		err := l.do(context.Background(), []string{"fast", "slow"}, func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		require.ErrorIs(t, err, eval.ErrDatasourceQueryTimeout)
+		assert.Equal(t, 1.0, testutil.ToFloat64(l.metrics.DatasourceEvaluationsLimited.WithLabelValues("slow", limitReasonQueryTimeout)))
+	})
+
+	t.Run("should return error of the evaluation if it completes in time", func(t *testing.T) {
+		l := newLimiter(DatasourceLimits{Default: DatasourceLimit{QueryTimeout: time.Hour}})
+		expectedErr := errors.New("test")
+
+		err := l.do(context.Background(), []string{"ds"}, func(ctx context.Context) error {
+			return expectedErr
+		})
+		require.ErrorIs(t, err, expectedErr)
+		require.NotErrorIs(t, err, eval.ErrDatasourceQueryTimeout)
+	})
+}
+
+func TestDatasourceLimits(t *testing.T) {
+	assert.True(t, DatasourceLimits{}.IsEmpty())
+	assert.True(t, DatasourceLimits{Datasources: map[string]DatasourceLimit{"ds": {MaxQueueTime: time.Second}}}.IsEmpty())
+	assert.False(t, DatasourceLimits{Default: DatasourceLimit{QueryTimeout: time.Second}}.IsEmpty())
+	assert.False(t, DatasourceLimits{Datasources: map[string]DatasourceLimit{"ds": {MaxConcurrentEvaluations: 1}}}.IsEmpty())
+}
+
+func TestDatasourceUIDs(t *testing.T) {
+	condition := ngmodels.Condition{
+		Data: []ngmodels.AlertQuery{
+			{RefID: "A", DatasourceUID: "b"},
+			{RefID: "B", DatasourceUID: "a"},
+			{RefID: "C", DatasourceUID: "b"},
+			{RefID: "D", DatasourceUID: expr.DatasourceUID},
+		},
+	}
+	assert.Equal(t, []string{"a", "b"}, datasourceUIDs(condition))
+}
+
+func TestLimitedEvaluatorFactory(t *testing.T) {
+	now := time.Now()
+	limiter := newDatasourceLimiter(DatasourceLimits{
+		Default: DatasourceLimit{MaxConcurrentEvaluations: 1, MaxQueueTime: time.Millisecond},
+	}, clock.New(), metrics.NewSchedulerMetrics(prometheus.NewPedanticRegistry()))
+
+	m := &eval_mocks.ConditionEvaluatorMock{}
+	m.EXPECT().Evaluate(mock.Anything, now).Return(eval.Results{{State: eval.Normal}}, nil)
+	factory := limitedEvaluatorFactory{factory: eval_mocks.NewEvaluatorFactory(m), limiter: limiter}
+
+	t.Run("should not limit conditions without datasource queries", func(t *testing.T) {
+		evaluator, err := factory.Create(eval.EvaluationContext{}, ngmodels.Condition{
+			Data: []ngmodels.AlertQuery{{RefID: "A", DatasourceUID: expr.DatasourceUID}},
+		})
+		require.NoError(t, err)
+		assert.Same(t, m, evaluator)
+	})
+
+	t.Run("should limit conditions with datasource queries", func(t *testing.T) {
+		evaluator, err := factory.Create(eval.EvaluationContext{}, ngmodels.Condition{
+			Data: []ngmodels.AlertQuery{{RefID: "A", DatasourceUID: "ds"}},
+		})
+		require.NoError(t, err)
+
+		results, err := evaluator.Evaluate(context.Background(), now)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+
+		release, err := limiter.acquire(context.Background(), []string{"ds"})
+		require.NoError(t, err)
+		defer release()
+		_, err = evaluator.Evaluate(context.Background(), now)
+		require.ErrorIs(t, err, eval.ErrDatasourceThrottled)
+	})
+}
@@ -0,0 +1,132 @@
+package schedule
+
+import (
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+)
+
+const (
+	// DefaultDebugEvaluations is the number of evaluations recorded for a rule in debug mode if no other number is requested.
+	DefaultDebugEvaluations = 10
+	// MaxDebugEvaluations is the maximum number of evaluations recorded for a rule in debug mode.
+	MaxDebugEvaluations = 100
+)
+
+// EvaluationTrace is the record of an evaluation attempt of a rule in debug mode.
+type EvaluationTrace struct {
+	ScheduledAt time.Time
+	Duration    time.Duration
+	Error       error
+	// Responses contains the raw responses of queries and expressions by RefID.
+	Responses   backend.Responses
+	Results     eval.Results
+	Transitions state.StateTransitions
+	// SentAlerts contains the alerts of every call to the sender.
+	SentAlerts []definitions.PostableAlerts
+}
+
+// evaluationRing keeps the last evaluations of a rule, up to its size.
+type evaluationRing struct {
+	mtx    sync.Mutex
+	traces []*EvaluationTrace
+	next   int
+	size   int
+}
+
+func newEvaluationRing(size int) *evaluationRing {
+	return &evaluationRing{
+		traces: make([]*EvaluationTrace, 0, size),
+		size:   size,
+	}
+}
+
+func (r *evaluationRing) add(trace *EvaluationTrace) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if len(r.traces) < r.size {
+		r.traces = append(r.traces, trace)
+		return
+	}
+	r.traces[r.next] = trace
+	r.next = (r.next + 1) % r.size
+}
+
+// list returns the recorded evaluations, oldest first.
+func (r *evaluationRing) list() []EvaluationTrace {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	result := make([]EvaluationTrace, 0, len(r.traces))
+	for i := range r.traces {
+		result = append(result, *r.traces[(r.next+i)%len(r.traces)])
+	}
+	return result
+}
+
+// ruleDebugger records evaluations of rules that are in debug mode.
+type ruleDebugger struct {
+	mtx   sync.RWMutex
+	rings map[ngmodels.AlertRuleKey]*evaluationRing
+}
+
+func newRuleDebugger() *ruleDebugger {
+	return &ruleDebugger{rings: make(map[ngmodels.AlertRuleKey]*evaluationRing)}
+}
+
+// ring returns the ring of the rule, or nil if the rule is not in debug mode.
+func (d *ruleDebugger) ring(key ngmodels.AlertRuleKey) *evaluationRing {
+	if d == nil {
+		return nil
+	}
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+	return d.rings[key]
+}
+
+// enable starts recording the last evaluations of the rule. Evaluations recorded before are discarded.
+func (d *ruleDebugger) enable(key ngmodels.AlertRuleKey, size int) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.rings[key] = newEvaluationRing(size)
+}
+
+// disable stops recording evaluations of the rule and discards the recorded ones.
+func (d *ruleDebugger) disable(key ngmodels.AlertRuleKey) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	delete(d.rings, key)
+}
+
+// SetRuleDebug puts the rule in debug mode in which the scheduler records its last evaluations, or takes it out of it
+// if evaluations is zero. If evaluations is negative, DefaultDebugEvaluations is used.
+// The evaluations are recorded in memory of this instance only and are lost when the rule is deleted or Grafana restarts.
+func (sch *schedule) SetRuleDebug(key ngmodels.AlertRuleKey, evaluations int) {
+	switch {
+	case evaluations == 0:
+		sch.debugger.disable(key)
+		sch.log.Info("Rule debug mode disabled", key.LogContext()...)
+		return
+	case evaluations < 0:
+		evaluations = DefaultDebugEvaluations
+	case evaluations > MaxDebugEvaluations:
+		evaluations = MaxDebugEvaluations
+	}
+	sch.debugger.enable(key, evaluations)
+	sch.log.Info("Rule debug mode enabled", append(key.LogContext(), "evaluations", evaluations)...)
+}
+
+// RuleEvaluationTraces returns the recorded evaluations of the rule, oldest first.
+// The second value is false if the rule is not in debug mode.
+func (sch *schedule) RuleEvaluationTraces(key ngmodels.AlertRuleKey) ([]EvaluationTrace, bool) {
+	ring := sch.debugger.ring(key)
+	if ring == nil {
+		return nil, false
+	}
+	return ring.list(), true
+}
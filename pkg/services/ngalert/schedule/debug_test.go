@@ -0,0 +1,65 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestEvaluationRing(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	trace := func(i int) *EvaluationTrace {
+		return &EvaluationTrace{ScheduledAt: now.Add(time.Duration(i) * time.Minute)}
+	}
+	scheduledAt := func(traces []EvaluationTrace) []time.Time {
+		result := make([]time.Time, 0, len(traces))
+		for _, tr := range traces {
+			result = append(result, tr.ScheduledAt)
+		}
+		return result
+	}
+
+	t.Run("should return evaluations oldest first when not full", func(t *testing.T) {
+		ring := newEvaluationRing(3)
+		ring.add(trace(0))
+		ring.add(trace(1))
+
+		assert.Equal(t, []time.Time{now, now.Add(time.Minute)}, scheduledAt(ring.list()))
+	})
+
+	t.Run("should keep only the last evaluations", func(t *testing.T) {
+		ring := newEvaluationRing(3)
+		for i := 0; i < 5; i++ {
+			ring.add(trace(i))
+		}
+
+		assert.Equal(t, []time.Time{now.Add(2 * time.Minute), now.Add(3 * time.Minute), now.Add(4 * time.Minute)}, scheduledAt(ring.list()))
+	})
+}
+
+func TestSetRuleDebug(t *testing.T) {
+	sch := &schedule{log: log.NewNopLogger(), debugger: newRuleDebugger()}
+	key := models.GenerateRuleKey(1)
+
+	_, ok := sch.RuleEvaluationTraces(key)
+	require.False(t, ok)
+
+	sch.SetRuleDebug(key, -1)
+	require.Equal(t, DefaultDebugEvaluations, sch.debugger.ring(key).size)
+
+	sch.SetRuleDebug(key, MaxDebugEvaluations+1)
+	require.Equal(t, MaxDebugEvaluations, sch.debugger.ring(key).size)
+
+	traces, ok := sch.RuleEvaluationTraces(key)
+	require.True(t, ok)
+	require.Empty(t, traces)
+
+	sch.SetRuleDebug(key, 0)
+	_, ok = sch.RuleEvaluationTraces(key)
+	require.False(t, ok)
+}
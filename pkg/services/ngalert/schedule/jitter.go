@@ -1,11 +1,14 @@
 package schedule
 
 import (
+	"cmp"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
 	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
 	"github.com/grafana/grafana/pkg/setting"
 )
@@ -17,6 +20,9 @@ const (
 	JitterNever JitterStrategy = iota
 	JitterByGroup
 	JitterByRule
+	// JitterByLoad balances rules across ticks by their observed evaluation duration.
+	// Rules keep the offset of JitterByRule unless the tick is overloaded.
+	JitterByLoad
 )
 
 // JitterStrategyFrom returns the JitterStrategy indicated by the current Grafana feature toggles.
@@ -31,6 +37,9 @@ func JitterStrategyFrom(cfg setting.UnifiedAlertingSettings, toggles featuremgmt
 	if toggles.IsEnabledGlobally(featuremgmt.FlagJitterAlertRulesWithinGroups) {
 		strategy = JitterByRule
 	}
+	if toggles.IsEnabledGlobally(featuremgmt.FlagAlertingLoadAwareJitter) {
+		strategy = JitterByLoad
+	}
 	return strategy
 }
 
@@ -63,8 +72,205 @@ func jitterHash(r *ngmodels.AlertRule, strategy JitterStrategy) uint64 {
 		"orgId": fmt.Sprint(r.OrgID),
 	}
 
-	if strategy == JitterByRule {
+	if strategy == JitterByRule || strategy == JitterByLoad {
 		ls["uid"] = r.UID
 	}
 	return uint64(ls.Fingerprint())
 }
+
+const (
+	// loadJitterMaxHorizon is the maximum number of ticks whose load is tracked by loadJitter.
+	// Rules with a longer interval are accounted for proportionally to their frequency.
+	loadJitterMaxHorizon = 720
+	// loadJitterTolerance is how much the load of a tick can exceed the average before rules are moved to other ticks.
+	loadJitterTolerance = 0.2
+	// loadJitterRebalanceInterval is how often offsets are recalculated when the set of rules does not change.
+	loadJitterRebalanceInterval = time.Hour
+)
+
+// loadJitter assigns offsets to rules so that their evaluation duration is balanced across ticks.
+// Every rule starts with the offset of JitterByRule, which is stable across restarts, and is moved to another offset
+// only if its tick would be loaded more than the average by loadJitterTolerance.
+// A new offset of a rule is applied at the start of its next cycle, so that a rebalance does not skip
+// or duplicate evaluations of the rule in the current cycle.
+type loadJitter struct {
+	baseInterval time.Duration
+	metrics      *metrics.Scheduler
+
+	// offsets are the offsets the rules are evaluated at.
+	offsets map[ngmodels.AlertRuleKey]int64
+	// planned are the offsets calculated by the last rebalance.
+	planned       map[ngmodels.AlertRuleKey]int64
+	lastRebalance int64
+	balanced      bool
+}
+
+func newLoadJitter(baseInterval time.Duration, m *metrics.Scheduler) *loadJitter {
+	return &loadJitter{
+		baseInterval: baseInterval,
+		metrics:      m,
+		offsets:      make(map[ngmodels.AlertRuleKey]int64),
+		planned:      make(map[ngmodels.AlertRuleKey]int64),
+	}
+}
+
+func (j *loadJitter) frequency(r *ngmodels.AlertRule) int64 {
+	return max(r.IntervalSeconds/int64(j.baseInterval.Seconds()), 1)
+}
+
+// offset returns the offset of the rule in ticks. Rules that are not balanced yet get the offset of JitterByRule.
+func (j *loadJitter) offset(r *ngmodels.AlertRule) int64 {
+	if offset, ok := j.offsets[r.GetKey()]; ok && offset < j.frequency(r) {
+		return offset
+	}
+	return jitterOffsetInTicks(r, j.baseInterval, JitterByLoad)
+}
+
+// update rebalances the offsets if there are rules without an offset, rules whose interval changed,
+// or if the offsets were not rebalanced for loadJitterRebalanceInterval. Then it applies the planned offsets
+// of the rules whose cycle starts at the tick.
+func (j *loadJitter) update(tickNum int64, rules []*ngmodels.AlertRule, costOf func(ngmodels.AlertRuleKey) time.Duration) {
+	rebalanceTicks := int64(loadJitterRebalanceInterval / j.baseInterval)
+	if !j.balanced || tickNum-j.lastRebalance >= rebalanceTicks || j.hasUnbalanced(rules) {
+		j.rebalance(rules, costOf)
+		j.lastRebalance = tickNum
+		j.balanced = true
+	}
+	j.apply(tickNum, rules)
+}
+
+func (j *loadJitter) hasUnbalanced(rules []*ngmodels.AlertRule) bool {
+	for _, r := range rules {
+		if offset, ok := j.planned[r.GetKey()]; !ok || offset >= j.frequency(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply sets the offsets of the rules to the planned ones. Rules that are evaluated at a valid offset
+// change it only at the first tick of their cycle, other rules change it immediately.
+func (j *loadJitter) apply(tickNum int64, rules []*ngmodels.AlertRule) {
+	offsets := make(map[ngmodels.AlertRuleKey]int64, len(rules))
+	for _, r := range rules {
+		key := r.GetKey()
+		frequency := j.frequency(r)
+		current, ok := j.offsets[key]
+		if planned, isPlanned := j.planned[key]; isPlanned && (!ok || current >= frequency || tickNum%frequency == 0) {
+			current, ok = planned, true
+		}
+		if ok {
+			offsets[key] = current
+		}
+	}
+	j.offsets = offsets
+}
+
+type loadJitterItem struct {
+	key       ngmodels.AlertRuleKey
+	frequency int64
+	cost      float64
+	preferred int64
+}
+
+// rebalance plans offsets of the rules starting from the most expensive ones. A rule keeps its planned offset
+// if the load of its ticks stays within the tolerance, otherwise it gets the offset with the lowest peak load.
+func (j *loadJitter) rebalance(rules []*ngmodels.AlertRule, costOf func(ngmodels.AlertRuleKey) time.Duration) {
+	items := make([]loadJitterItem, 0, len(rules))
+	horizon := int64(1)
+	var knownCost float64
+	var known int
+	for _, r := range rules {
+		key := r.GetKey()
+		item := loadJitterItem{
+			key:       key,
+			frequency: j.frequency(r),
+			cost:      costOf(key).Seconds(),
+			preferred: j.offset(r),
+		}
+		if planned, ok := j.planned[key]; ok && planned < item.frequency {
+			item.preferred = planned
+		}
+		if item.cost > 0 {
+			knownCost += item.cost
+			known++
+		}
+		horizon = max(horizon, item.frequency)
+		items = append(items, item)
+	}
+	horizon = min(horizon, loadJitterMaxHorizon)
+
+	// Rules that were not evaluated yet are expected to cost as much as an average rule.
+	defaultCost := 1.0
+	if known > 0 {
+		defaultCost = knownCost / float64(known)
+	}
+	var average float64
+	for i := range items {
+		if items[i].cost <= 0 {
+			items[i].cost = defaultCost
+		}
+		average += items[i].cost / float64(items[i].frequency)
+	}
+	target := average * (1 + loadJitterTolerance)
+
+	slices.SortFunc(items, func(a, b loadJitterItem) int {
+		if c := cmp.Compare(b.cost, a.cost); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.key.String(), b.key.String())
+	})
+
+	load := make([]float64, horizon)
+	offsets := make(map[ngmodels.AlertRuleKey]int64, len(items))
+	moved := 0
+	for _, item := range items {
+		offset := item.preferred
+		if peak := peakLoad(load, item, offset); peak > target {
+			for candidate := int64(0); candidate < min(item.frequency, horizon); candidate++ {
+				if p := peakLoad(load, item, candidate); p < peak {
+					offset, peak = candidate, p
+				}
+			}
+		}
+		if previous, ok := j.planned[item.key]; ok && previous != offset {
+			moved++
+		}
+		addLoad(load, item, offset)
+		offsets[item.key] = offset
+	}
+	j.planned = offsets
+
+	if j.metrics == nil {
+		return
+	}
+	j.metrics.JitterRebalancedRules.Add(float64(moved))
+	if average > 0 {
+		j.metrics.TickLoadImbalance.Set(slices.Max(load) / average)
+	}
+}
+
+// loadPositions calls fn for every tick of the horizon the rule is evaluated at with the share of its cost.
+func loadPositions(horizon int64, item loadJitterItem, offset int64, fn func(pos int64, cost float64)) {
+	if item.frequency > horizon {
+		fn(offset%horizon, item.cost*float64(horizon)/float64(item.frequency))
+		return
+	}
+	for pos := offset; pos < horizon; pos += item.frequency {
+		fn(pos, item.cost)
+	}
+}
+
+func peakLoad(load []float64, item loadJitterItem, offset int64) float64 {
+	var peak float64
+	loadPositions(int64(len(load)), item, offset, func(pos int64, cost float64) {
+		peak = max(peak, load[pos]+cost)
+	})
+	return peak
+}
+
+func addLoad(load []float64, item loadJitterItem, offset int64) {
+	loadPositions(int64(len(load)), item, offset, func(pos int64, cost float64) {
+		load[pos] += cost
+	})
+}
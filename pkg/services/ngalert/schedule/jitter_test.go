@@ -93,3 +93,128 @@ func TestJitter(t *testing.T) {
 		})
 	})
 }
+
+func TestLoadJitter(t *testing.T) {
+	gen := ngmodels.RuleGen
+	baseInterval := 10 * time.Second
+	costs := func(rules []*ngmodels.AlertRule, cost func(i int) time.Duration) func(ngmodels.AlertRuleKey) time.Duration {
+		byKey := make(map[ngmodels.AlertRuleKey]time.Duration, len(rules))
+		for i, r := range rules {
+			byKey[r.GetKey()] = cost(i)
+		}
+		return func(key ngmodels.AlertRuleKey) time.Duration {
+			return byKey[key]
+		}
+	}
+
+	t.Run("offset is on the interval [0, interval/baseInterval)", func(t *testing.T) {
+		rules := gen.With(gen.WithIntervalBetween(10, 600)).GenerateManyRef(1000)
+		j := newLoadJitter(baseInterval, nil)
+		j.update(0, rules, costs(rules, func(i int) time.Duration { return time.Duration(i%7) * time.Second }))
+
+		for _, r := range rules {
+			offset := j.offset(r)
+			require.GreaterOrEqual(t, offset, int64(0))
+			require.Less(t, offset, r.IntervalSeconds/int64(baseInterval.Seconds()))
+		}
+	})
+
+	t.Run("rules that were not balanced get offset of JitterByRule", func(t *testing.T) {
+		rule := gen.With(gen.WithIntervalBetween(10, 600)).GenerateRef()
+		j := newLoadJitter(baseInterval, nil)
+		require.Equal(t, jitterOffsetInTicks(rule, baseInterval, JitterByRule), j.offset(rule))
+	})
+
+	t.Run("balances load of rules with the same cost", func(t *testing.T) {
+		rules := gen.With(gen.WithInterval(time.Minute)).GenerateManyRef(120)
+		j := newLoadJitter(baseInterval, nil)
+		j.update(0, rules, costs(rules, func(int) time.Duration { return time.Second }))
+
+		perTick := make(map[int64]int)
+		for _, r := range rules {
+			perTick[j.offset(r)]++
+		}
+		// 120 rules over 6 ticks is 20 rules per tick, with the tolerance of 20%
+		for offset, count := range perTick {
+			require.LessOrEqualf(t, count, 24, "tick %d has too many rules", offset)
+		}
+	})
+
+	t.Run("does not evaluate expensive rules in the same tick", func(t *testing.T) {
+		rules := gen.With(gen.WithInterval(time.Minute)).GenerateManyRef(12)
+		j := newLoadJitter(baseInterval, nil)
+		j.update(0, rules, costs(rules, func(i int) time.Duration {
+			if i < 2 {
+				return time.Minute
+			}
+			return time.Second
+		}))
+
+		require.NotEqual(t, j.offset(rules[0]), j.offset(rules[1]))
+	})
+
+	t.Run("offsets are stable", func(t *testing.T) {
+		rules := gen.With(gen.WithIntervalBetween(10, 600)).GenerateManyRef(500)
+		costOf := costs(rules, func(i int) time.Duration { return time.Duration(i%13) * time.Second })
+		j := newLoadJitter(baseInterval, nil)
+		j.update(0, rules, costOf)
+		expected := make(map[ngmodels.AlertRuleKey]int64, len(rules))
+		for _, r := range rules {
+			expected[r.GetKey()] = j.offset(r)
+		}
+
+		t.Run("when rebalanced with the same costs", func(t *testing.T) {
+			j.rebalance(rules, costOf)
+			for _, r := range rules {
+				require.Equal(t, expected[r.GetKey()], j.offset(r))
+			}
+		})
+
+		t.Run("after restart", func(t *testing.T) {
+			restarted := newLoadJitter(baseInterval, nil)
+			restarted.update(0, rules, costOf)
+			for _, r := range rules {
+				require.Equal(t, expected[r.GetKey()], restarted.offset(r))
+			}
+		})
+	})
+
+	t.Run("rebalances when rules are added or the interval passes", func(t *testing.T) {
+		rules := gen.With(gen.WithInterval(time.Minute)).GenerateManyRef(10)
+		costOf := costs(rules, func(int) time.Duration { return time.Second })
+		j := newLoadJitter(baseInterval, nil)
+		j.update(0, rules, costOf)
+		require.EqualValues(t, 0, j.lastRebalance)
+
+		j.update(1, rules, costOf)
+		require.EqualValues(t, 0, j.lastRebalance)
+
+		added := append(rules, gen.With(gen.WithInterval(time.Minute)).GenerateRef())
+		j.update(2, added, costOf)
+		require.EqualValues(t, 2, j.lastRebalance)
+		require.Contains(t, j.offsets, added[len(added)-1].GetKey())
+
+		rebalanceTicks := int64(loadJitterRebalanceInterval / baseInterval)
+		j.update(2+rebalanceTicks, added, costOf)
+		require.Equal(t, 2+rebalanceTicks, j.lastRebalance)
+	})
+
+	t.Run("applies new offsets at the start of the cycle of the rule", func(t *testing.T) {
+		rule := gen.With(gen.WithInterval(time.Minute)).GenerateRef()
+		rules := []*ngmodels.AlertRule{rule}
+		costOf := costs(rules, func(int) time.Duration { return time.Second })
+		frequency := int64(time.Minute / baseInterval)
+		j := newLoadJitter(baseInterval, nil)
+		j.update(0, rules, costOf)
+		current := j.offset(rule)
+
+		next := (current + 1) % frequency
+		j.planned[rule.GetKey()] = next
+		for tick := int64(1); tick < frequency; tick++ {
+			j.update(tick, rules, costOf)
+			require.Equalf(t, current, j.offset(rule), "offset changed in the middle of the cycle at tick %d", tick)
+		}
+		j.update(frequency, rules, costOf)
+		require.Equal(t, next, j.offset(rule))
+	})
+}
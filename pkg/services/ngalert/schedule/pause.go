@@ -0,0 +1,68 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+var (
+	// ErrRuleNotScheduled is returned when an evaluation is requested for a rule that is not evaluated by this instance.
+	ErrRuleNotScheduled = errors.New("rule is not scheduled for evaluation by this instance")
+	// ErrRulePaused is returned when an evaluation is requested for a paused rule.
+	ErrRulePaused = errors.New("rule is paused")
+)
+
+// RuleResumer resumes rules whose pause expired.
+type RuleResumer interface {
+	ResumeAlertRules(ctx context.Context, keys []ngmodels.AlertRuleKeyWithVersion) error
+}
+
+// EvaluateNow evaluates the rule at the current time, regardless of its schedule. The evaluation replaces the one
+// the rule routine has not started yet, if any. It does not wait for the evaluation to complete.
+func (sch *schedule) EvaluateNow(key ngmodels.AlertRuleKey) error {
+	rule := sch.schedulableAlertRules.get(key)
+	routine, ok := sch.registry.get(key)
+	if rule == nil || !ok {
+		return ErrRuleNotScheduled
+	}
+	if rule.IsPaused {
+		return ErrRulePaused
+	}
+	var folderTitle string
+	if !sch.disableGrafanaFolder {
+		folderTitle = sch.schedulableAlertRules.folderTitle(rule.GetFolderKey())
+	}
+	if !sch.sendEvaluation(routine, &Evaluation{
+		scheduledAt: sch.clock.Now(),
+		rule:        rule,
+		folderTitle: folderTitle,
+	}) {
+		return ErrRuleNotScheduled
+	}
+	sch.log.Info("Rule evaluation requested out of schedule", key.LogContext()...)
+	return nil
+}
+
+// expiredPauses returns the rules whose pause expired at the tick.
+func expiredPauses(rules []*ngmodels.AlertRule, tick time.Time) []ngmodels.AlertRuleKeyWithVersion {
+	var result []ngmodels.AlertRuleKeyWithVersion
+	for _, rule := range rules {
+		if rule.PauseExpired(tick) {
+			result = append(result, ngmodels.AlertRuleKeyWithVersion{AlertRuleKey: rule.GetKey(), Version: rule.Version})
+		}
+	}
+	return result
+}
+
+// resumeRules resumes the rules in the store. The scheduler starts evaluating them when it fetches the new versions.
+// If resuming fails, it is retried at the next tick because the rules stay paused.
+func (sch *schedule) resumeRules(ctx context.Context, keys []ngmodels.AlertRuleKeyWithVersion) {
+	if err := sch.ruleResumer.ResumeAlertRules(ctx, keys); err != nil {
+		sch.log.Error("Failed to resume rules whose pause expired", "rules", len(keys), "error", err)
+		return
+	}
+	sch.log.Info("Resumed rules whose pause expired", "rules", len(keys))
+}
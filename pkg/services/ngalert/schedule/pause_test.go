@@ -0,0 +1,29 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestExpiredPauses(t *testing.T) {
+	tick := time.Now()
+	pausedUntil := func(ts time.Time) ngmodels.AlertRuleMutator {
+		return func(rule *ngmodels.AlertRule) {
+			rule.Metadata.PausedUntil = &ts
+		}
+	}
+	gen := ngmodels.RuleGen
+
+	expired := gen.With(gen.WithIsPaused(true), pausedUntil(tick)).GenerateRef()
+	notExpired := gen.With(gen.WithIsPaused(true), pausedUntil(tick.Add(time.Second))).GenerateRef()
+	pausedForever := gen.With(gen.WithIsPaused(true)).GenerateRef()
+	resumed := gen.With(gen.WithIsPaused(false), pausedUntil(tick.Add(-time.Minute))).GenerateRef()
+
+	result := expiredPauses([]*ngmodels.AlertRule{expired, notExpired, pausedForever, resumed}, tick)
+
+	assert.Equal(t, []ngmodels.AlertRuleKeyWithVersion{{AlertRuleKey: expired.GetKey(), Version: expired.Version}}, result)
+}
@@ -138,6 +138,7 @@ func (r *recordingRule) Run() error {
 			}
 			if !r.cfg.Enabled {
 				r.logger.Warn("Recording rule scheduled but subsystem is not enabled. Skipping")
+				eval.complete()
 				return nil
 			}
 			// TODO: Skipping the "evalRunning" guard that the alert rule routine does, because it seems to be dead code and impossible to hit.
@@ -170,6 +171,7 @@ func (r *recordingRule) doEvaluate(ctx context.Context, ev *Evaluation) {
 		r.evaluationDuration.Store(dur)
 
 		r.evaluationDoneTestHook(ev)
+		ev.complete()
 	}()
 
 	if ev.rule.IsPaused {
@@ -269,7 +271,7 @@ func (r *recordingRule) tryEvaluation(ctx context.Context, ev *Evaluation, logge
 	}
 
 	writeStart := r.clock.Now()
-	err = r.writer.Write(ctx, ev.rule.Record.Metric, ev.scheduledAt, frames, ev.rule.OrgID, ev.rule.Labels)
+	err = r.writer.WriteDatasource(ctx, ev.rule.Record.TargetDatasourceUID, ev.rule.Record.Metric, ev.rule.Record.Metadata, ev.scheduledAt, frames, ev.rule.OrgID, ev.rule.Labels)
 	writeDur := r.clock.Now().Sub(writeStart)
 
 	if err != nil {
@@ -91,6 +91,18 @@ type Evaluation struct {
 	scheduledAt time.Time
 	rule        *models.AlertRule
 	folderTitle string
+	// done is closed when the evaluation is completed or dropped. It is nil if nobody waits for the evaluation.
+	done chan struct{}
+	// catchUp is true if the evaluation catches up a missed one. Its alerts are not sent because they are historical,
+	// the scheduled evaluation that follows sends the current ones.
+	catchUp bool
+}
+
+// complete signals that the evaluation is completed or dropped.
+func (e *Evaluation) complete() {
+	if e.done != nil {
+		close(e.done)
+	}
 }
 
 func (e *Evaluation) Fingerprint() fingerprint {
@@ -120,6 +132,13 @@ func (r *alertRulesRegistry) get(k models.AlertRuleKey) *models.AlertRule {
 	return r.rules[k]
 }
 
+// folderTitle returns the title of the folder, or an empty string if the folder is unknown.
+func (r *alertRulesRegistry) folderTitle(k models.FolderKey) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.folderTitles[k]
+}
+
 // set replaces all rules in the registry. Returns difference between previous and the new current version of the registry
 func (r *alertRulesRegistry) set(rules []*models.AlertRule, folders map[models.FolderKey]string) diff {
 	r.mu.Lock()
@@ -310,6 +329,7 @@ func (r ruleWithFolder) Fingerprint() fingerprint {
 	// fields that do not affect the state.
 	// TODO consider removing fields below from the fingerprint
 	writeInt(int64(rule.For))
+	writeInt(int64(rule.KeepFiringFor))
 	if rule.DashboardUID != nil {
 		writeString(*rule.DashboardUID)
 	}
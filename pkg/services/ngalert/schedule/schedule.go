@@ -48,8 +48,10 @@ type RulesStore interface {
 	GetAlertRulesForScheduling(ctx context.Context, query *ngmodels.GetAlertRulesForSchedulingQuery) error
 }
 
+// RecordingWriter writes results of recording rules to the data source with the given UID,
+// or to the default remote write endpoint if the UID is empty.
 type RecordingWriter interface {
-	Write(ctx context.Context, name string, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error
+	WriteDatasource(ctx context.Context, dsUID string, name string, metadata ngmodels.RecordMetadata, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error
 }
 
 // AlertRuleStopReasonProvider is an interface for determining the reason why an alert rule was stopped.
@@ -97,6 +99,30 @@ type schedule struct {
 	jitterEvaluations    JitterStrategy
 	rrCfg                setting.RecordingRuleSettings
 
+	// sequentialGroupEvaluation makes rules of a group evaluate one after another in the order of RuleGroupIndex.
+	sequentialGroupEvaluation bool
+
+	// loadJitter assigns offsets to rules if jitterEvaluations is JitterByLoad.
+	loadJitter *loadJitter
+
+	// ruleSharder, if set, selects the rules evaluated by this instance.
+	ruleSharder *ruleSharder
+
+	// ruleResumer, if set, resumes rules whose pause expired.
+	ruleResumer RuleResumer
+
+	// catchUpMaxLookback is how far back missed evaluations of alert rules are caught up. Zero disables the catch-up.
+	catchUpMaxLookback time.Duration
+
+	// sharedQueries, if set, shares results of identical queries of rules evaluated in the same tick.
+	sharedQueries *eval.SharedQueries
+
+	// datasourceLimiter, if set, limits evaluations that query datasources.
+	datasourceLimiter *datasourceLimiter
+
+	// debugger records the last evaluations of rules in debug mode.
+	debugger *ruleDebugger
+
 	metrics *metrics.Scheduler
 
 	alertsSender    AlertsSender
@@ -131,6 +157,20 @@ type SchedulerCfg struct {
 	Log                    log.Logger
 	RecordingWriter        RecordingWriter
 	RuleStopReasonProvider AlertRuleStopReasonProvider
+	// SequentialGroupEvaluation makes rules of a group evaluate one after another in the order of RuleGroupIndex,
+	// like Prometheus does, instead of independently. It forces jitter by group.
+	SequentialGroupEvaluation bool
+	// SharedQueries, if set, makes identical datasource queries of rules evaluated in the same tick run only once.
+	SharedQueries *eval.SharedQueries
+	// DatasourceLimits limits the concurrency and duration of evaluations of rules by the datasources they query.
+	DatasourceLimits DatasourceLimits
+	// ClusterMembership, if set, shards the evaluation of rules across the members of the cluster.
+	ClusterMembership ClusterMembership
+	// CatchUpMaxLookback, if positive, makes alert rules evaluate at the timestamps they missed since their last
+	// evaluation, for example, because the scheduler stalled or Grafana restarted, as long as they are within the lookback.
+	CatchUpMaxLookback time.Duration
+	// RuleResumer, if set, resumes rules that are paused until a time when that time comes.
+	RuleResumer RuleResumer
 }
 
 // NewScheduler returns a new scheduler.
@@ -141,6 +181,12 @@ func NewScheduler(cfg SchedulerCfg, stateManager *state.Manager) *schedule {
 		cfg.MaxAttempts = minMaxAttempts
 	}
 
+	if cfg.SequentialGroupEvaluation && (cfg.JitterEvaluations == JitterByRule || cfg.JitterEvaluations == JitterByLoad) {
+		// Rules of a group that is evaluated sequentially must be scheduled at the same tick.
+		cfg.Log.Info("Sequential evaluation of rule groups is enabled, using jitter by group instead of jitter by rule or by load")
+		cfg.JitterEvaluations = JitterByGroup
+	}
+
 	sch := schedule{
 		registry:               newRuleRegistry(),
 		maxAttempts:            cfg.MaxAttempts,
@@ -161,11 +207,40 @@ func NewScheduler(cfg SchedulerCfg, stateManager *state.Manager) *schedule {
 		tracer:                 cfg.Tracer,
 		recordingWriter:        cfg.RecordingWriter,
 		ruleStopReasonProvider: cfg.RuleStopReasonProvider,
+
+		sequentialGroupEvaluation: cfg.SequentialGroupEvaluation,
+		catchUpMaxLookback:        cfg.CatchUpMaxLookback,
+		ruleResumer:               cfg.RuleResumer,
+		debugger:                  newRuleDebugger(),
+	}
+
+	if cfg.ClusterMembership != nil {
+		sch.ruleSharder = newRuleSharder(cfg.ClusterMembership)
+	}
+	if cfg.JitterEvaluations == JitterByLoad {
+		sch.loadJitter = newLoadJitter(cfg.BaseInterval, cfg.Metrics)
+	}
+	if cfg.SharedQueries != nil {
+		sch.sharedQueries = cfg.SharedQueries
+		sch.evaluatorFactory = sharedQueriesEvaluatorFactory{factory: cfg.EvaluatorFactory, shared: cfg.SharedQueries}
+	}
+	if !cfg.DatasourceLimits.IsEmpty() {
+		sch.datasourceLimiter = newDatasourceLimiter(cfg.DatasourceLimits, cfg.C, cfg.Metrics)
+		sch.evaluatorFactory = sch.LimitEvaluations(sch.evaluatorFactory)
 	}
 
 	return &sch
 }
 
+// LimitEvaluations returns a factory of evaluators that share the limits of datasources with the rules evaluated by
+// the scheduler. It is used by evaluations outside the scheduler, such as backfills, which must not bypass the limits.
+func (sch *schedule) LimitEvaluations(factory eval.EvaluatorFactory) eval.EvaluatorFactory {
+	if sch.datasourceLimiter == nil {
+		return factory
+	}
+	return limitedEvaluatorFactory{factory: factory, limiter: sch.datasourceLimiter}
+}
+
 func (sch *schedule) Run(ctx context.Context) error {
 	sch.log.Info("Starting scheduler", "tickInterval", sch.baseInterval, "maxAttempts", sch.maxAttempts)
 	t := ticker.New(sch.clock, sch.baseInterval, sch.metrics.Ticker)
@@ -202,6 +277,7 @@ func (sch *schedule) deleteAlertRule(ctx context.Context, keys ...ngmodels.Alert
 		if !ok {
 			sch.log.Info("Alert rule cannot be removed from the scheduler as it is not scheduled", key.LogContext()...)
 		}
+		sch.debugger.disable(key)
 		// Delete the rule routine
 		ruleRoutine, ok := sch.registry.del(key)
 		if !ok {
@@ -270,6 +346,11 @@ type readyToRunItem struct {
 func (sch *schedule) processTick(ctx context.Context, dispatcherGroup *errgroup.Group, tick time.Time) ([]readyToRunItem, map[ngmodels.AlertRuleKey]struct{}, []ngmodels.AlertRuleKeyWithVersion) {
 	tickNum := tick.Unix() / int64(sch.baseInterval.Seconds())
 
+	if sch.sharedQueries != nil {
+		// keep results of the previous tick because its evaluations can still be running.
+		sch.sharedQueries.Expire(tick.Add(-sch.baseInterval))
+	}
+
 	// update the local registry. If there was a difference between the previous state and the current new state, rulesDiff will contains keys of rules that were updated.
 	rulesDiff, err := sch.updateSchedulableAlertRules(ctx)
 	updated := rulesDiff.updated
@@ -283,6 +364,12 @@ func (sch *schedule) processTick(ctx context.Context, dispatcherGroup *errgroup.
 	// this is the new current state. rulesDiff contains the previously existing rules that were different between this state and the previous state.
 	alertRules, folderTitles := sch.schedulableAlertRules.all()
 
+	// takeOver is true if the rules that start being evaluated by this instance could have been evaluated by another instance.
+	takeOver := false
+	if sch.ruleSharder != nil {
+		alertRules, takeOver = sch.shardRules(ctx, alertRules)
+	}
+
 	// registeredDefinitions is a map used for finding deleted alert rules
 	// initially it is assigned to all known alert rules from the previous cycle
 	// each alert rule found also in this cycle is removed
@@ -291,6 +378,16 @@ func (sch *schedule) processTick(ctx context.Context, dispatcherGroup *errgroup.
 
 	sch.updateRulesMetrics(alertRules)
 
+	if sch.ruleResumer != nil {
+		if expired := expiredPauses(alertRules, tick); len(expired) > 0 {
+			go sch.resumeRules(ctx, expired)
+		}
+	}
+
+	if sch.loadJitter != nil {
+		sch.loadJitter.update(tickNum, alertRules, sch.lastEvaluationDuration)
+	}
+
 	readyToRun := make([]readyToRunItem, 0)
 	updatedRules := make([]ngmodels.AlertRuleKeyWithVersion, 0, len(updated)) // this is needed for tests only
 	restartedRules := make([]Rule, 0)
@@ -310,6 +407,7 @@ func (sch *schedule) processTick(ctx context.Context, dispatcherGroup *errgroup.
 		sch.recordingWriter,
 		sch.evalAppliedFunc,
 		sch.stopAppliedFunc,
+		sch.debugger,
 	)
 	for _, item := range alertRules {
 		ruleRoutine, newRoutine := sch.registry.getOrCreate(ctx, item, ruleFactory)
@@ -333,7 +431,17 @@ func (sch *schedule) processTick(ctx context.Context, dispatcherGroup *errgroup.
 		}
 
 		if newRoutine && !invalidInterval {
+			loadState := takeOver && item.Type() == ngmodels.RuleTypeAlerting
+			// The previous owner stops evaluating the rule when it sees the change of the cluster, so its last
+			// evaluation was scheduled within the last interval of the rule.
+			interval := time.Duration(item.IntervalSeconds) * time.Second
+			handedOffAfter := tick.Add(-interval)
 			dispatcherGroup.Go(func() error {
+				if loadState {
+					// Continue from the state that the previous owner of the rule handed off.
+					sch.stateManager.LoadStateByRuleUID(ctx, item, handedOffAfter, interval)
+					sch.metrics.ShardHandOffs.WithLabelValues("in").Inc()
+				}
 				return ruleRoutine.Run()
 			})
 		}
@@ -346,7 +454,7 @@ func (sch *schedule) processTick(ctx context.Context, dispatcherGroup *errgroup.
 		}
 
 		itemFrequency := item.IntervalSeconds / int64(sch.baseInterval.Seconds())
-		offset := jitterOffsetInTicks(item, sch.baseInterval, sch.jitterEvaluations)
+		offset := sch.jitterOffsetInTicks(item)
 		isReadyToRun := item.IntervalSeconds != 0 && (tickNum%itemFrequency)-offset == 0
 
 		var folderTitle string
@@ -390,6 +498,14 @@ func (sch *schedule) processTick(ctx context.Context, dispatcherGroup *errgroup.
 		sch.log.Warn("Unable to obtain folder titles for some rules", "missingFolderUIDToRuleUID", missingFolder)
 	}
 
+	if sch.loadJitter != nil {
+		var tickLoad time.Duration
+		for _, item := range readyToRun {
+			tickLoad += item.ruleRoutine.Status().EvaluationDuration
+		}
+		sch.metrics.TickLoad.Observe(tickLoad.Seconds())
+	}
+
 	var step int64 = 0
 	if len(readyToRun) > 0 {
 		step = sch.baseInterval.Nanoseconds() / int64(len(readyToRun))
@@ -398,21 +514,41 @@ func (sch *schedule) processTick(ctx context.Context, dispatcherGroup *errgroup.
 	slices.SortFunc(readyToRun, func(a, b readyToRunItem) int {
 		return strings.Compare(a.rule.UID, b.rule.UID)
 	})
+
+	var groups map[ngmodels.AlertRuleGroupKey][]readyToRunItem
+	if sch.sequentialGroupEvaluation {
+		groups = make(map[ngmodels.AlertRuleGroupKey][]readyToRunItem)
+		for _, item := range readyToRun {
+			groupKey := item.rule.GetGroupKey()
+			groups[groupKey] = append(groups[groupKey], item)
+		}
+	}
 	for i := range readyToRun {
 		item := readyToRun[i]
 
-		time.AfterFunc(time.Duration(int64(i)*step), func() {
-			key := item.rule.GetKey()
-			success, dropped := item.ruleRoutine.Eval(&item.Evaluation)
-			if !success {
-				sch.log.Debug("Scheduled evaluation was canceled because evaluation routine was stopped", append(key.LogContext(), "time", tick)...)
-				return
-			}
-			if dropped != nil {
-				sch.log.Warn("Tick dropped because alert rule evaluation is too slow", append(key.LogContext(), "time", tick, "droppedTick", dropped.scheduledAt)...)
-				orgID := fmt.Sprint(key.OrgID)
-				sch.metrics.EvaluationMissed.WithLabelValues(orgID, item.rule.Title).Inc()
+		if sch.sequentialGroupEvaluation {
+			// The whole group is evaluated in the time slot of the first of its rules.
+			groupKey := item.rule.GetGroupKey()
+			group, ok := groups[groupKey]
+			if !ok {
+				continue
 			}
+			delete(groups, groupKey)
+			time.AfterFunc(time.Duration(int64(i)*step), func() {
+				sch.evaluateGroupSequentially(ctx, group)
+			})
+			continue
+		}
+
+		if missed := sch.missedEvaluationsOf(item); len(missed) > 0 {
+			time.AfterFunc(time.Duration(int64(i)*step), func() {
+				sch.catchUp(ctx, item, missed)
+			})
+			continue
+		}
+
+		time.AfterFunc(time.Duration(int64(i)*step), func() {
+			sch.sendEvaluation(item.ruleRoutine, &item.Evaluation)
 		})
 	}
 
@@ -429,3 +565,121 @@ func (sch *schedule) processTick(ctx context.Context, dispatcherGroup *errgroup.
 	sch.deleteAlertRule(ctx, toDelete...)
 	return readyToRun, registeredDefinitions, updatedRules
 }
+
+// sendEvaluation sends the evaluation to the rule routine. It returns false if the routine was stopped.
+func (sch *schedule) sendEvaluation(routine Rule, ev *Evaluation) bool {
+	key := ev.rule.GetKey()
+	success, dropped := routine.Eval(ev)
+	if !success {
+		sch.log.Debug("Scheduled evaluation was canceled because evaluation routine was stopped", append(key.LogContext(), "time", ev.scheduledAt)...)
+		return false
+	}
+	if dropped != nil {
+		sch.log.Warn("Tick dropped because alert rule evaluation is too slow", append(key.LogContext(), "time", ev.scheduledAt, "droppedTick", dropped.scheduledAt)...)
+		orgID := fmt.Sprint(key.OrgID)
+		sch.metrics.EvaluationMissed.WithLabelValues(orgID, ev.rule.Title).Inc()
+		dropped.complete()
+	}
+	return true
+}
+
+// evaluateGroupSequentially evaluates rules of a group one after another in the order of RuleGroupIndex.
+// A rule is evaluated only after the evaluation of the previous rule is completed, including the write of
+// the results of a recording rule, so rules can use the results of the rules that precede them in the group.
+// If an evaluation does not complete within the interval of the rule, the next rule is evaluated anyway.
+func (sch *schedule) evaluateGroupSequentially(ctx context.Context, items []readyToRunItem) {
+	slices.SortFunc(items, func(a, b readyToRunItem) int {
+		if a.rule.RuleGroupIndex != b.rule.RuleGroupIndex {
+			return a.rule.RuleGroupIndex - b.rule.RuleGroupIndex
+		}
+		return strings.Compare(a.rule.UID, b.rule.UID)
+	})
+	for _, item := range items {
+		if missed := sch.missedEvaluationsOf(item); len(missed) > 0 {
+			sch.evaluateMissed(ctx, item, missed)
+		}
+		ev := item.Evaluation
+		ev.done = make(chan struct{})
+		if !sch.sendEvaluation(item.ruleRoutine, &ev) {
+			continue
+		}
+		if !sch.waitEvaluation(ctx, &ev) && ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// waitEvaluation waits until the evaluation is completed, but not longer than the interval of the rule. It returns
+// false if the evaluation did not complete in time or the context was canceled.
+func (sch *schedule) waitEvaluation(ctx context.Context, ev *Evaluation) bool {
+	select {
+	case <-ev.done:
+		return true
+	case <-time.After(time.Duration(ev.rule.IntervalSeconds) * time.Second):
+		sch.log.Warn("Rule evaluation did not complete within the interval", append(ev.rule.GetKey().LogContext(), "time", ev.scheduledAt)...)
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// shardRules returns the rules that are evaluated by this instance. If the members of the cluster changed, routines of
+// the rules that moved to other instances are stopped and hand off the state. The returned bool is true if the ownership
+// of rules changed after the instance started, and therefore the routines of new rules should load the state.
+func (sch *schedule) shardRules(ctx context.Context, alertRules []*ngmodels.AlertRule) ([]*ngmodels.AlertRule, bool) {
+	initial := sch.ruleSharder.ring == nil
+	changed := sch.ruleSharder.update()
+	if changed {
+		sch.log.Info("Members of the cluster changed, sharding rules", "members", len(sch.ruleSharder.members), "self", sch.ruleSharder.self)
+		sch.metrics.ShardMembers.Set(float64(len(sch.ruleSharder.members)))
+	}
+
+	owned := make([]*ngmodels.AlertRule, 0, len(alertRules))
+	for _, rule := range alertRules {
+		key := rule.GetKey()
+		if sch.ruleSharder.owns(rule.GetGroupKey()) {
+			owned = append(owned, rule)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if routine, ok := sch.registry.del(key); ok {
+			sch.log.Debug("Rule is evaluated by another instance, stopping the evaluation", append(key.LogContext(), "owner", sch.ruleSharder.owner(rule.GetGroupKey()))...)
+			routine.Stop(errRuleNotOwned)
+			sch.metrics.ShardHandOffs.WithLabelValues("out").Inc()
+		} else {
+			// The state could have been loaded when the instance started.
+			sch.stateManager.ForgetStateByRuleUID(ctx, rule.GetKeyWithGroup())
+		}
+	}
+	sch.metrics.ShardOwnedRules.Set(float64(len(owned)))
+	return owned, changed && !initial
+}
+
+// jitterOffsetInTicks returns the offset of the rule according to the jitter strategy of the scheduler.
+func (sch *schedule) jitterOffsetInTicks(r *ngmodels.AlertRule) int64 {
+	if sch.loadJitter != nil {
+		return sch.loadJitter.offset(r)
+	}
+	return jitterOffsetInTicks(r, sch.baseInterval, sch.jitterEvaluations)
+}
+
+// lastEvaluationDuration returns the duration of the last evaluation of the rule, or zero if the rule was not evaluated yet.
+func (sch *schedule) lastEvaluationDuration(key ngmodels.AlertRuleKey) time.Duration {
+	rule, ok := sch.registry.get(key)
+	if !ok {
+		return 0
+	}
+	return rule.Status().EvaluationDuration
+}
+
+// sharedQueriesEvaluatorFactory creates evaluators that share results of identical datasource queries.
+type sharedQueriesEvaluatorFactory struct {
+	factory eval.EvaluatorFactory
+	shared  *eval.SharedQueries
+}
+
+func (f sharedQueriesEvaluatorFactory) Create(ctx eval.EvaluationContext, condition ngmodels.Condition) (eval.ConditionEvaluator, error) {
+	return f.factory.Create(ctx.WithSharedQueries(f.shared), condition)
+}
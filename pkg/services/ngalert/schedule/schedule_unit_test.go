@@ -9,6 +9,7 @@ import (
 	"math/rand"
 	"net/url"
 	"slices"
+	"sync"
 	"testing"
 	"time"
 
@@ -1026,6 +1027,54 @@ func TestSchedule_deleteAlertRule(t *testing.T) {
 	})
 }
 
+func TestSchedule_evaluateGroupSequentially(t *testing.T) {
+	ctx := context.Background()
+	sch := setupScheduler(t, nil, nil, nil, nil, nil, nil)
+
+	var mtx sync.Mutex
+	var events []string
+	record := func(e string) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		events = append(events, e)
+	}
+
+	gen := models.RuleGen
+	rules := gen.With(gen.WithSameGroup(), gen.WithInterval(10*time.Second)).GenerateManyRef(3)
+	items := make([]readyToRunItem, 0, len(rules))
+	for i, rule := range rules {
+		rule.RuleGroupIndex = len(rules) - i
+		items = append(items, readyToRunItem{
+			ruleRoutine: &fakeSequentialRule{record: record},
+			Evaluation:  Evaluation{scheduledAt: time.Now(), rule: rule},
+		})
+	}
+
+	sch.evaluateGroupSequentially(ctx, items)
+
+	expected := make([]string, 0, 2*len(rules))
+	for i := len(rules) - 1; i >= 0; i-- {
+		expected = append(expected, "start "+rules[i].UID, "end "+rules[i].UID)
+	}
+	require.Equal(t, expected, events)
+}
+
+// fakeSequentialRule is a rule routine that completes evaluations asynchronously and records when they start and end.
+type fakeSequentialRule struct {
+	Rule
+	record func(string)
+}
+
+func (f *fakeSequentialRule) Eval(e *Evaluation) (bool, *Evaluation) {
+	f.record("start " + e.rule.UID)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		f.record("end " + e.rule.UID)
+		e.complete()
+	}()
+	return true, nil
+}
+
 func setupScheduler(t *testing.T, rs *fakeRulesStore, is *state.FakeInstanceStore, registry *prometheus.Registry, senderMock *SyncAlertsSenderMock, evalMock eval.EvaluatorFactory, ruleStopReasonProvider AlertRuleStopReasonProvider) *schedule {
 	t.Helper()
 	testTracer := tracing.InitializeTracerForTest()
@@ -0,0 +1,103 @@
+package schedule
+
+import (
+	"cmp"
+	"errors"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// shardingVirtualNodes is the number of tokens every member has in the hash ring. More tokens make the distribution of rules more even.
+const shardingVirtualNodes = 128
+
+// errRuleNotOwned is the reason to stop a rule routine when the evaluation of the rule is moved to another instance.
+var errRuleNotOwned = errors.New("rule is evaluated by another instance")
+
+// ClusterMembership provides the members of the cluster of Grafana instances.
+type ClusterMembership interface {
+	// Members returns the names of the active members of the cluster.
+	Members() []string
+	// Self returns the name of the current instance.
+	Self() string
+}
+
+type ringToken struct {
+	hash   uint64
+	member string
+}
+
+// ruleSharder assigns every rule group to exactly one member of the cluster using consistent hashing over AlertRuleGroupKey,
+// so that only a small share of rules moves to another member when members join or leave the cluster.
+// All rules of a group are evaluated by the same member, which keeps the order of evaluation of the group
+// and lets rules use the series recorded by the recording rules that precede them.
+type ruleSharder struct {
+	membership ClusterMembership
+
+	self    string
+	members []string
+	ring    []ringToken
+}
+
+func newRuleSharder(membership ClusterMembership) *ruleSharder {
+	return &ruleSharder{membership: membership}
+}
+
+// update rebuilds the hash ring if the members of the cluster changed. It returns true if the ring was rebuilt.
+// The current instance is always considered a member, even if the cluster has not discovered it yet.
+func (s *ruleSharder) update() bool {
+	self := s.membership.Self()
+	members := append(slices.Clone(s.membership.Members()), self)
+	slices.Sort(members)
+	members = slices.Compact(members)
+	if s.ring != nil && self == s.self && slices.Equal(members, s.members) {
+		return false
+	}
+
+	ring := make([]ringToken, 0, len(members)*shardingVirtualNodes)
+	for _, member := range members {
+		for i := 0; i < shardingVirtualNodes; i++ {
+			ring = append(ring, ringToken{hash: shardingHash(member + "-" + strconv.Itoa(i)), member: member})
+		}
+	}
+	slices.SortFunc(ring, func(a, b ringToken) int {
+		if c := cmp.Compare(a.hash, b.hash); c != 0 {
+			return c
+		}
+		return strings.Compare(a.member, b.member)
+	})
+	s.self = self
+	s.members = members
+	s.ring = ring
+	return true
+}
+
+// owner returns the member that evaluates the rules of the group.
+func (s *ruleSharder) owner(key ngmodels.AlertRuleGroupKey) string {
+	if len(s.ring) == 0 {
+		return s.self
+	}
+	h := shardingHash(key.String())
+	idx := sort.Search(len(s.ring), func(i int) bool {
+		return s.ring[i].hash >= h
+	})
+	if idx == len(s.ring) {
+		idx = 0
+	}
+	return s.ring[idx].member
+}
+
+// owns returns true if the current instance evaluates the rules of the group.
+func (s *ruleSharder) owns(key ngmodels.AlertRuleGroupKey) bool {
+	return s.owner(key) == s.self
+}
+
+func shardingHash(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum64()
+}
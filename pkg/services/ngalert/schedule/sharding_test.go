@@ -0,0 +1,130 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+type fakeClusterMembership struct {
+	self    string
+	members []string
+}
+
+func (f *fakeClusterMembership) Members() []string {
+	return f.members
+}
+
+func (f *fakeClusterMembership) Self() string {
+	return f.self
+}
+
+func TestRuleSharder(t *testing.T) {
+	rules := ngmodels.RuleGen.GenerateManyRef(3000)
+
+	ownersOf := func(sharders ...*ruleSharder) map[ngmodels.AlertRuleGroupKey]string {
+		owners := make(map[ngmodels.AlertRuleGroupKey]string, len(rules))
+		for _, r := range rules {
+			key := r.GetGroupKey()
+			count := 0
+			for _, s := range sharders {
+				if s.owns(key) {
+					owners[key] = s.self
+					count++
+				}
+			}
+			require.Equalf(t, 1, count, "rule group %s must be owned by exactly one member", key)
+		}
+		return owners
+	}
+	newSharders := func(members ...string) []*ruleSharder {
+		result := make([]*ruleSharder, 0, len(members))
+		for _, m := range members {
+			s := newRuleSharder(&fakeClusterMembership{self: m, members: members})
+			require.True(t, s.update())
+			result = append(result, s)
+		}
+		return result
+	}
+
+	t.Run("single instance owns all rules", func(t *testing.T) {
+		s := newRuleSharder(&fakeClusterMembership{self: "a"})
+		require.True(t, s.update())
+		for _, r := range rules {
+			require.True(t, s.owns(r.GetGroupKey()))
+		}
+	})
+
+	t.Run("every rule is owned by exactly one member", func(t *testing.T) {
+		owners := ownersOf(newSharders("a", "b", "c")...)
+
+		perMember := make(map[string]int)
+		for _, owner := range owners {
+			perMember[owner]++
+		}
+		require.Len(t, perMember, 3)
+		for member, count := range perMember {
+			assert.Greaterf(t, count, len(rules)/6, "member %s owns too few rules", member)
+			assert.Lessf(t, count, len(rules)/2, "member %s owns too many rules", member)
+		}
+	})
+
+	t.Run("only rules of the new member move when it joins", func(t *testing.T) {
+		before := ownersOf(newSharders("a", "b", "c")...)
+		after := ownersOf(newSharders("a", "b", "c", "d")...)
+
+		for key, owner := range after {
+			if owner != "d" {
+				require.Equal(t, before[key], owner)
+			}
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		membership := &fakeClusterMembership{self: "a", members: []string{"b", "a"}}
+		s := newRuleSharder(membership)
+
+		require.True(t, s.update())
+		require.Equal(t, []string{"a", "b"}, s.members)
+
+		membership.members = []string{"a", "b"}
+		require.False(t, s.update(), "the order of members should not matter")
+
+		membership.members = []string{"b"}
+		require.False(t, s.update(), "the current instance is always a member")
+
+		membership.members = []string{"a", "b", "c"}
+		require.True(t, s.update())
+		require.Equal(t, []string{"a", "b", "c"}, s.members)
+	})
+}
+
+func TestSchedule_shardRules(t *testing.T) {
+	gen := ngmodels.RuleGen
+	var rules []*ngmodels.AlertRule
+	for i := 0; i < 100; i++ {
+		rules = append(rules, gen.With(gen.WithGroupKey(ngmodels.GenerateGroupKey(1))).GenerateManyRef(2, 5)...)
+	}
+
+	sch := setupScheduler(t, nil, nil, nil, nil, nil, nil)
+	sch.ruleSharder = newRuleSharder(&fakeClusterMembership{self: "a", members: []string{"a", "b", "c"}})
+	owned, _ := sch.shardRules(context.Background(), rules)
+	require.NotEmpty(t, owned)
+	require.Less(t, len(owned), len(rules))
+
+	groupSize := make(map[ngmodels.AlertRuleGroupKey]int)
+	for _, r := range rules {
+		groupSize[r.GetGroupKey()]++
+	}
+	ownedPerGroup := make(map[ngmodels.AlertRuleGroupKey]int)
+	for _, r := range owned {
+		ownedPerGroup[r.GetGroupKey()]++
+	}
+	for key, count := range ownedPerGroup {
+		require.Equalf(t, groupSize[key], count, "all rules of group %s must be evaluated by the same instance", key)
+	}
+}
@@ -0,0 +1,171 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/dataplane/sdata/numeric"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state/template"
+)
+
+const (
+	templateQueryRefID = "A"
+	// datasourceQueryRange is the time range of queries of DatasourceQuerier. Instant queries of Prometheus do not use it.
+	datasourceQueryRange = time.Minute
+)
+
+var (
+	errNoTemplateQueryDatasource         = errors.New("rule has no datasource to execute the query against")
+	errTemplateQueryDatasourceNotAllowed = errors.New("datasource is not queried by the rule")
+)
+
+// TemplateQuerierProvider creates queriers for the query function in templates of alert rules.
+// The queries are executed on behalf of the scheduler, so they are limited to the datasources that the queries
+// of the rule use, which the author of the rule was allowed to query when the rule was saved.
+type TemplateQuerierProvider struct {
+	evaluatorFactory eval.EvaluatorFactory
+}
+
+func NewTemplateQuerierProvider(evaluatorFactory eval.EvaluatorFactory) *TemplateQuerierProvider {
+	return &TemplateQuerierProvider{evaluatorFactory: evaluatorFactory}
+}
+
+// QuerierFor returns a querier that executes queries against the datasource of the rule, unless another datasource
+// of the queries of the rule is requested. Queries use the time range of the first query of the rule against the datasource.
+func (p *TemplateQuerierProvider) QuerierFor(rule *ngmodels.AlertRule) template.Querier {
+	timeRanges := make(map[string]ngmodels.RelativeTimeRange, len(rule.Data))
+	for _, q := range rule.Data {
+		if isExpr, _ := q.IsExpression(); isExpr {
+			continue
+		}
+		if _, ok := timeRanges[q.DatasourceUID]; !ok {
+			timeRanges[q.DatasourceUID] = q.RelativeTimeRange
+		}
+	}
+	return &templateQuerier{
+		evaluatorFactory: p.evaluatorFactory,
+		orgID:            rule.OrgID,
+		datasourceUID:    rule.GetDatasourceUID(),
+		timeRanges:       timeRanges,
+	}
+}
+
+type templateQuerier struct {
+	evaluatorFactory eval.EvaluatorFactory
+	orgID            int64
+	datasourceUID    string
+	// timeRanges are the time ranges of the queries by the UIDs of the datasources that the queries may use.
+	timeRanges map[string]ngmodels.RelativeTimeRange
+}
+
+func (q *templateQuerier) Query(ctx context.Context, datasourceUID, expr string, ts time.Time) (promql.Vector, error) {
+	if datasourceUID == "" {
+		datasourceUID = q.datasourceUID
+	}
+	if datasourceUID == "" {
+		return nil, errNoTemplateQueryDatasource
+	}
+	timeRange, ok := q.timeRanges[datasourceUID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errTemplateQueryDatasourceNotAllowed, datasourceUID)
+	}
+	return queryVector(ctx, q.evaluatorFactory, q.orgID, datasourceUID, expr, timeRange, ts)
+}
+
+// DatasourceQuerier executes instant queries against any datasource of an organization on behalf of the scheduler.
+// It is meant for datasources configured by the operator, such as the one that contains the state history.
+type DatasourceQuerier struct {
+	evaluatorFactory eval.EvaluatorFactory
+}
+
+func NewDatasourceQuerier(evaluatorFactory eval.EvaluatorFactory) *DatasourceQuerier {
+	return &DatasourceQuerier{evaluatorFactory: evaluatorFactory}
+}
+
+// Query executes the instant query against the datasource at the given time.
+func (q *DatasourceQuerier) Query(ctx context.Context, orgID int64, datasourceUID, expr string, ts time.Time) (promql.Vector, error) {
+	timeRange := ngmodels.RelativeTimeRange{From: ngmodels.Duration(datasourceQueryRange)}
+	return queryVector(ctx, q.evaluatorFactory, orgID, datasourceUID, expr, timeRange, ts)
+}
+
+// queryVector executes the instant query against the datasource and returns the result as a vector.
+func queryVector(ctx context.Context, evaluatorFactory eval.EvaluatorFactory, orgID int64, datasourceUID, expr string, timeRange ngmodels.RelativeTimeRange, ts time.Time) (promql.Vector, error) {
+	model, err := json.Marshal(map[string]any{
+		"refId":   templateQueryRefID,
+		"expr":    expr,
+		"instant": true,
+		"range":   false,
+	})
+	if err != nil {
+		return nil, err
+	}
+	condition := ngmodels.Condition{
+		Condition: templateQueryRefID,
+		Data: []ngmodels.AlertQuery{{
+			RefID:             templateQueryRefID,
+			DatasourceUID:     datasourceUID,
+			RelativeTimeRange: timeRange,
+			Model:             model,
+		}},
+	}
+
+	evaluator, err := evaluatorFactory.Create(eval.NewContext(ctx, SchedulerUserFor(orgID)), condition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+	resp, err := evaluator.EvaluateRaw(ctx, ts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	res, ok := resp.Responses[templateQueryRefID]
+	if !ok {
+		return nil, nil
+	}
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", res.Error)
+	}
+	if eval.IsNoData(res) {
+		return nil, nil
+	}
+	return vectorFromFrames(res.Frames, ts)
+}
+
+// vectorFromFrames converts numeric frames to an instant vector. Series without a value are skipped.
+func vectorFromFrames(frames data.Frames, ts time.Time) (promql.Vector, error) {
+	cr, err := numeric.CollectionReaderFromFrames(frames)
+	if err != nil {
+		return nil, err
+	}
+	col, err := cr.GetCollection(false)
+	if err != nil {
+		return nil, err
+	}
+
+	vector := make(promql.Vector, 0, len(col.Refs))
+	for _, ref := range col.Refs {
+		fp, empty, err := ref.NullableFloat64Value()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read float64 value: %w", err)
+		}
+		if empty || fp == nil {
+			continue
+		}
+		vector = append(vector, promql.Sample{
+			T:      timestamp.FromTime(ts),
+			F:      *fp,
+			Metric: labels.FromMap(ref.GetLabels()),
+		})
+	}
+	return vector, nil
+}
@@ -0,0 +1,145 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval/eval_mocks"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+type capturingEvaluatorFactory struct {
+	eval.EvaluatorFactory
+	evalCtx   eval.EvaluationContext
+	condition ngmodels.Condition
+}
+
+func (f *capturingEvaluatorFactory) Create(ctx eval.EvaluationContext, condition ngmodels.Condition) (eval.ConditionEvaluator, error) {
+	f.evalCtx = ctx
+	f.condition = condition
+	return f.EvaluatorFactory.Create(ctx, condition)
+}
+
+func TestTemplateQuerier(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	rule := ngmodels.RuleGen.With(ngmodels.RuleGen.WithOrgID(2)).GenerateRef()
+	rule.Data = []ngmodels.AlertQuery{
+		{RefID: "B", DatasourceUID: expr.DatasourceUID},
+		{RefID: "A", DatasourceUID: "rule-ds", RelativeTimeRange: ngmodels.RelativeTimeRange{From: ngmodels.Duration(10 * time.Minute)}},
+		{RefID: "C", DatasourceUID: "other-ds", RelativeTimeRange: ngmodels.RelativeTimeRange{From: ngmodels.Duration(time.Hour), To: ngmodels.Duration(time.Minute)}},
+	}
+
+	newFrame := func(instance string, v float64) *data.Frame {
+		return data.NewFrame("",
+			data.NewField("Value", data.Labels{"instance": instance}, []*float64{util.Pointer(v)}),
+		).SetMeta(&data.FrameMeta{Type: data.FrameTypeNumericMulti, TypeVersion: data.FrameTypeVersion{0, 1}})
+	}
+
+	setup := func(resp *backend.QueryDataResponse, err error) *capturingEvaluatorFactory {
+		m := &eval_mocks.ConditionEvaluatorMock{}
+		m.EXPECT().EvaluateRaw(mock.Anything, ts).Return(resp, err)
+		return &capturingEvaluatorFactory{EvaluatorFactory: eval_mocks.NewEvaluatorFactory(m)}
+	}
+
+	t.Run("executes query against the datasource of the rule as the scheduler", func(t *testing.T) {
+		factory := setup(&backend.QueryDataResponse{Responses: backend.Responses{
+			templateQueryRefID: {Frames: data.Frames{newFrame("foo", 1), newFrame("bar", 2)}},
+		}}, nil)
+		q := NewTemplateQuerierProvider(factory).QuerierFor(rule)
+
+		v, err := q.Query(context.Background(), "", "up", ts)
+		require.NoError(t, err)
+		require.Len(t, v, 2)
+		assert.Equal(t, labels.FromStrings("instance", "foo"), v[0].Metric)
+		assert.Equal(t, 1.0, v[0].F)
+		assert.Equal(t, ts.UnixMilli(), v[0].T)
+		assert.Equal(t, labels.FromStrings("instance", "bar"), v[1].Metric)
+		assert.Equal(t, 2.0, v[1].F)
+
+		require.Len(t, factory.condition.Data, 1)
+		assert.Equal(t, "rule-ds", factory.condition.Data[0].DatasourceUID)
+		assert.Equal(t, rule.Data[1].RelativeTimeRange, factory.condition.Data[0].RelativeTimeRange)
+		query, err := factory.condition.Data[0].GetQuery()
+		require.NoError(t, err)
+		assert.Equal(t, "up", query)
+		assert.Equal(t, SchedulerUserFor(rule.OrgID), factory.evalCtx.User)
+	})
+
+	t.Run("executes query against the requested datasource of the rule", func(t *testing.T) {
+		factory := setup(&backend.QueryDataResponse{}, nil)
+		q := NewTemplateQuerierProvider(factory).QuerierFor(rule)
+
+		v, err := q.Query(context.Background(), "other-ds", "up", ts)
+		require.NoError(t, err)
+		assert.Empty(t, v)
+		assert.Equal(t, "other-ds", factory.condition.Data[0].DatasourceUID)
+		assert.Equal(t, rule.Data[2].RelativeTimeRange, factory.condition.Data[0].RelativeTimeRange)
+	})
+
+	t.Run("returns error if datasource is not queried by the rule", func(t *testing.T) {
+		factory := setup(&backend.QueryDataResponse{}, nil)
+		q := NewTemplateQuerierProvider(factory).QuerierFor(rule)
+
+		_, err := q.Query(context.Background(), "unknown-ds", "up", ts)
+		require.ErrorIs(t, err, errTemplateQueryDatasourceNotAllowed)
+		_, err = q.Query(context.Background(), expr.DatasourceUID, "up", ts)
+		require.ErrorIs(t, err, errTemplateQueryDatasourceNotAllowed)
+		assert.Nil(t, factory.condition.Data)
+	})
+
+	t.Run("returns error if rule has no datasource", func(t *testing.T) {
+		factory := setup(&backend.QueryDataResponse{}, nil)
+		r := ngmodels.CopyRule(rule)
+		r.Data = r.Data[:1]
+		q := NewTemplateQuerierProvider(factory).QuerierFor(r)
+
+		_, err := q.Query(context.Background(), "", "up", ts)
+		require.ErrorIs(t, err, errNoTemplateQueryDatasource)
+	})
+
+	t.Run("returns query errors", func(t *testing.T) {
+		expectedErr := errors.New("test")
+		factory := setup(&backend.QueryDataResponse{Responses: backend.Responses{
+			templateQueryRefID: {Error: expectedErr},
+		}}, nil)
+		q := NewTemplateQuerierProvider(factory).QuerierFor(rule)
+
+		_, err := q.Query(context.Background(), "", "up", ts)
+		require.ErrorIs(t, err, expectedErr)
+	})
+}
+
+func TestDatasourceQuerier(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	m := &eval_mocks.ConditionEvaluatorMock{}
+	m.EXPECT().EvaluateRaw(mock.Anything, ts).Return(&backend.QueryDataResponse{Responses: backend.Responses{
+		templateQueryRefID: {Frames: data.Frames{data.NewFrame("",
+			data.NewField("Value", data.Labels{"instance": "foo"}, []*float64{util.Pointer(1.0)}),
+		).SetMeta(&data.FrameMeta{Type: data.FrameTypeNumericMulti, TypeVersion: data.FrameTypeVersion{0, 1}})}},
+	}}, nil)
+	factory := &capturingEvaluatorFactory{EvaluatorFactory: eval_mocks.NewEvaluatorFactory(m)}
+
+	v, err := NewDatasourceQuerier(factory).Query(context.Background(), 2, "history-ds", "ALERTS", ts)
+	require.NoError(t, err)
+	require.Len(t, v, 1)
+	assert.Equal(t, labels.FromStrings("instance", "foo"), v[0].Metric)
+
+	require.Len(t, factory.condition.Data, 1)
+	assert.Equal(t, "history-ds", factory.condition.Data[0].DatasourceUID)
+	query, err := factory.condition.Data[0].GetQuery()
+	require.NoError(t, err)
+	assert.Equal(t, "ALERTS", query)
+	assert.Equal(t, SchedulerUserFor(2), factory.evalCtx.User)
+}
@@ -71,7 +71,15 @@ func (c *cache) countAlertsBy(state eval.State) float64 {
 	return count
 }
 
+// expandAnnotationsAndLabels expands the labels and annotations of the rule for a new alert instance.
 func expandAnnotationsAndLabels(ctx context.Context, log log.Logger, alertRule *ngModels.AlertRule, result eval.Result, extraLabels data.Labels, externalURL *url.URL) (data.Labels, data.Labels) {
+	lbs, templateData := expandLabels(ctx, log, alertRule, result, extraLabels, externalURL)
+	return lbs, expandAnnotations(ctx, log, alertRule, templateData, externalURL, result.EvaluatedAt, result.EvaluatedAt)
+}
+
+// expandLabels expands the labels of the rule, and returns them along with the template data that annotations
+// of the alert instance are expanded with.
+func expandLabels(ctx context.Context, log log.Logger, alertRule *ngModels.AlertRule, result eval.Result, extraLabels data.Labels, externalURL *url.URL) (data.Labels, template.Data) {
 	var reserved []string
 	resultLabels := result.Instance
 	if len(resultLabels) > 0 {
@@ -102,11 +110,12 @@ func expandAnnotationsAndLabels(ctx context.Context, log log.Logger, alertRule *
 	// Merge both the extra labels and the labels from the evaluation into a common set
 	// of labels that can be expanded in custom labels and annotations.
 	templateData := template.NewData(mergeLabels(extraLabels, resultLabels), result)
+	templateData.Rule = template.NewRuleData(alertRule)
+	templateData.StartsAt = result.EvaluatedAt
 
 	// For now, do nothing with these errors as they are already logged in expand.
 	// In the future, we want to show these errors to the user somehow.
 	labels, _ := expand(ctx, log, alertRule.Title, alertRule.Labels, templateData, externalURL, result.EvaluatedAt)
-	annotations, _ := expand(ctx, log, alertRule.Title, alertRule.Annotations, templateData, externalURL, result.EvaluatedAt)
 
 	lbs := make(data.Labels, len(extraLabels)+len(labels)+len(resultLabels))
 	dupes := make(data.Labels)
@@ -140,7 +149,16 @@ func expandAnnotationsAndLabels(ctx context.Context, log log.Logger, alertRule *
 	if len(dupes) > 0 {
 		log.Debug("Evaluation result contains either reserved labels or labels declared in the rules. Those labels from the result will be ignored", "labels", dupes)
 	}
-	return lbs, annotations
+	return lbs, templateData
+}
+
+// expandAnnotations expands the annotations of the rule. startsAt is the time the alert instance entered its
+// current state. It is known only after the state of the alert instance is updated, so unlike labels,
+// annotations can use it.
+func expandAnnotations(ctx context.Context, log log.Logger, alertRule *ngModels.AlertRule, templateData template.Data, externalURL *url.URL, evaluatedAt, startsAt time.Time) data.Labels {
+	templateData.StartsAt = startsAt
+	annotations, _ := expand(ctx, log, alertRule.Title, alertRule.Annotations, templateData, externalURL, evaluatedAt)
+	return annotations
 }
 
 // expand returns the expanded templates of all annotations or labels for the template data.
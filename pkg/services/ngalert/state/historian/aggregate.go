@@ -0,0 +1,335 @@
+package historian
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+)
+
+const (
+	// maxAggregatedTransitions is the maximum number of transitions a backend reads to calculate an aggregation.
+	maxAggregatedTransitions   = maximumPageSize
+	defaultAggregationInterval = time.Hour
+	defaultAggregationTopN     = 10
+)
+
+var ErrAggregationNotSupported = errutil.NotImplemented("alerting.stateHistory.aggregationNotSupported").Errorf("state history backend does not support aggregations")
+
+// transition is a state transition of an alert instance read from a state history backend.
+type transition struct {
+	Time      time.Time
+	RuleUID   string
+	RuleTitle string
+	// Instance identifies the alert instance within the rule.
+	Instance       string
+	Previous       eval.State
+	PreviousReason string
+	Current        eval.State
+	CurrentReason  string
+}
+
+// parseTransition parses states formatted by state.FormatStateAndReason.
+func parseTransition(t time.Time, ruleUID, ruleTitle, instance, previous, current string) (transition, error) {
+	prev, prevReason, err := state.ParseFormattedState(previous)
+	if err != nil {
+		return transition{}, fmt.Errorf("failed to parse previous state: %w", err)
+	}
+	cur, curReason, err := state.ParseFormattedState(current)
+	if err != nil {
+		return transition{}, fmt.Errorf("failed to parse current state: %w", err)
+	}
+	return transition{
+		Time:           t,
+		RuleUID:        ruleUID,
+		RuleTitle:      ruleTitle,
+		Instance:       instance,
+		Previous:       prev,
+		PreviousReason: prevReason,
+		Current:        cur,
+		CurrentReason:  curReason,
+	}, nil
+}
+
+// transitionsFromFrame parses transitions from a frame in the format returned by the Loki backend.
+// Entries that cannot be parsed are skipped.
+func transitionsFromFrame(frame *data.Frame, logger log.Logger) ([]transition, error) {
+	times, err := frameTimes(frame)
+	if err != nil {
+		return nil, err
+	}
+	lineField, _ := frame.FieldByName(dfLine)
+	if lineField == nil {
+		return nil, fmt.Errorf("unexpected format of state history, field %s is required", dfLine)
+	}
+	result := make([]transition, 0, len(times))
+	for i, ts := range times {
+		line, ok := lineField.At(i).(json.RawMessage)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type of field %s", dfLine)
+		}
+		var entry LokiEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			logger.Warn("Failed to parse state history entry, skipping", "error", err)
+			continue
+		}
+		t, err := parseTransition(ts, entry.RuleUID, entry.RuleTitle, entry.Fingerprint, entry.Previous, entry.Current)
+		if err != nil {
+			logger.Warn("Failed to parse state history entry, skipping", "error", err)
+			continue
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+// matchesStateFilters returns true if the formatted states match the state filters of the query.
+// Unparseable states match only if the query has no state filters.
+func matchesStateFilters(query models.HistoryQuery, previous, current string) bool {
+	if !hasStateFilters(query) {
+		return true
+	}
+	prev, _, err := state.ParseFormattedState(previous)
+	if err != nil {
+		return false
+	}
+	cur, reason, err := state.ParseFormattedState(current)
+	if err != nil {
+		return false
+	}
+	return (query.PreviousState == "" || query.PreviousState == prev.String()) &&
+		(query.CurrentState == "" || query.CurrentState == cur.String()) &&
+		(query.Reason == "" || query.Reason == reason)
+}
+
+func hasStateFilters(query models.HistoryQuery) bool {
+	return query.PreviousState != "" || query.CurrentState != "" || query.Reason != ""
+}
+
+// formattedStateRegex returns a regular expression that matches states formatted by state.FormatStateAndReason.
+// An empty state or reason matches any.
+func formattedStateRegex(s, reason string) string {
+	result := `[^ ]+`
+	if s != "" {
+		result = regexp.QuoteMeta(s)
+	}
+	if reason == "" {
+		return result + `( \(.*\))?`
+	}
+	return result + ` \(` + regexp.QuoteMeta(reason) + `\)`
+}
+
+// aggregate calculates the aggregation of the transitions. All transitions must be within the time range of the query.
+// If truncated is true, the frame gets a notice that the result is calculated from incomplete data.
+func aggregate(query models.HistoryAggregationQuery, transitions []transition, truncated bool) (*data.Frame, error) {
+	var frame *data.Frame
+	switch query.Type {
+	case models.HistoryAggregationTransitions:
+		frame = aggregateTransitions(query, transitions)
+	case models.HistoryAggregationFlapping:
+		frame = aggregateFlapping(query, transitions)
+	case models.HistoryAggregationTimeInState:
+		frame = aggregateTimeInState(query, transitions)
+	default:
+		return nil, fmt.Errorf("unknown aggregation type %q", query.Type)
+	}
+	if truncated {
+		frame.AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("The aggregation is calculated from the most recent %d state transitions only. Narrow down the query to get exact results.", maxAggregatedTransitions),
+		})
+	}
+	return frame, nil
+}
+
+// ruleTitles returns the title of every rule of the transitions. The transitions are expected to be sorted by time,
+// so if the rule was renamed, the latest title wins.
+func ruleTitles(transitions []transition) map[string]string {
+	result := make(map[string]string)
+	for _, t := range transitions {
+		if prev, ok := result[t.RuleUID]; !ok || prev == "" || t.RuleTitle != "" {
+			result[t.RuleUID] = t.RuleTitle
+		}
+	}
+	return result
+}
+
+// aggregateTransitions counts transitions of every rule in time buckets of the query interval.
+// Buckets without transitions are omitted.
+func aggregateTransitions(query models.HistoryAggregationQuery, transitions []transition) *data.Frame {
+	interval := query.Interval
+	if interval <= 0 {
+		interval = defaultAggregationInterval
+	}
+	type bucketKey struct {
+		start   time.Time
+		ruleUID string
+	}
+	titles := ruleTitles(transitions)
+	counts := make(map[bucketKey]int64)
+	for _, t := range transitions {
+		counts[bucketKey{start: t.Time.Truncate(interval).UTC(), ruleUID: t.RuleUID}]++
+	}
+	keys := make([]bucketKey, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].start.Equal(keys[j].start) {
+			return keys[i].start.Before(keys[j].start)
+		}
+		return keys[i].ruleUID < keys[j].ruleUID
+	})
+
+	times := make([]time.Time, 0, len(keys))
+	uids := make([]string, 0, len(keys))
+	titleValues := make([]string, 0, len(keys))
+	values := make([]int64, 0, len(keys))
+	for _, k := range keys {
+		times = append(times, k.start)
+		uids = append(uids, k.ruleUID)
+		titleValues = append(titleValues, titles[k.ruleUID])
+		values = append(values, counts[k])
+	}
+	return data.NewFrame(string(models.HistoryAggregationTransitions),
+		data.NewField("time", nil, times),
+		data.NewField("ruleUID", nil, uids),
+		data.NewField("ruleTitle", nil, titleValues),
+		data.NewField("transitions", nil, values),
+	)
+}
+
+// aggregateFlapping returns the top N rules by the number of times their alert instances started or stopped firing.
+func aggregateFlapping(query models.HistoryAggregationQuery, transitions []transition) *data.Frame {
+	topN := query.TopN
+	if topN <= 0 {
+		topN = defaultAggregationTopN
+	}
+	type flaps struct {
+		ruleUID   string
+		count     int64
+		instances map[string]struct{}
+	}
+	titles := ruleTitles(transitions)
+	byRule := make(map[string]*flaps)
+	for _, t := range transitions {
+		if (t.Previous == eval.Alerting) == (t.Current == eval.Alerting) {
+			continue
+		}
+		f, ok := byRule[t.RuleUID]
+		if !ok {
+			f = &flaps{ruleUID: t.RuleUID, instances: make(map[string]struct{})}
+			byRule[t.RuleUID] = f
+		}
+		f.count++
+		f.instances[t.Instance] = struct{}{}
+	}
+	result := make([]*flaps, 0, len(byRule))
+	for _, f := range byRule {
+		result = append(result, f)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].count != result[j].count {
+			return result[i].count > result[j].count
+		}
+		return result[i].ruleUID < result[j].ruleUID
+	})
+	if len(result) > topN {
+		result = result[:topN]
+	}
+
+	uids := make([]string, 0, len(result))
+	titleValues := make([]string, 0, len(result))
+	counts := make([]int64, 0, len(result))
+	instances := make([]int64, 0, len(result))
+	for _, f := range result {
+		uids = append(uids, f.ruleUID)
+		titleValues = append(titleValues, titles[f.ruleUID])
+		counts = append(counts, f.count)
+		instances = append(instances, int64(len(f.instances)))
+	}
+	return data.NewFrame(string(models.HistoryAggregationFlapping),
+		data.NewField("ruleUID", nil, uids),
+		data.NewField("ruleTitle", nil, titleValues),
+		data.NewField("flaps", nil, counts),
+		data.NewField("instances", nil, instances),
+	)
+}
+
+// aggregateTimeInState sums the time that alert instances of every rule spent in every state within the time range
+// of the query. An instance is considered to be in the previous state of its first transition since the beginning of
+// the range, and in the current state of its last transition until the end of the range.
+// Instances without transitions in the range are not accounted for.
+func aggregateTimeInState(query models.HistoryAggregationQuery, transitions []transition) *data.Frame {
+	type instanceKey struct {
+		ruleUID  string
+		instance string
+	}
+	type stateKey struct {
+		ruleUID string
+		state   string
+	}
+	titles := ruleTitles(transitions)
+	byInstance := make(map[instanceKey][]transition)
+	for _, t := range transitions {
+		key := instanceKey{ruleUID: t.RuleUID, instance: t.Instance}
+		byInstance[key] = append(byInstance[key], t)
+	}
+
+	durations := make(map[stateKey]time.Duration)
+	add := func(t transition, s eval.State, from, to time.Time) {
+		if to.After(from) {
+			durations[stateKey{ruleUID: t.RuleUID, state: s.String()}] += to.Sub(from)
+		}
+	}
+	for _, ts := range byInstance {
+		sort.SliceStable(ts, func(i, j int) bool {
+			return ts[i].Time.Before(ts[j].Time)
+		})
+		add(ts[0], ts[0].Previous, query.From, ts[0].Time)
+		for i, t := range ts {
+			end := query.To
+			if i+1 < len(ts) {
+				end = ts[i+1].Time
+			}
+			add(t, t.Current, t.Time, end)
+		}
+	}
+
+	keys := make([]stateKey, 0, len(durations))
+	for k := range durations {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].ruleUID != keys[j].ruleUID {
+			return keys[i].ruleUID < keys[j].ruleUID
+		}
+		return keys[i].state < keys[j].state
+	})
+
+	uids := make([]string, 0, len(keys))
+	titleValues := make([]string, 0, len(keys))
+	states := make([]string, 0, len(keys))
+	seconds := make([]float64, 0, len(keys))
+	for _, k := range keys {
+		uids = append(uids, k.ruleUID)
+		titleValues = append(titleValues, titles[k.ruleUID])
+		states = append(states, k.state)
+		seconds = append(seconds, durations[k].Seconds())
+	}
+	return data.NewFrame(string(models.HistoryAggregationTimeInState),
+		data.NewField("ruleUID", nil, uids),
+		data.NewField("ruleTitle", nil, titleValues),
+		data.NewField("state", nil, states),
+		data.NewField("seconds", nil, seconds),
+	)
+}
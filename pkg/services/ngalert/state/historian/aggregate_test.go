@@ -0,0 +1,199 @@
+package historian
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/services/annotations"
+	acfakes "github.com/grafana/grafana/pkg/services/ngalert/accesscontrol/fakes"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestAggregate(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(4 * time.Hour)
+	tr := func(offset time.Duration, ruleUID, instance string, prev, cur eval.State) transition {
+		return transition{
+			Time:      from.Add(offset),
+			RuleUID:   ruleUID,
+			RuleTitle: "title-" + ruleUID,
+			Instance:  instance,
+			Previous:  prev,
+			Current:   cur,
+		}
+	}
+	transitions := []transition{
+		tr(30*time.Minute, "rule-1", "a", eval.Normal, eval.Pending),
+		tr(40*time.Minute, "rule-1", "a", eval.Pending, eval.Alerting),
+		tr(90*time.Minute, "rule-1", "a", eval.Alerting, eval.Normal),
+		tr(100*time.Minute, "rule-2", "a", eval.Normal, eval.Alerting),
+		tr(110*time.Minute, "rule-2", "b", eval.Normal, eval.Alerting),
+		tr(3*time.Hour, "rule-2", "a", eval.Alerting, eval.Normal),
+	}
+	query := func(aggregation models.HistoryAggregationType) models.HistoryAggregationQuery {
+		return models.HistoryAggregationQuery{
+			HistoryQuery: models.HistoryQuery{From: from, To: to},
+			Type:         aggregation,
+		}
+	}
+
+	t.Run("transitions", func(t *testing.T) {
+		frame, err := aggregate(query(models.HistoryAggregationTransitions), transitions, false)
+		require.NoError(t, err)
+		require.Equal(t, 4, frame.Rows())
+		expected := [][]any{
+			{from, "rule-1", "title-rule-1", int64(2)},
+			{from.Add(time.Hour), "rule-1", "title-rule-1", int64(1)},
+			{from.Add(time.Hour), "rule-2", "title-rule-2", int64(2)},
+			{from.Add(3 * time.Hour), "rule-2", "title-rule-2", int64(1)},
+		}
+		for i, row := range expected {
+			require.Equal(t, row, frame.RowCopy(i))
+		}
+	})
+
+	t.Run("transitions with custom interval", func(t *testing.T) {
+		q := query(models.HistoryAggregationTransitions)
+		q.Interval = 4 * time.Hour
+		frame, err := aggregate(q, transitions, false)
+		require.NoError(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, []any{from, "rule-1", "title-rule-1", int64(3)}, frame.RowCopy(0))
+		require.Equal(t, []any{from, "rule-2", "title-rule-2", int64(3)}, frame.RowCopy(1))
+	})
+
+	t.Run("flapping", func(t *testing.T) {
+		frame, err := aggregate(query(models.HistoryAggregationFlapping), transitions, false)
+		require.NoError(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, []any{"rule-2", "title-rule-2", int64(3), int64(2)}, frame.RowCopy(0))
+		require.Equal(t, []any{"rule-1", "title-rule-1", int64(2), int64(1)}, frame.RowCopy(1))
+
+		q := query(models.HistoryAggregationFlapping)
+		q.TopN = 1
+		frame, err = aggregate(q, transitions, false)
+		require.NoError(t, err)
+		require.Equal(t, 1, frame.Rows())
+		require.Equal(t, "rule-2", frame.Fields[0].At(0))
+	})
+
+	t.Run("time in state", func(t *testing.T) {
+		frame, err := aggregate(query(models.HistoryAggregationTimeInState), transitions, false)
+		require.NoError(t, err)
+		expected := [][]any{
+			{"rule-1", "title-rule-1", "Alerting", (50 * time.Minute).Seconds()},
+			{"rule-1", "title-rule-1", "Normal", (30*time.Minute + 150*time.Minute).Seconds()},
+			{"rule-1", "title-rule-1", "Pending", (10 * time.Minute).Seconds()},
+			{"rule-2", "title-rule-2", "Alerting", (80*time.Minute + 130*time.Minute).Seconds()},
+			{"rule-2", "title-rule-2", "Normal", (100*time.Minute + 60*time.Minute + 110*time.Minute).Seconds()},
+		}
+		require.Equal(t, len(expected), frame.Rows())
+		for i, row := range expected {
+			require.Equal(t, row, frame.RowCopy(i))
+		}
+	})
+
+	t.Run("adds notice if transitions are truncated", func(t *testing.T) {
+		frame, err := aggregate(query(models.HistoryAggregationFlapping), transitions, true)
+		require.NoError(t, err)
+		require.NotNil(t, frame.Meta)
+		require.Len(t, frame.Meta.Notices, 1)
+	})
+
+	t.Run("fails on unknown aggregation", func(t *testing.T) {
+		_, err := aggregate(query("unknown"), transitions, false)
+		require.Error(t, err)
+	})
+}
+
+func TestMatchesStateFilters(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    models.HistoryQuery
+		expected bool
+	}{
+		{name: "no filters", query: models.HistoryQuery{}, expected: true},
+		{name: "previous state", query: models.HistoryQuery{PreviousState: "Normal"}, expected: true},
+		{name: "different previous state", query: models.HistoryQuery{PreviousState: "Pending"}, expected: false},
+		{name: "current state and reason", query: models.HistoryQuery{CurrentState: "Alerting", Reason: "NoData"}, expected: true},
+		{name: "different reason", query: models.HistoryQuery{Reason: "Error"}, expected: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, matchesStateFilters(tc.query, "Normal (Updated)", "Alerting (NoData)"))
+		})
+	}
+}
+
+func TestAnnotationInstance(t *testing.T) {
+	require.Equal(t, "a=b, c=d", annotationInstance("my rule", "my rule {a=b, c=d} - A=1.000000"))
+	require.Equal(t, "", annotationInstance("my rule", "my rule {} - No data"))
+	require.Equal(t, "unexpected", annotationInstance("my rule", "unexpected"))
+}
+
+func TestAnnotationBackend_Aggregate(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	store := &interceptingAnnotationStore{}
+	anns := createTestAnnotationSutWithStore(t, store)
+	rule, err := anns.rules.GetAlertRuleByUID(context.Background(), &models.GetAlertRuleByUIDQuery{UID: "my-rule", OrgID: 1})
+	require.NoError(t, err)
+	store.items = []*annotations.ItemDTO{
+		{ID: 2, AlertID: rule.ID, Time: now.UnixMilli(), PrevState: "Alerting", NewState: "Normal", Text: rule.Title + " {a=b} - A=0.000000"},
+		{ID: 1, AlertID: rule.ID, Time: now.Add(-time.Minute).UnixMilli(), PrevState: "Normal", NewState: "Alerting", Text: rule.Title + " {a=b} - A=1.000000"},
+		// annotation of a rule the user cannot read
+		{ID: 3, AlertID: rule.ID + 1000, Time: now.UnixMilli(), PrevState: "Normal", NewState: "Alerting"},
+	}
+
+	frame, err := anns.Aggregate(context.Background(), models.HistoryAggregationQuery{
+		HistoryQuery: models.HistoryQuery{OrgID: 1, RuleUID: "my-rule"},
+		Type:         models.HistoryAggregationFlapping,
+	})
+	require.NoError(t, err)
+	require.Equal(t, rule.ID, store.lastQuery.AlertID)
+	require.EqualValues(t, maxAggregatedTransitions, store.lastQuery.Limit)
+	require.Equal(t, 1, frame.Rows())
+	require.Equal(t, []any{rule.UID, rule.Title, int64(2), int64(1)}, frame.RowCopy(0))
+}
+
+func TestRemoteLokiBackend_Aggregate(t *testing.T) {
+	req := NewFakeRequester().WithResponse(&http.Response{
+		Status:        "200 OK",
+		StatusCode:    200,
+		Body:          io.NopCloser(bytes.NewBufferString(`{"data": {"result": []}}`)),
+		ContentLength: int64(0),
+		Header:        make(http.Header, 0),
+	})
+	loki := createTestLokiBackend(t, req, metrics.NewHistorianMetrics(prometheus.NewRegistry(), metrics.Subsystem))
+	ac := &acfakes.FakeRuleService{}
+	ac.CanReadAllRulesFunc = func(ctx context.Context, requester identity.Requester) (bool, error) {
+		return true, nil
+	}
+	loki.ac = ac
+	clk := clock.NewMock()
+	clk.Set(time.Now())
+	loki.clock = clk
+
+	frame, err := loki.Aggregate(context.Background(), models.HistoryAggregationQuery{
+		HistoryQuery: models.HistoryQuery{OrgID: 1, RuleUID: "my-rule"},
+		Type:         models.HistoryAggregationFlapping,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, frame.Rows())
+
+	// The default time range ends at the time of the backend clock.
+	params := req.lastRequest.URL.Query()
+	require.Equal(t, fmt.Sprint(clk.Now().UnixNano()), params.Get("end"))
+	require.Equal(t, fmt.Sprint(clk.Now().Add(-defaultQueryRange).UnixNano()), params.Get("start"))
+}
@@ -43,6 +43,7 @@ type AnnotationBackend struct {
 
 type RuleStore interface {
 	GetAlertRuleByUID(ctx context.Context, query *ngmodels.GetAlertRuleByUIDQuery) (*ngmodels.AlertRule, error)
+	ListAlertRules(ctx context.Context, query *ngmodels.ListAlertRulesQuery) (ngmodels.RulesGroup, error)
 	GetUserVisibleNamespaces(ctx context.Context, orgID int64, user identity.Requester) (map[string]*folder.Folder, error)
 }
 
@@ -119,19 +120,14 @@ func (h *AnnotationBackend) Query(ctx context.Context, query ngmodels.HistoryQue
 		logger.Warn("Annotation state history backend does not support label queries, ignoring that filter")
 	}
 
-	rq := ngmodels.GetAlertRuleByUIDQuery{
-		UID:   query.RuleUID,
-		OrgID: query.OrgID,
-	}
-	rule, err := h.rules.GetAlertRuleByUID(ctx, &rq)
+	token, err := parseContinueToken(query.ContinueToken)
 	if err != nil {
-		return nil, fmt.Errorf("failed to look up the requested rule")
-	}
-	if rule == nil {
-		return nil, fmt.Errorf("no such rule exists")
+		return nil, err
 	}
+	fetchLimit := token.applyTo(&query)
 
-	if err := h.ac.AuthorizeAccessInFolder(ctx, query.SignedInUser, rule); err != nil {
+	rule, err := h.getAuthorizedRule(ctx, query)
+	if err != nil {
 		return nil, err
 	}
 
@@ -142,10 +138,18 @@ func (h *AnnotationBackend) Query(ctx context.Context, query ngmodels.HistoryQue
 		To:           query.To.UnixMilli(),
 		SignedInUser: query.SignedInUser,
 	}
-	items, err := h.store.Find(ctx, &q)
+	items, err := h.findAnnotations(ctx, q, fetchLimit, func(item *annotations.ItemDTO) bool {
+		return matchesStateFilters(query, item.PrevState, item.NewState)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to query annotations for state history: %w", err)
+		return nil, err
+	}
+
+	itemTimes := make([]time.Time, 0, len(items))
+	for _, item := range items {
+		itemTimes = append(itemTimes, time.UnixMilli(item.Time))
 	}
+	rows, next := token.page(itemTimes, query.Limit, fetchLimit)
 
 	frame := data.NewFrame("states")
 
@@ -170,12 +174,13 @@ func (h *AnnotationBackend) Query(ctx context.Context, query ngmodels.HistoryQue
 	//   3. `prev` - the previous state and reason
 	//   4. `next` - the next state and reason
 	//   5. `data` - a JSON string, containing the annotation's contents. analogous to item.Data
-	times := make([]time.Time, 0, len(items))
-	texts := make([]string, 0, len(items))
-	prevStates := make([]string, 0, len(items))
-	nextStates := make([]string, 0, len(items))
-	values := make([]string, 0, len(items))
-	for _, item := range items {
+	times := make([]time.Time, 0, len(rows))
+	texts := make([]string, 0, len(rows))
+	prevStates := make([]string, 0, len(rows))
+	nextStates := make([]string, 0, len(rows))
+	values := make([]string, 0, len(rows))
+	for _, row := range rows {
+		item := items[row]
 		data, err := json.Marshal(item.Data)
 		if err != nil {
 			logger.Error("Annotation service gave an annotation with unparseable data, skipping", "id", item.ID, "err", err)
@@ -193,10 +198,154 @@ func (h *AnnotationBackend) Query(ctx context.Context, query ngmodels.HistoryQue
 	frame.Fields = append(frame.Fields, data.NewField("prev", lbls, prevStates))
 	frame.Fields = append(frame.Fields, data.NewField("next", lbls, nextStates))
 	frame.Fields = append(frame.Fields, data.NewField("data", lbls, values))
+	setContinueToken(frame, next)
 
 	return frame, nil
 }
 
+// Aggregate calculates an aggregation of state history annotations.
+// If the query does not filter by rule, it aggregates annotations of all rules the user can read.
+func (h *AnnotationBackend) Aggregate(ctx context.Context, query ngmodels.HistoryAggregationQuery) (*data.Frame, error) {
+	logger := h.log.FromContext(ctx)
+	now := h.clock.Now().UTC()
+	if query.To.IsZero() {
+		query.To = now
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-defaultQueryRange)
+	}
+
+	q := annotations.ItemQuery{
+		OrgID:        query.OrgID,
+		From:         query.From.UnixMilli(),
+		To:           query.To.UnixMilli(),
+		SignedInUser: query.SignedInUser,
+	}
+	rules := make(map[int64]*ngmodels.AlertRule)
+	if query.RuleUID != "" {
+		rule, err := h.getAuthorizedRule(ctx, query.HistoryQuery)
+		if err != nil {
+			return nil, err
+		}
+		rules[rule.ID] = rule
+		q.AlertID = rule.ID
+	} else {
+		uids, err := getFolderUIDsForFilter(ctx, h.ac, h.rules, query.HistoryQuery)
+		if err != nil {
+			return nil, err
+		}
+		found, err := h.rules.ListAlertRules(ctx, &ngmodels.ListAlertRulesQuery{
+			OrgID:         query.OrgID,
+			NamespaceUIDs: uids,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch alert rules: %w", err)
+		}
+		for _, rule := range found {
+			rules[rule.ID] = rule
+		}
+		// Restrict the query to annotations created by alert rules.
+		q.Type = "alert"
+	}
+
+	items, err := h.findAnnotations(ctx, q, maxAggregatedTransitions, func(item *annotations.ItemDTO) bool {
+		_, ok := rules[item.AlertID]
+		return ok && matchesStateFilters(query.HistoryQuery, item.PrevState, item.NewState)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	transitions := make([]transition, 0, len(items))
+	for _, item := range items {
+		rule := rules[item.AlertID]
+		t, err := parseTransition(time.UnixMilli(item.Time), rule.UID, rule.Title, annotationInstance(rule.Title, item.Text), item.PrevState, item.NewState)
+		if err != nil {
+			logger.Warn("Failed to parse state history annotation, skipping", "id", item.ID, "error", err)
+			continue
+		}
+		transitions = append(transitions, t)
+	}
+	sort.SliceStable(transitions, func(i, j int) bool {
+		return transitions[i].Time.Before(transitions[j].Time)
+	})
+	return aggregate(query, transitions, len(items) >= maxAggregatedTransitions)
+}
+
+// findAnnotations returns up to limit of the most recent annotations that match. The annotation store cannot filter
+// by state, so it reads annotations in batches of limit, newest first, until it finds enough matching ones or there
+// are no more. If limit is not positive, it reads a single batch of the default size of the store.
+func (h *AnnotationBackend) findAnnotations(ctx context.Context, q annotations.ItemQuery, limit int, match func(*annotations.ItemDTO) bool) ([]*annotations.ItemDTO, error) {
+	q.Limit = int64(limit)
+	result := make([]*annotations.ItemDTO, 0)
+	seen := make(map[int64]struct{})
+	for {
+		items, err := h.store.Find(ctx, &q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query annotations for state history: %w", err)
+		}
+		found := false
+		oldest := int64(math.MaxInt64)
+		for _, item := range items {
+			oldest = min(oldest, item.Time)
+			// The next batch starts at the time of the oldest annotation of this batch, which is included again.
+			if _, ok := seen[item.ID]; ok {
+				continue
+			}
+			seen[item.ID] = struct{}{}
+			found = true
+			if match(item) {
+				result = append(result, item)
+			}
+		}
+		if limit <= 0 || len(result) >= limit || len(items) < limit || !found {
+			break
+		}
+		q.To = oldest
+	}
+	if limit > 0 && len(result) > limit {
+		sort.SliceStable(result, func(i, j int) bool {
+			return result[i].Time > result[j].Time
+		})
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// getAuthorizedRule returns the rule the query filters by, if the user has access to it.
+func (h *AnnotationBackend) getAuthorizedRule(ctx context.Context, query ngmodels.HistoryQuery) (*ngmodels.AlertRule, error) {
+	rq := ngmodels.GetAlertRuleByUIDQuery{
+		UID:   query.RuleUID,
+		OrgID: query.OrgID,
+	}
+	rule, err := h.rules.GetAlertRuleByUID(ctx, &rq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the requested rule")
+	}
+	if rule == nil {
+		return nil, fmt.Errorf("no such rule exists")
+	}
+
+	if err := h.ac.AuthorizeAccessInFolder(ctx, query.SignedInUser, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// annotationInstance extracts the labels of the alert instance from the text of the annotation built by BuildAnnotationTextAndData.
+// It returns the entire text if it is not in the expected format.
+func annotationInstance(ruleTitle, text string) string {
+	lbls, ok := strings.CutPrefix(text, ruleTitle+" {")
+	if !ok {
+		return text
+	}
+	idx := strings.LastIndex(lbls, "} - ")
+	if idx < 0 {
+		return text
+	}
+	return lbls[:idx]
+}
+
 func buildAnnotations(rule history_model.RuleMeta, states []state.StateTransition, logger log.Logger) []annotations.Item {
 	items := make([]annotations.Item, 0, len(states))
 	for _, state := range states {
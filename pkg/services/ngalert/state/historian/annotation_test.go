@@ -97,6 +97,54 @@ func TestAnnotationHistorian(t *testing.T) {
 		require.Equal(t, now.Add(-10*time.Second).UnixMilli(), query.From)
 	})
 
+	t.Run("annotation queries are paginated and filtered by state", func(t *testing.T) {
+		now := time.Now().Truncate(time.Millisecond)
+		item := func(id int64, ts time.Time, prev, next string) *annotations.ItemDTO {
+			return &annotations.ItemDTO{ID: id, Time: ts.UnixMilli(), PrevState: prev, NewState: next}
+		}
+		// The annotation store returns the newest annotations first.
+		store := &interceptingAnnotationStore{items: []*annotations.ItemDTO{
+			item(5, now, "Alerting", "Normal"),
+			item(4, now.Add(-time.Second), "Normal (NoData)", "Alerting"),
+			item(3, now.Add(-2*time.Second), "Alerting", "Normal"),
+			item(2, now.Add(-3*time.Second), "Normal", "Alerting"),
+			item(1, now.Add(-4*time.Second), "Normal", "Alerting"),
+		}}
+		anns := createTestAnnotationSutWithStore(t, store)
+
+		q := models.HistoryQuery{
+			RuleUID:      "my-rule",
+			OrgID:        1,
+			From:         now.Add(-time.Minute),
+			To:           now,
+			Limit:        2,
+			CurrentState: "Alerting",
+		}
+		frame, err := anns.Query(context.Background(), q)
+		require.NoError(t, err)
+		// the filter is applied before the page is selected, so the store is read until the page is full.
+		require.Len(t, store.queries, 3)
+		require.EqualValues(t, 2, store.queries[0].Limit)
+		require.Equal(t, now.Add(-time.Second).UnixMilli(), store.queries[1].To)
+		require.Equal(t, now.Add(-2*time.Second).UnixMilli(), store.queries[2].To)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, "Alerting", frame.Fields[3].At(0))
+		require.Equal(t, "Alerting", frame.Fields[3].At(1))
+		require.NotNil(t, frame.Meta)
+		meta, ok := frame.Meta.Custom.(models.HistoryQueryResultMeta)
+		require.True(t, ok)
+		require.NotEmpty(t, meta.ContinueToken)
+
+		store.queries = nil
+		q.ContinueToken = meta.ContinueToken
+		frame, err = anns.Query(context.Background(), q)
+		require.NoError(t, err)
+		require.EqualValues(t, 3, store.queries[0].Limit)
+		require.Equal(t, now.Add(-3*time.Second).UnixMilli(), store.queries[0].To)
+		require.Equal(t, 1, frame.Rows())
+		require.Nil(t, frame.Meta)
+	})
+
 	t.Run("writing state transitions as annotations succeeds", func(t *testing.T) {
 		anns := createTestAnnotationBackendSut(t)
 		rule := createTestRule()
@@ -276,11 +324,26 @@ func assertValidJSON(t *testing.T, j *simplejson.Json) string {
 
 type interceptingAnnotationStore struct {
 	lastQuery *annotations.ItemQuery
+	queries   []annotations.ItemQuery
+	items     []*annotations.ItemDTO
 }
 
+// Find returns the items within the time range of the query, up to its limit. The items are expected to be sorted
+// from the newest to the oldest, like the annotation store returns them.
 func (i *interceptingAnnotationStore) Find(ctx context.Context, query *annotations.ItemQuery) ([]*annotations.ItemDTO, error) {
 	i.lastQuery = query
-	return []*annotations.ItemDTO{}, nil
+	i.queries = append(i.queries, *query)
+	result := []*annotations.ItemDTO{}
+	for _, item := range i.items {
+		if query.From > 0 && query.To > 0 && (item.Time < query.From || item.Time > query.To) {
+			continue
+		}
+		if query.Limit > 0 && int64(len(result)) >= query.Limit {
+			break
+		}
+		result = append(result, item)
+	}
+	return result, nil
 }
 
 func (i *interceptingAnnotationStore) Save(ctx context.Context, panel *PanelKey, annotations []annotations.Item, orgID int64, logger log.Logger) error {
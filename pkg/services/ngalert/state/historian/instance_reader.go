@@ -0,0 +1,168 @@
+package historian
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+const (
+	// DefaultInstanceReaderLookback is how far back InstanceReader looks for state transitions by default.
+	DefaultInstanceReaderLookback = 24 * time.Hour
+	// instanceReaderPageSize is the number of state transitions InstanceReader requests per page.
+	instanceReaderPageSize = 1000
+	// instanceReaderMaxPages limits the number of pages InstanceReader reads, so a very long history does not
+	// block the warm up of the state cache.
+	instanceReaderMaxPages = 100
+)
+
+var errInstanceReaderTooManyPages = fmt.Errorf("state history has more than %d transitions in the lookback period", instanceReaderPageSize*instanceReaderMaxPages)
+
+// InstanceReader is a state.InstanceReader that reconstructs alert instances from the state history.
+// For every alert instance it returns the state the instance transitioned to most recently,
+// and the time of that transition as the time since when the instance is in that state.
+// It works with backends that return state history in the format of the Loki backend.
+type InstanceReader struct {
+	querier  Querier
+	lookback time.Duration
+	userFor  func(orgID int64) *user.SignedInUser
+	clock    clock.Clock
+	log      log.Logger
+}
+
+// NewInstanceReader creates an InstanceReader. It queries the state history of an organization with the identity
+// returned by userFor, which must be able to read all rules of the organization.
+func NewInstanceReader(querier Querier, lookback time.Duration, userFor func(orgID int64) *user.SignedInUser, logger log.Logger) *InstanceReader {
+	return &InstanceReader{
+		querier:  querier,
+		lookback: lookback,
+		userFor:  userFor,
+		clock:    clock.New(),
+		log:      logger,
+	}
+}
+
+// ListAlertInstances returns alert instances of the organization, and optionally of a single rule, whose state changed
+// during the lookback period. It reads all pages of the history, and returns an error if the history has more than
+// instanceReaderMaxPages pages rather than returning an incomplete set of instances.
+func (r *InstanceReader) ListAlertInstances(ctx context.Context, cmd *models.ListAlertInstancesQuery) ([]*models.AlertInstance, error) {
+	now := r.clock.Now()
+	query := models.HistoryQuery{
+		OrgID:        cmd.RuleOrgID,
+		RuleUID:      cmd.RuleUID,
+		From:         now.Add(-r.lookback),
+		To:           now,
+		Limit:        instanceReaderPageSize,
+		SignedInUser: r.userFor(cmd.RuleOrgID),
+	}
+	instances := newInstanceSet()
+	for page := 0; ; page++ {
+		if page >= instanceReaderMaxPages {
+			return nil, errInstanceReaderTooManyPages
+		}
+		frame, err := r.querier.Query(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query state history: %w", err)
+		}
+		if err := r.addInstancesFromFrame(instances, cmd.RuleOrgID, frame); err != nil {
+			return nil, err
+		}
+		query.ContinueToken = continueTokenOf(frame)
+		if query.ContinueToken == "" {
+			break
+		}
+	}
+	return instances.list(), nil
+}
+
+type instanceKey struct {
+	ruleUID     string
+	fingerprint string
+}
+
+// instanceSet keeps the latest transition of every alert instance in the order the instances were first seen.
+type instanceSet struct {
+	byKey map[instanceKey]*models.AlertInstance
+	keys  []instanceKey
+}
+
+func newInstanceSet() *instanceSet {
+	return &instanceSet{byKey: make(map[instanceKey]*models.AlertInstance)}
+}
+
+func (s *instanceSet) add(key instanceKey, instance *models.AlertInstance) {
+	existing, ok := s.byKey[key]
+	if !ok {
+		s.keys = append(s.keys, key)
+	} else if instance.CurrentStateSince.Before(existing.CurrentStateSince) {
+		return
+	}
+	s.byKey[key] = instance
+}
+
+func (s *instanceSet) list() []*models.AlertInstance {
+	if len(s.keys) == 0 {
+		return nil
+	}
+	result := make([]*models.AlertInstance, 0, len(s.keys))
+	for _, key := range s.keys {
+		result = append(result, s.byKey[key])
+	}
+	return result
+}
+
+func (r *InstanceReader) addInstancesFromFrame(instances *instanceSet, orgID int64, frame *data.Frame) error {
+	if frame == nil || frame.Rows() == 0 {
+		return nil
+	}
+	timeField, _ := frame.FieldByName(dfTime)
+	lineField, _ := frame.FieldByName(dfLine)
+	if timeField == nil || lineField == nil {
+		return fmt.Errorf("unexpected format of state history, fields %s and %s are required", dfTime, dfLine)
+	}
+
+	// pages can come in any order of time, so the latest transition of an instance wins.
+	for i := 0; i < frame.Rows(); i++ {
+		t, ok := timeField.At(i).(time.Time)
+		if !ok {
+			return fmt.Errorf("unexpected type of field %s", dfTime)
+		}
+		line, ok := lineField.At(i).(json.RawMessage)
+		if !ok {
+			return fmt.Errorf("unexpected type of field %s", dfLine)
+		}
+		var entry LokiEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			r.log.Warn("Failed to parse state history entry, skipping", "error", err)
+			continue
+		}
+		current, reason, err := state.ParseFormattedState(entry.Current)
+		if err != nil {
+			r.log.Warn("Failed to parse state of state history entry, skipping", "error", err, "state", entry.Current)
+			continue
+		}
+
+		key := instanceKey{ruleUID: entry.RuleUID, fingerprint: entry.Fingerprint}
+		instances.add(key, &models.AlertInstance{
+			AlertInstanceKey: models.AlertInstanceKey{
+				RuleOrgID: orgID,
+				RuleUID:   entry.RuleUID,
+			},
+			Labels:            models.InstanceLabels(entry.InstanceLabels),
+			CurrentState:      models.InstanceStateType(current.String()),
+			CurrentReason:     reason,
+			CurrentStateSince: t,
+			LastEvalTime:      t,
+		})
+	}
+	return nil
+}
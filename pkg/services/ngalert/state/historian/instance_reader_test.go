@@ -0,0 +1,150 @@
+package historian
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestInstanceReader_ListAlertInstances(t *testing.T) {
+	now := time.Now().UTC()
+	entry := func(ruleUID, fingerprint, current, reason string, ts time.Time) models.StateHistoryEntry {
+		return models.StateHistoryEntry{
+			OrgID:         1,
+			RuleUID:       ruleUID,
+			Fingerprint:   fingerprint,
+			Labels:        map[string]string{"fp": fingerprint},
+			PreviousState: "Normal",
+			CurrentState:  current,
+			CurrentReason: reason,
+			Timestamp:     ts,
+		}
+	}
+
+	t.Run("should return the last transition of every instance", func(t *testing.T) {
+		frame, err := entriesToFrame([]models.StateHistoryEntry{
+			entry("rule-1", "a", "Pending", "", now.Add(-3*time.Minute)),
+			entry("rule-1", "b", "Alerting", "", now.Add(-2*time.Minute)),
+			entry("rule-1", "a", "Alerting", models.StateReasonNoData, now.Add(-time.Minute)),
+			entry("rule-2", "a", "Normal", "", now),
+		})
+		require.NoError(t, err)
+		querier := &fakeQuerier{frame: frame}
+		reader := createTestInstanceReader(querier)
+		clk := clock.NewMock()
+		clk.Set(now)
+		reader.clock = clk
+
+		instances, err := reader.ListAlertInstances(context.Background(), &models.ListAlertInstancesQuery{RuleOrgID: 1, RuleUID: "rule-1"})
+		require.NoError(t, err)
+
+		require.Equal(t, int64(1), querier.lastQuery.OrgID)
+		require.Equal(t, "rule-1", querier.lastQuery.RuleUID)
+		require.Equal(t, now, querier.lastQuery.To)
+		require.Equal(t, now.Add(-DefaultInstanceReaderLookback), querier.lastQuery.From)
+		require.Equal(t, instanceReaderPageSize, querier.lastQuery.Limit)
+		require.Equal(t, testHistoryReaderUser(1), querier.lastQuery.SignedInUser)
+
+		require.Len(t, instances, 3)
+		require.Equal(t, "rule-1", instances[0].RuleUID)
+		require.Equal(t, models.InstanceStateFiring, instances[0].CurrentState)
+		require.Equal(t, models.StateReasonNoData, instances[0].CurrentReason)
+		require.Equal(t, now.Add(-time.Minute), instances[0].CurrentStateSince)
+		require.Equal(t, models.InstanceLabels{"fp": "a"}, instances[0].Labels)
+
+		require.Equal(t, models.InstanceStateFiring, instances[1].CurrentState)
+		require.Equal(t, now.Add(-2*time.Minute), instances[1].CurrentStateSince)
+
+		require.Equal(t, "rule-2", instances[2].RuleUID)
+		require.Equal(t, models.InstanceStateNormal, instances[2].CurrentState)
+	})
+
+	t.Run("should read all pages of the history", func(t *testing.T) {
+		newest, err := entriesToFrame([]models.StateHistoryEntry{
+			entry("rule-1", "b", "Normal", "", now.Add(-time.Minute)),
+			entry("rule-1", "a", "Alerting", "", now),
+		})
+		require.NoError(t, err)
+		setContinueToken(newest, "next")
+		oldest, err := entriesToFrame([]models.StateHistoryEntry{
+			entry("rule-1", "a", "Pending", "", now.Add(-3*time.Minute)),
+			entry("rule-1", "b", "Alerting", "", now.Add(-2*time.Minute)),
+		})
+		require.NoError(t, err)
+		querier := &fakeQuerier{pages: map[string]*data.Frame{"": newest, "next": oldest}}
+		reader := createTestInstanceReader(querier)
+
+		instances, err := reader.ListAlertInstances(context.Background(), &models.ListAlertInstancesQuery{RuleOrgID: 1})
+		require.NoError(t, err)
+
+		require.Len(t, querier.queries, 2)
+		require.Equal(t, "next", querier.queries[1].ContinueToken)
+		require.Len(t, instances, 2)
+		require.Equal(t, models.InstanceLabels{"fp": "b"}, instances[0].Labels)
+		require.Equal(t, models.InstanceStateNormal, instances[0].CurrentState)
+		require.Equal(t, models.InstanceLabels{"fp": "a"}, instances[1].Labels)
+		require.Equal(t, models.InstanceStateFiring, instances[1].CurrentState)
+		require.Equal(t, now, instances[1].CurrentStateSince)
+	})
+
+	t.Run("should return error if the history has too many pages", func(t *testing.T) {
+		frame, err := entriesToFrame([]models.StateHistoryEntry{entry("rule-1", "a", "Alerting", "", now)})
+		require.NoError(t, err)
+		setContinueToken(frame, "next")
+		querier := &fakeQuerier{frame: frame}
+		reader := createTestInstanceReader(querier)
+
+		_, err = reader.ListAlertInstances(context.Background(), &models.ListAlertInstancesQuery{RuleOrgID: 1})
+		require.ErrorIs(t, err, errInstanceReaderTooManyPages)
+		require.Len(t, querier.queries, instanceReaderMaxPages)
+	})
+
+	t.Run("should return nothing if there is no history", func(t *testing.T) {
+		reader := createTestInstanceReader(&fakeQuerier{frame: data.NewFrame("states")})
+		instances, err := reader.ListAlertInstances(context.Background(), &models.ListAlertInstancesQuery{RuleOrgID: 1})
+		require.NoError(t, err)
+		require.Empty(t, instances)
+	})
+
+	t.Run("should return error if query fails", func(t *testing.T) {
+		expected := errors.New("test")
+		reader := createTestInstanceReader(&fakeQuerier{err: expected})
+		_, err := reader.ListAlertInstances(context.Background(), &models.ListAlertInstancesQuery{RuleOrgID: 1})
+		require.ErrorIs(t, err, expected)
+	})
+}
+
+func createTestInstanceReader(querier Querier) *InstanceReader {
+	return NewInstanceReader(querier, DefaultInstanceReaderLookback, testHistoryReaderUser, log.NewNopLogger())
+}
+
+func testHistoryReaderUser(orgID int64) *user.SignedInUser {
+	return &user.SignedInUser{OrgID: orgID, Login: "history-reader"}
+}
+
+type fakeQuerier struct {
+	frame *data.Frame
+	// pages are frames by continue token. If set, frame is ignored.
+	pages     map[string]*data.Frame
+	err       error
+	lastQuery models.HistoryQuery
+	queries   []models.HistoryQuery
+}
+
+func (f *fakeQuerier) Query(_ context.Context, query models.HistoryQuery) (*data.Frame, error) {
+	f.lastQuery = query
+	f.queries = append(f.queries, query)
+	if f.pages != nil {
+		return f.pages[query.ContinueToken], f.err
+	}
+	return f.frame, f.err
+}
@@ -141,6 +141,11 @@ func (h *RemoteLokiBackend) Record(ctx context.Context, rule history_model.RuleM
 
 // Query retrieves state history entries from an external Loki instance and formats the results into a dataframe.
 func (h *RemoteLokiBackend) Query(ctx context.Context, query models.HistoryQuery) (*data.Frame, error) {
+	token, err := parseContinueToken(query.ContinueToken)
+	if err != nil {
+		return nil, err
+	}
+
 	uids, err := h.getFolderUIDsForFilter(ctx, query)
 	if err != nil {
 		return nil, err
@@ -161,18 +166,53 @@ func (h *RemoteLokiBackend) Query(ctx context.Context, query models.HistoryQuery
 	if query.From.IsZero() {
 		query.From = now.Add(-defaultQueryRange)
 	}
+	fetchLimit := token.applyTo(&query)
 	var res []Stream
 	for _, logQL := range queries {
 		// Timestamps are expected in RFC3339Nano.
 		// Apply user-defined limit to every request. Multiple batches is a very rare case, and therefore we can tolerate getting more data than needed.
 		// The limit can be applied after all results are merged
-		r, err := h.client.RangeQuery(ctx, logQL, query.From.UnixNano(), query.To.UnixNano(), int64(query.Limit))
+		r, err := h.client.RangeQuery(ctx, logQL, query.From.UnixNano(), query.To.UnixNano(), int64(fetchLimit))
 		if err != nil {
 			return nil, err
 		}
 		res = append(res, r.Data.Result...)
 	}
-	return merge(res, uids)
+	frame, err := merge(res, uids)
+	if err != nil {
+		return nil, err
+	}
+	times, err := frameTimes(frame)
+	if err != nil {
+		return nil, err
+	}
+	rows, next := token.page(times, query.Limit, fetchLimit)
+	frame = selectRows(frame, rows)
+	setContinueToken(frame, next)
+	return frame, nil
+}
+
+// Aggregate retrieves state history entries from an external Loki instance and calculates the aggregation of them.
+func (h *RemoteLokiBackend) Aggregate(ctx context.Context, query models.HistoryAggregationQuery) (*data.Frame, error) {
+	now := h.clock.Now().UTC()
+	if query.To.IsZero() {
+		query.To = now
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-defaultQueryRange)
+	}
+	query.Limit = maxAggregatedTransitions
+	query.ContinueToken = ""
+
+	frame, err := h.Query(ctx, query.HistoryQuery)
+	if err != nil {
+		return nil, err
+	}
+	transitions, err := transitionsFromFrame(frame, h.log.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return aggregate(query, transitions, frame.Rows() >= maxAggregatedTransitions)
 }
 
 // merge will put all the results in one array sorted by timestamp.
@@ -463,6 +503,20 @@ func buildQueryTail(query models.HistoryQuery) (string, error) {
 		b.WriteString(" | panelID=")
 		b.WriteString(strconv.FormatInt(query.PanelID, 10))
 	}
+	if query.PreviousState != "" {
+		b.WriteString(" | previous=~")
+		_, err := fmt.Fprintf(&b, "%q", formattedStateRegex(query.PreviousState, ""))
+		if err != nil {
+			return "", err
+		}
+	}
+	if query.CurrentState != "" || query.Reason != "" {
+		b.WriteString(" | current=~")
+		_, err := fmt.Fprintf(&b, "%q", formattedStateRegex(query.CurrentState, query.Reason))
+		if err != nil {
+			return "", err
+		}
+	}
 
 	requiredSize := 0
 	labelKeys := make([]string, 0, len(query.Labels))
@@ -489,11 +543,18 @@ func queryHasLogFilters(query models.HistoryQuery) bool {
 	return query.RuleUID != "" ||
 		query.DashboardUID != "" ||
 		query.PanelID != 0 ||
-		len(query.Labels) > 0
+		len(query.Labels) > 0 ||
+		hasStateFilters(query)
 }
 
 func (h *RemoteLokiBackend) getFolderUIDsForFilter(ctx context.Context, query models.HistoryQuery) ([]string, error) {
-	bypass, err := h.ac.CanReadAllRules(ctx, query.SignedInUser)
+	return getFolderUIDsForFilter(ctx, h.ac, h.ruleStore, query)
+}
+
+// getFolderUIDsForFilter returns UIDs of folders the user can read rules in. It returns an empty slice if the user can read all rules
+// or if the query filters by a rule the user has access to.
+func getFolderUIDsForFilter(ctx context.Context, ac AccessControl, ruleStore RuleStore, query models.HistoryQuery) ([]string, error) {
+	bypass, err := ac.CanReadAllRules(ctx, query.SignedInUser)
 	if err != nil {
 		return nil, err
 	}
@@ -502,7 +563,7 @@ func (h *RemoteLokiBackend) getFolderUIDsForFilter(ctx context.Context, query mo
 	}
 	// if there is a filter by rule UID, find that rule UID and make sure that user has access to it.
 	if query.RuleUID != "" {
-		rule, err := h.ruleStore.GetAlertRuleByUID(ctx, &models.GetAlertRuleByUIDQuery{
+		rule, err := ruleStore.GetAlertRuleByUID(ctx, &models.GetAlertRuleByUIDQuery{
 			UID:   query.RuleUID,
 			OrgID: query.OrgID,
 		})
@@ -512,17 +573,17 @@ func (h *RemoteLokiBackend) getFolderUIDsForFilter(ctx context.Context, query mo
 		if rule == nil {
 			return nil, models.ErrAlertRuleNotFound
 		}
-		return nil, h.ac.AuthorizeAccessInFolder(ctx, query.SignedInUser, rule)
+		return nil, ac.AuthorizeAccessInFolder(ctx, query.SignedInUser, rule)
 	}
 	// if no filter, then we need to get all namespaces user has access to
-	folders, err := h.ruleStore.GetUserVisibleNamespaces(ctx, query.OrgID, query.SignedInUser)
+	folders, err := ruleStore.GetUserVisibleNamespaces(ctx, query.OrgID, query.SignedInUser)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch folders that user can access: %w", err)
 	}
 	uids := make([]string, 0, len(folders))
 	// now keep only UIDs of folder in which user can read rules.
 	for _, f := range folders {
-		hasAccess, err := h.ac.HasAccessInFolder(ctx, query.SignedInUser, models.Namespace(*f))
+		hasAccess, err := ac.HasAccessInFolder(ctx, query.SignedInUser, models.Namespace(*f))
 		if err != nil {
 			return nil, err
 		}
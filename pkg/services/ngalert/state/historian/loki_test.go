@@ -249,6 +249,31 @@ func TestBuildLogQuery(t *testing.T) {
 			},
 			exp: []string{`{orgID="123",from="state-history"} | json | panelID=456`},
 		},
+		{
+			name: "filters previous state in log line",
+			query: models.HistoryQuery{
+				OrgID:         123,
+				PreviousState: "Normal",
+			},
+			exp: []string{`{orgID="123",from="state-history"} | json | previous=~"Normal( \\(.*\\))?"`},
+		},
+		{
+			name: "filters current state and reason in log line",
+			query: models.HistoryQuery{
+				OrgID:        123,
+				CurrentState: "Normal",
+				Reason:       "NoData",
+			},
+			exp: []string{`{orgID="123",from="state-history"} | json | current=~"Normal \\(NoData\\)"`},
+		},
+		{
+			name: "filters reason of any state in log line",
+			query: models.HistoryQuery{
+				OrgID:  123,
+				Reason: "Error",
+			},
+			exp: []string{`{orgID="123",from="state-history"} | json | current=~"[^ ]+ \\(Error\\)"`},
+		},
 		{
 			name: "filters instance labels in log line",
 			query: models.HistoryQuery{
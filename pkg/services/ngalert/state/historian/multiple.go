@@ -53,6 +53,14 @@ func (h *MultipleBackend) Query(ctx context.Context, query ngmodels.HistoryQuery
 	return h.primary.Query(ctx, query)
 }
 
+func (h *MultipleBackend) Aggregate(ctx context.Context, query ngmodels.HistoryAggregationQuery) (*data.Frame, error) {
+	aggregator, ok := h.primary.(Aggregator)
+	if !ok {
+		return nil, ErrAggregationNotSupported
+	}
+	return aggregator.Aggregate(ctx, query)
+}
+
 // TODO: This is vendored verbatim from the Go standard library.
 // TODO: The grafana project doesn't support go 1.20 yet, so we can't use errors.Join() directly.
 // TODO: Remove this and replace calls with "errors.Join(...)" when go 1.20 becomes the minimum supported version.
@@ -25,3 +25,7 @@ func (f *NoOpHistorian) Record(ctx context.Context, _ history_model.RuleMeta, _
 func (f *NoOpHistorian) Query(ctx context.Context, query models.HistoryQuery) (*data.Frame, error) {
 	return data.NewFrame("states"), nil
 }
+
+func (f *NoOpHistorian) Aggregate(ctx context.Context, query models.HistoryAggregationQuery) (*data.Frame, error) {
+	return aggregate(query, nil, false)
+}
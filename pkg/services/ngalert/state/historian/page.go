@@ -0,0 +1,169 @@
+package historian
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+var ErrInvalidContinueToken = errutil.BadRequest("alerting.stateHistory.invalidContinueToken").Errorf("invalid continue token")
+
+// continueToken identifies the position in the state history where the next page of results starts.
+// Pages are returned from the newest to the oldest transitions, so the position is the time of the oldest
+// transition of the previous page. Because several transitions can happen at the same time, the token also
+// keeps the number of transitions with that time that were already returned.
+type continueToken struct {
+	// To is the time of the oldest transition of the previous page in Unix nanoseconds.
+	To int64 `json:"to"`
+	// Skip is the number of transitions with time To that were already returned.
+	Skip int `json:"skip"`
+}
+
+func parseContinueToken(s string) (continueToken, error) {
+	if s == "" {
+		return continueToken{}, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return continueToken{}, ErrInvalidContinueToken
+	}
+	var t continueToken
+	if err := json.Unmarshal(b, &t); err != nil || t.To <= 0 || t.Skip < 0 {
+		return continueToken{}, ErrInvalidContinueToken
+	}
+	return t, nil
+}
+
+func (t continueToken) String() string {
+	b, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// isFirstPage returns true if the token does not point to a position in the history.
+func (t continueToken) isFirstPage() bool {
+	return t.To == 0
+}
+
+// applyTo restricts the time range of the query to the transitions that were not returned yet,
+// and returns the number of transitions that the backend must fetch to fill the page.
+func (t continueToken) applyTo(query *models.HistoryQuery) int {
+	if query.Limit <= 0 {
+		return query.Limit
+	}
+	if t.isFirstPage() {
+		return query.Limit
+	}
+	// Some backends do not include transitions that happened exactly at the end of the range.
+	// Transitions that happened after the position are dropped by page.
+	to := time.Unix(0, t.To+1)
+	if query.To.IsZero() || to.Before(query.To) {
+		query.To = to
+	}
+	return query.Limit + t.Skip
+}
+
+// page selects the transitions that belong to the page, given the times of transitions fetched by a query
+// modified by applyTo. The times can be in any order. It returns the indices of selected transitions in their
+// original order, and the token to the next page, which is empty if there are no more transitions.
+func (t continueToken) page(times []time.Time, limit int, fetchLimit int) ([]int, string) {
+	idx := make([]int, 0, len(times))
+	for i, ts := range times {
+		if !t.isFirstPage() && ts.UnixNano() > t.To {
+			continue
+		}
+		idx = append(idx, i)
+	}
+	// newest first
+	sort.SliceStable(idx, func(i, j int) bool {
+		return times[idx[i]].After(times[idx[j]])
+	})
+
+	skipped := 0
+	for len(idx) > 0 && skipped < t.Skip && times[idx[0]].UnixNano() == t.To {
+		idx = idx[1:]
+		skipped++
+	}
+
+	if limit <= 0 {
+		sort.Ints(idx)
+		return idx, ""
+	}
+	if len(idx) > limit {
+		idx = idx[:limit]
+	}
+
+	next := ""
+	if len(idx) > 0 && len(times) >= fetchLimit {
+		oldest := times[idx[len(idx)-1]].UnixNano()
+		nextToken := continueToken{To: oldest}
+		for _, i := range idx {
+			if times[i].UnixNano() == oldest {
+				nextToken.Skip++
+			}
+		}
+		if oldest == t.To {
+			nextToken.Skip += t.Skip
+		}
+		next = nextToken.String()
+	}
+	sort.Ints(idx)
+	return idx, next
+}
+
+// selectRows returns a copy of the frame that contains only the given rows.
+func selectRows(frame *data.Frame, rows []int) *data.Frame {
+	if len(rows) == frame.Rows() {
+		return frame
+	}
+	result := frame.EmptyCopy()
+	for _, row := range rows {
+		result.AppendRow(frame.RowCopy(row)...)
+	}
+	return result
+}
+
+// setContinueToken adds the token to the next page of results to the metadata of the frame.
+func setContinueToken(frame *data.Frame, token string) {
+	if token == "" {
+		return
+	}
+	frame.SetMeta(&data.FrameMeta{
+		Custom: models.HistoryQueryResultMeta{ContinueToken: token},
+	})
+}
+
+// continueTokenOf returns the token to the next page of results set by setContinueToken.
+func continueTokenOf(frame *data.Frame) string {
+	if frame == nil || frame.Meta == nil {
+		return ""
+	}
+	meta, ok := frame.Meta.Custom.(models.HistoryQueryResultMeta)
+	if !ok {
+		return ""
+	}
+	return meta.ContinueToken
+}
+
+// frameTimes returns the values of the time field of a frame in the format returned by the Loki backend.
+func frameTimes(frame *data.Frame) ([]time.Time, error) {
+	field, _ := frame.FieldByName(dfTime)
+	if field == nil {
+		return nil, fmt.Errorf("unexpected format of state history, field %s is required", dfTime)
+	}
+	times := make([]time.Time, 0, field.Len())
+	for i := 0; i < field.Len(); i++ {
+		t, ok := field.At(i).(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type of field %s", dfTime)
+		}
+		times = append(times, t)
+	}
+	return times, nil
+}
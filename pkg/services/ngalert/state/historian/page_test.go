@@ -0,0 +1,95 @@
+package historian
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestContinueToken(t *testing.T) {
+	t.Run("should parse encoded token", func(t *testing.T) {
+		token := continueToken{To: time.Now().UnixNano(), Skip: 3}
+		parsed, err := parseContinueToken(token.String())
+		require.NoError(t, err)
+		require.Equal(t, token, parsed)
+	})
+
+	t.Run("should return first page if token is empty", func(t *testing.T) {
+		parsed, err := parseContinueToken("")
+		require.NoError(t, err)
+		require.True(t, parsed.isFirstPage())
+	})
+
+	t.Run("should fail if token is invalid", func(t *testing.T) {
+		for _, s := range []string{"not-a-token!", "e30", "eyJ0byI6LTF9"} {
+			_, err := parseContinueToken(s)
+			require.ErrorIs(t, err, ErrInvalidContinueToken, s)
+		}
+	})
+
+	t.Run("should return all transitions page by page", func(t *testing.T) {
+		base := time.Now().Truncate(time.Second)
+		// Transitions that happened at the same time must not be lost or duplicated at the page boundary.
+		all := []time.Time{
+			base, base.Add(time.Second), base.Add(time.Second), base.Add(time.Second),
+			base.Add(2 * time.Second), base.Add(3 * time.Second), base.Add(3 * time.Second),
+		}
+		// fetch simulates a backend that returns the newest transitions in the range in ascending order.
+		fetch := func(query models.HistoryQuery, limit int) []time.Time {
+			var result []time.Time
+			for _, ts := range all {
+				if query.To.IsZero() || !ts.After(query.To) {
+					result = append(result, ts)
+				}
+			}
+			if len(result) > limit {
+				result = result[len(result)-limit:]
+			}
+			return result
+		}
+
+		for _, limit := range []int{1, 2, 3, 7, 10} {
+			var got []time.Time
+			s := ""
+			for pages := 0; pages <= len(all); pages++ {
+				token, err := parseContinueToken(s)
+				require.NoError(t, err)
+				query := models.HistoryQuery{Limit: limit}
+				fetchLimit := token.applyTo(&query)
+				times := fetch(query, fetchLimit)
+				rows, next := token.page(times, limit, fetchLimit)
+				require.LessOrEqual(t, len(rows), limit)
+				for _, row := range rows {
+					got = append(got, times[row])
+				}
+				s = next
+				if s == "" {
+					break
+				}
+			}
+			require.Empty(t, s, "limit %d: pagination did not finish", limit)
+			sort.Slice(got, func(i, j int) bool { return got[i].Before(got[j]) })
+			require.Equal(t, all, got, "limit %d", limit)
+		}
+	})
+
+	t.Run("should return all transitions if there is no limit", func(t *testing.T) {
+		now := time.Now()
+		times := []time.Time{now.Add(time.Second), now, now.Add(2 * time.Second)}
+		rows, next := continueToken{}.page(times, 0, 0)
+		require.Equal(t, []int{0, 1, 2}, rows)
+		require.Empty(t, next)
+	})
+
+	t.Run("should keep original order of transitions", func(t *testing.T) {
+		now := time.Now()
+		times := []time.Time{now.Add(2 * time.Second), now.Add(time.Second), now}
+		rows, next := continueToken{}.page(times, 2, 2)
+		require.Equal(t, []int{0, 1}, rows)
+		require.NotEmpty(t, next)
+	})
+}
@@ -0,0 +1,176 @@
+package historian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/prometheus/model/value"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	history_model "github.com/grafana/grafana/pkg/services/ngalert/state/historian/model"
+	"github.com/grafana/grafana/pkg/services/ngalert/writer"
+)
+
+const (
+	// AlertsMetricName is the name of the metric that Prometheus uses to expose active alerts.
+	AlertsMetricName = "ALERTS"
+	// AlertsForStateMetricName is the name of the metric that Prometheus uses to persist the time alerts became active.
+	AlertsForStateMetricName = "ALERTS_FOR_STATE"
+	// AlertStateLabel is the label of the ALERTS metric that contains the state of the alert.
+	AlertStateLabel = "alertstate"
+	// AlertNameLabel is the label that contains the name of the alert.
+	AlertNameLabel = "alertname"
+	// PrometheusRuleUIDLabel is the label that contains the UID of the alert rule. Private labels of alert instances are not
+	// written, so it allows to find the rule of the series.
+	PrometheusRuleUIDLabel = "grafana_rule_uid"
+
+	alertStatePending = "pending"
+	alertStateFiring  = "firing"
+)
+
+var ErrPrometheusQueryNotSupported = errors.New("prometheus state history backend does not support queries")
+
+// PointsWriter writes points to a Prometheus remote write endpoint.
+type PointsWriter interface {
+	WritePoints(ctx context.Context, points []writer.Point, orgID int64) error
+}
+
+// PrometheusBackend is a state.Historian that records state history as Prometheus series ALERTS and ALERTS_FOR_STATE
+// using Prometheus remote write. The series have the same format as the ones that Prometheus produces for alerting rules.
+type PrometheusBackend struct {
+	writer  PointsWriter
+	metrics *metrics.Historian
+	log     log.Logger
+}
+
+func NewPrometheusBackend(logger log.Logger, writer PointsWriter, metrics *metrics.Historian) *PrometheusBackend {
+	return &PrometheusBackend{
+		writer:  writer,
+		metrics: metrics,
+		log:     logger,
+	}
+}
+
+// Record writes the state of alert instances as samples of ALERTS and ALERTS_FOR_STATE series.
+func (h *PrometheusBackend) Record(ctx context.Context, rule history_model.RuleMeta, states []state.StateTransition) <-chan error {
+	// Build points before starting goroutine, to make sure all data is copied and won't mutate underneath us.
+	points, transitions := statesToPoints(rule, states)
+
+	errCh := make(chan error, 1)
+	if len(points) == 0 {
+		close(errCh)
+		return errCh
+	}
+
+	// This is a new background job, so let's create a brand new context for it.
+	// We want it to be isolated, i.e. we don't want grafana shutdowns to interrupt this work
+	// immediately but rather try to flush writes.
+	// This also prevents timeouts or other lingering objects (like transactions) from being
+	// incorrectly propagated here from other areas.
+	writeCtx := context.Background()
+	writeCtx, cancel := context.WithTimeout(writeCtx, StateHistoryWriteTimeout)
+	writeCtx = history_model.WithRuleData(writeCtx, rule)
+	writeCtx = trace.ContextWithSpan(writeCtx, trace.SpanFromContext(ctx))
+
+	go func(ctx context.Context) {
+		defer cancel()
+		defer close(errCh)
+		logger := h.log.FromContext(ctx)
+		logger.Debug("Saving state history batch", "samples", len(points))
+		org := fmt.Sprint(rule.OrgID)
+		h.metrics.WritesTotal.WithLabelValues(org, BackendTypePrometheus.String()).Inc()
+		h.metrics.TransitionsTotal.WithLabelValues(org).Add(float64(transitions))
+
+		if err := h.writer.WritePoints(ctx, points, rule.OrgID); err != nil {
+			logger.Error("Failed to save alert state history batch", "error", err)
+			h.metrics.WritesFailed.WithLabelValues(org, BackendTypePrometheus.String()).Inc()
+			h.metrics.TransitionsFailed.WithLabelValues(org).Add(float64(transitions))
+			errCh <- fmt.Errorf("failed to save alert state history batch: %w", err)
+			return
+		}
+		logger.Debug("Done saving alert state history batch", "samples", len(points))
+	}(writeCtx)
+	return errCh
+}
+
+// Query is not supported because the history is available via PromQL in the data source the series are written to.
+func (h *PrometheusBackend) Query(_ context.Context, _ models.HistoryQuery) (*data.Frame, error) {
+	return nil, ErrPrometheusQueryNotSupported
+}
+
+// statesToPoints converts the states of alert instances to points of ALERTS and ALERTS_FOR_STATE series.
+// Like Prometheus, only Pending and Alerting instances are active. The value of ALERTS_FOR_STATE is the time in seconds
+// when the instance became active, which is the start of the pending period for firing instances. When an instance stops being active, or changes the state, a stale marker
+// is written for series that stopped, so that they end immediately instead of after the lookback delta.
+// It returns the number of state transitions the points represent.
+func statesToPoints(rule history_model.RuleMeta, states []state.StateTransition) ([]writer.Point, int) {
+	points := make([]writer.Point, 0, 2*len(states))
+	transitions := 0
+	for _, s := range states {
+		previous, wasActive := prometheusAlertState(s.PreviousState)
+		current, isActive := prometheusAlertState(s.State.State)
+		if !wasActive && !isActive {
+			continue
+		}
+		if shouldRecord(s) {
+			transitions++
+		}
+
+		labels := removePrivateLabels(s.Labels)
+		if _, ok := labels[AlertNameLabel]; !ok {
+			labels[AlertNameLabel] = rule.Title
+		}
+		labels[PrometheusRuleUIDLabel] = rule.UID
+		t := s.LastEvaluationTime
+		stale := math.Float64frombits(value.StaleNaN)
+
+		if wasActive && (!isActive || previous != current) {
+			points = append(points, alertsPoint(labels, previous, t, stale))
+		}
+		if !isActive {
+			points = append(points, writer.Point{Name: AlertsForStateMetricName, Labels: labels.Copy(), Metric: writer.Metric{T: t, V: stale}})
+			continue
+		}
+		points = append(points,
+			alertsPoint(labels, current, t, 1),
+			writer.Point{Name: AlertsForStateMetricName, Labels: labels.Copy(), Metric: writer.Metric{T: t, V: float64(activeAt(s.State).Unix())}},
+		)
+	}
+	return points, transitions
+}
+
+// activeAt returns the time the state became active. States that were active before ActiveAt was tracked use StartsAt.
+func activeAt(s *state.State) time.Time {
+	if s.ActiveAt.IsZero() {
+		return s.StartsAt
+	}
+	return s.ActiveAt
+}
+
+func alertsPoint(labels data.Labels, alertState string, t time.Time, v float64) writer.Point {
+	lbls := labels.Copy()
+	lbls[AlertStateLabel] = alertState
+	return writer.Point{Name: AlertsMetricName, Labels: lbls, Metric: writer.Metric{T: t, V: v}}
+}
+
+// prometheusAlertState returns the value of the label alertstate of the ALERTS metric that corresponds to the state.
+// It returns false if the alert is not active.
+func prometheusAlertState(s eval.State) (string, bool) {
+	switch s {
+	case eval.Pending:
+		return alertStatePending, true
+	case eval.Alerting:
+		return alertStateFiring, true
+	default:
+		return "", false
+	}
+}
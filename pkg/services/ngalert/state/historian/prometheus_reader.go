@@ -0,0 +1,106 @@
+package historian
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// PrometheusQuerier executes instant PromQL queries against a data source.
+type PrometheusQuerier interface {
+	Query(ctx context.Context, orgID int64, datasourceUID, expr string, ts time.Time) (promql.Vector, error)
+}
+
+// PrometheusInstanceReader is a state.InstanceReader that reconstructs active alert instances from the ALERTS and
+// ALERTS_FOR_STATE series written by PrometheusBackend. Like Prometheus, it only knows about Pending and Alerting
+// instances, and the time since when an instance is in its state is the time it became active, which is the start
+// of the pending period for firing instances. Series are found only if the last sample is within the lookback delta
+// of the data source, which limits how long Grafana can be down for the state to be restored.
+type PrometheusInstanceReader struct {
+	querier       PrometheusQuerier
+	datasourceUID string
+	clock         clock.Clock
+	log           log.Logger
+}
+
+func NewPrometheusInstanceReader(querier PrometheusQuerier, datasourceUID string, logger log.Logger) *PrometheusInstanceReader {
+	return &PrometheusInstanceReader{
+		querier:       querier,
+		datasourceUID: datasourceUID,
+		clock:         clock.New(),
+		log:           logger,
+	}
+}
+
+// ListAlertInstances returns active alert instances of the organization, and optionally of a single rule.
+// Series do not have the organization, so instances of rules of other organizations can be returned too.
+func (r *PrometheusInstanceReader) ListAlertInstances(ctx context.Context, cmd *models.ListAlertInstancesQuery) ([]*models.AlertInstance, error) {
+	now := r.clock.Now()
+	selector := ruleSelector(cmd.RuleUID)
+	alerts, err := r.querier.Query(ctx, cmd.RuleOrgID, r.datasourceUID, AlertsMetricName+selector, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", AlertsMetricName, err)
+	}
+	if len(alerts) == 0 {
+		return nil, nil
+	}
+	forState, err := r.querier.Query(ctx, cmd.RuleOrgID, r.datasourceUID, AlertsForStateMetricName+selector, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", AlertsForStateMetricName, err)
+	}
+
+	activeAt := make(map[uint64]time.Time, len(forState))
+	for _, s := range forState {
+		activeAt[labels.NewBuilder(s.Metric).Del(labels.MetricName).Labels().Hash()] = time.Unix(int64(s.F), 0)
+	}
+
+	result := make([]*models.AlertInstance, 0, len(alerts))
+	for _, s := range alerts {
+		var current models.InstanceStateType
+		switch alertState := s.Metric.Get(AlertStateLabel); alertState {
+		case alertStatePending:
+			current = models.InstanceStatePending
+		case alertStateFiring:
+			current = models.InstanceStateFiring
+		default:
+			r.log.Warn("Unexpected state of alert instance, skipping", "state", alertState, "labels", s.Metric.String())
+			continue
+		}
+		ruleUID := s.Metric.Get(PrometheusRuleUIDLabel)
+		seriesLabels := labels.NewBuilder(s.Metric).Del(labels.MetricName, AlertStateLabel).Labels()
+		since, ok := activeAt[seriesLabels.Hash()]
+		if !ok {
+			r.log.Warn("Alert instance has no active time, skipping", "labels", s.Metric.String())
+			continue
+		}
+		instanceLabels := seriesLabels.Map()
+		delete(instanceLabels, PrometheusRuleUIDLabel)
+
+		result = append(result, &models.AlertInstance{
+			AlertInstanceKey: models.AlertInstanceKey{
+				RuleOrgID: cmd.RuleOrgID,
+				RuleUID:   ruleUID,
+			},
+			Labels:            models.InstanceLabels(instanceLabels),
+			CurrentState:      current,
+			CurrentStateSince: since,
+			LastEvalTime:      time.UnixMilli(s.T),
+		})
+	}
+	return result, nil
+}
+
+// ruleSelector returns the selector of series of the rule, or of all rules if ruleUID is empty.
+func ruleSelector(ruleUID string) string {
+	if ruleUID == "" {
+		return fmt.Sprintf(`{%s!=""}`, PrometheusRuleUIDLabel)
+	}
+	return fmt.Sprintf(`{%s=%q}`, PrometheusRuleUIDLabel, ruleUID)
+}
@@ -0,0 +1,104 @@
+package historian
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestPrometheusInstanceReader_ListAlertInstances(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	activeAt := now.Add(-5 * time.Minute)
+	sample := func(v float64, lbls ...string) promql.Sample {
+		return promql.Sample{Metric: labels.FromStrings(lbls...), T: now.UnixMilli(), F: v}
+	}
+
+	t.Run("should return active instances with the time they became active", func(t *testing.T) {
+		querier := &fakePrometheusQuerier{vectors: map[string]promql.Vector{
+			`ALERTS{grafana_rule_uid="rule-1"}`: {
+				sample(1, "__name__", AlertsMetricName, "alertstate", "firing", "alertname", "test", "a", "1", PrometheusRuleUIDLabel, "rule-1"),
+				sample(1, "__name__", AlertsMetricName, "alertstate", "pending", "alertname", "test", "a", "2", PrometheusRuleUIDLabel, "rule-1"),
+			},
+			`ALERTS_FOR_STATE{grafana_rule_uid="rule-1"}`: {
+				sample(float64(activeAt.Unix()), "__name__", AlertsForStateMetricName, "alertname", "test", "a", "1", PrometheusRuleUIDLabel, "rule-1"),
+				sample(float64(now.Unix()), "__name__", AlertsForStateMetricName, "alertname", "test", "a", "2", PrometheusRuleUIDLabel, "rule-1"),
+			},
+		}}
+		reader := createTestPrometheusInstanceReader(querier, now)
+
+		instances, err := reader.ListAlertInstances(context.Background(), &models.ListAlertInstancesQuery{RuleOrgID: 1, RuleUID: "rule-1"})
+		require.NoError(t, err)
+
+		require.Equal(t, []int64{1, 1}, querier.orgIDs)
+		require.Len(t, instances, 2)
+		require.Equal(t, &models.AlertInstance{
+			AlertInstanceKey:  models.AlertInstanceKey{RuleOrgID: 1, RuleUID: "rule-1"},
+			Labels:            models.InstanceLabels{"alertname": "test", "a": "1"},
+			CurrentState:      models.InstanceStateFiring,
+			CurrentStateSince: activeAt,
+			LastEvalTime:      now,
+		}, instances[0])
+		require.Equal(t, models.InstanceStatePending, instances[1].CurrentState)
+		require.Equal(t, now, instances[1].CurrentStateSince)
+	})
+
+	t.Run("should query series of all rules if rule is not specified", func(t *testing.T) {
+		querier := &fakePrometheusQuerier{}
+		reader := createTestPrometheusInstanceReader(querier, now)
+
+		instances, err := reader.ListAlertInstances(context.Background(), &models.ListAlertInstancesQuery{RuleOrgID: 1})
+		require.NoError(t, err)
+		require.Empty(t, instances)
+		require.Equal(t, []string{`ALERTS{grafana_rule_uid!=""}`}, querier.exprs)
+	})
+
+	t.Run("should skip instances without active time", func(t *testing.T) {
+		querier := &fakePrometheusQuerier{vectors: map[string]promql.Vector{
+			`ALERTS{grafana_rule_uid!=""}`: {
+				sample(1, "__name__", AlertsMetricName, "alertstate", "firing", "alertname", "test", PrometheusRuleUIDLabel, "rule-1"),
+			},
+		}}
+		reader := createTestPrometheusInstanceReader(querier, now)
+
+		instances, err := reader.ListAlertInstances(context.Background(), &models.ListAlertInstancesQuery{RuleOrgID: 1})
+		require.NoError(t, err)
+		require.Empty(t, instances)
+	})
+
+	t.Run("should return error if query fails", func(t *testing.T) {
+		expected := errors.New("test")
+		reader := createTestPrometheusInstanceReader(&fakePrometheusQuerier{err: expected}, now)
+		_, err := reader.ListAlertInstances(context.Background(), &models.ListAlertInstancesQuery{RuleOrgID: 1})
+		require.ErrorIs(t, err, expected)
+	})
+}
+
+func createTestPrometheusInstanceReader(querier PrometheusQuerier, now time.Time) *PrometheusInstanceReader {
+	reader := NewPrometheusInstanceReader(querier, "history-ds", log.NewNopLogger())
+	clk := clock.NewMock()
+	clk.Set(now)
+	reader.clock = clk
+	return reader
+}
+
+type fakePrometheusQuerier struct {
+	vectors map[string]promql.Vector
+	err     error
+	exprs   []string
+	orgIDs  []int64
+}
+
+func (f *fakePrometheusQuerier) Query(_ context.Context, orgID int64, _, expr string, _ time.Time) (promql.Vector, error) {
+	f.exprs = append(f.exprs, expr)
+	f.orgIDs = append(f.orgIDs, orgID)
+	return f.vectors[expr], f.err
+}
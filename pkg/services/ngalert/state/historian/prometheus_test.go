@@ -0,0 +1,190 @@
+package historian
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	"github.com/grafana/grafana/pkg/services/ngalert/writer"
+)
+
+func TestStatesToPoints(t *testing.T) {
+	rule := createTestRule()
+	now := time.Now()
+	startsAt := now.Add(-time.Minute)
+	activeAt := startsAt.Add(-5 * time.Minute)
+	labels := data.Labels{"alertname": "test", "a": "b", "__alert_rule_uid__": "rule-uid"}
+	expectedLabels := map[string]string{"alertname": "test", "a": "b", PrometheusRuleUIDLabel: rule.UID}
+
+	transition := func(previous, current eval.State) state.StateTransition {
+		return state.StateTransition{
+			PreviousState: previous,
+			State: &state.State{
+				State:              current,
+				Labels:             labels,
+				StartsAt:           startsAt,
+				ActiveAt:           activeAt,
+				LastEvaluationTime: now,
+			},
+		}
+	}
+	alerts := func(alertState string, v float64) writer.Point {
+		lbls := map[string]string{AlertStateLabel: alertState}
+		for k, v := range expectedLabels {
+			lbls[k] = v
+		}
+		return writer.Point{Name: AlertsMetricName, Labels: lbls, Metric: writer.Metric{T: now, V: v}}
+	}
+	forState := func(v float64) writer.Point {
+		return writer.Point{Name: AlertsForStateMetricName, Labels: expectedLabels, Metric: writer.Metric{T: now, V: v}}
+	}
+
+	testCases := []struct {
+		name        string
+		transition  state.StateTransition
+		expected    []writer.Point
+		transitions int
+	}{
+		{
+			name:       "normal alert is not written",
+			transition: transition(eval.Normal, eval.Normal),
+		},
+		{
+			name:        "pending alert",
+			transition:  transition(eval.Normal, eval.Pending),
+			expected:    []writer.Point{alerts(alertStatePending, 1), forState(float64(activeAt.Unix()))},
+			transitions: 1,
+		},
+		{
+			name:       "firing alert",
+			transition: transition(eval.Alerting, eval.Alerting),
+			expected:   []writer.Point{alerts(alertStateFiring, 1), forState(float64(activeAt.Unix()))},
+		},
+		{
+			name:        "pending alert starts firing",
+			transition:  transition(eval.Pending, eval.Alerting),
+			expected:    []writer.Point{alerts(alertStatePending, math.Float64frombits(value.StaleNaN)), alerts(alertStateFiring, 1), forState(float64(activeAt.Unix()))},
+			transitions: 1,
+		},
+		{
+			name:        "firing alert is resolved",
+			transition:  transition(eval.Alerting, eval.Normal),
+			expected:    []writer.Point{alerts(alertStateFiring, math.Float64frombits(value.StaleNaN)), forState(math.Float64frombits(value.StaleNaN))},
+			transitions: 1,
+		},
+		{
+			name:        "error is not an active alert",
+			transition:  transition(eval.Alerting, eval.Error),
+			expected:    []writer.Point{alerts(alertStateFiring, math.Float64frombits(value.StaleNaN)), forState(math.Float64frombits(value.StaleNaN))},
+			transitions: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			points, transitions := statesToPoints(rule, []state.StateTransition{tc.transition})
+			require.Equal(t, tc.transitions, transitions)
+			require.Len(t, points, len(tc.expected))
+			for i, p := range points {
+				require.Equal(t, tc.expected[i].Name, p.Name)
+				require.Equal(t, tc.expected[i].Labels, p.Labels)
+				require.Equal(t, tc.expected[i].Metric.T, p.Metric.T)
+				if math.IsNaN(tc.expected[i].Metric.V) {
+					require.True(t, value.IsStaleNaN(p.Metric.V))
+					continue
+				}
+				require.Equal(t, tc.expected[i].Metric.V, p.Metric.V)
+			}
+		})
+	}
+
+	t.Run("should use start of the state if active time is unknown", func(t *testing.T) {
+		tr := transition(eval.Alerting, eval.Alerting)
+		tr.ActiveAt = time.Time{}
+		points, _ := statesToPoints(rule, []state.StateTransition{tr})
+		require.Len(t, points, 2)
+		require.Equal(t, float64(startsAt.Unix()), points[1].Metric.V)
+	})
+
+	t.Run("should add alertname if it is missing", func(t *testing.T) {
+		tr := transition(eval.Normal, eval.Alerting)
+		tr.Labels = data.Labels{"a": "b"}
+		points, _ := statesToPoints(rule, []state.StateTransition{tr})
+		require.NotEmpty(t, points)
+		for _, p := range points {
+			require.Equal(t, rule.Title, p.Labels[AlertNameLabel])
+		}
+	})
+}
+
+func TestPrometheusBackend(t *testing.T) {
+	states := singleFromNormal(&state.State{
+		State:  eval.Alerting,
+		Labels: data.Labels{"a": "b"},
+	})
+
+	t.Run("should write points", func(t *testing.T) {
+		w := &fakePointsWriter{}
+		backend := createTestPrometheusBackend(w)
+
+		err := <-backend.Record(context.Background(), createTestRule(), states)
+		require.NoError(t, err)
+		require.Len(t, w.points, 2)
+		require.EqualValues(t, 1, w.orgID)
+	})
+
+	t.Run("should not write if there are no active alerts", func(t *testing.T) {
+		w := &fakePointsWriter{}
+		backend := createTestPrometheusBackend(w)
+
+		err := <-backend.Record(context.Background(), createTestRule(), singleFromNormal(&state.State{State: eval.Normal}))
+		require.NoError(t, err)
+		require.Nil(t, w.points)
+	})
+
+	t.Run("should return error if writer fails", func(t *testing.T) {
+		w := &fakePointsWriter{err: errors.New("test")}
+		backend := createTestPrometheusBackend(w)
+
+		err := <-backend.Record(context.Background(), createTestRule(), states)
+		require.ErrorIs(t, err, w.err)
+	})
+
+	t.Run("should not support queries", func(t *testing.T) {
+		backend := createTestPrometheusBackend(&fakePointsWriter{})
+		_, err := backend.Query(context.Background(), models.HistoryQuery{})
+		require.ErrorIs(t, err, ErrPrometheusQueryNotSupported)
+	})
+}
+
+func createTestPrometheusBackend(w PointsWriter) *PrometheusBackend {
+	met := metrics.NewHistorianMetrics(prometheus.NewRegistry(), metrics.Subsystem)
+	return NewPrometheusBackend(log.NewNopLogger(), w, met)
+}
+
+type fakePointsWriter struct {
+	points []writer.Point
+	orgID  int64
+	err    error
+}
+
+func (f *fakePointsWriter) WritePoints(_ context.Context, points []writer.Point, orgID int64) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.points = append(f.points, points...)
+	f.orgID = orgID
+	return nil
+}
@@ -14,3 +14,8 @@ import (
 type Querier interface {
 	Query(ctx context.Context, query models.HistoryQuery) (*data.Frame, error)
 }
+
+// Aggregator represents the ability to aggregate state history.
+type Aggregator interface {
+	Aggregate(ctx context.Context, query models.HistoryAggregationQuery) (*data.Frame, error)
+}
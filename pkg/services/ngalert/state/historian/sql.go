@@ -0,0 +1,352 @@
+package historian
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	history_model "github.com/grafana/grafana/pkg/services/ngalert/state/historian/model"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	// defaultSQLRetention is how long state transitions are kept in the database.
+	defaultSQLRetention = 30 * 24 * time.Hour
+	// defaultSQLCleanupInterval is the minimal interval between deletions of expired state transitions.
+	defaultSQLCleanupInterval = time.Hour
+)
+
+// StateHistoryStore is the database interface used by the SQL state history backend.
+type StateHistoryStore interface {
+	SaveStateHistory(ctx context.Context, entries []models.StateHistoryEntry) error
+	FindStateHistory(ctx context.Context, query models.HistoryQuery, namespaceUIDs []string) ([]models.StateHistoryEntry, error)
+	DeleteStateHistory(ctx context.Context, before time.Time) (int64, error)
+}
+
+type SQLConfig struct {
+	// Retention is how long state transitions are kept. Zero disables the cleanup.
+	Retention time.Duration
+	// CleanupInterval is the minimal interval between deletions of expired state transitions.
+	CleanupInterval time.Duration
+}
+
+// NewSQLConfig returns the configuration of the SQL backend from the sql_retention and sql_cleanup_interval options of
+// the state history section of the settings. Options that are not set fall back to the defaults.
+func NewSQLConfig(section *setting.DynamicSection) (SQLConfig, error) {
+	retention, err := durationOption(section, "sql_retention", defaultSQLRetention)
+	if err != nil {
+		return SQLConfig{}, err
+	}
+	cleanupInterval, err := durationOption(section, "sql_cleanup_interval", defaultSQLCleanupInterval)
+	if err != nil {
+		return SQLConfig{}, err
+	}
+	if cleanupInterval == 0 {
+		return SQLConfig{}, errors.New("sql_cleanup_interval must be greater than zero")
+	}
+	return SQLConfig{
+		Retention:       retention,
+		CleanupInterval: cleanupInterval,
+	}, nil
+}
+
+// durationOption returns the duration of the option key of the section, or def if the option is not set.
+func durationOption(section *setting.DynamicSection, key string, def time.Duration) (time.Duration, error) {
+	v := section.Key(key).MustString("")
+	if v == "" {
+		return def, nil
+	}
+	d, err := gtime.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%s must not be negative, got %s", key, d)
+	}
+	return d, nil
+}
+
+// SQLBackend is a state.Historian that records state history to a dedicated table in the Grafana database.
+type SQLBackend struct {
+	store     StateHistoryStore
+	cfg       SQLConfig
+	clock     clock.Clock
+	metrics   *metrics.Historian
+	log       log.Logger
+	ac        AccessControl
+	ruleStore RuleStore
+
+	cleanupMtx  sync.Mutex
+	lastCleanup time.Time
+}
+
+func NewSQLBackend(logger log.Logger, cfg SQLConfig, store StateHistoryStore, ruleStore RuleStore, metrics *metrics.Historian, ac AccessControl) *SQLBackend {
+	return &SQLBackend{
+		store:     store,
+		cfg:       cfg,
+		clock:     clock.New(),
+		metrics:   metrics,
+		log:       logger,
+		ac:        ac,
+		ruleStore: ruleStore,
+	}
+}
+
+// Record writes a number of state transitions for a given rule to the database.
+func (h *SQLBackend) Record(ctx context.Context, rule history_model.RuleMeta, states []state.StateTransition) <-chan error {
+	logger := h.log.FromContext(ctx)
+	// Build entries before starting goroutine, to make sure all data is copied and won't mutate underneath us.
+	entries := statesToEntries(rule, states, logger)
+
+	errCh := make(chan error, 1)
+	if len(entries) == 0 {
+		close(errCh)
+		return errCh
+	}
+
+	// This is a new background job, so let's create a brand new context for it.
+	// We want it to be isolated, i.e. we don't want grafana shutdowns to interrupt this work
+	// immediately but rather try to flush writes.
+	// This also prevents timeouts or other lingering objects (like transactions) from being
+	// incorrectly propagated here from other areas.
+	writeCtx := context.Background()
+	writeCtx, cancel := context.WithTimeout(writeCtx, StateHistoryWriteTimeout)
+	writeCtx = history_model.WithRuleData(writeCtx, rule)
+	writeCtx = trace.ContextWithSpan(writeCtx, trace.SpanFromContext(ctx))
+
+	go func(ctx context.Context) {
+		defer cancel()
+		defer close(errCh)
+		logger := h.log.FromContext(ctx)
+		logger.Debug("Saving state history batch", "samples", len(entries))
+		org := fmt.Sprint(rule.OrgID)
+		h.metrics.WritesTotal.WithLabelValues(org, BackendTypeSQL.String()).Inc()
+		h.metrics.TransitionsTotal.WithLabelValues(org).Add(float64(len(entries)))
+
+		if err := h.store.SaveStateHistory(ctx, entries); err != nil {
+			logger.Error("Failed to save alert state history batch", "error", err)
+			h.metrics.WritesFailed.WithLabelValues(org, BackendTypeSQL.String()).Inc()
+			h.metrics.TransitionsFailed.WithLabelValues(org).Add(float64(len(entries)))
+			errCh <- fmt.Errorf("failed to save alert state history batch: %w", err)
+			return
+		}
+		logger.Debug("Done saving alert state history batch", "samples", len(entries))
+
+		h.deleteExpired(ctx, logger)
+	}(writeCtx)
+	return errCh
+}
+
+// Query retrieves state history entries from the database and formats the results into a dataframe.
+// The dataframe has the same format as the one returned by the Loki backend.
+func (h *SQLBackend) Query(ctx context.Context, query models.HistoryQuery) (*data.Frame, error) {
+	token, err := parseContinueToken(query.ContinueToken)
+	if err != nil {
+		return nil, err
+	}
+
+	uids, err := getFolderUIDsForFilter(ctx, h.ac, h.ruleStore, query)
+	if err != nil {
+		return nil, err
+	}
+
+	now := h.clock.Now().UTC()
+	if query.To.IsZero() {
+		query.To = now
+	}
+	if query.From.IsZero() {
+		query.From = now.Add(-defaultQueryRange)
+	}
+	fetchLimit := token.applyTo(&query)
+	limit := query.Limit
+	query.Limit = fetchLimit
+
+	entries, err := h.store.FindStateHistory(ctx, query, uids)
+	if err != nil {
+		return nil, err
+	}
+	times := make([]time.Time, 0, len(entries))
+	for _, e := range entries {
+		times = append(times, e.Timestamp)
+	}
+	rows, next := token.page(times, limit, fetchLimit)
+	page := make([]models.StateHistoryEntry, 0, len(rows))
+	for _, row := range rows {
+		page = append(page, entries[row])
+	}
+
+	frame, err := entriesToFrame(page)
+	if err != nil {
+		return nil, err
+	}
+	setContinueToken(frame, next)
+	return frame, nil
+}
+
+// Aggregate retrieves state history entries from the database and calculates the aggregation of them.
+func (h *SQLBackend) Aggregate(ctx context.Context, query models.HistoryAggregationQuery) (*data.Frame, error) {
+	now := h.clock.Now().UTC()
+	if query.To.IsZero() {
+		query.To = now
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-defaultQueryRange)
+	}
+	query.Limit = maxAggregatedTransitions
+	query.ContinueToken = ""
+
+	frame, err := h.Query(ctx, query.HistoryQuery)
+	if err != nil {
+		return nil, err
+	}
+	transitions, err := transitionsFromFrame(frame, h.log.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return aggregate(query, transitions, frame.Rows() >= maxAggregatedTransitions)
+}
+
+// deleteExpired deletes state transitions that are older than the retention period.
+// It does nothing if the previous deletion happened less than the cleanup interval ago.
+func (h *SQLBackend) deleteExpired(ctx context.Context, logger log.Logger) {
+	if h.cfg.Retention <= 0 {
+		return
+	}
+	now := h.clock.Now()
+	h.cleanupMtx.Lock()
+	if !h.lastCleanup.IsZero() && now.Sub(h.lastCleanup) < h.cfg.CleanupInterval {
+		h.cleanupMtx.Unlock()
+		return
+	}
+	h.lastCleanup = now
+	h.cleanupMtx.Unlock()
+
+	deleted, err := h.store.DeleteStateHistory(ctx, now.Add(-h.cfg.Retention))
+	if err != nil {
+		logger.Error("Failed to delete expired alert state history", "error", err)
+		return
+	}
+	logger.Debug("Deleted expired alert state history", "deleted", deleted, "retention", h.cfg.Retention)
+}
+
+func statesToEntries(rule history_model.RuleMeta, states []state.StateTransition, logger log.Logger) []models.StateHistoryEntry {
+	entries := make([]models.StateHistoryEntry, 0, len(states))
+	for _, state := range states {
+		if !shouldRecord(state) {
+			continue
+		}
+
+		var values json.RawMessage
+		if blob := valuesAsDataBlob(state.State); blob != nil {
+			b, err := blob.Encode()
+			if err != nil {
+				logger.Error("Failed to encode values of state, skipping", "error", err)
+				continue
+			}
+			values = b
+		}
+
+		sanitizedLabels := removePrivateLabels(state.Labels)
+		entry := models.StateHistoryEntry{
+			OrgID:          rule.OrgID,
+			RuleUID:        rule.UID,
+			RuleID:         rule.ID,
+			RuleTitle:      rule.Title,
+			RuleGroup:      rule.Group,
+			NamespaceUID:   rule.NamespaceUID,
+			DashboardUID:   rule.DashboardUID,
+			PanelID:        rule.PanelID,
+			Condition:      rule.Condition,
+			Fingerprint:    labelFingerprint(sanitizedLabels),
+			Labels:         sanitizedLabels,
+			Values:         values,
+			PreviousState:  state.PreviousState.String(),
+			PreviousReason: state.PreviousStateReason,
+			CurrentState:   state.State.State.String(),
+			CurrentReason:  state.State.StateReason,
+			Timestamp:      state.State.LastEvaluationTime,
+		}
+		if state.State.State == eval.Error && state.Error != nil {
+			entry.Error = state.Error.Error()
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func entriesToFrame(entries []models.StateHistoryEntry) (*data.Frame, error) {
+	frame := data.NewFrame("states")
+	lbls := data.Labels(map[string]string{})
+
+	times := make([]time.Time, 0, len(entries))
+	lines := make([]json.RawMessage, 0, len(entries))
+	labels := make([]json.RawMessage, 0, len(entries))
+	for _, e := range entries {
+		values := simplejson.New()
+		if len(e.Values) > 0 {
+			v, err := simplejson.NewJson(e.Values)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse values of state history entry %d: %w", e.ID, err)
+			}
+			values = v
+		}
+		line, err := json.Marshal(LokiEntry{
+			SchemaVersion:  1,
+			Previous:       formatState(e.PreviousState, e.PreviousReason),
+			Current:        formatState(e.CurrentState, e.CurrentReason),
+			Error:          e.Error,
+			Values:         values,
+			Condition:      e.Condition,
+			DashboardUID:   e.DashboardUID,
+			PanelID:        e.PanelID,
+			Fingerprint:    e.Fingerprint,
+			RuleTitle:      e.RuleTitle,
+			RuleID:         e.RuleID,
+			RuleUID:        e.RuleUID,
+			InstanceLabels: e.Labels,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize state history entry: %w", err)
+		}
+		entryLabels, err := json.Marshal(map[string]string{
+			StateHistoryLabelKey: StateHistoryLabelValue,
+			OrgIDLabel:           fmt.Sprint(e.OrgID),
+			GroupLabel:           e.RuleGroup,
+			FolderUIDLabel:       e.NamespaceUID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize state history labels: %w", err)
+		}
+
+		times = append(times, e.Timestamp)
+		lines = append(lines, line)
+		labels = append(labels, entryLabels)
+	}
+
+	frame.Fields = append(frame.Fields, data.NewField(dfTime, lbls, times))
+	frame.Fields = append(frame.Fields, data.NewField(dfLine, lbls, lines))
+	frame.Fields = append(frame.Fields, data.NewField(dfLabels, lbls, labels))
+	return frame, nil
+}
+
+// formatState formats the state and the reason the same way as state.FormatStateAndReason.
+func formatState(s, reason string) string {
+	if reason == "" {
+		return s
+	}
+	return fmt.Sprintf("%s (%s)", s, reason)
+}
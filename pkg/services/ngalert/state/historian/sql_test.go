@@ -0,0 +1,257 @@
+package historian
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/log"
+	acfakes "github.com/grafana/grafana/pkg/services/ngalert/accesscontrol/fakes"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests/fakes"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestSQLBackend_Record(t *testing.T) {
+	t.Run("should save state transitions", func(t *testing.T) {
+		store := &fakeStateHistoryStore{}
+		backend := createTestSQLBackend(t, store)
+		rule := createTestRule()
+		now := time.Now()
+		states := []state.StateTransition{
+			{
+				PreviousState: eval.Normal,
+				State: &state.State{
+					State:              eval.Alerting,
+					Labels:             data.Labels{"a": "b", "__private__": "c"},
+					Values:             map[string]float64{"A": 1},
+					LastEvaluationTime: now,
+				},
+			},
+			{
+				PreviousState:       eval.Alerting,
+				PreviousStateReason: models.StateReasonNoData,
+				State: &state.State{
+					State:              eval.Normal,
+					StateReason:        models.StateReasonMissingSeries,
+					Labels:             data.Labels{"a": "c"},
+					LastEvaluationTime: now,
+				},
+			},
+			{
+				// not changed
+				PreviousState: eval.Alerting,
+				State: &state.State{
+					State:  eval.Alerting,
+					Labels: data.Labels{"a": "d"},
+				},
+			},
+		}
+
+		err := <-backend.Record(context.Background(), rule, states)
+		require.NoError(t, err)
+
+		require.Len(t, store.entries, 2)
+		first := store.entries[0]
+		require.Equal(t, rule.OrgID, first.OrgID)
+		require.Equal(t, rule.UID, first.RuleUID)
+		require.Equal(t, rule.NamespaceUID, first.NamespaceUID)
+		require.Equal(t, rule.Group, first.RuleGroup)
+		require.Equal(t, map[string]string{"a": "b"}, first.Labels)
+		require.JSONEq(t, `{"A": 1}`, string(first.Values))
+		require.Equal(t, "Normal", first.PreviousState)
+		require.Equal(t, "Alerting", first.CurrentState)
+		require.Equal(t, now, first.Timestamp)
+
+		second := store.entries[1]
+		require.Equal(t, "Alerting", second.PreviousState)
+		require.Equal(t, models.StateReasonNoData, second.PreviousReason)
+		require.Equal(t, "Normal", second.CurrentState)
+		require.Equal(t, models.StateReasonMissingSeries, second.CurrentReason)
+	})
+
+	t.Run("should return error if store fails", func(t *testing.T) {
+		store := &fakeStateHistoryStore{saveErr: errors.New("test")}
+		backend := createTestSQLBackend(t, store)
+		err := <-backend.Record(context.Background(), createTestRule(), singleFromNormal(&state.State{State: eval.Alerting}))
+		require.ErrorIs(t, err, store.saveErr)
+	})
+
+	t.Run("should delete expired transitions not more often than cleanup interval", func(t *testing.T) {
+		store := &fakeStateHistoryStore{}
+		backend := createTestSQLBackend(t, store)
+		clk := clock.NewMock()
+		backend.clock = clk
+		backend.cfg = SQLConfig{Retention: 24 * time.Hour, CleanupInterval: time.Hour}
+		states := singleFromNormal(&state.State{State: eval.Alerting})
+
+		require.NoError(t, <-backend.Record(context.Background(), createTestRule(), states))
+		require.NoError(t, <-backend.Record(context.Background(), createTestRule(), states))
+		require.Equal(t, []time.Time{clk.Now().Add(-24 * time.Hour)}, store.deletedBefore)
+
+		clk.Add(time.Hour)
+		require.NoError(t, <-backend.Record(context.Background(), createTestRule(), states))
+		require.Len(t, store.deletedBefore, 2)
+		require.Equal(t, clk.Now().Add(-24*time.Hour), store.deletedBefore[1])
+	})
+}
+
+func TestNewSQLConfig(t *testing.T) {
+	t.Run("uses defaults if not set", func(t *testing.T) {
+		cfg, err := NewSQLConfig(stateHistorySection(t, nil))
+		require.NoError(t, err)
+		require.Equal(t, defaultSQLRetention, cfg.Retention)
+		require.Equal(t, defaultSQLCleanupInterval, cfg.CleanupInterval)
+	})
+
+	t.Run("reads retention and cleanup interval from settings", func(t *testing.T) {
+		cfg, err := NewSQLConfig(stateHistorySection(t, map[string]string{"sql_retention": "7d", "sql_cleanup_interval": "10m"}))
+		require.NoError(t, err)
+		require.Equal(t, 7*24*time.Hour, cfg.Retention)
+		require.Equal(t, 10*time.Minute, cfg.CleanupInterval)
+	})
+
+	t.Run("disables cleanup if retention is zero", func(t *testing.T) {
+		cfg, err := NewSQLConfig(stateHistorySection(t, map[string]string{"sql_retention": "0"}))
+		require.NoError(t, err)
+		require.Zero(t, cfg.Retention)
+	})
+
+	t.Run("fails if retention is negative", func(t *testing.T) {
+		_, err := NewSQLConfig(stateHistorySection(t, map[string]string{"sql_retention": "-1h"}))
+		require.ErrorContains(t, err, "sql_retention")
+	})
+
+	t.Run("fails if cleanup interval is invalid", func(t *testing.T) {
+		_, err := NewSQLConfig(stateHistorySection(t, map[string]string{"sql_cleanup_interval": "often"}))
+		require.ErrorContains(t, err, "sql_cleanup_interval")
+		_, err = NewSQLConfig(stateHistorySection(t, map[string]string{"sql_cleanup_interval": "0"}))
+		require.ErrorContains(t, err, "sql_cleanup_interval")
+	})
+}
+
+func stateHistorySection(t *testing.T, options map[string]string) *setting.DynamicSection {
+	t.Helper()
+	cfg := setting.NewCfg()
+	section := cfg.Raw.Section("unified_alerting.state_history")
+	for k, v := range options {
+		_, err := section.NewKey(k, v)
+		require.NoError(t, err)
+	}
+	return cfg.SectionWithEnvOverrides("unified_alerting.state_history")
+}
+
+func TestSQLBackend_Query(t *testing.T) {
+	now := time.Now().UTC()
+	store := &fakeStateHistoryStore{
+		found: []models.StateHistoryEntry{
+			{
+				ID:             1,
+				OrgID:          1,
+				RuleUID:        "rule-uid",
+				RuleGroup:      "my-group",
+				NamespaceUID:   "my-folder",
+				Labels:         map[string]string{"a": "b"},
+				Values:         json.RawMessage(`{"A": 1}`),
+				PreviousState:  "Normal",
+				CurrentState:   "Alerting",
+				CurrentReason:  "",
+				PreviousReason: models.StateReasonNoData,
+				Timestamp:      now,
+			},
+		},
+	}
+	backend := createTestSQLBackend(t, store)
+	ac := &acfakes.FakeRuleService{}
+	ac.CanReadAllRulesFunc = func(ctx context.Context, user identity.Requester) (bool, error) {
+		return true, nil
+	}
+	backend.ac = ac
+
+	query := models.HistoryQuery{
+		OrgID:   1,
+		RuleUID: "rule-uid",
+		Labels:  map[string]string{"a": "b"},
+		Limit:   10,
+	}
+	frame, err := backend.Query(context.Background(), query)
+	require.NoError(t, err)
+
+	require.Equal(t, query.RuleUID, store.lastQuery.RuleUID)
+	require.Equal(t, query.Labels, store.lastQuery.Labels)
+	require.Equal(t, query.Limit, store.lastQuery.Limit)
+	require.False(t, store.lastQuery.From.IsZero())
+	require.False(t, store.lastQuery.To.IsZero())
+
+	require.Len(t, frame.Fields, 3)
+	require.Equal(t, 1, frame.Rows())
+	require.Equal(t, now, frame.Fields[0].At(0))
+
+	var entry LokiEntry
+	require.NoError(t, json.Unmarshal(frame.Fields[1].At(0).(json.RawMessage), &entry))
+	require.Equal(t, "Normal (NoData)", entry.Previous)
+	require.Equal(t, "Alerting", entry.Current)
+	require.Equal(t, map[string]string{"a": "b"}, entry.InstanceLabels)
+	require.Equal(t, 1.0, entry.Values.Get("A").MustFloat64())
+
+	var lbls map[string]string
+	require.NoError(t, json.Unmarshal(frame.Fields[2].At(0).(json.RawMessage), &lbls))
+	require.Equal(t, "my-folder", lbls[FolderUIDLabel])
+	require.Equal(t, "my-group", lbls[GroupLabel])
+}
+
+func createTestSQLBackend(t *testing.T, store StateHistoryStore) *SQLBackend {
+	t.Helper()
+	met := metrics.NewHistorianMetrics(prometheus.NewRegistry(), metrics.Subsystem)
+	rules := fakes.NewRuleStore(t)
+	ac := &acfakes.FakeRuleService{}
+	logger := log.New("ngalert.state.historian", "backend", "sql")
+	cfg, err := NewSQLConfig(stateHistorySection(t, nil))
+	require.NoError(t, err)
+	return NewSQLBackend(logger, cfg, store, rules, met, ac)
+}
+
+type fakeStateHistoryStore struct {
+	mtx           sync.Mutex
+	entries       []models.StateHistoryEntry
+	saveErr       error
+	found         []models.StateHistoryEntry
+	lastQuery     models.HistoryQuery
+	deletedBefore []time.Time
+}
+
+func (f *fakeStateHistoryStore) SaveStateHistory(_ context.Context, entries []models.StateHistoryEntry) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.saveErr != nil {
+		return f.saveErr
+	}
+	f.entries = append(f.entries, entries...)
+	return nil
+}
+
+func (f *fakeStateHistoryStore) FindStateHistory(_ context.Context, query models.HistoryQuery, _ []string) ([]models.StateHistoryEntry, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.lastQuery = query
+	return f.found, nil
+}
+
+func (f *fakeStateHistoryStore) DeleteStateHistory(_ context.Context, before time.Time) (int64, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.deletedBefore = append(f.deletedBefore, before)
+	return 0, nil
+}
@@ -14,16 +14,21 @@ import (
 
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
 	ngModels "github.com/grafana/grafana/pkg/services/ngalert/models"
 	history_model "github.com/grafana/grafana/pkg/services/ngalert/state/historian/model"
+	"github.com/grafana/grafana/pkg/services/ngalert/state/template"
 )
 
 var (
 	ResendDelay = 30 * time.Second
 )
 
+// handOffPollInterval is how often LoadStateByRuleUID checks whether the previous owner of the rule saved its state.
+const handOffPollInterval = time.Second
+
 type takeImageFn func(reason string) *ngModels.Image
 
 // AlertInstanceManager defines the interface for querying the current alert instances.
@@ -40,6 +45,18 @@ type StatePersister interface {
 // Sender is an optional callback intended for sending the states to an alertmanager.
 type Sender func(context.Context, StateTransitions)
 
+// TemplateQuerierProvider provides the queriers used by the query function in templates of annotations and labels.
+type TemplateQuerierProvider interface {
+	// QuerierFor returns a querier that executes queries on behalf of the rule.
+	QuerierFor(rule *ngModels.AlertRule) template.Querier
+}
+
+// LookupTablesReader provides the lookup tables of organizations for the lookup function in templates.
+// It is called for every evaluation, so implementations are expected to cache the tables.
+type LookupTablesReader interface {
+	GetLookupTables(ctx context.Context, orgID int64) (definitions.LookupTables, error)
+}
+
 type Manager struct {
 	log     log.Logger
 	metrics *metrics.State
@@ -53,8 +70,13 @@ type Manager struct {
 	instanceStore InstanceStore
 	images        ImageCapturer
 	historian     Historian
+	historyReader InstanceReader
 	externalURL   *url.URL
 
+	templateQuerier      TemplateQuerierProvider
+	templateQueryTimeout time.Duration
+	lookupTables         LookupTablesReader
+
 	applyNoDataAndErrorToAllStates bool
 	rulesPerRuleGroupLimit         int64
 
@@ -68,11 +90,21 @@ type ManagerCfg struct {
 	Images        ImageCapturer
 	Clock         clock.Clock
 	Historian     Historian
+	// HistoryReader is an optional source of alert instances that Warm uses to restore Pending and Alerting states
+	// of rules that have no state in the instance store, e.g. because the store was reset.
+	HistoryReader InstanceReader
 	// MaxStateSaveConcurrency controls the number of goroutines (per rule) that can save alert state in parallel.
 	MaxStateSaveConcurrency int
 	// StatePeriodicSaveBatchSize controls the size of the alert instance batch that is saved periodically when the
 	// alertingSaveStatePeriodic feature flag is enabled.
 	StatePeriodicSaveBatchSize int
+	// TemplateQuerier is an optional provider of queriers for the query function in templates.
+	// If it is not set, the query function returns no results.
+	TemplateQuerier TemplateQuerierProvider
+	// TemplateQueryTimeout is the time within which all queries of the templates of an evaluation must complete.
+	TemplateQueryTimeout time.Duration
+	// LookupTables is an optional source of the lookup tables for the lookup function in templates.
+	LookupTables LookupTablesReader
 	// ApplyNoDataAndErrorToAllStates makes state manager to apply exceptional results (NoData and Error)
 	// to all states when corresponding execution in the rule definition is set to either `Alerting` or `OK`
 	ApplyNoDataAndErrorToAllStates bool
@@ -104,8 +136,12 @@ func NewManager(cfg ManagerCfg, statePersister StatePersister) *Manager {
 		instanceStore:                  cfg.InstanceStore,
 		images:                         cfg.Images,
 		historian:                      cfg.Historian,
+		historyReader:                  cfg.HistoryReader,
 		clock:                          cfg.Clock,
 		externalURL:                    cfg.ExternalURL,
+		templateQuerier:                cfg.TemplateQuerier,
+		templateQueryTimeout:           cfg.TemplateQueryTimeout,
+		lookupTables:                   cfg.LookupTables,
 		applyNoDataAndErrorToAllStates: cfg.ApplyNoDataAndErrorToAllStates,
 		rulesPerRuleGroupLimit:         cfg.RulesPerRuleGroupLimit,
 		persister:                      statePersister,
@@ -181,51 +217,114 @@ func (st *Manager) Warm(ctx context.Context, orgReader OrgReader, rulesReader Ru
 			logger.Error("Unable to fetch previous state", "error", err)
 		}
 
+		rulesWithState := make(map[string]struct{}, len(alertInstances))
 		for _, entry := range alertInstances {
 			ruleForEntry, ok := ruleByUID[entry.RuleUID]
 			if !ok {
 				// TODO Should we delete the orphaned state from the db?
 				continue
 			}
+			rulesWithState[entry.RuleUID] = struct{}{}
+			st.cache.set(stateFromInstance(entry, ruleForEntry, logger))
+			statesCount++
+		}
 
-			// nil safety.
-			annotations := ruleForEntry.Annotations
-			if annotations == nil {
-				annotations = make(map[string]string)
-			}
+		if st.historyReader != nil {
+			statesCount += st.warmFromHistory(ctx, orgId, ruleByUID, rulesWithState, logger)
+		}
+	}
 
-			lbs := map[string]string(entry.Labels)
-			cacheID := entry.Labels.Fingerprint()
-			var resultFp data.Fingerprint
-			if entry.ResultFingerprint != "" {
-				fp, err := strconv.ParseUint(entry.ResultFingerprint, 16, 64)
-				if err != nil {
-					logger.Error("Failed to parse result fingerprint of alert instance", "error", err, "rule_uid", entry.RuleUID)
-				}
-				resultFp = data.Fingerprint(fp)
-			}
-			state := &State{
-				AlertRuleUID:         entry.RuleUID,
-				OrgID:                entry.RuleOrgID,
-				CacheID:              cacheID,
-				Labels:               lbs,
-				State:                translateInstanceState(entry.CurrentState),
-				StateReason:          entry.CurrentReason,
-				LastEvaluationString: "",
-				StartsAt:             entry.CurrentStateSince,
-				EndsAt:               entry.CurrentStateEnd,
-				LastEvaluationTime:   entry.LastEvalTime,
-				Annotations:          annotations,
-				ResultFingerprint:    resultFp,
-				ResolvedAt:           entry.ResolvedAt,
-				LastSentAt:           entry.LastSentAt,
+	logger.Info("State cache has been initialized", "states", statesCount, "duration", time.Since(startTime))
+}
+
+// warmFromHistory restores Pending and Alerting states of alert rules that have no state in the instance store
+// from the state history, so that the pending period of alerts does not start over. It returns the number of restored states.
+func (st *Manager) warmFromHistory(ctx context.Context, orgID int64, ruleByUID map[string]*ngModels.AlertRule, rulesWithState map[string]struct{}, logger log.Logger) int {
+	instances, err := st.historyReader.ListAlertInstances(ctx, &ngModels.ListAlertInstancesQuery{
+		RuleOrgID: orgID,
+	})
+	if err != nil {
+		logger.Error("Unable to restore state from state history", "error", err)
+		return 0
+	}
+
+	count := 0
+	for _, entry := range instances {
+		if _, ok := rulesWithState[entry.RuleUID]; ok {
+			continue
+		}
+		rule, ok := ruleByUID[entry.RuleUID]
+		if !ok || rule.IsPaused || rule.Type() != ngModels.RuleTypeAlerting {
+			continue
+		}
+		if entry.CurrentState != ngModels.InstanceStatePending && entry.CurrentState != ngModels.InstanceStateFiring {
+			continue
+		}
+		// State history does not contain private labels, restore them from the rule.
+		lbs := make(ngModels.InstanceLabels, len(entry.Labels)+3)
+		for k, v := range GetRuleExtraLabels(logger, rule, "", false) {
+			if strings.HasPrefix(k, "__") || strings.HasSuffix(k, "__") {
+				lbs[k] = v
 			}
-			st.cache.set(state)
-			statesCount++
 		}
+		for k, v := range entry.Labels {
+			lbs[k] = v
+		}
+		entry.Labels = lbs
+
+		st.cache.set(stateFromInstance(entry, rule, logger))
+		count++
+	}
+	if count > 0 {
+		logger.Info("Restored state of alert instances from state history", "orgID", orgID, "states", count)
 	}
+	return count
+}
 
-	logger.Info("State cache has been initialized", "states", statesCount, "duration", time.Since(startTime))
+func stateFromInstance(entry *ngModels.AlertInstance, ruleForEntry *ngModels.AlertRule, logger log.Logger) *State {
+	// nil safety.
+	annotations := ruleForEntry.Annotations
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	lbs := map[string]string(entry.Labels)
+	cacheID := entry.Labels.Fingerprint()
+	var resultFp data.Fingerprint
+	if entry.ResultFingerprint != "" {
+		fp, err := strconv.ParseUint(entry.ResultFingerprint, 16, 64)
+		if err != nil {
+			logger.Error("Failed to parse result fingerprint of alert instance", "error", err, "rule_uid", entry.RuleUID)
+		}
+		resultFp = data.Fingerprint(fp)
+	}
+	state := &State{
+		AlertRuleUID:         entry.RuleUID,
+		OrgID:                entry.RuleOrgID,
+		CacheID:              cacheID,
+		Labels:               lbs,
+		State:                translateInstanceState(entry.CurrentState),
+		StateReason:          entry.CurrentReason,
+		LastEvaluationString: "",
+		StartsAt:             entry.CurrentStateSince,
+		EndsAt:               entry.CurrentStateEnd,
+		LastEvaluationTime:   entry.LastEvalTime,
+		Annotations:          annotations,
+		ResultFingerprint:    resultFp,
+		ResolvedAt:           entry.ResolvedAt,
+		LastSentAt:           entry.LastSentAt,
+	}
+	if state.State == eval.Pending || state.State == eval.Alerting {
+		// The time when the state became active is not persisted. For firing states, the start of the pending
+		// period is unknown, so the time when the state started to fire is used instead.
+		state.ActiveAt = entry.CurrentStateSince
+	}
+	if state.State == eval.Alerting && state.StateReason == ngModels.StateReasonKeepFiring {
+		// The time when the state started to keep firing is not persisted, so the last evaluation is the
+		// closest approximation. It can extend the keep firing period by one evaluation interval at most.
+		state.KeepFiringSince = entry.LastEvalTime
+	}
+	return state
 }
 
 func (st *Manager) Get(orgID int64, alertRuleUID string, stateId data.Fingerprint) *State {
@@ -290,6 +389,101 @@ func (st *Manager) ForgetStateByRuleUID(ctx context.Context, ruleKey ngModels.Al
 	return st.cache.removeByRuleUID(ruleKey.OrgID, ruleKey.UID)
 }
 
+// HandOffStateByRuleUID saves the current state of the rule to the instance store and removes it from the cache.
+// It is used when the evaluation of the rule moves to another instance, which loads the state with LoadStateByRuleUID.
+func (st *Manager) HandOffStateByRuleUID(ctx context.Context, ruleKey ngModels.AlertRuleKeyWithGroup) []*State {
+	logger := st.log.FromContext(ctx)
+	states := st.cache.removeByRuleUID(ruleKey.OrgID, ruleKey.UID)
+	if st.instanceStore == nil {
+		return states
+	}
+
+	instances := make([]ngModels.AlertInstance, 0, len(states))
+	for _, s := range states {
+		key, err := s.GetAlertInstanceKey()
+		if err != nil {
+			logger.Error("Failed to create a key for alert state to hand it off. The state will be ignored", "cacheID", s.CacheID, "error", err)
+			continue
+		}
+		instances = append(instances, ngModels.AlertInstance{
+			AlertInstanceKey:  key,
+			Labels:            ngModels.InstanceLabels(s.Labels),
+			CurrentState:      ngModels.InstanceStateType(s.State.String()),
+			CurrentReason:     s.StateReason,
+			LastEvalTime:      s.LastEvaluationTime,
+			CurrentStateSince: s.StartsAt,
+			CurrentStateEnd:   s.EndsAt,
+			ResolvedAt:        s.ResolvedAt,
+			LastSentAt:        s.LastSentAt,
+			ResultFingerprint: s.ResultFingerprint.String(),
+		})
+	}
+	if err := st.instanceStore.SaveAlertInstancesForRule(ctx, ruleKey, instances); err != nil {
+		logger.Error("Failed to save rule state to hand it off", "error", err)
+		return states
+	}
+	logger.Debug("Rule state has been handed off", "states", len(instances))
+	return states
+}
+
+// LoadStateByRuleUID replaces the cached state of the rule with the state in the instance store. It returns the number of loaded states.
+// It is used when the evaluation of the rule moves to this instance from another one. The previous owner saves the state
+// when it stops evaluating the rule, so the state is loaded once the stored state was evaluated at or after handedOffAfter.
+// If that does not happen within timeout, for example, because the previous owner left the cluster, the stored state is loaded as is.
+func (st *Manager) LoadStateByRuleUID(ctx context.Context, rule *ngModels.AlertRule, handedOffAfter time.Time, timeout time.Duration) int {
+	if st.instanceStore == nil {
+		return 0
+	}
+	logger := st.log.FromContext(ctx)
+	deadline := st.clock.Now().Add(timeout)
+	var instances []*ngModels.AlertInstance
+	for {
+		var err error
+		instances, err = st.instanceStore.ListAlertInstances(ctx, &ngModels.ListAlertInstancesQuery{
+			RuleOrgID: rule.OrgID,
+			RuleUID:   rule.UID,
+		})
+		if err != nil {
+			logger.Error("Unable to load rule state", "error", err)
+			return 0
+		}
+		if isHandedOff(instances, handedOffAfter) {
+			break
+		}
+		if !st.clock.Now().Before(deadline) {
+			logger.Warn("Rule state was not handed off by the previous owner in time, loading the last saved state", "handedOffAfter", handedOffAfter, "states", len(instances))
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-st.clock.After(handOffPollInterval):
+		}
+	}
+
+	st.cache.removeByRuleUID(rule.OrgID, rule.UID)
+	count := 0
+	for _, entry := range instances {
+		if entry.RuleUID != rule.UID {
+			continue
+		}
+		st.cache.set(stateFromInstance(entry, rule, logger))
+		count++
+	}
+	logger.Debug("Rule state has been loaded", "states", count)
+	return count
+}
+
+// isHandedOff returns true if any of the instances was evaluated at or after the given time.
+func isHandedOff(instances []*ngModels.AlertInstance, handedOffAfter time.Time) bool {
+	for _, i := range instances {
+		if !i.LastEvalTime.Before(handedOffAfter) {
+			return true
+		}
+	}
+	return false
+}
+
 // ResetStateByRuleUID removes the rule instances from cache and instanceStore and saves state history. If the state
 // history has to be saved, rule must not be nil.
 func (st *Manager) ResetStateByRuleUID(ctx context.Context, rule *ngModels.AlertRule, reason string) []StateTransition {
@@ -358,7 +552,7 @@ func (st *Manager) ProcessEvalResults(
 	}
 
 	logger.Debug("State manager processing evaluation results", "resultCount", len(results))
-	states := st.setNextStateForRule(ctx, alertRule, results, extraLabels, logger, fn)
+	states := st.setNextStateForRule(st.templateContext(ctx, logger, alertRule), alertRule, results, extraLabels, logger, fn)
 
 	staleStates := st.deleteStaleStatesFromCache(logger, evaluatedAt, alertRule, fn)
 	span.AddEvent("results processed", trace.WithAttributes(
@@ -389,6 +583,24 @@ func (st *Manager) ProcessEvalResults(
 	return allChanges
 }
 
+// templateContext returns a copy of ctx with the querier and the lookup tables for the templates of the rule.
+// The querier caches the results of queries, so a new one is created for every evaluation.
+func (st *Manager) templateContext(ctx context.Context, logger log.Logger, alertRule *ngModels.AlertRule) context.Context {
+	if st.templateQuerier != nil {
+		q := template.NewEvaluationQuerier(st.templateQuerier.QuerierFor(alertRule), st.clock.Now(), st.templateQueryTimeout)
+		ctx = template.WithQuerier(ctx, q)
+	}
+	if st.lookupTables != nil {
+		tables, err := st.lookupTables.GetLookupTables(ctx, alertRule.OrgID)
+		if err != nil {
+			logger.Warn("Failed to get lookup tables, templates that use them will fail to expand", "error", err)
+		} else {
+			ctx = template.WithLookupTables(ctx, template.LookupTables(tables))
+		}
+	}
+	return ctx
+}
+
 // updateLastSentAt returns the subset StateTransitions that need sending and updates their LastSentAt field.
 // Note: This is not idempotent, running this twice can (and usually will) return different results.
 func (st *Manager) updateLastSentAt(states StateTransitions, evaluatedAt time.Time) StateTransitions {
@@ -403,6 +615,10 @@ func (st *Manager) updateLastSentAt(states StateTransitions, evaluatedAt time.Ti
 }
 
 func (st *Manager) setNextStateForRule(ctx context.Context, alertRule *ngModels.AlertRule, results eval.Results, extraLabels data.Labels, logger log.Logger, takeImageFn takeImageFn) []StateTransition {
+	if len(results) == 1 && isThrottled(results[0]) {
+		// The queries did not run, so the current alert instances keep their state and no new ones are created.
+		return st.setNextStateForAll(alertRule, results[0], logger, nil, takeImageFn)
+	}
 	if st.applyNoDataAndErrorToAllStates && results.IsNoData() && (alertRule.NoDataState == ngModels.Alerting || alertRule.NoDataState == ngModels.OK || alertRule.NoDataState == ngModels.KeepLast) { // If it is no data, check the mapping and switch all results to the new state
 		// aggregate UID of datasources that returned NoData into one and provide as auxiliary info via annotationa. See: https://github.com/grafana/grafana/issues/88184
 		var refIds strings.Builder
@@ -444,7 +660,8 @@ func (st *Manager) setNextStateForRule(ctx context.Context, alertRule *ngModels.
 	}
 	transitions := make([]StateTransition, 0, len(results))
 	for _, result := range results {
-		newState := newState(ctx, logger, alertRule, result, extraLabels, st.externalURL)
+		lbs, templateData := expandLabels(ctx, logger, alertRule, result, extraLabels, st.externalURL)
+		newState := newStateWithLabels(alertRule, result, lbs, make(data.Labels, len(alertRule.Annotations)))
 		if curState := st.cache.get(alertRule.OrgID, alertRule.UID, newState.CacheID); curState != nil {
 			patch(newState, curState, result)
 		}
@@ -453,6 +670,8 @@ func (st *Manager) setNextStateForRule(ctx context.Context, alertRule *ngModels.
 		if st.metrics != nil {
 			st.metrics.StateUpdateDuration.Observe(st.clock.Now().Sub(start).Seconds())
 		}
+		// Annotations can use the time the alert instance entered its current state, which is known only after the transition.
+		newState.setRuleAnnotations(expandAnnotations(ctx, logger, alertRule, templateData, st.externalURL, result.EvaluatedAt, newState.StartsAt))
 		st.cache.set(newState) // replace the existing state with the new one
 		transitions = append(transitions, s)
 	}
@@ -518,10 +737,29 @@ func translateInstanceState(state ngModels.InstanceStateType) eval.State {
 func (st *Manager) deleteStaleStatesFromCache(logger log.Logger, evaluatedAt time.Time, alertRule *ngModels.AlertRule, takeImageFn takeImageFn) []StateTransition {
 	// If we are removing two or more stale series it makes sense to share the resolved image as the alert rule is the same.
 	// TODO: We will need to change this when we support images without screenshots as each series will have a different image
+	// Firing alert instances whose series disappeared keep firing for KeepFiringFor, the same way as when their
+	// condition is no longer met. They stay in the cache until the period passes, unless the series comes back.
+	var keptStates []StateTransition
 	staleStates := st.cache.deleteRuleStates(alertRule.GetKey(), func(s *State) bool {
-		return stateIsStale(evaluatedAt, s.LastEvaluationTime, alertRule.IntervalSeconds)
+		if !stateIsStale(evaluatedAt, s.LastEvaluationTime, alertRule.IntervalSeconds) {
+			return false
+		}
+		if s.State != eval.Alerting || !keepFiring(s, alertRule, evaluatedAt) {
+			return true
+		}
+		oldReason := s.StateReason
+		s.StateReason = ngModels.StateReasonKeepFiring
+		s.Maintain(alertRule.IntervalSeconds, evaluatedAt)
+		logger.Debug("Keeping stale state firing", "cacheID", s.CacheID, "keep_firing_since", s.KeepFiringSince, "next_ends_at", s.EndsAt)
+		keptStates = append(keptStates, StateTransition{
+			State:               s,
+			PreviousState:       s.State,
+			PreviousStateReason: oldReason,
+		})
+		return false
 	})
-	resolvedStates := make([]StateTransition, 0, len(staleStates))
+	resolvedStates := make([]StateTransition, 0, len(staleStates)+len(keptStates))
+	resolvedStates = append(resolvedStates, keptStates...)
 
 	for _, s := range staleStates {
 		logger.Info("Detected stale state entry", "cacheID", s.CacheID, "state", s.State, "reason", s.StateReason)
@@ -17,10 +17,13 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	alertingModels "github.com/grafana/alerting/models"
+
 	"github.com/grafana/grafana/pkg/expr"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/log/logtest"
 	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
 	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
@@ -241,6 +244,15 @@ func TestProcessEvalResults_StateTransitions(t *testing.T) {
 		if s.Values == nil {
 			s.Values = make(map[string]float64)
 		}
+		if s.ActiveAt.IsZero() {
+			// Evaluations of the tests are aligned with For, so firing states were pending for exactly For.
+			switch s.State {
+			case eval.Pending:
+				s.ActiveAt = s.StartsAt
+			case eval.Alerting:
+				s.ActiveAt = s.StartsAt.Add(-r.For)
+			}
+		}
 		if s.ResultFingerprint == data.Fingerprint(0) {
 			for key, set := range labels {
 				if set.Fingerprint() == s.Labels.Fingerprint() {
@@ -4085,3 +4097,291 @@ func setCacheID(s *State) *State {
 
 	return s
 }
+
+func TestWarmFromHistory(t *testing.T) {
+	gen := ngmodels.RuleGen
+	rule := gen.With(gen.WithOrgID(1)).GenerateRef()
+	ruleWithState := gen.With(gen.WithOrgID(1)).GenerateRef()
+	pausedRule := gen.With(gen.WithOrgID(1), gen.WithIsPaused(true)).GenerateRef()
+	ruleByUID := map[string]*ngmodels.AlertRule{
+		rule.UID:          rule,
+		ruleWithState.UID: ruleWithState,
+		pausedRule.UID:    pausedRule,
+	}
+	startsAt := time.Now().Add(-time.Minute).UTC()
+	instance := func(ruleUID string, s ngmodels.InstanceStateType, lbls ngmodels.InstanceLabels) *ngmodels.AlertInstance {
+		return &ngmodels.AlertInstance{
+			AlertInstanceKey:  ngmodels.AlertInstanceKey{RuleOrgID: 1, RuleUID: ruleUID},
+			Labels:            lbls,
+			CurrentState:      s,
+			CurrentStateSince: startsAt,
+			LastEvalTime:      startsAt,
+		}
+	}
+	reader := &fakeInstanceReader{instances: []*ngmodels.AlertInstance{
+		instance(rule.UID, ngmodels.InstanceStatePending, ngmodels.InstanceLabels{"a": "1"}),
+		instance(rule.UID, ngmodels.InstanceStateFiring, ngmodels.InstanceLabels{"a": "2"}),
+		instance(rule.UID, ngmodels.InstanceStateNormal, ngmodels.InstanceLabels{"a": "3"}),
+		instance(ruleWithState.UID, ngmodels.InstanceStatePending, ngmodels.InstanceLabels{"a": "1"}),
+		instance(pausedRule.UID, ngmodels.InstanceStatePending, ngmodels.InstanceLabels{"a": "1"}),
+		instance("unknown-rule", ngmodels.InstanceStatePending, ngmodels.InstanceLabels{"a": "1"}),
+	}}
+
+	cfg := ManagerCfg{
+		Metrics:       metrics.NewNGAlert(prometheus.NewPedanticRegistry()).GetStateMetrics(),
+		InstanceStore: &FakeInstanceStore{},
+		Images:        &NoopImageService{},
+		Clock:         clock.NewMock(),
+		Historian:     &FakeHistorian{},
+		HistoryReader: reader,
+		Tracer:        tracing.InitializeTracerForTest(),
+		Log:           &logtest.Fake{},
+	}
+	mgr := NewManager(cfg, NewNoopPersister())
+
+	count := mgr.warmFromHistory(context.Background(), 1, ruleByUID, map[string]struct{}{ruleWithState.UID: {}}, &logtest.Fake{})
+	require.Equal(t, 2, count)
+
+	require.Empty(t, mgr.cache.getStatesForRuleUID(1, ruleWithState.UID))
+	require.Empty(t, mgr.cache.getStatesForRuleUID(1, pausedRule.UID))
+
+	states := mgr.cache.getStatesForRuleUID(1, rule.UID)
+	require.Len(t, states, 2)
+	sort.Slice(states, func(i, j int) bool { return states[i].Labels["a"] < states[j].Labels["a"] })
+	require.Equal(t, eval.Pending, states[0].State)
+	require.Equal(t, eval.Alerting, states[1].State)
+	for _, s := range states {
+		require.Equal(t, startsAt, s.StartsAt)
+		require.Equal(t, rule.NamespaceUID, s.Labels[alertingModels.NamespaceUIDLabel])
+		require.Equal(t, rule.UID, s.Labels[alertingModels.RuleUIDLabel])
+		require.Equal(t, s.Labels.Fingerprint(), s.CacheID)
+	}
+}
+
+func TestProcessEvalResults_TemplateData(t *testing.T) {
+	gen := ngmodels.RuleGen
+	rule := gen.With(
+		gen.WithOrgID(1),
+		gen.WithIntervalSeconds(10),
+		gen.WithFor(20*time.Second),
+		gen.WithKeepFiringFor(0),
+		gen.WithLabels(data.Labels{}),
+		gen.WithAnnotations(data.Labels{
+			"active_for": "{{ activeFor }}",
+			"owner":      `{{ lookup "owners" $labels.service }}`,
+		}),
+	).GenerateRef()
+
+	cfg := ManagerCfg{
+		Metrics:       metrics.NewNGAlert(prometheus.NewPedanticRegistry()).GetStateMetrics(),
+		InstanceStore: &FakeInstanceStore{},
+		Images:        &NoopImageService{},
+		Clock:         clock.NewMock(),
+		Historian:     &FakeHistorian{},
+		LookupTables:  fakeLookupTablesReader{"owners": {"api": "team-a"}},
+		Tracer:        tracing.InitializeTracerForTest(),
+		Log:           &logtest.Fake{},
+	}
+	mgr := NewManager(cfg, NewNoopPersister())
+
+	t0 := time.Now().Truncate(time.Second)
+	process := func(offset time.Duration, state eval.State) *State {
+		results := eval.Results{{
+			Instance:    data.Labels{"service": "api"},
+			State:       state,
+			EvaluatedAt: t0.Add(offset),
+		}}
+		transitions := mgr.ProcessEvalResults(context.Background(), t0.Add(offset), rule, results, nil, nil)
+		require.Len(t, transitions, 1)
+		return transitions[0].State
+	}
+
+	s := process(0, eval.Alerting)
+	require.Equal(t, eval.Pending, s.State)
+	assert.Equal(t, "0s", s.Annotations["active_for"])
+	assert.Equal(t, "team-a", s.Annotations["owner"])
+
+	s = process(10*time.Second, eval.Alerting)
+	require.Equal(t, eval.Pending, s.State)
+	assert.Equal(t, "10s", s.Annotations["active_for"])
+
+	// The alert fires, so it is active since this evaluation.
+	s = process(20*time.Second, eval.Alerting)
+	require.Equal(t, eval.Alerting, s.State)
+	assert.Equal(t, "0s", s.Annotations["active_for"])
+
+	s = process(30*time.Second, eval.Alerting)
+	require.Equal(t, eval.Alerting, s.State)
+	assert.Equal(t, "10s", s.Annotations["active_for"])
+
+	s = process(40*time.Second, eval.Normal)
+	require.Equal(t, eval.Normal, s.State)
+	assert.Equal(t, "0s", s.Annotations["active_for"])
+
+	// The alert keeps firing after the condition is no longer met, so it is still active since it fired.
+	keepFiringRule := gen.With(
+		gen.WithOrgID(1),
+		gen.WithIntervalSeconds(10),
+		gen.WithFor(0),
+		gen.WithKeepFiringFor(20*time.Second),
+		gen.WithLabels(data.Labels{}),
+		gen.WithAnnotations(data.Labels{"active_for": "{{ activeFor }}"}),
+	).GenerateRef()
+	for _, tc := range []struct {
+		offset   time.Duration
+		result   eval.State
+		state    eval.State
+		expected string
+	}{
+		{offset: 0, result: eval.Alerting, state: eval.Alerting, expected: "0s"},
+		{offset: 10 * time.Second, result: eval.Normal, state: eval.Alerting, expected: "10s"},
+		{offset: 20 * time.Second, result: eval.Normal, state: eval.Alerting, expected: "20s"},
+		{offset: 30 * time.Second, result: eval.Normal, state: eval.Normal, expected: "0s"},
+	} {
+		results := eval.Results{{
+			Instance:    data.Labels{"service": "api"},
+			State:       tc.result,
+			EvaluatedAt: t0.Add(tc.offset),
+		}}
+		transitions := mgr.ProcessEvalResults(context.Background(), t0.Add(tc.offset), keepFiringRule, results, nil, nil)
+		require.Len(t, transitions, 1)
+		require.Equalf(t, tc.state, transitions[0].State.State, "at %s", tc.offset)
+		assert.Equalf(t, tc.expected, transitions[0].State.Annotations["active_for"], "at %s", tc.offset)
+	}
+}
+
+type fakeLookupTablesReader definitions.LookupTables
+
+func (f fakeLookupTablesReader) GetLookupTables(context.Context, int64) (definitions.LookupTables, error) {
+	return definitions.LookupTables(f), nil
+}
+
+type fakeInstanceReader struct {
+	instances []*ngmodels.AlertInstance
+}
+
+func (f *fakeInstanceReader) ListAlertInstances(_ context.Context, _ *ngmodels.ListAlertInstancesQuery) ([]*ngmodels.AlertInstance, error) {
+	return f.instances, nil
+}
+
+type handOffInstanceStore struct {
+	FakeInstanceStore
+	instances []*ngmodels.AlertInstance
+}
+
+func (f *handOffInstanceStore) ListAlertInstances(_ context.Context, q *ngmodels.ListAlertInstancesQuery) ([]*ngmodels.AlertInstance, error) {
+	result := make([]*ngmodels.AlertInstance, 0, len(f.instances))
+	for _, i := range f.instances {
+		if i.RuleOrgID == q.RuleOrgID && i.RuleUID == q.RuleUID {
+			result = append(result, i)
+		}
+	}
+	return result, nil
+}
+
+func TestHandOffState(t *testing.T) {
+	gen := ngmodels.RuleGen
+	rule := gen.With(gen.WithOrgID(1)).GenerateRef()
+	now := time.Now().UTC()
+	newManager := func(store InstanceStore) *Manager {
+		return NewManager(ManagerCfg{
+			Metrics:       metrics.NewNGAlert(prometheus.NewPedanticRegistry()).GetStateMetrics(),
+			InstanceStore: store,
+			Images:        &NoopImageService{},
+			Clock:         clock.NewMock(),
+			Historian:     &FakeHistorian{},
+			Tracer:        tracing.InitializeTracerForTest(),
+			Log:           &logtest.Fake{},
+		}, NewNoopPersister())
+	}
+
+	t.Run("HandOffStateByRuleUID saves the state and removes it from the cache", func(t *testing.T) {
+		store := &FakeInstanceStore{}
+		mgr := newManager(store)
+		s := &State{
+			OrgID:              rule.OrgID,
+			AlertRuleUID:       rule.UID,
+			Labels:             data.Labels{"a": "1"},
+			State:              eval.Alerting,
+			StartsAt:           now,
+			LastEvaluationTime: now,
+		}
+		s.CacheID = s.Labels.Fingerprint()
+		mgr.Put([]*State{s})
+
+		states := mgr.HandOffStateByRuleUID(context.Background(), rule.GetKeyWithGroup())
+		require.Len(t, states, 1)
+		require.Empty(t, mgr.GetStatesForRuleUID(rule.OrgID, rule.UID))
+
+		ops := store.RecordedOps()
+		require.Len(t, ops, 1)
+		op, ok := ops[0].(FakeInstanceStoreOp)
+		require.True(t, ok)
+		require.Equal(t, "SaveAlertInstancesForRule", op.Name)
+		instances := op.Args[2].([]ngmodels.AlertInstance)
+		require.Len(t, instances, 1)
+		require.Equal(t, ngmodels.InstanceStateFiring, instances[0].CurrentState)
+		require.Equal(t, now, instances[0].CurrentStateSince)
+	})
+
+	t.Run("LoadStateByRuleUID replaces the cached state with the state in the store", func(t *testing.T) {
+		store := &handOffInstanceStore{instances: []*ngmodels.AlertInstance{
+			{
+				AlertInstanceKey:  ngmodels.AlertInstanceKey{RuleOrgID: rule.OrgID, RuleUID: rule.UID},
+				Labels:            ngmodels.InstanceLabels{"a": "1"},
+				CurrentState:      ngmodels.InstanceStatePending,
+				CurrentStateSince: now,
+				LastEvalTime:      now,
+			},
+			{
+				AlertInstanceKey: ngmodels.AlertInstanceKey{RuleOrgID: rule.OrgID, RuleUID: "other"},
+				Labels:           ngmodels.InstanceLabels{"a": "2"},
+				CurrentState:     ngmodels.InstanceStateFiring,
+			},
+		}}
+		mgr := newManager(store)
+		stale := &State{OrgID: rule.OrgID, AlertRuleUID: rule.UID, Labels: data.Labels{"a": "stale"}, State: eval.Alerting}
+		stale.CacheID = stale.Labels.Fingerprint()
+		mgr.Put([]*State{stale})
+
+		count := mgr.LoadStateByRuleUID(context.Background(), rule, now, time.Minute)
+		require.Equal(t, 1, count)
+
+		states := mgr.GetStatesForRuleUID(rule.OrgID, rule.UID)
+		require.Len(t, states, 1)
+		require.Equal(t, eval.Pending, states[0].State)
+		require.Equal(t, "1", states[0].Labels["a"])
+		require.Equal(t, now, states[0].StartsAt)
+	})
+
+	t.Run("LoadStateByRuleUID waits until the previous owner saves the state", func(t *testing.T) {
+		store := &handOffInstanceStore{instances: []*ngmodels.AlertInstance{
+			{
+				AlertInstanceKey: ngmodels.AlertInstanceKey{RuleOrgID: rule.OrgID, RuleUID: rule.UID},
+				Labels:           ngmodels.InstanceLabels{"a": "1"},
+				CurrentState:     ngmodels.InstanceStatePending,
+				LastEvalTime:     now.Add(-time.Minute),
+			},
+		}}
+		clk := clock.NewMock()
+		mgr := newManager(store)
+		mgr.clock = clk
+
+		done := make(chan int)
+		go func() {
+			done <- mgr.LoadStateByRuleUID(context.Background(), rule, now, time.Minute)
+		}()
+		// the stored state is older than the handoff, so the manager keeps polling until the state is saved or the timeout passes
+		require.Eventually(t, func() bool {
+			clk.Add(handOffPollInterval)
+			select {
+			case count := <-done:
+				require.Equal(t, 1, count)
+				return true
+			default:
+				return false
+			}
+		}, 5*time.Second, time.Millisecond)
+		require.GreaterOrEqual(t, clk.Now().Sub(time.Unix(0, 0)), time.Minute, "the state must be loaded only after the timeout")
+	})
+}
@@ -80,6 +80,7 @@ func TestWarmStateCache(t *testing.T) {
 			State:              eval.Alerting,
 			LatestResult:       &state.Evaluation{EvaluationTime: evaluationTime, EvaluationState: eval.Alerting},
 			StartsAt:           evaluationTime.Add(-1 * time.Minute),
+			ActiveAt:           evaluationTime.Add(-1 * time.Minute),
 			EndsAt:             evaluationTime.Add(1 * time.Minute),
 			LastEvaluationTime: evaluationTime,
 			LastSentAt:         util.Pointer(evaluationTime.Add(-1 * time.Minute)),
@@ -122,6 +123,7 @@ func TestWarmStateCache(t *testing.T) {
 			State:              eval.Pending,
 			LatestResult:       &state.Evaluation{EvaluationTime: evaluationTime, EvaluationState: eval.Pending},
 			StartsAt:           evaluationTime.Add(-1 * time.Minute),
+			ActiveAt:           evaluationTime.Add(-1 * time.Minute),
 			EndsAt:             evaluationTime.Add(1 * time.Minute),
 			LastEvaluationTime: evaluationTime,
 			LastSentAt:         nil,
@@ -1559,6 +1561,15 @@ func TestProcessEvalResults(t *testing.T) {
 				if s.Values == nil {
 					s.Values = make(map[string]float64)
 				}
+				if s.ActiveAt.IsZero() {
+					// Evaluations of the tests are aligned with For, so firing states were pending for exactly For.
+					switch s.State {
+					case eval.Pending:
+						s.ActiveAt = s.StartsAt
+					case eval.Alerting:
+						s.ActiveAt = s.StartsAt.Add(-tc.alertRule.For)
+					}
+				}
 				expectedStates[s.CacheID] = s
 			}
 
@@ -1906,7 +1917,7 @@ func TestStaleResults(t *testing.T) {
 	st := state.NewManager(cfg, state.NewNoopPersister())
 
 	gen := models.RuleGen
-	rule := gen.With(gen.WithFor(0)).GenerateRef()
+	rule := gen.With(gen.WithFor(0), gen.WithKeepFiringFor(0)).GenerateRef()
 
 	initResults := eval.Results{
 		eval.ResultGen(eval.WithState(eval.Alerting), eval.WithEvaluatedAt(clk.Now()))(),
@@ -1986,6 +1997,153 @@ func TestStaleResults(t *testing.T) {
 	})
 }
 
+func TestStaleResultsKeepFiring(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewMock()
+	cfg := state.ManagerCfg{
+		Metrics:       metrics.NewNGAlert(prometheus.NewPedanticRegistry()).GetStateMetrics(),
+		InstanceStore: &state.FakeInstanceStore{},
+		Images:        &state.NoopImageService{},
+		Clock:         clk,
+		Historian:     &state.FakeHistorian{},
+		Tracer:        tracing.InitializeTracerForTest(),
+		Log:           log.New("ngalert.state.manager"),
+	}
+	st := state.NewManager(cfg, state.NewNoopPersister())
+
+	gen := models.RuleGen
+	rule := gen.With(gen.WithFor(0), gen.WithIntervalSeconds(10)).GenerateRef()
+	rule.KeepFiringFor = 30 * time.Second
+
+	firing := eval.ResultGen(eval.WithState(eval.Alerting), eval.WithEvaluatedAt(clk.Now()))()
+	other := eval.ResultGen(eval.WithState(eval.Normal))()
+	processed := st.ProcessEvalResults(ctx, clk.Now(), rule, eval.Results{firing}, nil, nil)
+	require.Len(t, processed, 1)
+	firingID := processed[0].CacheID
+
+	// evaluate returns the transition of the firing alert instance, whose series is missing in the results
+	evaluate := func(d time.Duration) *state.StateTransition {
+		t.Helper()
+		clk.Add(d)
+		other.EvaluatedAt = clk.Now()
+		for _, tr := range st.ProcessEvalResults(ctx, clk.Now(), rule, eval.Results{other}, nil, nil) {
+			if tr.CacheID == firingID {
+				return &tr
+			}
+		}
+		return nil
+	}
+
+	require.Nil(t, evaluate(10*time.Second))
+
+	stale := clk.Now().Add(10 * time.Second)
+	tr := evaluate(10 * time.Second)
+	require.NotNil(t, tr)
+	assert.Equal(t, eval.Alerting, tr.State.State)
+	assert.Equal(t, models.StateReasonKeepFiring, tr.StateReason)
+	assert.Equal(t, stale, tr.KeepFiringSince)
+	assert.True(t, tr.EndsAt.After(clk.Now()))
+
+	tr = evaluate(20 * time.Second)
+	require.NotNil(t, tr)
+	assert.Equal(t, eval.Alerting, tr.State.State)
+	assert.Equal(t, models.StateReasonKeepFiring, tr.StateReason)
+	assert.Len(t, st.GetStatesForRuleUID(rule.OrgID, rule.UID), 2)
+
+	tr = evaluate(10 * time.Second)
+	require.NotNil(t, tr)
+	assert.Equal(t, eval.Normal, tr.State.State)
+	assert.Equal(t, models.StateReasonMissingSeries, tr.StateReason)
+	assert.Equal(t, clk.Now(), *tr.ResolvedAt)
+	assert.Len(t, st.GetStatesForRuleUID(rule.OrgID, rule.UID), 1)
+}
+
+func TestProcessEvalResultsKeepFiring(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewMock()
+	cfg := state.ManagerCfg{
+		Metrics:       metrics.NewNGAlert(prometheus.NewPedanticRegistry()).GetStateMetrics(),
+		InstanceStore: &state.FakeInstanceStore{},
+		Images:        &state.NoopImageService{},
+		Clock:         clk,
+		Historian:     &state.FakeHistorian{},
+		Tracer:        tracing.InitializeTracerForTest(),
+		Log:           log.New("ngalert.state.manager"),
+	}
+	st := state.NewManager(cfg, state.NewNoopPersister())
+
+	gen := models.RuleGen
+	rule := gen.With(gen.WithFor(0), gen.WithIntervalSeconds(10)).GenerateRef()
+	rule.KeepFiringFor = 20 * time.Second
+
+	evaluate := func(s eval.State) state.StateTransition {
+		t.Helper()
+		result := eval.ResultGen(eval.WithState(s), eval.WithEvaluatedAt(clk.Now()))()
+		result.Instance = data.Labels{"instance": "1"}
+		processed := st.ProcessEvalResults(ctx, clk.Now(), rule, eval.Results{result}, nil, nil)
+		require.Len(t, processed, 1)
+		return processed[0]
+	}
+
+	require.Equal(t, eval.Alerting, evaluate(eval.Alerting).State.State)
+
+	keepFiringSince := clk.Now().Add(10 * time.Second)
+	for range 2 {
+		clk.Add(10 * time.Second)
+		tr := evaluate(eval.Normal)
+		assert.Equal(t, eval.Alerting, tr.State.State)
+		assert.Equal(t, models.StateReasonKeepFiring, tr.StateReason)
+		assert.Equal(t, keepFiringSince, tr.KeepFiringSince)
+	}
+
+	clk.Add(10 * time.Second)
+	tr := evaluate(eval.Normal)
+	assert.Equal(t, eval.Normal, tr.State.State)
+	assert.Equal(t, clk.Now(), *tr.ResolvedAt)
+}
+
+func TestProcessEvalResultsThrottled(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewMock()
+	cfg := state.ManagerCfg{
+		Metrics:       metrics.NewNGAlert(prometheus.NewPedanticRegistry()).GetStateMetrics(),
+		InstanceStore: &state.FakeInstanceStore{},
+		Images:        &state.NoopImageService{},
+		Clock:         clk,
+		Historian:     &state.FakeHistorian{},
+		Tracer:        tracing.InitializeTracerForTest(),
+		Log:           log.New("ngalert.state.manager"),
+	}
+	st := state.NewManager(cfg, state.NewNoopPersister())
+
+	gen := models.RuleGen
+	rule := gen.With(gen.WithFor(0), gen.WithIntervalSeconds(10)).GenerateRef()
+	rule.ExecErrState = models.ErrorErrState
+	throttled := fmt.Errorf("%w: datasource ds", eval.ErrDatasourceThrottled)
+
+	t.Run("does not create alert instances", func(t *testing.T) {
+		processed := st.ProcessEvalResults(ctx, clk.Now(), rule, eval.Results{eval.NewResultFromError(throttled, clk.Now(), 0)}, nil, nil)
+		require.Empty(t, processed)
+		require.Empty(t, st.GetStatesForRuleUID(rule.OrgID, rule.UID))
+	})
+
+	t.Run("keeps the state of alert instances", func(t *testing.T) {
+		firing := eval.ResultGen(eval.WithState(eval.Alerting), eval.WithEvaluatedAt(clk.Now()))()
+		processed := st.ProcessEvalResults(ctx, clk.Now(), rule, eval.Results{firing}, nil, nil)
+		require.Len(t, processed, 1)
+		startsAt := processed[0].StartsAt
+
+		clk.Add(10 * time.Second)
+		processed = st.ProcessEvalResults(ctx, clk.Now(), rule, eval.Results{eval.NewResultFromError(throttled, clk.Now(), 0)}, nil, nil)
+		require.Len(t, processed, 1)
+		assert.Equal(t, eval.Alerting, processed[0].State.State)
+		assert.Equal(t, models.StateReasonThrottled, processed[0].StateReason)
+		assert.Equal(t, startsAt, processed[0].StartsAt)
+		assert.True(t, processed[0].EndsAt.After(clk.Now()))
+		assert.Equal(t, clk.Now(), processed[0].LastEvaluationTime)
+	})
+}
+
 func TestDeleteStateByRuleUID(t *testing.T) {
 	interval := time.Minute
 	ctx := context.Background()
@@ -64,13 +64,19 @@ type State struct {
 	Values map[string]float64
 
 	StartsAt time.Time
+	// ActiveAt is the time the alert instance became active, that is Pending or Alerting. Unlike StartsAt, it does not
+	// change when a Pending state starts firing, so it is the start of the pending period. It is zero for inactive states.
+	ActiveAt time.Time
 	// EndsAt is different from the Prometheus EndsAt as EndsAt is updated for both Normal states
 	// and states that have been resolved. It cannot be used to determine when a state was resolved.
 	EndsAt time.Time
 	// ResolvedAt is set when the state is first resolved. That is to say, when the state first transitions
 	// from Alerting, NoData, or Error to Normal. It is reset to zero when the state transitions from Normal
 	// to any other state.
-	ResolvedAt           *time.Time
+	ResolvedAt *time.Time
+	// KeepFiringSince is set when the condition of an Alerting state stops being met while the rule has
+	// KeepFiringFor set. It is reset to zero when the state changes or the condition is met again.
+	KeepFiringSince      time.Time
 	LastSentAt           *time.Time
 	LastEvaluationString string
 	LastEvaluationTime   time.Time
@@ -79,7 +85,11 @@ type State struct {
 
 func newState(ctx context.Context, log log.Logger, alertRule *models.AlertRule, result eval.Result, extraLabels data.Labels, externalURL *url.URL) *State {
 	lbs, annotations := expandAnnotationsAndLabels(ctx, log, alertRule, result, extraLabels, externalURL)
+	return newStateWithLabels(alertRule, result, lbs, annotations)
+}
 
+// newStateWithLabels creates a state of the alert instance with the given expanded labels and annotations.
+func newStateWithLabels(alertRule *models.AlertRule, result eval.Result, lbs data.Labels, annotations data.Labels) *State {
 	cacheID := lbs.Fingerprint()
 	// For new states, we set StartsAt & EndsAt to EvaluatedAt as this is the
 	// expected value for a Normal state during state transition.
@@ -127,8 +137,10 @@ func (a *State) Copy() *State {
 		Labels:               labelsCopy,
 		Values:               a.Values,
 		StartsAt:             a.StartsAt,
+		ActiveAt:             a.ActiveAt,
 		EndsAt:               a.EndsAt,
 		ResolvedAt:           a.ResolvedAt,
+		KeepFiringSince:      a.KeepFiringSince,
 		LastSentAt:           a.LastSentAt,
 		LastEvaluationString: a.LastEvaluationString,
 		LastEvaluationTime:   a.LastEvaluationTime,
@@ -154,47 +166,64 @@ func (a *State) GetAlertInstanceKey() (models.AlertInstanceKey, error) {
 
 // SetAlerting sets the state to Alerting. It changes both the start and end time.
 func (a *State) SetAlerting(reason string, startsAt, endsAt time.Time) {
+	a.setActive(startsAt)
 	a.State = eval.Alerting
 	a.StateReason = reason
 	a.StartsAt = startsAt
 	a.EndsAt = endsAt
 	a.Error = nil
+	a.KeepFiringSince = time.Time{}
 }
 
 // SetPending the state to Pending. It changes both the start and end time.
 func (a *State) SetPending(reason string, startsAt, endsAt time.Time) {
+	a.setActive(startsAt)
 	a.State = eval.Pending
 	a.StateReason = reason
 	a.StartsAt = startsAt
 	a.EndsAt = endsAt
 	a.Error = nil
+	a.KeepFiringSince = time.Time{}
 }
 
 // SetNoData sets the state to NoData. It changes both the start and end time.
 func (a *State) SetNoData(reason string, startsAt, endsAt time.Time) {
+	a.ActiveAt = time.Time{}
 	a.State = eval.NoData
 	a.StateReason = reason
 	a.StartsAt = startsAt
 	a.EndsAt = endsAt
 	a.Error = nil
+	a.KeepFiringSince = time.Time{}
 }
 
 // SetError sets the state to Error. It changes both the start and end time.
 func (a *State) SetError(err error, startsAt, endsAt time.Time) {
+	a.ActiveAt = time.Time{}
 	a.State = eval.Error
 	a.StateReason = models.StateReasonError
 	a.StartsAt = startsAt
 	a.EndsAt = endsAt
 	a.Error = err
+	a.KeepFiringSince = time.Time{}
 }
 
 // SetNormal sets the state to Normal. It changes both the start and end time.
 func (a *State) SetNormal(reason string, startsAt, endsAt time.Time) {
+	a.ActiveAt = time.Time{}
 	a.State = eval.Normal
 	a.StateReason = reason
 	a.StartsAt = startsAt
 	a.EndsAt = endsAt
 	a.Error = nil
+	a.KeepFiringSince = time.Time{}
+}
+
+// setActive sets the time the state became active, unless it is already Pending or Alerting.
+func (a *State) setActive(activeAt time.Time) {
+	if a.ActiveAt.IsZero() || (a.State != eval.Pending && a.State != eval.Alerting) {
+		a.ActiveAt = activeAt
+	}
 }
 
 // Maintain updates the end time using the most recent evaluation.
@@ -202,6 +231,19 @@ func (a *State) Maintain(interval int64, evaluatedAt time.Time) {
 	a.EndsAt = nextEndsTime(interval, evaluatedAt)
 }
 
+// setRuleAnnotations sets the expanded annotations of the rule. Annotations that the transition added, such as the
+// error, take precedence, except for internal annotations, which are only copied from the previous state if the
+// rule does not define them.
+func (a *State) setRuleAnnotations(annotations data.Labels) {
+	for key, value := range annotations {
+		_, internal := models.InternalAnnotationNameSet[key]
+		if _, ok := a.Annotations[key]; ok && !internal {
+			continue
+		}
+		a.Annotations[key] = value
+	}
+}
+
 // AddErrorInformation adds annotations to the state to indicate that an error occurred.
 // If addDatasourceInfoToLabels is true, the ref_id and datasource_uid are added to the labels,
 // otherwise, they are added to the annotations.
@@ -319,9 +361,22 @@ func NewEvaluationValues(m map[string]eval.NumberValueCapture) map[string]float6
 	return result
 }
 
-func resultNormal(state *State, _ *models.AlertRule, result eval.Result, logger log.Logger, reason string) {
+func resultNormal(state *State, rule *models.AlertRule, result eval.Result, logger log.Logger, reason string) {
 	if state.State == eval.Normal {
 		logger.Debug("Keeping state", "state", state.State)
+	} else if state.State == eval.Alerting && keepFiring(state, rule, result.EvaluatedAt) {
+		prevEndsAt := state.EndsAt
+		state.StateReason = models.StateReasonKeepFiring
+		state.Maintain(rule.IntervalSeconds, result.EvaluatedAt)
+		logger.Debug("Keeping state firing",
+			"state",
+			state.State,
+			"keep_firing_since",
+			state.KeepFiringSince,
+			"previous_ends_at",
+			prevEndsAt,
+			"next_ends_at",
+			state.EndsAt)
 	} else {
 		nextEndsAt := result.EvaluatedAt
 		logger.Debug("Changing state",
@@ -338,10 +393,27 @@ func resultNormal(state *State, _ *models.AlertRule, result eval.Result, logger
 	}
 }
 
+// keepFiring returns true if the Alerting state should keep firing although its condition is no longer met,
+// because the rule's KeepFiringFor has not passed yet. The first call for a state starts the keep firing period.
+func keepFiring(state *State, rule *models.AlertRule, evaluatedAt time.Time) bool {
+	if rule.KeepFiringFor <= 0 {
+		return false
+	}
+	if state.KeepFiringSince.IsZero() {
+		state.KeepFiringSince = evaluatedAt
+	}
+	return evaluatedAt.Sub(state.KeepFiringSince) < rule.KeepFiringFor
+}
+
 func resultAlerting(state *State, rule *models.AlertRule, result eval.Result, logger log.Logger, reason string) {
 	switch state.State {
 	case eval.Alerting:
 		prevEndsAt := state.EndsAt
+		if !state.KeepFiringSince.IsZero() {
+			// The condition is met again, so the state no longer fires only because of KeepFiringFor.
+			state.KeepFiringSince = time.Time{}
+			state.StateReason = reason
+		}
 		state.Maintain(rule.IntervalSeconds, result.EvaluatedAt)
 		logger.Debug("Keeping state",
 			"state",
@@ -492,6 +564,29 @@ func resultNoData(state *State, rule *models.AlertRule, result eval.Result, logg
 	}
 }
 
+// resultThrottled keeps the state of the alert instance when the evaluation was skipped because of the concurrency
+// limit of a datasource. The queries did not run, so there is no result to change the state with.
+func resultThrottled(state *State, rule *models.AlertRule, result eval.Result, logger log.Logger) {
+	if state.State == eval.Normal {
+		logger.Debug("Keeping state", "state", state.State)
+		return
+	}
+	prevEndsAt := state.EndsAt
+	state.Maintain(rule.IntervalSeconds, result.EvaluatedAt)
+	logger.Debug("Keeping state",
+		"state",
+		state.State,
+		"previous_ends_at",
+		prevEndsAt,
+		"next_ends_at",
+		state.EndsAt)
+}
+
+// isThrottled returns true if the evaluation was skipped because of the concurrency limit of a datasource.
+func isThrottled(result eval.Result) bool {
+	return result.State == eval.Error && errors.Is(result.Error, eval.ErrDatasourceThrottled)
+}
+
 func resultKeepLast(state *State, rule *models.AlertRule, result eval.Result, logger log.Logger) {
 	reason := models.ConcatReasons(result.State.String(), models.StateReasonKeepLast)
 
@@ -706,8 +801,10 @@ func patch(newState, existingState *State, result eval.Result) {
 	newState.Values = existingState.Values
 	newState.LastEvaluationString = existingState.LastEvaluationString
 	newState.StartsAt = existingState.StartsAt
+	newState.ActiveAt = existingState.ActiveAt
 	newState.EndsAt = existingState.EndsAt
 	newState.ResolvedAt = existingState.ResolvedAt
+	newState.KeepFiringSince = existingState.KeepFiringSince
 	newState.LastSentAt = existingState.LastSentAt
 	// Annotations can change over time, however we also want to maintain
 	// certain annotations across evaluations
@@ -784,6 +881,11 @@ func (a *State) transition(alertRule *models.AlertRule, result eval.Result, extr
 		logger.Debug("Setting next state", "handler", "resultAlerting")
 		resultAlerting(a, alertRule, result, logger, "")
 	case eval.Error:
+		if isThrottled(result) {
+			logger.Debug("Setting next state", "handler", "resultThrottled")
+			resultThrottled(a, alertRule, result, logger)
+			break
+		}
 		logger.Debug("Setting next state", "handler", "resultError")
 		resultError(a, alertRule, result, logger)
 	case eval.NoData:
@@ -796,10 +898,18 @@ func (a *State) transition(alertRule *models.AlertRule, result eval.Result, extr
 	// Set reason iff: result and state are different, reason is not Alerting or Normal
 	a.StateReason = ""
 
-	if a.State != result.State &&
+	if isThrottled(result) {
+		a.StateReason = models.StateReasonThrottled
+	} else if a.State != result.State &&
 		result.State != eval.Normal &&
 		result.State != eval.Alerting {
 		a.StateReason = resultStateReason(result, alertRule)
+	} else if reason := errorStateReason(result.Error); reason != "" && result.State == eval.Error {
+		// The state does not change but the evaluation failed because of the limits of the scheduler, not the queries.
+		a.StateReason = reason
+	} else if a.State == eval.Alerting && result.State == eval.Normal && !a.KeepFiringSince.IsZero() {
+		// The condition is no longer met, and the state keeps firing only because of KeepFiringFor.
+		a.StateReason = models.StateReasonKeepFiring
 	}
 
 	// Set Resolved property so the scheduler knows to send a postable alert
@@ -832,9 +942,25 @@ func (a *State) transition(alertRule *models.AlertRule, result eval.Result, extr
 }
 
 func resultStateReason(result eval.Result, rule *models.AlertRule) string {
+	reason := result.State.String()
+	if r := errorStateReason(result.Error); r != "" {
+		reason = r
+	}
 	if rule.ExecErrState == models.KeepLastErrState || rule.NoDataState == models.KeepLast {
-		return models.ConcatReasons(result.State.String(), models.StateReasonKeepLast)
+		return models.ConcatReasons(reason, models.StateReasonKeepLast)
 	}
 
-	return result.State.String()
+	return reason
+}
+
+// errorStateReason returns the state reason for errors caused by the evaluation limits of datasources, or an empty string for other errors.
+func errorStateReason(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, eval.ErrDatasourceQueryTimeout):
+		return models.StateReasonQueryTimeout
+	default:
+		return ""
+	}
 }
@@ -271,6 +271,106 @@ func TestMaintain(t *testing.T) {
 	assert.Equal(t, now.Add(250*time.Second), s.EndsAt)
 }
 
+func TestActiveAt(t *testing.T) {
+	mock := clock.NewMock()
+	start := mock.Now()
+	rule := &ngmodels.AlertRule{IntervalSeconds: 10, For: 20 * time.Second}
+	logger := log.NewNopLogger()
+	result := func(d time.Duration) eval.Result {
+		return eval.Result{EvaluatedAt: start.Add(d)}
+	}
+
+	s := &State{State: eval.Normal, StartsAt: start}
+	resultAlerting(s, rule, result(10*time.Second), logger, "")
+	require.Equal(t, eval.Pending, s.State)
+	assert.Equal(t, start.Add(10*time.Second), s.ActiveAt)
+
+	resultAlerting(s, rule, result(30*time.Second), logger, "")
+	require.Equal(t, eval.Alerting, s.State)
+	assert.Equal(t, start.Add(30*time.Second), s.StartsAt)
+	assert.Equal(t, start.Add(10*time.Second), s.ActiveAt, "firing state should keep the start of the pending period")
+
+	resultNormal(s, rule, result(40*time.Second), logger, "")
+	require.Equal(t, eval.Normal, s.State)
+	assert.True(t, s.ActiveAt.IsZero())
+
+	resultAlerting(s, rule, result(50*time.Second), logger, "")
+	require.Equal(t, eval.Pending, s.State)
+	assert.Equal(t, start.Add(50*time.Second), s.ActiveAt)
+}
+
+func TestKeepFiring(t *testing.T) {
+	mock := clock.NewMock()
+	start := mock.Now()
+	rule := &ngmodels.AlertRule{IntervalSeconds: 10, KeepFiringFor: 30 * time.Second}
+	logger := log.NewNopLogger()
+	result := func(d time.Duration) eval.Result {
+		return eval.Result{EvaluatedAt: start.Add(d)}
+	}
+
+	t.Run("should keep firing until KeepFiringFor passes", func(t *testing.T) {
+		s := &State{State: eval.Alerting, StartsAt: start}
+
+		resultNormal(s, rule, result(10*time.Second), logger, "")
+		assert.Equal(t, eval.Alerting, s.State)
+		assert.Equal(t, ngmodels.StateReasonKeepFiring, s.StateReason)
+		assert.Equal(t, start.Add(10*time.Second), s.KeepFiringSince)
+		assert.Equal(t, start, s.StartsAt)
+
+		resultNormal(s, rule, result(30*time.Second), logger, "")
+		assert.Equal(t, eval.Alerting, s.State)
+		assert.Equal(t, start.Add(10*time.Second), s.KeepFiringSince)
+
+		resultNormal(s, rule, result(40*time.Second), logger, "")
+		assert.Equal(t, eval.Normal, s.State)
+		assert.Empty(t, s.StateReason)
+		assert.True(t, s.KeepFiringSince.IsZero())
+	})
+
+	t.Run("should stop keeping firing when the condition is met again", func(t *testing.T) {
+		s := &State{State: eval.Alerting, StartsAt: start, StateReason: "reason"}
+
+		resultNormal(s, rule, result(10*time.Second), logger, "")
+		assert.Equal(t, ngmodels.StateReasonKeepFiring, s.StateReason)
+
+		resultAlerting(s, rule, result(20*time.Second), logger, "")
+		assert.Equal(t, eval.Alerting, s.State)
+		assert.Empty(t, s.StateReason)
+		assert.True(t, s.KeepFiringSince.IsZero())
+		assert.Equal(t, start, s.StartsAt)
+
+		// the keep firing period starts over
+		resultNormal(s, rule, result(50*time.Second), logger, "")
+		assert.Equal(t, eval.Alerting, s.State)
+		assert.Equal(t, start.Add(50*time.Second), s.KeepFiringSince)
+	})
+
+	t.Run("should report keep firing as the reason of the transition", func(t *testing.T) {
+		s := &State{State: eval.Alerting, StartsAt: start, Labels: data.Labels{}, Annotations: map[string]string{}}
+		noImage := func(reason string) *ngmodels.Image { return nil }
+
+		tr := s.transition(rule, eval.Result{State: eval.Normal, EvaluatedAt: start.Add(10 * time.Second)}, nil, logger, noImage)
+		assert.Equal(t, eval.Alerting, tr.State.State)
+		assert.Equal(t, ngmodels.StateReasonKeepFiring, tr.StateReason)
+
+		tr = s.transition(rule, eval.Result{State: eval.Normal, EvaluatedAt: start.Add(40 * time.Second)}, nil, logger, noImage)
+		assert.Equal(t, eval.Normal, tr.State.State)
+		assert.Empty(t, tr.StateReason)
+	})
+
+	t.Run("should resolve immediately if KeepFiringFor is not set", func(t *testing.T) {
+		s := &State{State: eval.Alerting, StartsAt: start}
+		resultNormal(s, &ngmodels.AlertRule{IntervalSeconds: 10}, result(10*time.Second), logger, "")
+		assert.Equal(t, eval.Normal, s.State)
+	})
+
+	t.Run("should not keep pending states", func(t *testing.T) {
+		s := &State{State: eval.Pending, StartsAt: start}
+		resultNormal(s, rule, result(10*time.Second), logger, "")
+		assert.Equal(t, eval.Normal, s.State)
+	})
+}
+
 func TestEnd(t *testing.T) {
 	evaluationTime, _ := time.Parse("2006-01-02", "2021-03-25")
 	testCases := []struct {
@@ -1132,3 +1232,80 @@ func TestPatch(t *testing.T) {
 		assert.EqualValues(t, orig.Annotations, state.Annotations)
 	})
 }
+
+func TestDatasourceLimitStateReason(t *testing.T) {
+	logger := log.NewNopLogger()
+	now := time.Now()
+	noImage := func(reason string) *ngmodels.Image { return nil }
+	throttled := fmt.Errorf("%w: datasource ds", eval.ErrDatasourceThrottled)
+	timeout := fmt.Errorf("%w: datasource ds", eval.ErrDatasourceQueryTimeout)
+
+	testCases := []struct {
+		name         string
+		execErrState ngmodels.ExecutionErrorState
+		state        eval.State
+		err          error
+		expected     string
+		keepsState   bool
+	}{
+		{
+			name:         "throttled evaluation of rule with Error state",
+			execErrState: ngmodels.ErrorErrState,
+			state:        eval.Error,
+			err:          throttled,
+			expected:     ngmodels.StateReasonThrottled,
+			keepsState:   true,
+		},
+		{
+			name:         "timed out evaluation of rule with Error state",
+			execErrState: ngmodels.ErrorErrState,
+			state:        eval.Normal,
+			err:          timeout,
+			expected:     ngmodels.StateReasonQueryTimeout,
+		},
+		{
+			name:         "throttled evaluation of rule with Alerting state",
+			execErrState: ngmodels.AlertingErrState,
+			state:        eval.Normal,
+			err:          throttled,
+			expected:     ngmodels.StateReasonThrottled,
+			keepsState:   true,
+		},
+		{
+			name:         "throttled evaluation of rule with KeepLast state",
+			execErrState: ngmodels.KeepLastErrState,
+			state:        eval.Normal,
+			err:          throttled,
+			expected:     ngmodels.StateReasonThrottled,
+			keepsState:   true,
+		},
+		{
+			name:         "throttled evaluation of alerting instance",
+			execErrState: ngmodels.ErrorErrState,
+			state:        eval.Alerting,
+			err:          throttled,
+			expected:     ngmodels.StateReasonThrottled,
+			keepsState:   true,
+		},
+		{
+			name:         "other errors",
+			execErrState: ngmodels.AlertingErrState,
+			state:        eval.Normal,
+			err:          errors.New("test"),
+			expected:     ngmodels.StateReasonError,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := &ngmodels.AlertRule{IntervalSeconds: 10, ExecErrState: tc.execErrState, NoDataState: ngmodels.NoData}
+			s := &State{State: tc.state, StartsAt: now, EndsAt: now, Labels: data.Labels{}, Annotations: map[string]string{}}
+
+			s.transition(rule, eval.NewResultFromError(tc.err, now.Add(10*time.Second), time.Second), nil, logger, noImage)
+			assert.Equal(t, tc.expected, s.StateReason)
+			if tc.keepsState {
+				assert.Equal(t, tc.state, s.State)
+				assert.Equal(t, now, s.StartsAt)
+			}
+		})
+	}
+}
@@ -2,12 +2,16 @@ package template
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/url"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 )
 
 type query struct {
@@ -16,12 +20,25 @@ type query struct {
 }
 
 const (
+	ActiveForFuncName        = "activeFor"
+	DashboardURLFuncName     = "dashboardURL"
+	ExploreURLFuncName       = "exploreURL"
 	FilterLabelFuncName      = "filterLabels"
 	FilterLabelReFuncName    = "filterLabelsRe"
+	FirstFuncName            = "first"
+	FormatValueFuncName      = "formatValue"
 	GraphLinkFuncName        = "graphLink"
+	LabelFuncName            = "label"
+	LookupFuncName           = "lookup"
+	PanelURLFuncName         = "panelURL"
+	QueryFuncName            = "query"
 	RemoveLabelsFuncName     = "removeLabels"
 	RemoveLabelsReFuncName   = "removeLabelsRe"
+	RunbookURLFuncName       = "runbookURL"
+	SortByLabelFuncName      = "sortByLabel"
+	StrValueFuncName         = "strvalue"
 	TableLinkFuncName        = "tableLink"
+	ValueFuncName            = "value"
 	MergeLabelValuesFuncName = "mergeLabelValues"
 )
 
@@ -29,6 +46,7 @@ var (
 	defaultFuncs = template.FuncMap{
 		FilterLabelFuncName:      filterLabelsFunc,
 		FilterLabelReFuncName:    filterLabelsReFunc,
+		FormatValueFuncName:      formatValueFunc,
 		GraphLinkFuncName:        graphLinkFunc,
 		RemoveLabelsFuncName:     removeLabelsFunc,
 		RemoveLabelsReFuncName:   removeLabelsReFunc,
@@ -37,6 +55,125 @@ var (
 	}
 )
 
+// dataFuncs returns the functions that use the data of the template, such as the labels of the
+// alert instance and the dashboard of the rule.
+func dataFuncs(data Data, externalURL *url.URL, evaluatedAt time.Time, tables LookupTables) template.FuncMap {
+	return template.FuncMap{
+		ActiveForFuncName: func() time.Duration {
+			return activeFor(data.StartsAt, evaluatedAt)
+		},
+		DashboardURLFuncName: func(labelNames ...string) string {
+			return dashboardURL(externalURL, data, -1, labelNames)
+		},
+		ExploreURLFuncName: func(expr string) (string, error) {
+			return exploreURL(externalURL, data, evaluatedAt, expr)
+		},
+		LookupFuncName: func(table, key string) (string, error) {
+			return lookup(tables, table, key)
+		},
+		PanelURLFuncName: func(labelNames ...string) string {
+			return dashboardURL(externalURL, data, data.Rule.PanelID, labelNames)
+		},
+		RunbookURLFuncName: func(base string, labelNames ...string) (string, error) {
+			return runbookURL(data.Labels, base, labelNames)
+		},
+	}
+}
+
+// activeFor returns the time since the alert instance entered its current state.
+func activeFor(startsAt, evaluatedAt time.Time) time.Duration {
+	if startsAt.IsZero() || startsAt.After(evaluatedAt) {
+		return 0
+	}
+	return evaluatedAt.Sub(startsAt)
+}
+
+// appURL returns the URL of the path in Grafana. The URL is relative if externalURL is nil.
+func appURL(externalURL *url.URL, path string, query url.Values) string {
+	u := &url.URL{Path: path}
+	if externalURL != nil {
+		u = externalURL.JoinPath(path)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// dashboardURL returns the URL of the dashboard of the rule, or of the panel if panelID is not -1.
+// The values of the labels with the given names are set as the dashboard variables of the same name.
+// It returns an empty string if the rule is not linked to a dashboard.
+func dashboardURL(externalURL *url.URL, data Data, panelID int64, labelNames []string) string {
+	if data.Rule.DashboardUID == "" {
+		return ""
+	}
+	query := url.Values{}
+	query.Set("orgId", strconv.FormatInt(data.Rule.OrgID, 10))
+	if panelID != -1 {
+		query.Set("viewPanel", strconv.FormatInt(panelID, 10))
+	}
+	for _, name := range labelNames {
+		if value, ok := data.Labels[name]; ok {
+			query.Set("var-"+name, value)
+		}
+	}
+	return appURL(externalURL, "/d/"+data.Rule.DashboardUID, query)
+}
+
+// exploreURL returns the URL of Explore with the expression as a range query of the datasource of the rule,
+// over the hour before the evaluation.
+func exploreURL(externalURL *url.URL, data Data, evaluatedAt time.Time, expr string) (string, error) {
+	if data.Rule.DatasourceUID == "" {
+		return "", errors.New("rule has no datasource")
+	}
+	left, err := json.Marshal(map[string]any{
+		"datasource": data.Rule.DatasourceUID,
+		"queries": []map[string]any{{
+			"refId":      "A",
+			"datasource": map[string]string{"uid": data.Rule.DatasourceUID},
+			"expr":       expr,
+			"instant":    false,
+			"range":      true,
+		}},
+		"range": map[string]string{
+			"from": strconv.FormatInt(evaluatedAt.Add(-time.Hour).UnixMilli(), 10),
+			"to":   strconv.FormatInt(evaluatedAt.UnixMilli(), 10),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{}
+	query.Set("orgId", strconv.FormatInt(data.Rule.OrgID, 10))
+	query.Set("left", string(left))
+	return appURL(externalURL, "/explore", query), nil
+}
+
+// lookup returns the value of the key in the lookup table, or an empty string if the table does not
+// have the key. It returns an error if the table does not exist.
+func lookup(tables LookupTables, table, key string) (string, error) {
+	t, ok := tables[table]
+	if !ok {
+		return "", fmt.Errorf("lookup table %q not found", table)
+	}
+	return t[key], nil
+}
+
+// runbookURL returns base with the values of the labels with the given names appended as path segments.
+// It returns an error if a label does not exist.
+func runbookURL(labels Labels, base string, labelNames []string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range labelNames {
+		value, ok := labels[name]
+		if !ok {
+			return "", fmt.Errorf("label %q not found", name)
+		}
+		u = u.JoinPath(value)
+	}
+	return u.String(), nil
+}
+
 // filterLabelsFunc removes all labels that do not match the string.
 func filterLabelsFunc(m Labels, match string) Labels {
 	res := make(Labels)
@@ -60,6 +197,70 @@ func filterLabelsReFunc(m Labels, pattern string) Labels {
 	return res
 }
 
+// formatValueFunc formats the value in the unit. Supported units are bytes (IEC), decbytes (SI), percent (0-100),
+// percentunit (0.0-1.0), s, ms and short. Other units are appended to the value.
+func formatValueFunc(v any, unit string) (string, error) {
+	f, err := toFloat64(v)
+	if err != nil {
+		return "", err
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return formatFloat(f), nil
+	}
+	switch unit {
+	case "bytes":
+		return formatWithPrefixes(f, 1024, []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}), nil
+	case "decbytes":
+		return formatWithPrefixes(f, 1000, []string{"B", "kB", "MB", "GB", "TB", "PB"}), nil
+	case "percent":
+		return formatFloat(f) + "%", nil
+	case "percentunit":
+		return formatFloat(f*100) + "%", nil
+	case "s":
+		return time.Duration(f * float64(time.Second)).Round(time.Millisecond).String(), nil
+	case "ms":
+		return time.Duration(f * float64(time.Millisecond)).Round(time.Microsecond).String(), nil
+	case "short":
+		return strings.TrimSpace(formatWithPrefixes(f, 1000, []string{"", "K", "Mil", "Bil", "Tri"})), nil
+	case "":
+		return formatFloat(f), nil
+	default:
+		return formatFloat(f) + " " + unit, nil
+	}
+}
+
+// formatWithPrefixes divides f by base until it is less than base, and returns it with the matching prefix.
+func formatWithPrefixes(f float64, base float64, prefixes []string) string {
+	i := 0
+	for math.Abs(f) >= base && i < len(prefixes)-1 {
+		f /= base
+		i++
+	}
+	return formatFloat(f) + " " + prefixes[i]
+}
+
+// formatFloat formats f with at most two decimals.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+func toFloat64(v any) (float64, error) {
+	switch v := v.(type) {
+	case Value:
+		return v.Value, nil
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("cannot convert %T to float64", v)
+	}
+}
+
 func graphLinkFunc(data string) string {
 	var q query
 	if err := json.Unmarshal([]byte(data), &q); err != nil {
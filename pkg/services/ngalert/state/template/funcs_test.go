@@ -1,9 +1,13 @@
 package template
 
 import (
+	"context"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFilterLabelsFunc(t *testing.T) {
@@ -49,3 +53,127 @@ func TestDeduplicateLabelsFuncNoDuplicates(t *testing.T) {
 	}
 	assert.Equal(t, Labels{"foo": "bar", "bar": "baz"}, mergeLabelValuesFunc(v))
 }
+
+func TestFormatValueFunc(t *testing.T) {
+	cases := []struct {
+		value    any
+		unit     string
+		expected string
+	}{
+		{value: 1536.0, unit: "bytes", expected: "1.5 KiB"},
+		{value: 1500.0, unit: "decbytes", expected: "1.5 kB"},
+		{value: 12.344, unit: "percent", expected: "12.34%"},
+		{value: 0.5, unit: "percentunit", expected: "50%"},
+		{value: 90.0, unit: "s", expected: "1m30s"},
+		{value: 1.5, unit: "ms", expected: "1.5ms"},
+		{value: 2500000.0, unit: "short", expected: "2.5 Mil"},
+		{value: 12.0, unit: "short", expected: "12"},
+		{value: 3, unit: "req/s", expected: "3 req/s"},
+		{value: "0.25", unit: "", expected: "0.25"},
+		{value: Value{Value: 2048}, unit: "bytes", expected: "2 KiB"},
+	}
+	for _, c := range cases {
+		actual, err := formatValueFunc(c.value, c.unit)
+		require.NoError(t, err)
+		assert.Equal(t, c.expected, actual)
+	}
+
+	_, err := formatValueFunc("invalid", "bytes")
+	require.Error(t, err)
+	_, err = formatValueFunc(true, "bytes")
+	require.Error(t, err)
+}
+
+func TestDataFuncs(t *testing.T) {
+	externalURL, err := url.Parse("http://localhost/grafana")
+	require.NoError(t, err)
+	evaluatedAt := time.UnixMilli(1700000000000)
+
+	data := Data{
+		Labels:   Labels{"service": "api", "env": "prod"},
+		StartsAt: evaluatedAt.Add(-90 * time.Second),
+		Rule: RuleData{
+			OrgID:         2,
+			DatasourceUID: "prom",
+			DashboardUID:  "dash",
+			PanelID:       3,
+		},
+	}
+	tables := LookupTables{"owners": {"api": "team-a"}}
+	ctx := WithLookupTables(context.Background(), tables)
+
+	cases := []struct {
+		name          string
+		text          string
+		data          Data
+		expected      string
+		expectedError string
+	}{{
+		name:     "activeFor returns the time since StartsAt",
+		text:     "{{ activeFor }}",
+		data:     data,
+		expected: "1m30s",
+	}, {
+		name:     "activeFor can be humanized",
+		text:     "{{ activeFor | humanizeDuration }}",
+		data:     data,
+		expected: "1m 30s",
+	}, {
+		name:     "dashboardURL sets labels as variables",
+		text:     `{{ dashboardURL "service" "missing" }}`,
+		data:     data,
+		expected: "http://localhost/grafana/d/dash?orgId=2&var-service=api",
+	}, {
+		name:     "panelURL links to the panel",
+		text:     `{{ panelURL }}`,
+		data:     data,
+		expected: "http://localhost/grafana/d/dash?orgId=2&viewPanel=3",
+	}, {
+		name:     "dashboardURL is empty without a dashboard",
+		text:     `{{ dashboardURL }}`,
+		data:     Data{},
+		expected: "",
+	}, {
+		name:     "exploreURL queries the datasource of the rule",
+		text:     `{{ exploreURL "up" }}`,
+		data:     data,
+		expected: "http://localhost/grafana/explore?left=%7B%22datasource%22%3A%22prom%22%2C%22queries%22%3A%5B%7B%22datasource%22%3A%7B%22uid%22%3A%22prom%22%7D%2C%22expr%22%3A%22up%22%2C%22instant%22%3Afalse%2C%22range%22%3Atrue%2C%22refId%22%3A%22A%22%7D%5D%2C%22range%22%3A%7B%22from%22%3A%221699996400000%22%2C%22to%22%3A%221700000000000%22%7D%7D&orgId=2",
+	}, {
+		name:          "exploreURL fails without a datasource",
+		text:          `{{ exploreURL "up" }}`,
+		data:          Data{},
+		expectedError: "rule has no datasource",
+	}, {
+		name:     "runbookURL appends label values",
+		text:     `{{ runbookURL "https://runbooks.example.com/alerts" "env" "service" }}`,
+		data:     data,
+		expected: "https://runbooks.example.com/alerts/prod/api",
+	}, {
+		name:          "runbookURL fails if label is missing",
+		text:          `{{ runbookURL "https://runbooks.example.com" "missing" }}`,
+		data:          data,
+		expectedError: `label "missing" not found`,
+	}, {
+		name:     "lookup returns the value of the key",
+		text:     `{{ lookup "owners" $labels.service }}:{{ lookup "owners" "unknown" }}`,
+		data:     data,
+		expected: "team-a:",
+	}, {
+		name:          "lookup fails if the table does not exist",
+		text:          `{{ lookup "missing" "api" }}`,
+		data:          data,
+		expectedError: `lookup table "missing" not found`,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v, err := Expand(ctx, "test", c.text, c.data, externalURL, evaluatedAt)
+			if c.expectedError != "" {
+				require.ErrorContains(t, err, c.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expected, v)
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package template
+
+import (
+	"context"
+)
+
+// LookupTables are the key/value lookup tables of an organization, by table name, for the lookup function.
+type LookupTables map[string]map[string]string
+
+type lookupTablesContextKey struct{}
+
+// WithLookupTables returns a copy of ctx that makes templates expanded with it use the lookup tables.
+func WithLookupTables(ctx context.Context, tables LookupTables) context.Context {
+	return context.WithValue(ctx, lookupTablesContextKey{}, tables)
+}
+
+// LookupTablesFromContext returns the lookup tables added to ctx by WithLookupTables, or nil if there are none.
+func LookupTablesFromContext(ctx context.Context) LookupTables {
+	tables, _ := ctx.Value(lookupTablesContextKey{}).(LookupTables)
+	return tables
+}
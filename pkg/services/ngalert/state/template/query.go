@@ -0,0 +1,158 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/prometheus/prometheus/promql"
+)
+
+// Querier executes instant queries for the query template function.
+type Querier interface {
+	// Query executes expr as an instant query at ts. If datasourceUID is empty, the query is
+	// executed against the default datasource of the Querier.
+	Query(ctx context.Context, datasourceUID, expr string, ts time.Time) (promql.Vector, error)
+}
+
+type querierContextKey struct{}
+
+// WithQuerier returns a copy of ctx that makes templates expanded with it execute `query()` against the querier.
+// Without a querier, `query()` returns no results.
+func WithQuerier(ctx context.Context, q Querier) context.Context {
+	return context.WithValue(ctx, querierContextKey{}, q)
+}
+
+// QuerierFromContext returns the querier added to ctx by WithQuerier, or nil if there is none.
+func QuerierFromContext(ctx context.Context) Querier {
+	q, _ := ctx.Value(querierContextKey{}).(Querier)
+	return q
+}
+
+type queryKey struct {
+	datasourceUID string
+	expr          string
+	ts            int64
+}
+
+// cachedQuery is the result of a query that EvaluationQuerier executed.
+type cachedQuery struct {
+	vector promql.Vector
+	err    error
+}
+
+// EvaluationQuerier wraps a Querier for the duration of a single evaluation. Identical queries
+// are executed once and their results shared between all series of the evaluation, and all
+// queries must complete within the timeout of the evaluation.
+type EvaluationQuerier struct {
+	querier  Querier
+	deadline time.Time
+
+	mtx     sync.Mutex
+	results map[queryKey]cachedQuery
+}
+
+// NewEvaluationQuerier creates an EvaluationQuerier. Queries that are executed after timeout has
+// elapsed since now fail with context.DeadlineExceeded. A timeout of zero disables the deadline.
+func NewEvaluationQuerier(q Querier, now time.Time, timeout time.Duration) *EvaluationQuerier {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = now.Add(timeout)
+	}
+	return &EvaluationQuerier{
+		querier:  q,
+		deadline: deadline,
+		results:  make(map[queryKey]cachedQuery),
+	}
+}
+
+func (q *EvaluationQuerier) Query(ctx context.Context, datasourceUID, expr string, ts time.Time) (promql.Vector, error) {
+	key := queryKey{datasourceUID: datasourceUID, expr: expr, ts: ts.UnixNano()}
+
+	// Series of an evaluation are expanded one after another, so holding the lock while the
+	// query is executed does not slow down the evaluation and prevents duplicate queries.
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	if r, ok := q.results[key]; ok {
+		return r.vector, r.err
+	}
+
+	if !q.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, q.deadline)
+		defer cancel()
+	}
+	vector, err := q.querier.Query(ctx, datasourceUID, expr, ts)
+	q.results[key] = cachedQuery{vector: vector, err: err}
+	return vector, err
+}
+
+// sample is a series of the result of the query function. It has the same fields as the samples of Prometheus templates.
+type sample struct {
+	Labels map[string]string
+	Value  any
+}
+
+// queryResult is the result of the query function. The functions of Prometheus templates that work with the results
+// of queries are overridden by queryResultFuncs, because the query function of Prometheus is replaced by queryFuncs.
+type queryResult []*sample
+
+// queryFuncs returns the query function. It executes expr as an instant query at ts against the default datasource
+// of the querier, or against the datasource whose UID is passed as the optional second argument, e.g.
+// {{ query "up" }} or {{ query "sum(up)" "my-prometheus" }}. Without a querier, it returns no results.
+func queryFuncs(ctx context.Context, q Querier, ts time.Time) template.FuncMap {
+	return template.FuncMap{
+		QueryFuncName: func(expr string, datasourceUID ...string) (queryResult, error) {
+			if len(datasourceUID) > 1 {
+				return nil, fmt.Errorf("query accepts a single datasource UID, got %d", len(datasourceUID))
+			}
+			if q == nil {
+				return nil, nil
+			}
+			var uid string
+			if len(datasourceUID) == 1 {
+				uid = datasourceUID[0]
+			}
+			vector, err := q.Query(ctx, uid, expr, ts)
+			if err != nil {
+				return nil, err
+			}
+			result := make(queryResult, 0, len(vector))
+			for _, s := range vector {
+				result = append(result, &sample{Labels: s.Metric.Map(), Value: s.F})
+			}
+			return result, nil
+		},
+	}
+}
+
+var queryResultFuncs = template.FuncMap{
+	FirstFuncName: func(v queryResult) (*sample, error) {
+		if len(v) > 0 {
+			return v[0], nil
+		}
+		return nil, errors.New("first() called on vector with no elements")
+	},
+	LabelFuncName: func(label string, s *sample) string {
+		if s == nil {
+			return ""
+		}
+		return s.Labels[label]
+	},
+	SortByLabelFuncName: func(label string, v queryResult) queryResult {
+		sort.SliceStable(v, func(i, j int) bool {
+			return v[i].Labels[label] < v[j].Labels[label]
+		})
+		return v
+	},
+	StrValueFuncName: func(s *sample) string {
+		return s.Labels["__value__"]
+	},
+	ValueFuncName: func(s *sample) any {
+		return s.Value
+	},
+}
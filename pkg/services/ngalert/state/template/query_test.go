@@ -0,0 +1,161 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeQuery struct {
+	datasourceUID string
+	expr          string
+	ts            time.Time
+}
+
+type fakeQuerier struct {
+	queries []fakeQuery
+	vector  promql.Vector
+	err     error
+}
+
+func (q *fakeQuerier) Query(ctx context.Context, datasourceUID, expr string, ts time.Time) (promql.Vector, error) {
+	q.queries = append(q.queries, fakeQuery{datasourceUID: datasourceUID, expr: expr, ts: ts})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return q.vector, q.err
+}
+
+func TestExpandTemplateQuery(t *testing.T) {
+	externalURL, err := url.Parse("http://localhost")
+	require.NoError(t, err)
+	evaluatedAt := time.Unix(1700000000, 0)
+
+	vector := promql.Vector{{
+		Metric: labels.FromStrings("instance", "foo"),
+		F:      1,
+	}, {
+		Metric: labels.FromStrings("instance", "bar"),
+		F:      2,
+	}}
+
+	t.Run("query returns no results without a querier", func(t *testing.T) {
+		v, err := Expand(context.Background(), "test", `{{ query "up" | len }}`, Data{}, externalURL, evaluatedAt)
+		require.NoError(t, err)
+		assert.Equal(t, "0", v)
+	})
+
+	t.Run("query is executed against the default datasource at evaluatedAt", func(t *testing.T) {
+		q := &fakeQuerier{vector: vector}
+		ctx := WithQuerier(context.Background(), q)
+		v, err := Expand(ctx, "test", `{{ range query "up" | sortByLabel "instance" }}{{ .Labels.instance }}={{ .Value }} {{ end }}`, Data{}, externalURL, evaluatedAt)
+		require.NoError(t, err)
+		assert.Equal(t, "bar=2 foo=1 ", v)
+		require.Len(t, q.queries, 1)
+		assert.Equal(t, fakeQuery{datasourceUID: "", expr: "up", ts: evaluatedAt}, q.queries[0])
+	})
+
+	t.Run("query is executed against the datasource passed as second argument", func(t *testing.T) {
+		q := &fakeQuerier{vector: vector}
+		ctx := WithQuerier(context.Background(), q)
+		v, err := Expand(ctx, "test", `{{ with query "sum(up)" "prom" }}{{ . | first | value }}{{ end }}`, Data{}, externalURL, evaluatedAt)
+		require.NoError(t, err)
+		assert.Equal(t, "1", v)
+		require.Len(t, q.queries, 1)
+		assert.Equal(t, fakeQuery{datasourceUID: "prom", expr: "sum(up)", ts: evaluatedAt}, q.queries[0])
+	})
+
+	t.Run("query accepts a single datasource", func(t *testing.T) {
+		q := &fakeQuerier{vector: vector}
+		ctx := WithQuerier(context.Background(), q)
+		_, err := Expand(ctx, "test", `{{ query "up" "prom" "other" }}`, Data{}, externalURL, evaluatedAt)
+		require.ErrorContains(t, err, "query accepts a single datasource UID")
+		require.Empty(t, q.queries)
+	})
+
+	t.Run("query results work with the functions of Prometheus templates", func(t *testing.T) {
+		q := &fakeQuerier{vector: vector}
+		ctx := WithQuerier(context.Background(), q)
+		v, err := Expand(ctx, "test", `{{ with query "up" | sortByLabel "instance" | first }}{{ label "instance" . }}={{ value . | humanize }}{{ end }}`, Data{}, externalURL, evaluatedAt)
+		require.NoError(t, err)
+		assert.Equal(t, "bar=2", v)
+
+		q.vector = nil
+		_, err = Expand(ctx, "test", `{{ query "up" | first }}`, Data{}, externalURL, evaluatedAt)
+		require.ErrorContains(t, err, "first() called on vector with no elements")
+	})
+
+	t.Run("query is executed once per evaluation with an evaluation querier", func(t *testing.T) {
+		q := &fakeQuerier{vector: vector}
+		ctx := WithQuerier(context.Background(), NewEvaluationQuerier(q, evaluatedAt, time.Minute))
+		for i := 0; i < 2; i++ {
+			v, err := Expand(ctx, "test", `{{ query "up" | len }}`, Data{}, externalURL, evaluatedAt)
+			require.NoError(t, err)
+			assert.Equal(t, "2", v)
+		}
+		require.Len(t, q.queries, 1)
+	})
+
+	t.Run("query error is returned", func(t *testing.T) {
+		q := &fakeQuerier{err: errors.New("failed")}
+		ctx := WithQuerier(context.Background(), q)
+		_, err := Expand(ctx, "test", `{{ query "up" }}`, Data{}, externalURL, evaluatedAt)
+		require.ErrorContains(t, err, "failed")
+	})
+}
+
+func TestEvaluationQuerier(t *testing.T) {
+	now := time.Now()
+	ts := time.Unix(1700000000, 0)
+
+	t.Run("identical queries are executed once", func(t *testing.T) {
+		fake := &fakeQuerier{vector: promql.Vector{{F: 1}}}
+		q := NewEvaluationQuerier(fake, now, time.Minute)
+
+		for i := 0; i < 3; i++ {
+			v, err := q.Query(context.Background(), "prom", "up", ts)
+			require.NoError(t, err)
+			require.Equal(t, fake.vector, v)
+		}
+		require.Len(t, fake.queries, 1)
+
+		_, _ = q.Query(context.Background(), "other", "up", ts)
+		_, _ = q.Query(context.Background(), "prom", "down", ts)
+		_, _ = q.Query(context.Background(), "prom", "up", ts.Add(time.Second))
+		require.Len(t, fake.queries, 4)
+	})
+
+	t.Run("errors are cached", func(t *testing.T) {
+		fake := &fakeQuerier{err: errors.New("failed")}
+		q := NewEvaluationQuerier(fake, now, time.Minute)
+
+		_, err := q.Query(context.Background(), "prom", "up", ts)
+		require.ErrorIs(t, err, fake.err)
+		_, err = q.Query(context.Background(), "prom", "up", ts)
+		require.ErrorIs(t, err, fake.err)
+		require.Len(t, fake.queries, 1)
+	})
+
+	t.Run("queries fail after timeout", func(t *testing.T) {
+		fake := &fakeQuerier{}
+		q := NewEvaluationQuerier(fake, now.Add(-time.Minute), time.Second)
+
+		_, err := q.Query(context.Background(), "prom", "up", ts)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("no timeout if zero", func(t *testing.T) {
+		fake := &fakeQuerier{}
+		q := NewEvaluationQuerier(fake, now.Add(-time.Hour), 0)
+
+		_, err := q.Query(context.Background(), "prom", "up", ts)
+		require.NoError(t, err)
+	})
+}
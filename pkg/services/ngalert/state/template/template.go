@@ -16,6 +16,7 @@ import (
 	"github.com/prometheus/prometheus/template"
 
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
 )
 
 type Labels map[string]string
@@ -70,10 +71,34 @@ func NewValues(captures map[string]eval.NumberValueCapture) map[string]Value {
 	return values
 }
 
+// RuleData contains the fields of the alert rule that are used by template functions.
+type RuleData struct {
+	OrgID         int64
+	UID           string
+	Title         string
+	DatasourceUID string
+	DashboardUID  string
+	PanelID       int64
+}
+
+func NewRuleData(rule *models.AlertRule) RuleData {
+	return RuleData{
+		OrgID:         rule.OrgID,
+		UID:           rule.UID,
+		Title:         rule.Title,
+		DatasourceUID: rule.GetDatasourceUID(),
+		DashboardUID:  rule.GetDashboardUID(),
+		PanelID:       rule.GetPanelID(),
+	}
+}
+
 type Data struct {
 	Labels Labels
 	Values map[string]Value
 	Value  string
+	// StartsAt is the time the alert instance entered its current state.
+	StartsAt time.Time
+	Rule     RuleData
 }
 
 func NewData(labels map[string]string, res eval.Result) Data {
@@ -104,7 +129,7 @@ func Expand(ctx context.Context, name, tmpl string, data Data, externalURL *url.
 	name = "__alert_" + name
 	// add variables for the labels and values to the beginning of the template
 	tmpl = "{{- $labels := .Labels -}}{{- $values := .Values -}}{{- $value := .Value -}}" + tmpl
-	// ctx and queryFunc are no-ops as `query()` is not supported in Grafana
+	// `query()` of Prometheus is replaced by queryFuncs, which execute queries with the querier added to ctx with WithQuerier
 	queryFunc := func(context.Context, string, time.Time) (promql.Vector, error) {
 		return nil, nil
 	}
@@ -114,6 +139,9 @@ func Expand(ctx context.Context, name, tmpl string, data Data, externalURL *url.
 
 	expander := template.NewTemplateExpander(ctx, tmpl, name, data, tm, queryFunc, externalURL, options)
 	expander.Funcs(defaultFuncs)
+	expander.Funcs(queryResultFuncs)
+	expander.Funcs(queryFuncs(ctx, QuerierFromContext(ctx), evaluatedAt))
+	expander.Funcs(dataFuncs(data, externalURL, evaluatedAt, LookupTablesFromContext(ctx)))
 
 	result, err := expander.Expand()
 	if err != nil {
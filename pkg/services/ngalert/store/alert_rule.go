@@ -966,6 +966,37 @@ func (st DBstore) filterImportedPrometheusRules(value bool, sess *xorm.Session)
 	), nil
 }
 
+// ResumeAlertRules resumes rules that are paused until a time that has passed. Rules that were changed since the given version,
+// or are not paused until a time anymore, are skipped. The change is attributed to the system, so that rule versions
+// distinguish automatic resumes from changes made by users.
+func (st DBstore) ResumeAlertRules(ctx context.Context, keys []ngmodels.AlertRuleKeyWithVersion) error {
+	updates := make([]ngmodels.UpdateRule, 0, len(keys))
+	now := TimeNow()
+	for _, key := range keys {
+		rule, err := st.GetAlertRuleByUID(ctx, &ngmodels.GetAlertRuleByUIDQuery{OrgID: key.OrgID, UID: key.UID})
+		if err != nil {
+			if errors.Is(err, ngmodels.ErrAlertRuleNotFound) {
+				continue
+			}
+			return err
+		}
+		if rule.Version != key.Version || !rule.PauseExpired(now) {
+			continue
+		}
+		r := rule.Copy()
+		r.IsPaused = false
+		r.Metadata.PausedUntil = nil
+		updates = append(updates, ngmodels.UpdateRule{
+			Existing: rule,
+			New:      *r,
+		})
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return st.UpdateAlertRules(ctx, &ngmodels.AlertingUserUID, updates)
+}
+
 func (st DBstore) RenameReceiverInNotificationSettings(ctx context.Context, orgID int64, oldReceiver, newReceiver string, validateProvenance func(ngmodels.Provenance) bool, dryRun bool) ([]ngmodels.AlertRuleKey, []ngmodels.AlertRuleKey, error) {
 	// fetch entire rules because Update method requires it because it copies rules to version table
 	rules, err := st.ListAlertRules(ctx, &ngmodels.ListAlertRulesQuery{
@@ -52,6 +52,27 @@ func (c *GroupDelta) NewOrUpdatedNotificationSettings() []models.NotificationSet
 	return settings
 }
 
+// NewOrUpdatedRecordingTargets returns the UIDs of the target data sources of recording rules that are either new
+// or whose target is changed in the group.
+func (c *GroupDelta) NewOrUpdatedRecordingTargets() []string {
+	var targets []string
+	for _, rule := range c.New {
+		if rule.Record != nil && rule.Record.TargetDatasourceUID != "" {
+			targets = append(targets, rule.Record.TargetDatasourceUID)
+		}
+	}
+	for _, delta := range c.Update {
+		if delta.New.Record == nil || delta.New.Record.TargetDatasourceUID == "" {
+			continue
+		}
+		if delta.Existing.Record != nil && delta.Existing.Record.TargetDatasourceUID == delta.New.Record.TargetDatasourceUID {
+			continue
+		}
+		targets = append(targets, delta.New.Record.TargetDatasourceUID)
+	}
+	return targets
+}
+
 type RuleReader interface {
 	ListAlertRules(ctx context.Context, query *models.ListAlertRulesQuery) (models.RulesGroup, error)
 	GetAlertRulesGroupByRuleUID(ctx context.Context, query *models.GetAlertRulesGroupByRuleUIDQuery) ([]*models.AlertRule, error)
@@ -14,6 +14,9 @@ import (
 	"github.com/grafana/grafana/pkg/services/sqlstore"
 )
 
+// saveAlertInstancesForRuleBatchSize is the number of instances inserted by a statement in SaveAlertInstancesForRule.
+const saveAlertInstancesForRuleBatchSize = 100
+
 type InstanceDBStore struct {
 	SQLStore db.DB
 	Logger   log.Logger
@@ -190,10 +193,20 @@ func (st InstanceDBStore) DeleteAlertInstances(ctx context.Context, keys ...mode
 	return err
 }
 
-// SaveAlertInstancesForRule is not implemented for instance database store.
+// SaveAlertInstancesForRule replaces all instances of the rule with the given ones in a single transaction.
 func (st InstanceDBStore) SaveAlertInstancesForRule(ctx context.Context, key models.AlertRuleKeyWithGroup, instances []models.AlertInstance) error {
-	st.Logger.Error("SaveAlertInstancesForRule is not implemented for instance database store.")
-	return errors.New("method SaveAlertInstancesForRule is not implemented for instance database store")
+	return st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		if _, err := sess.Exec("DELETE FROM alert_instance WHERE rule_org_id = ? AND rule_uid = ?", key.OrgID, key.UID); err != nil {
+			return fmt.Errorf("failed to delete instances of the rule: %w", err)
+		}
+		for start := 0; start < len(instances); start += saveAlertInstancesForRuleBatchSize {
+			end := min(start+saveAlertInstancesForRuleBatchSize, len(instances))
+			if err := st.insertInstancesBatch(sess, instances[start:end]); err != nil {
+				return fmt.Errorf("failed to insert batch [%d:%d]: %w", start, end, err)
+			}
+		}
+		return nil
+	})
 }
 
 // DeleteAlertInstancesByRule deletes all instances for a given rule.
@@ -255,10 +268,10 @@ func (st InstanceDBStore) insertInstancesBatch(sess *sqlstore.DBSession, batch [
 
 	query := strings.Builder{}
 	placeholders := make([]string, 0, len(batch))
-	args := make([]any, 0, len(batch)*11)
+	args := make([]any, 0, len(batch)*12)
 
 	query.WriteString("INSERT INTO alert_instance ")
-	query.WriteString("(rule_org_id, rule_uid, labels, labels_hash, current_state, current_reason, current_state_since, current_state_end, last_eval_time, resolved_at, last_sent_at) VALUES ")
+	query.WriteString("(rule_org_id, rule_uid, labels, labels_hash, current_state, current_reason, current_state_since, current_state_end, last_eval_time, resolved_at, last_sent_at, result_fingerprint) VALUES ")
 
 	for _, instance := range batch {
 		if err := models.ValidateAlertInstance(instance); err != nil {
@@ -272,7 +285,7 @@ func (st InstanceDBStore) insertInstancesBatch(sess *sqlstore.DBSession, batch [
 			continue
 		}
 
-		placeholders = append(placeholders, "(?,?,?,?,?,?,?,?,?,?,?)")
+		placeholders = append(placeholders, "(?,?,?,?,?,?,?,?,?,?,?,?)")
 		args = append(args,
 			instance.RuleOrgID,
 			instance.RuleUID,
@@ -285,6 +298,7 @@ func (st InstanceDBStore) insertInstancesBatch(sess *sqlstore.DBSession, batch [
 			instance.LastEvalTime.Unix(),
 			nullableTimeToUnix(instance.ResolvedAt),
 			nullableTimeToUnix(instance.LastSentAt),
+			instance.ResultFingerprint,
 		)
 	}
 
@@ -507,6 +507,35 @@ func TestIntegrationFullSync(t *testing.T) {
 	})
 }
 
+func TestIntegrationSaveAlertInstancesForRule(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	ng, _ := tests.SetupTestEnv(t, baseIntervalSeconds)
+	orgID := int64(1)
+
+	other := generateTestAlertInstance(orgID, "other")
+	stale := generateTestAlertInstance(orgID, "rule")
+	stale.LabelsHash = "stale"
+	require.NoError(t, ng.InstanceStore.FullSync(ctx, []models.AlertInstance{other, stale}, 1))
+
+	current := generateTestAlertInstance(orgID, "rule")
+	ruleKey := models.AlertRuleKeyWithGroup{AlertRuleKey: models.AlertRuleKey{OrgID: orgID, UID: "rule"}}
+	require.NoError(t, ng.InstanceStore.SaveAlertInstancesForRule(ctx, ruleKey, []models.AlertInstance{current}))
+
+	res, err := ng.InstanceStore.ListAlertInstances(ctx, &models.ListAlertInstancesQuery{RuleOrgID: orgID, RuleUID: "rule"})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, current.LabelsHash, res[0].LabelsHash)
+	require.Equal(t, current.ResultFingerprint, res[0].ResultFingerprint)
+
+	res, err = ng.InstanceStore.ListAlertInstances(ctx, &models.ListAlertInstancesQuery{RuleOrgID: orgID, RuleUID: "other"})
+	require.NoError(t, err)
+	require.Len(t, res, 1, "instances of other rules must not change")
+}
+
 func TestIntegration_ProtoInstanceDBStore_VerifyCompressedData(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
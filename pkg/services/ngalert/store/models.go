@@ -24,6 +24,7 @@ type alertRule struct {
 	NoDataState          string
 	ExecErrState         string
 	For                  time.Duration
+	KeepFiringFor        time.Duration `xorm:"keep_firing_for"`
 	Annotations          string
 	Labels               string
 	IsPaused             bool
@@ -60,6 +61,7 @@ type alertRuleVersion struct {
 	// ideally this field should have been apimodels.ApiDuration
 	// but this is currently not possible because of circular dependencies
 	For                  time.Duration
+	KeepFiringFor        time.Duration `xorm:"keep_firing_for"`
 	Annotations          string
 	Labels               string
 	IsPaused             bool
@@ -84,6 +86,7 @@ func (a alertRuleVersion) EqualSpec(b alertRuleVersion) bool {
 		a.NoDataState == b.NoDataState &&
 		a.ExecErrState == b.ExecErrState &&
 		a.For == b.For &&
+		a.KeepFiringFor == b.KeepFiringFor &&
 		a.Annotations == b.Annotations &&
 		a.Labels == b.Labels &&
 		a.IsPaused == b.IsPaused &&
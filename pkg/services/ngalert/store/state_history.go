@@ -0,0 +1,257 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+const (
+	// stateHistoryBatchSize is the maximum number of state transitions that are inserted by a single statement.
+	stateHistoryBatchSize = 100
+	// stateHistoryDeleteBatchSize is the maximum number of state transitions that are deleted by a single transaction.
+	stateHistoryDeleteBatchSize = 1000
+)
+
+// stateHistoryEntry represents a record in alert_state_history table
+type stateHistoryEntry struct {
+	ID             int64     `xorm:"pk autoincr 'id'"`
+	OrgID          int64     `xorm:"org_id"`
+	RuleUID        string    `xorm:"rule_uid"`
+	RuleID         int64     `xorm:"rule_id"`
+	RuleTitle      string    `xorm:"rule_title"`
+	RuleGroup      string    `xorm:"rule_group"`
+	NamespaceUID   string    `xorm:"namespace_uid"`
+	DashboardUID   string    `xorm:"dashboard_uid"`
+	PanelID        int64     `xorm:"panel_id"`
+	Condition      string    `xorm:"condition"`
+	Fingerprint    string    `xorm:"fingerprint"`
+	Labels         string    `xorm:"labels"`
+	Values         string    `xorm:"values"`
+	PreviousState  string    `xorm:"previous_state"`
+	PreviousReason string    `xorm:"previous_reason"`
+	CurrentState   string    `xorm:"current_state"`
+	CurrentReason  string    `xorm:"current_reason"`
+	Error          string    `xorm:"error"`
+	Timestamp      time.Time `xorm:"evaluated_at"`
+}
+
+func (e stateHistoryEntry) TableName() string {
+	return "alert_state_history"
+}
+
+// SaveStateHistory inserts state transitions into the alert_state_history table.
+func (st DBstore) SaveStateHistory(ctx context.Context, entries []models.StateHistoryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	rows := make([]stateHistoryEntry, 0, len(entries))
+	for _, e := range entries {
+		row, err := stateHistoryEntryFromModel(e)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+	return st.SQLStore.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		for i := 0; i < len(rows); i += stateHistoryBatchSize {
+			end := min(i+stateHistoryBatchSize, len(rows))
+			if _, err := sess.InsertMulti(rows[i:end]); err != nil {
+				return fmt.Errorf("failed to insert state history: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// FindStateHistory returns state transitions that match the query ordered by time. If the query has a limit, the most recent transitions are returned.
+// If namespaceUIDs is not empty, only transitions of rules in those folders are returned.
+func (st DBstore) FindStateHistory(ctx context.Context, query models.HistoryQuery, namespaceUIDs []string) ([]models.StateHistoryEntry, error) {
+	var rows []stateHistoryEntry
+	err := st.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+		q := sess.Table(stateHistoryEntry{}).Where("org_id = ?", query.OrgID)
+		if query.RuleUID != "" {
+			q = q.And("rule_uid = ?", query.RuleUID)
+		}
+		if query.DashboardUID != "" {
+			q = q.And("dashboard_uid = ?", query.DashboardUID)
+		}
+		if query.PanelID != 0 {
+			q = q.And("panel_id = ?", query.PanelID)
+		}
+		if query.PreviousState != "" {
+			q = q.And("previous_state = ?", query.PreviousState)
+		}
+		if query.CurrentState != "" {
+			q = q.And("current_state = ?", query.CurrentState)
+		}
+		if query.Reason != "" {
+			q = q.And("current_reason = ?", query.Reason)
+		}
+		if !query.From.IsZero() {
+			q = q.And("evaluated_at >= ?", query.From.UTC())
+		}
+		if !query.To.IsZero() {
+			q = q.And("evaluated_at <= ?", query.To.UTC())
+		}
+		if len(namespaceUIDs) > 0 {
+			args := make([]any, 0, len(namespaceUIDs))
+			for _, uid := range namespaceUIDs {
+				args = append(args, uid)
+			}
+			q = q.In("namespace_uid", args...)
+		}
+		keys := make([]string, 0, len(query.Labels))
+		for k := range query.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			search, err := st.labelSearchString(k, query.Labels[k])
+			if err != nil {
+				return err
+			}
+			q = q.And(fmt.Sprintf("labels %s ?", st.SQLStore.GetDialect().LikeStr()), "%"+search+"%")
+		}
+		q = q.Desc("evaluated_at", "id")
+		if query.Limit > 0 {
+			q = q.Limit(query.Limit)
+		}
+		return q.Find(&rows)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query state history: %w", err)
+	}
+
+	result := make([]models.StateHistoryEntry, 0, len(rows))
+	for i := len(rows) - 1; i >= 0; i-- {
+		e, err := stateHistoryEntryToModel(rows[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// DeleteStateHistory deletes state transitions that happened before the given time. It returns the number of deleted transitions.
+// Transitions are deleted in batches, each in its own transaction, so that the table is not locked for the whole deletion.
+func (st DBstore) DeleteStateHistory(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for {
+		var n int64
+		if err := st.SQLStore.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+			var ids []int64
+			err := sess.Table(stateHistoryEntry{}).Cols("id").Where("evaluated_at < ?", before.UTC()).Asc("id").Limit(stateHistoryDeleteBatchSize).Find(&ids)
+			if err != nil {
+				return fmt.Errorf("failed to find expired state history: %w", err)
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+			args := make([]any, 0, len(ids))
+			for _, id := range ids {
+				args = append(args, id)
+			}
+			rows, err := sess.In("id", args...).Delete(&stateHistoryEntry{})
+			if err != nil {
+				return fmt.Errorf("failed to delete state history: %w", err)
+			}
+			n = rows
+			return nil
+		}); err != nil {
+			return -1, err
+		}
+		total += n
+		if n < stateHistoryDeleteBatchSize {
+			return total, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return -1, err
+		}
+	}
+}
+
+// labelSearchString returns the JSON representation of a label pair as it appears in the labels column.
+func (st DBstore) labelSearchString(key, value string) (string, error) {
+	b, err := json.Marshal(map[string]string{key: value})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshall label for state history filter: %w", err)
+	}
+	// strip the curly braces
+	search := string(b[1 : len(b)-1])
+	if st.SQLStore.GetDialect().DriverName() != migrator.SQLite {
+		// this escapes escaped double quote (\") to \\\"
+		search = strings.ReplaceAll(strings.ReplaceAll(search, `\`, `\\`), `"`, `\"`)
+	}
+	return search, nil
+}
+
+func stateHistoryEntryFromModel(e models.StateHistoryEntry) (stateHistoryEntry, error) {
+	labels, err := json.Marshal(e.Labels)
+	if err != nil {
+		return stateHistoryEntry{}, fmt.Errorf("failed to marshal state history labels: %w", err)
+	}
+	values := "{}"
+	if len(e.Values) > 0 {
+		values = string(e.Values)
+	}
+	return stateHistoryEntry{
+		ID:             e.ID,
+		OrgID:          e.OrgID,
+		RuleUID:        e.RuleUID,
+		RuleID:         e.RuleID,
+		RuleTitle:      e.RuleTitle,
+		RuleGroup:      e.RuleGroup,
+		NamespaceUID:   e.NamespaceUID,
+		DashboardUID:   e.DashboardUID,
+		PanelID:        e.PanelID,
+		Condition:      e.Condition,
+		Fingerprint:    e.Fingerprint,
+		Labels:         string(labels),
+		Values:         values,
+		PreviousState:  e.PreviousState,
+		PreviousReason: e.PreviousReason,
+		CurrentState:   e.CurrentState,
+		CurrentReason:  e.CurrentReason,
+		Error:          e.Error,
+		Timestamp:      e.Timestamp.UTC(),
+	}, nil
+}
+
+func stateHistoryEntryToModel(e stateHistoryEntry) (models.StateHistoryEntry, error) {
+	var labels map[string]string
+	if e.Labels != "" {
+		if err := json.Unmarshal([]byte(e.Labels), &labels); err != nil {
+			return models.StateHistoryEntry{}, fmt.Errorf("failed to unmarshal labels of state history entry %d: %w", e.ID, err)
+		}
+	}
+	return models.StateHistoryEntry{
+		ID:             e.ID,
+		OrgID:          e.OrgID,
+		RuleUID:        e.RuleUID,
+		RuleID:         e.RuleID,
+		RuleTitle:      e.RuleTitle,
+		RuleGroup:      e.RuleGroup,
+		NamespaceUID:   e.NamespaceUID,
+		DashboardUID:   e.DashboardUID,
+		PanelID:        e.PanelID,
+		Condition:      e.Condition,
+		Fingerprint:    e.Fingerprint,
+		Labels:         labels,
+		Values:         json.RawMessage(e.Values),
+		PreviousState:  e.PreviousState,
+		PreviousReason: e.PreviousReason,
+		CurrentState:   e.CurrentState,
+		CurrentReason:  e.CurrentReason,
+		Error:          e.Error,
+		Timestamp:      e.Timestamp,
+	}, nil
+}
@@ -0,0 +1,159 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+)
+
+const (
+	// DefaultBatchFlushInterval is how long points are collected before they are written if no other is configured.
+	DefaultBatchFlushInterval = time.Second
+	// DefaultBatchMaxSeries is the maximum number of series written in one request if no other is configured.
+	DefaultBatchMaxSeries = 2000
+)
+
+// BatchConfig configures the batching of writes of many rules into fewer remote write requests.
+type BatchConfig struct {
+	// FlushInterval is how long points are collected before they are written.
+	FlushInterval time.Duration
+	// MaxSeries is the maximum number of series written in one request. A batch is written as soon as it is full.
+	MaxSeries int
+}
+
+func (cfg BatchConfig) withDefaults() BatchConfig {
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultBatchFlushInterval
+	}
+	if cfg.MaxSeries <= 0 {
+		cfg.MaxSeries = DefaultBatchMaxSeries
+	}
+	return cfg
+}
+
+type writePointsFunc func(ctx context.Context, points []Point, orgID int64) error
+
+// batchedWrite is a write of a rule that waits for its batch to be written.
+type batchedWrite struct {
+	points []Point
+	done   chan error
+}
+
+type batch struct {
+	orgID  int64
+	writes []*batchedWrite
+	series int
+	timer  *clock.Timer
+	taken  bool
+}
+
+// batcher coalesces writes of many rules into fewer requests. Points are collected per organization for the flush
+// interval, or until the batch reaches the maximum number of series, and then written in one request.
+// Every write waits until its batch is written and returns the error of the points it contributed.
+type batcher struct {
+	flushInterval time.Duration
+	maxSeries     int
+	write         writePointsFunc
+	clock         clock.Clock
+	logger        log.Logger
+	metrics       *metrics.RemoteWriter
+
+	mtx     sync.Mutex
+	pending map[int64]*batch
+}
+
+func newBatcher(cfg BatchConfig, write writePointsFunc, clock clock.Clock, l log.Logger, metrics *metrics.RemoteWriter) *batcher {
+	cfg = cfg.withDefaults()
+	return &batcher{
+		flushInterval: cfg.FlushInterval,
+		maxSeries:     cfg.MaxSeries,
+		write:         write,
+		clock:         clock,
+		logger:        l,
+		metrics:       metrics,
+		pending:       make(map[int64]*batch),
+	}
+}
+
+// WritePoints adds the points to the pending batch of the organization and waits until the batch is written.
+// Points that do not fit into a batch on their own are written immediately.
+func (b *batcher) WritePoints(ctx context.Context, points []Point, orgID int64) error {
+	if len(points) >= b.maxSeries {
+		return b.write(ctx, points, orgID)
+	}
+
+	w := &batchedWrite{points: points, done: make(chan error, 1)}
+	b.mtx.Lock()
+	current := b.pending[orgID]
+	if current != nil && current.series+len(points) > b.maxSeries {
+		b.flushLocked(current)
+		current = nil
+	}
+	if current == nil {
+		bt := &batch{orgID: orgID}
+		bt.timer = b.clock.AfterFunc(b.flushInterval, func() {
+			b.mtx.Lock()
+			defer b.mtx.Unlock()
+			b.flushLocked(bt)
+		})
+		b.pending[orgID] = bt
+		current = bt
+	}
+	current.writes = append(current.writes, w)
+	current.series += len(points)
+	if current.series == b.maxSeries {
+		b.flushLocked(current)
+	}
+	b.mtx.Unlock()
+
+	select {
+	case err := <-w.done:
+		return err
+	case <-ctx.Done():
+		// The points are still written with the batch.
+		return ctx.Err()
+	}
+}
+
+// flushLocked writes the batch in the background unless it is already being written. It must be called with the lock held.
+func (b *batcher) flushLocked(bt *batch) {
+	if bt.taken {
+		return
+	}
+	bt.taken = true
+	bt.timer.Stop()
+	if b.pending[bt.orgID] == bt {
+		delete(b.pending, bt.orgID)
+	}
+	go b.send(bt)
+}
+
+func (b *batcher) send(bt *batch) {
+	// The batch outlives the contexts of the writes it contains.
+	ctx := context.Background()
+	points := make([]Point, 0, bt.series)
+	for _, w := range bt.writes {
+		points = append(points, w.points...)
+	}
+	b.metrics.BatchWrites.Observe(float64(len(bt.writes)))
+
+	err := b.write(ctx, points, bt.orgID)
+	if err != nil && len(bt.writes) > 1 && errors.Is(err, ErrRejectedWrite) {
+		// The target rejected some series of the batch. Points of every rule are written again on their own, so that
+		// only the rules whose series are invalid fail. Points that were accepted before are ignored as duplicates.
+		b.logger.Debug("Batch was rejected, writing points of every rule separately", "org_id", bt.orgID, "writes", len(bt.writes), "error", err)
+		for _, w := range bt.writes {
+			w.done <- b.write(ctx, w.points, bt.orgID)
+		}
+		return
+	}
+	for _, w := range bt.writes {
+		w.done <- err
+	}
+}
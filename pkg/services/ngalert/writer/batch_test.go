@@ -0,0 +1,145 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+)
+
+func TestBatcher(t *testing.T) {
+	ctx := context.Background()
+	points := func(name string, n int) []Point {
+		result := make([]Point, 0, n)
+		for i := 0; i < n; i++ {
+			result = append(result, Point{Name: name, Labels: map[string]string{"i": fmt.Sprint(i)}, Metric: Metric{T: time.Now(), V: 1}})
+		}
+		return result
+	}
+
+	type request struct {
+		orgID int64
+		names []string
+	}
+	setup := func(writeErr func(points []Point) error) (*batcher, *clock.Mock, func() []request) {
+		clk := clock.NewMock()
+		var mtx sync.Mutex
+		var requests []request
+		write := func(_ context.Context, points []Point, orgID int64) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			req := request{orgID: orgID}
+			for _, p := range points {
+				req.names = append(req.names, p.Name)
+			}
+			requests = append(requests, req)
+			if writeErr != nil {
+				return writeErr(points)
+			}
+			return nil
+		}
+		b := newBatcher(BatchConfig{FlushInterval: time.Second, MaxSeries: 4}, write, clk, log.NewNopLogger(), metrics.NewRemoteWriterMetrics(prometheus.NewRegistry()))
+		return b, clk, func() []request {
+			mtx.Lock()
+			defer mtx.Unlock()
+			return requests
+		}
+	}
+	pendingWrites := func(b *batcher, orgID int64) int {
+		b.mtx.Lock()
+		defer b.mtx.Unlock()
+		if b.pending[orgID] == nil {
+			return 0
+		}
+		return len(b.pending[orgID].writes)
+	}
+	writeAsync := func(b *batcher, name string, n int, orgID int64) <-chan error {
+		result := make(chan error, 1)
+		go func() {
+			result <- b.WritePoints(ctx, points(name, n), orgID)
+		}()
+		return result
+	}
+
+	t.Run("coalesces writes of an organization within the flush interval", func(t *testing.T) {
+		b, clk, requests := setup(nil)
+
+		first := writeAsync(b, "first", 1, 1)
+		require.Eventually(t, func() bool { return pendingWrites(b, 1) == 1 }, time.Second, time.Millisecond)
+		second := writeAsync(b, "second", 1, 1)
+		other := writeAsync(b, "other", 1, 2)
+		require.Eventually(t, func() bool { return pendingWrites(b, 1) == 2 && pendingWrites(b, 2) == 1 }, time.Second, time.Millisecond)
+		require.Empty(t, requests())
+
+		clk.Add(time.Second)
+		require.NoError(t, <-first)
+		require.NoError(t, <-second)
+		require.NoError(t, <-other)
+		require.ElementsMatch(t, []request{
+			{orgID: 1, names: []string{"first", "second"}},
+			{orgID: 2, names: []string{"other"}},
+		}, requests())
+	})
+
+	t.Run("writes batch as soon as it is full", func(t *testing.T) {
+		b, _, requests := setup(nil)
+
+		first := writeAsync(b, "first", 1, 1)
+		require.Eventually(t, func() bool { return pendingWrites(b, 1) == 1 }, time.Second, time.Millisecond)
+		require.NoError(t, b.WritePoints(ctx, points("second", 3), 1))
+		require.NoError(t, <-first)
+
+		// Points exceeding the size of a batch are written on their own.
+		require.NoError(t, b.WritePoints(ctx, points("large", 5), 1))
+		require.Equal(t, []request{
+			{orgID: 1, names: []string{"first", "second", "second", "second"}},
+			{orgID: 1, names: []string{"large", "large", "large", "large", "large"}},
+		}, requests())
+	})
+
+	t.Run("attributes rejected writes to rules with invalid series", func(t *testing.T) {
+		b, clk, requests := setup(func(points []Point) error {
+			for _, p := range points {
+				if p.Name == "invalid" {
+					return errors.Join(ErrRejectedWrite, errors.New("invalid label"))
+				}
+			}
+			return nil
+		})
+
+		valid := writeAsync(b, "valid", 1, 1)
+		require.Eventually(t, func() bool { return pendingWrites(b, 1) == 1 }, time.Second, time.Millisecond)
+		invalid := writeAsync(b, "invalid", 1, 1)
+		require.Eventually(t, func() bool { return pendingWrites(b, 1) == 2 }, time.Second, time.Millisecond)
+
+		clk.Add(time.Second)
+		require.NoError(t, <-valid)
+		require.ErrorIs(t, <-invalid, ErrRejectedWrite)
+		require.Len(t, requests(), 3)
+	})
+
+	t.Run("returns unexpected errors to all rules of the batch", func(t *testing.T) {
+		b, clk, requests := setup(func([]Point) error {
+			return errors.Join(ErrUnexpectedWriteFailure, errors.New("unavailable"))
+		})
+
+		first := writeAsync(b, "first", 1, 1)
+		require.Eventually(t, func() bool { return pendingWrites(b, 1) == 1 }, time.Second, time.Millisecond)
+		second := writeAsync(b, "second", 1, 1)
+		require.Eventually(t, func() bool { return pendingWrites(b, 1) == 2 }, time.Second, time.Millisecond)
+
+		clk.Add(time.Second)
+		require.ErrorIs(t, <-first, ErrUnexpectedWriteFailure)
+		require.ErrorIs(t, <-second, ErrUnexpectedWriteFailure)
+		require.Len(t, requests(), 1)
+	})
+}
@@ -0,0 +1,381 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	sdkhttpclient "github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/infra/httpclient"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// ErrInvalidTarget is returned when a recording rule targets a data source that cannot be written to.
+var ErrInvalidTarget = errors.New("invalid target data source")
+
+// datasourceCheckInterval is how often a cached writer is checked against its data source. Updates of the data source,
+// such as a new URL or credentials, apply to writes after at most this interval.
+const datasourceCheckInterval = 10 * time.Second
+
+// DatasourceService provides the data sources that recording rules write to.
+type DatasourceService interface {
+	GetDataSource(ctx context.Context, query *datasources.GetDataSourceQuery) (*datasources.DataSource, error)
+	GetHTTPTransport(ctx context.Context, ds *datasources.DataSource, provider httpclient.Provider, customMiddlewares ...sdkhttpclient.Middleware) (http.RoundTripper, error)
+}
+
+type datasourceKey struct {
+	orgID int64
+	uid   string
+}
+
+// datasourceWriter is a writer of a data source, valid until the data source is updated.
+type datasourceWriter struct {
+	version int
+	writer  *PrometheusWriter
+	// checkedAt is the last time the version was compared with the one of the data source.
+	checkedAt time.Time
+}
+
+// DatasourceWriter writes series of recording rules to the Prometheus-compatible data sources they target.
+// URL, authentication and custom headers, such as the tenant header of Mimir, are resolved from the data source.
+// Series of rules without a target are written to the remote write endpoint configured in settings, if any.
+type DatasourceWriter struct {
+	defaultWriter      *PrometheusWriter
+	datasources        DatasourceService
+	httpClientProvider httpclient.Provider
+	timeout            time.Duration
+	protocol           string
+	clock              clock.Clock
+	logger             log.Logger
+	metrics            *metrics.RemoteWriter
+
+	mtx     sync.Mutex
+	writers map[datasourceKey]datasourceWriter
+	// walCfg is nil if the write-ahead log is disabled.
+	walCfg *WALConfig
+	wals   map[datasourceKey]*WAL
+	// batchCfg is nil if writes are not batched.
+	batchCfg *BatchConfig
+}
+
+// NewDatasourceWriter creates a DatasourceWriter. defaultWriter can be nil, in which case series of rules without
+// a target data source are not written.
+func NewDatasourceWriter(
+	settings setting.RecordingRuleSettings,
+	defaultWriter *PrometheusWriter,
+	datasources DatasourceService,
+	httpClientProvider httpclient.Provider,
+	clock clock.Clock,
+	l log.Logger,
+	metrics *metrics.RemoteWriter,
+) *DatasourceWriter {
+	return &DatasourceWriter{
+		defaultWriter:      defaultWriter,
+		datasources:        datasources,
+		httpClientProvider: httpClientProvider,
+		timeout:            settings.Timeout,
+		protocol:           settings.RemoteWriteProtocol,
+		clock:              clock,
+		logger:             l,
+		metrics:            metrics,
+		writers:            make(map[datasourceKey]datasourceWriter),
+		wals:               make(map[datasourceKey]*WAL),
+	}
+}
+
+// EnableWAL makes writes that fail because of unexpected errors buffer the points in a write-ahead log on disk,
+// from which they are replayed by Run. Every target has its own log in a subdirectory of cfg.Dir.
+// It must be called before the writer is used.
+func (w *DatasourceWriter) EnableWAL(cfg WALConfig) error {
+	cfg = cfg.withDefaults()
+	w.walCfg = &cfg
+	if w.defaultWriter == nil {
+		return nil
+	}
+	wal, err := OpenWAL(filepath.Join(cfg.Dir, defaultWALTarget), defaultWALTarget, cfg, w.clock, w.logger, w.metrics)
+	if err != nil {
+		return err
+	}
+	w.defaultWriter.wal = wal
+	return nil
+}
+
+// EnableBatching makes writes of many rules to the same target be coalesced into fewer remote write requests.
+// It must be called before the writer is used.
+func (w *DatasourceWriter) EnableBatching(cfg BatchConfig) {
+	cfg = cfg.withDefaults()
+	w.batchCfg = &cfg
+	if w.defaultWriter != nil {
+		w.defaultWriter.enableBatching(cfg)
+	}
+}
+
+// Run periodically replays the write-ahead logs until the context is cancelled. Logs of data sources left by a
+// previous run are opened first, so their points are replayed even if no rule writes to the data source anymore.
+func (w *DatasourceWriter) Run(ctx context.Context) error {
+	if w.walCfg == nil {
+		return nil
+	}
+	w.resumeWALs(ctx)
+
+	ticker := w.clock.Ticker(walReplayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			w.replayWALs(ctx)
+		}
+	}
+}
+
+func (w *DatasourceWriter) replayWALs(ctx context.Context) {
+	w.mtx.Lock()
+	writers := make([]*PrometheusWriter, 0, len(w.writers)+1)
+	if w.defaultWriter != nil {
+		writers = append(writers, w.defaultWriter)
+	}
+	for _, cached := range w.writers {
+		writers = append(writers, cached.writer)
+	}
+	w.mtx.Unlock()
+
+	for _, wr := range writers {
+		wr.replayWAL(ctx)
+	}
+}
+
+// resumeWALs creates the writers of data sources that have a write-ahead log on disk.
+func (w *DatasourceWriter) resumeWALs(ctx context.Context) {
+	dirs, err := os.ReadDir(w.walCfg.Dir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			w.logger.Error("Failed to read write-ahead log directory", "error", err)
+		}
+		return
+	}
+	for _, dir := range dirs {
+		key, ok := parseWALTarget(dir.Name())
+		if !dir.IsDir() || !ok {
+			continue
+		}
+		_, err := w.writerFor(ctx, key.orgID, key.uid)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, ErrInvalidTarget) {
+			// The points cannot be written anymore, e.g. because the data source was deleted.
+			w.logger.Warn("Deleting write-ahead log of invalid target data source", "org_id", key.orgID, "datasource_uid", key.uid, "error", err)
+			if err := os.RemoveAll(filepath.Join(w.walCfg.Dir, dir.Name())); err != nil {
+				w.logger.Error("Failed to delete write-ahead log", "org_id", key.orgID, "datasource_uid", key.uid, "error", err)
+			}
+			continue
+		}
+		w.logger.Error("Failed to resume write-ahead log of target data source", "org_id", key.orgID, "datasource_uid", key.uid, "error", err)
+	}
+}
+
+// DefaultWriter returns the writer of the remote write endpoint configured in settings, or nil if there is none.
+func (w *DatasourceWriter) DefaultWriter() *PrometheusWriter {
+	return w.defaultWriter
+}
+
+// WriteDatasource writes the given frames to the data source with the given UID, along with the metadata of the metric.
+// If the UID is empty, the frames are written to the default remote write endpoint.
+func (w *DatasourceWriter) WriteDatasource(ctx context.Context, dsUID string, name string, metadata ngmodels.RecordMetadata, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
+	wr, points, err := w.prepare(ctx, dsUID, name, metadata, t, frames, orgID, extraLabels)
+	if wr == nil || err != nil {
+		return err
+	}
+	w.logger.FromContext(ctx).Debug("Writing metric", "name", name, "datasource_uid", dsUID)
+	return wr.WritePoints(ctx, points, orgID)
+}
+
+// WriteDatasourceDirect writes the given frames the same way as WriteDatasource, but in a request of its own that
+// bypasses the batching and the write-ahead log of the target, and returns any error of the write. It is used to
+// write historical samples, which targets often reject, without holding back the series of the rules that are evaluated.
+func (w *DatasourceWriter) WriteDatasourceDirect(ctx context.Context, dsUID string, name string, metadata ngmodels.RecordMetadata, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
+	wr, points, err := w.prepare(ctx, dsUID, name, metadata, t, frames, orgID, extraLabels)
+	if wr == nil || err != nil {
+		return err
+	}
+	w.logger.FromContext(ctx).Debug("Writing metric directly", "name", name, "datasource_uid", dsUID)
+	return wr.send(ctx, points, orgID)
+}
+
+// prepare returns the writer of the target and the points to write. The writer is nil if the frames
+// have no target, that is, the UID is empty and there is no default remote write endpoint.
+func (w *DatasourceWriter) prepare(ctx context.Context, dsUID string, name string, metadata ngmodels.RecordMetadata, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) (*PrometheusWriter, []Point, error) {
+	wr := w.defaultWriter
+	if dsUID != "" {
+		var err error
+		if wr, err = w.writerFor(ctx, orgID, dsUID); err != nil {
+			return nil, nil, err
+		}
+	} else if wr == nil {
+		return nil, nil, nil
+	}
+
+	points, err := PointsFromFrames(name, t, frames, extraLabels)
+	if err != nil {
+		return nil, nil, errors.Join(ErrBadFrame, err)
+	}
+	for i := range points {
+		points[i].Metadata = metadata
+	}
+	return wr, points, nil
+}
+
+// writerFor returns the writer of the data source. Writers are cached until the data source is updated, which is
+// checked at most every datasourceCheckInterval, so that writes do not query the data source from the database.
+func (w *DatasourceWriter) writerFor(ctx context.Context, orgID int64, dsUID string) (*PrometheusWriter, error) {
+	key := datasourceKey{orgID: orgID, uid: dsUID}
+	w.mtx.Lock()
+	cached, ok := w.writers[key]
+	w.mtx.Unlock()
+	if ok && w.clock.Since(cached.checkedAt) < datasourceCheckInterval {
+		return cached.writer, nil
+	}
+
+	ds, err := w.datasources.GetDataSource(ctx, &datasources.GetDataSourceQuery{UID: dsUID, OrgID: orgID})
+	if err != nil {
+		if errors.Is(err, datasources.ErrDataSourceNotFound) {
+			return nil, errors.Join(ErrInvalidTarget, err)
+		}
+		return nil, errors.Join(ErrUnexpectedWriteFailure, fmt.Errorf("failed to get target data source: %w", err))
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if cached, ok := w.writers[key]; ok && cached.version == ds.Version {
+		cached.checkedAt = w.clock.Now()
+		w.writers[key] = cached
+		return cached.writer, nil
+	}
+
+	wr, err := w.newWriter(ctx, ds)
+	if err != nil {
+		return nil, err
+	}
+	w.writers[key] = datasourceWriter{version: ds.Version, writer: wr, checkedAt: w.clock.Now()}
+	w.logger.FromContext(ctx).Debug("Created writer of target data source", "datasource_uid", dsUID, "version", ds.Version)
+	return wr, nil
+}
+
+func (w *DatasourceWriter) newWriter(ctx context.Context, ds *datasources.DataSource) (*PrometheusWriter, error) {
+	if ds.Type != datasources.DS_PROMETHEUS {
+		return nil, fmt.Errorf("%w: data source %s of type %s is not Prometheus-compatible", ErrInvalidTarget, ds.UID, ds.Type)
+	}
+	writeURL, err := remoteWriteURL(ds)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTarget, err)
+	}
+
+	// The transport applies the authentication and custom headers of the data source.
+	transport, err := w.datasources.GetHTTPTransport(ctx, ds, w.httpClientProvider)
+	if err != nil {
+		return nil, errors.Join(ErrUnexpectedWriteFailure, fmt.Errorf("failed to create transport of target data source: %w", err))
+	}
+	client, err := newRemoteWriteClient(writeURL, w.timeout, &http.Client{Transport: transport})
+	if err != nil {
+		return nil, errors.Join(ErrUnexpectedWriteFailure, err)
+	}
+
+	wal, err := w.walFor(datasourceKey{orgID: ds.OrgID, uid: ds.UID})
+	if err != nil {
+		return nil, errors.Join(ErrUnexpectedWriteFailure, err)
+	}
+
+	wr := &PrometheusWriter{
+		client:  client,
+		clock:   w.clock,
+		logger:  w.logger.New("datasource_uid", ds.UID),
+		metrics: w.metrics,
+		wal:     wal,
+	}
+	if w.protocol == RemoteWriteProtocolV2 {
+		wr.clientV2 = newRemoteWriteV2Client(writeURL, w.timeout, &http.Client{Transport: transport})
+	}
+	if w.batchCfg != nil {
+		wr.enableBatching(*w.batchCfg)
+	}
+	return wr, nil
+}
+
+// walFor returns the write-ahead log of the data source, or nil if the write-ahead log is disabled. Logs outlive the
+// writers, which are recreated when the data source is updated. It must be called with the lock held.
+func (w *DatasourceWriter) walFor(key datasourceKey) (*WAL, error) {
+	if w.walCfg == nil {
+		return nil, nil
+	}
+	if wal, ok := w.wals[key]; ok {
+		return wal, nil
+	}
+	target := walTarget(key)
+	wal, err := OpenWAL(filepath.Join(w.walCfg.Dir, target), key.uid, *w.walCfg, w.clock, w.logger, w.metrics)
+	if err != nil {
+		return nil, err
+	}
+	w.wals[key] = wal
+	return wal, nil
+}
+
+const defaultWALTarget = "default"
+
+// walTarget returns the name of the directory of the write-ahead log of the data source.
+func walTarget(key datasourceKey) string {
+	return fmt.Sprintf("%d-%s", key.orgID, key.uid)
+}
+
+func parseWALTarget(name string) (datasourceKey, bool) {
+	org, uid, ok := strings.Cut(name, "-")
+	if !ok || uid == "" {
+		return datasourceKey{}, false
+	}
+	orgID, err := strconv.ParseInt(org, 10, 64)
+	if err != nil {
+		return datasourceKey{}, false
+	}
+	return datasourceKey{orgID: orgID, uid: uid}, true
+}
+
+// remoteWriteURL returns the remote write endpoint of a Prometheus data source.
+// Mimir and Cortex accept writes at /api/v1/push of the root of their API, while their Prometheus API is usually
+// served under /prometheus. Prometheus accepts writes at /api/v1/write.
+func remoteWriteURL(ds *datasources.DataSource) (string, error) {
+	u, err := url.Parse(ds.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL of data source %s: %w", ds.UID, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid URL of data source %s: scheme and host are required", ds.UID)
+	}
+
+	var prometheusType string
+	if ds.JsonData != nil {
+		prometheusType = ds.JsonData.Get("prometheusType").MustString()
+	}
+	switch prometheusType {
+	case "Mimir", "Cortex":
+		u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/prometheus")
+		return u.JoinPath("api/v1/push").String(), nil
+	default:
+		return u.JoinPath("api/v1/write").String(), nil
+	}
+}
@@ -0,0 +1,149 @@
+package writer
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	sdkhttpclient "github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/httpclient"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestRemoteWriteURL(t *testing.T) {
+	testCases := []struct {
+		name           string
+		url            string
+		prometheusType string
+		expected       string
+		expectedErr    bool
+	}{
+		{name: "prometheus", url: "http://prometheus:9090", expected: "http://prometheus:9090/api/v1/write"},
+		{name: "prometheus with path", url: "http://proxy/prom/", prometheusType: "Prometheus", expected: "http://proxy/prom/api/v1/write"},
+		{name: "mimir", url: "http://mimir:8080/prometheus", prometheusType: "Mimir", expected: "http://mimir:8080/api/v1/push"},
+		{name: "cortex without prometheus prefix", url: "http://cortex:8080/", prometheusType: "Cortex", expected: "http://cortex:8080/api/v1/push"},
+		{name: "missing host", url: "/prometheus", expectedErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ds := &datasources.DataSource{UID: "test", URL: tc.url}
+			if tc.prometheusType != "" {
+				ds.JsonData = simplejson.NewFromAny(map[string]any{"prometheusType": tc.prometheusType})
+			}
+
+			u, err := remoteWriteURL(ds)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, u)
+		})
+	}
+}
+
+func TestDatasourceWriter_WriteDatasource(t *testing.T) {
+	target := NewTestRemoteWriteTarget(t)
+	defer target.Close()
+
+	ds := &datasources.DataSource{UID: "target", OrgID: 1, Type: datasources.DS_PROMETHEUS, URL: target.srv.URL, Version: 1}
+	dsService := &fakeDatasourceService{datasources: []*datasources.DataSource{ds}}
+	clk := clock.NewMock()
+	writer := NewDatasourceWriter(
+		setting.RecordingRuleSettings{Timeout: time.Second},
+		nil,
+		dsService,
+		nil,
+		clk,
+		log.NewNopLogger(),
+		metrics.NewRemoteWriterMetrics(prometheus.NewRegistry()),
+	)
+	frames := frameGenFromLabels(t, data.FrameTypeNumericWide, []map[string]string{{"foo": "1"}})
+	ctx := context.Background()
+
+	t.Run("does not write rules without target if there is no default writer", func(t *testing.T) {
+		target.Reset()
+
+		err := writer.WriteDatasource(ctx, "", "test", ngmodels.RecordMetadata{}, time.Now(), frames, 1, nil)
+		require.NoError(t, err)
+		require.Zero(t, target.RequestsCount)
+	})
+
+	t.Run("writes to target data source and caches its writer", func(t *testing.T) {
+		target.Reset()
+
+		require.NoError(t, writer.WriteDatasource(ctx, "target", "test", ngmodels.RecordMetadata{}, time.Now(), frames, 1, nil))
+		require.NoError(t, writer.WriteDatasource(ctx, "target", "test", ngmodels.RecordMetadata{}, time.Now(), frames, 1, nil))
+		require.Equal(t, 2, target.RequestsCount)
+		require.Equal(t, 1, dsService.transports)
+		require.Equal(t, 1, dsService.gets)
+	})
+
+	t.Run("recreates writer when data source is updated", func(t *testing.T) {
+		ds.Version++
+
+		require.NoError(t, writer.WriteDatasource(ctx, "target", "test", ngmodels.RecordMetadata{}, time.Now(), frames, 1, nil))
+		require.Equal(t, 1, dsService.transports)
+
+		clk.Add(datasourceCheckInterval)
+		require.NoError(t, writer.WriteDatasource(ctx, "target", "test", ngmodels.RecordMetadata{}, time.Now(), frames, 1, nil))
+		require.Equal(t, 2, dsService.transports)
+		require.Equal(t, 2, dsService.gets)
+	})
+
+	t.Run("returns error when target does not exist", func(t *testing.T) {
+		err := writer.WriteDatasource(ctx, "missing", "test", ngmodels.RecordMetadata{}, time.Now(), frames, 1, nil)
+		require.ErrorIs(t, err, ErrInvalidTarget)
+	})
+
+	t.Run("returns error when target is not a Prometheus data source", func(t *testing.T) {
+		dsService.datasources = append(dsService.datasources, &datasources.DataSource{UID: "loki", OrgID: 1, Type: datasources.DS_LOKI, URL: target.srv.URL})
+
+		err := writer.WriteDatasource(ctx, "loki", "test", ngmodels.RecordMetadata{}, time.Now(), frames, 1, nil)
+		require.ErrorIs(t, err, ErrInvalidTarget)
+	})
+
+	t.Run("writes directly to target data source", func(t *testing.T) {
+		target.Reset()
+
+		require.NoError(t, writer.WriteDatasourceDirect(ctx, "target", "test", ngmodels.RecordMetadata{}, time.Now(), frames, 1, nil))
+		require.Equal(t, 1, target.RequestsCount)
+	})
+
+	t.Run("returns error of direct write when target does not exist", func(t *testing.T) {
+		err := writer.WriteDatasourceDirect(ctx, "missing", "test", ngmodels.RecordMetadata{}, time.Now(), frames, 1, nil)
+		require.ErrorIs(t, err, ErrInvalidTarget)
+	})
+}
+
+type fakeDatasourceService struct {
+	datasources []*datasources.DataSource
+	gets        int
+	transports  int
+}
+
+func (f *fakeDatasourceService) GetDataSource(_ context.Context, query *datasources.GetDataSourceQuery) (*datasources.DataSource, error) {
+	f.gets++
+	for _, ds := range f.datasources {
+		if ds.UID == query.UID && ds.OrgID == query.OrgID {
+			return ds, nil
+		}
+	}
+	return nil, datasources.ErrDataSourceNotFound
+}
+
+func (f *fakeDatasourceService) GetHTTPTransport(_ context.Context, _ *datasources.DataSource, _ httpclient.Provider, _ ...sdkhttpclient.Middleware) (http.RoundTripper, error) {
+	f.transports++
+	return http.DefaultTransport, nil
+}
@@ -5,16 +5,23 @@ import (
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
 )
 
 type FakeWriter struct {
-	WriteFunc func(ctx context.Context, name string, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error
+	WriteFunc func(ctx context.Context, dsUID string, name string, metadata ngmodels.RecordMetadata, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error
 }
 
-func (w FakeWriter) Write(ctx context.Context, name string, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
+func (w FakeWriter) WriteDatasource(ctx context.Context, dsUID string, name string, metadata ngmodels.RecordMetadata, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
 	if w.WriteFunc == nil {
 		return nil
 	}
 
-	return w.WriteFunc(ctx, name, t, frames, orgID, extraLabels)
+	return w.WriteFunc(ctx, dsUID, name, metadata, t, frames, orgID, extraLabels)
+}
+
+// WriteDatasourceDirect calls WriteFunc, the same way as WriteDatasource.
+func (w FakeWriter) WriteDatasourceDirect(ctx context.Context, dsUID string, name string, metadata ngmodels.RecordMetadata, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
+	return w.WriteDatasource(ctx, dsUID, name, metadata, t, frames, orgID, extraLabels)
 }
@@ -0,0 +1,283 @@
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/prometheus/model/histogram"
+)
+
+const (
+	// Fields of heatmap cells frames, in which every row is a bucket.
+	histogramLowerField = "yMin"
+	histogramUpperField = "yMax"
+	histogramCountField = "count"
+
+	// Boundaries of buckets are compared with this relative tolerance, since they are floats computed by the data source.
+	histogramTolerance = 1e-9
+)
+
+type histogramBucket struct {
+	lower, upper, count float64
+}
+
+func isHistogramFrames(frames data.Frames) bool {
+	if len(frames) == 0 {
+		return false
+	}
+	for _, f := range frames {
+		if f.Meta == nil || f.Meta.Type != data.FrameTypeHeatmapCells {
+			return false
+		}
+	}
+	return true
+}
+
+// histogramPointsFromFrames returns a point with a native histogram for every frame. The sum of observations is not
+// part of the frames, so it is NaN, and histogram_sum and histogram_avg return NaN for the recorded series.
+func histogramPointsFromFrames(name string, t time.Time, frames data.Frames, extraLabels map[string]string) ([]Point, error) {
+	points := make([]Point, 0, len(frames))
+	for _, frame := range frames {
+		buckets, labels, err := histogramBucketsFromFrame(frame)
+		if err != nil {
+			return nil, err
+		}
+		h, err := histogramFromBuckets(buckets)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, Point{
+			Name:   name,
+			Labels: pointLabels(labels, extraLabels),
+			Metric: Metric{T: t, H: h},
+		})
+	}
+	return points, nil
+}
+
+// histogramBucketsFromFrame returns the buckets of the frame. If the frame has a time field with several timestamps,
+// only the buckets at the latest one are returned.
+func histogramBucketsFromFrame(frame *data.Frame) ([]histogramBucket, data.Labels, error) {
+	lower, _ := frame.FieldByName(histogramLowerField)
+	upper, _ := frame.FieldByName(histogramUpperField)
+	count, _ := frame.FieldByName(histogramCountField)
+	if lower == nil || upper == nil || count == nil {
+		return nil, nil, fmt.Errorf("histogram frame must have fields %s, %s and %s", histogramLowerField, histogramUpperField, histogramCountField)
+	}
+	if count.Len() == 0 {
+		return nil, nil, fmt.Errorf("empty frame")
+	}
+
+	var times *data.Field
+	var latest time.Time
+	for _, f := range frame.Fields {
+		if f.Type() != data.FieldTypeTime && f.Type() != data.FieldTypeNullableTime {
+			continue
+		}
+		times = f
+		for i := 0; i < f.Len(); i++ {
+			if ts, ok := f.ConcreteAt(i); ok && ts.(time.Time).After(latest) {
+				latest = ts.(time.Time)
+			}
+		}
+		break
+	}
+
+	buckets := make([]histogramBucket, 0, count.Len())
+	for i := 0; i < count.Len(); i++ {
+		if times != nil {
+			if ts, ok := times.ConcreteAt(i); !ok || !ts.(time.Time).Equal(latest) {
+				continue
+			}
+		}
+		var b histogramBucket
+		var err error
+		if b.lower, err = lower.FloatAt(i); err != nil {
+			return nil, nil, fmt.Errorf("unable to read lower bound of bucket: %w", err)
+		}
+		if b.upper, err = upper.FloatAt(i); err != nil {
+			return nil, nil, fmt.Errorf("unable to read upper bound of bucket: %w", err)
+		}
+		if b.count, err = count.FloatAt(i); err != nil {
+			return nil, nil, fmt.Errorf("unable to read count of bucket: %w", err)
+		}
+		if b.count != 0 {
+			buckets = append(buckets, b)
+		}
+	}
+
+	labels := count.Labels
+	if len(labels) == 0 {
+		labels = upper.Labels
+	}
+	return buckets, labels, nil
+}
+
+// histogramFromBuckets returns a native histogram with the buckets. If the boundaries of the buckets follow an
+// exponential schema, the histogram has that schema, like the histogram the buckets were read from.
+// Otherwise, it is a histogram with custom buckets.
+func histogramFromBuckets(buckets []histogramBucket) (*histogram.FloatHistogram, error) {
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].lower < buckets[j].lower
+	})
+	if h, ok := exponentialHistogram(buckets); ok {
+		return h, nil
+	}
+	return customBucketsHistogram(buckets)
+}
+
+// exponentialHistogram returns a histogram with an exponential schema, or false if the buckets do not fit one.
+// A bucket that contains zero is the zero bucket. Its threshold must not be above the lower bound of any other
+// bucket, because the zero bucket of a native histogram covers [-threshold, threshold].
+func exponentialHistogram(buckets []histogramBucket) (*histogram.FloatHistogram, bool) {
+	h := &histogram.FloatHistogram{Sum: math.NaN()}
+	schema := int32(math.MinInt32)
+	var positive, negative []bucketIndex
+	// closest is the smallest distance from zero of the bounds of buckets other than the zero bucket.
+	closest := math.Inf(1)
+	for _, b := range buckets {
+		h.Count += b.count
+		switch {
+		case b.lower <= 0 && b.upper >= 0:
+			if h.ZeroCount != 0 || math.IsInf(b.upper, 0) || (b.lower != 0 && !floatEqual(-b.lower, b.upper)) {
+				return nil, false
+			}
+			h.ZeroThreshold = b.upper
+			h.ZeroCount = b.count
+			continue
+		case b.lower > 0:
+			s, idx, ok := exponentialBucket(b.lower, b.upper)
+			if !ok || (schema != math.MinInt32 && s != schema) {
+				return nil, false
+			}
+			schema = s
+			positive = append(positive, bucketIndex{idx: idx, count: b.count})
+			closest = math.Min(closest, b.lower)
+		default:
+			s, idx, ok := exponentialBucket(-b.upper, -b.lower)
+			if !ok || (schema != math.MinInt32 && s != schema) {
+				return nil, false
+			}
+			schema = s
+			negative = append(negative, bucketIndex{idx: idx, count: b.count})
+			closest = math.Min(closest, -b.upper)
+		}
+	}
+	if h.ZeroThreshold > closest && !floatEqual(h.ZeroThreshold, closest) {
+		return nil, false
+	}
+	if schema == math.MinInt32 {
+		// Only the zero bucket, or no buckets at all.
+		schema = 0
+	}
+	h.Schema = schema
+
+	var ok bool
+	if h.PositiveSpans, h.PositiveBuckets, ok = spansFromIndices(positive); !ok {
+		return nil, false
+	}
+	if h.NegativeSpans, h.NegativeBuckets, ok = spansFromIndices(negative); !ok {
+		return nil, false
+	}
+	return h, true
+}
+
+// exponentialBucket returns the schema and the index of the bucket (lower, upper] with 0 < lower < upper.
+// Buckets of schema s have the boundaries base^(i-1) and base^i, where base is 2^(2^-s).
+func exponentialBucket(lower, upper float64) (int32, int32, bool) {
+	if lower <= 0 || upper <= lower || math.IsInf(upper, 0) {
+		return 0, 0, false
+	}
+	s := -math.Log2(math.Log2(upper / lower))
+	schema := math.Round(s)
+	if math.Abs(s-schema) > 1e-6 || schema < -4 || schema > 8 {
+		return 0, 0, false
+	}
+	i := math.Log2(upper) * math.Exp2(schema)
+	idx := math.Round(i)
+	if math.Abs(i-idx) > 1e-6 {
+		return 0, 0, false
+	}
+	return int32(schema), int32(idx), true
+}
+
+func customBucketsHistogram(buckets []histogramBucket) (*histogram.FloatHistogram, error) {
+	h := &histogram.FloatHistogram{Schema: histogram.CustomBucketsSchema, Sum: math.NaN()}
+
+	// Custom values are the upper bounds of all buckets but the last, which is unbounded. The boundaries of buckets
+	// that have no observations, and are thus not in the frame, are the bounds of their neighbours.
+	var bounds []float64
+	for _, b := range buckets {
+		for _, v := range []float64{b.lower, b.upper} {
+			if !math.IsInf(v, 0) {
+				bounds = append(bounds, v)
+			}
+		}
+	}
+	slices.Sort(bounds)
+	bounds = slices.CompactFunc(bounds, floatEqual)
+	h.CustomValues = bounds
+
+	indices := make([]bucketIndex, 0, len(buckets))
+	for _, b := range buckets {
+		idx := len(bounds)
+		if !math.IsInf(b.upper, 1) {
+			idx = slices.IndexFunc(bounds, func(v float64) bool { return floatEqual(v, b.upper) })
+		}
+		// The bucket must cover exactly the range between its bound and the previous one.
+		if (idx > 0 && !floatEqual(b.lower, bounds[idx-1])) || (idx == 0 && !math.IsInf(b.lower, -1)) {
+			return nil, errors.New("histogram buckets overlap")
+		}
+		indices = append(indices, bucketIndex{idx: int32(idx), count: b.count})
+		h.Count += b.count
+	}
+
+	var ok bool
+	if h.PositiveSpans, h.PositiveBuckets, ok = spansFromIndices(indices); !ok {
+		return nil, errors.New("histogram buckets overlap")
+	}
+	return h, nil
+}
+
+type bucketIndex struct {
+	idx   int32
+	count float64
+}
+
+// spansFromIndices returns the spans and counts of buckets, given by their index. It returns false if several
+// buckets have the same index.
+func spansFromIndices(indices []bucketIndex) ([]histogram.Span, []float64, bool) {
+	if len(indices) == 0 {
+		return nil, nil, true
+	}
+	sort.Slice(indices, func(i, j int) bool {
+		return indices[i].idx < indices[j].idx
+	})
+	var spans []histogram.Span
+	counts := make([]float64, 0, len(indices))
+	for i, b := range indices {
+		switch {
+		case i == 0:
+			spans = append(spans, histogram.Span{Offset: b.idx})
+		case b.idx == indices[i-1].idx:
+			return nil, nil, false
+		case b.idx > indices[i-1].idx+1:
+			spans = append(spans, histogram.Span{Offset: b.idx - indices[i-1].idx - 1})
+		}
+		spans[len(spans)-1].Length++
+		counts = append(counts, b.count)
+	}
+	return spans, counts, true
+}
+
+func floatEqual(a, b float64) bool {
+	if a == b || math.IsInf(a, 0) || math.IsInf(b, 0) {
+		return a == b
+	}
+	return math.Abs(a-b) <= histogramTolerance*math.Max(math.Abs(a), math.Abs(b))
+}
@@ -0,0 +1,124 @@
+package writer
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPointsFromFrames_Histogram(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Minute)
+	frame := data.NewFrame("",
+		data.NewField("xMax", nil, []time.Time{earlier, now, now, now, now, now, now}),
+		data.NewField("yMin", nil, []float64{0.5, -0.001, -2, 0.5, 1, 2, 4}),
+		data.NewField("yMax", nil, []float64{1, 0.001, -1, 1, 2, 4, 8}),
+		data.NewField("count", data.Labels{"job": "api", "__name__": "latency"}, []float64{100, 4, 5, 1, 2, 0, 3}),
+	)
+	frame.SetMeta(&data.FrameMeta{Type: data.FrameTypeHeatmapCells})
+
+	points, err := PointsFromFrames("test", now, data.Frames{frame}, map[string]string{"extra": "label"})
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	require.Equal(t, "test", points[0].Name)
+	require.Equal(t, map[string]string{"job": "api", "extra": "label"}, points[0].Labels)
+	require.Equal(t, now, points[0].Metric.T)
+
+	// Buckets at earlier timestamps and empty buckets are ignored.
+	h := points[0].Metric.H
+	require.NotNil(t, h)
+	require.True(t, math.IsNaN(h.Sum))
+	h.Sum = 0
+	require.Equal(t, &histogram.FloatHistogram{
+		Schema:          0,
+		ZeroThreshold:   0.001,
+		ZeroCount:       4,
+		Count:           15,
+		PositiveSpans:   []histogram.Span{{Offset: 0, Length: 2}, {Offset: 1, Length: 1}},
+		PositiveBuckets: []float64{1, 2, 3},
+		NegativeSpans:   []histogram.Span{{Offset: 1, Length: 1}},
+		NegativeBuckets: []float64{5},
+	}, h)
+}
+
+func TestExponentialBucket(t *testing.T) {
+	for _, schema := range []int32{-4, 0, 3, 8} {
+		for _, idx := range []int32{-5, 1, 10} {
+			lower := math.Exp2(float64(idx-1) * math.Exp2(-float64(schema)))
+			upper := math.Exp2(float64(idx) * math.Exp2(-float64(schema)))
+
+			s, i, ok := exponentialBucket(lower, upper)
+			require.True(t, ok, "schema %d, index %d", schema, idx)
+			require.Equal(t, schema, s)
+			require.Equal(t, idx, i)
+		}
+	}
+
+	_, _, ok := exponentialBucket(0.1, 0.25)
+	require.False(t, ok)
+}
+
+func TestHistogramFromBuckets(t *testing.T) {
+	t.Run("custom buckets if boundaries are not exponential", func(t *testing.T) {
+		h, err := histogramFromBuckets([]histogramBucket{
+			{lower: 1, upper: math.Inf(1), count: 4},
+			{lower: math.Inf(-1), upper: 0.1, count: 1},
+			{lower: 0.1, upper: 0.25, count: 2},
+			{lower: 0.5, upper: 1, count: 3},
+		})
+		require.NoError(t, err)
+		h.Sum = 0
+		require.Equal(t, &histogram.FloatHistogram{
+			Schema:          histogram.CustomBucketsSchema,
+			Count:           10,
+			PositiveSpans:   []histogram.Span{{Offset: 0, Length: 2}, {Offset: 1, Length: 2}},
+			PositiveBuckets: []float64{1, 2, 3, 4},
+			CustomValues:    []float64{0.1, 0.25, 0.5, 1},
+		}, h)
+	})
+
+	t.Run("zero bucket up to the first positive bucket", func(t *testing.T) {
+		h, err := histogramFromBuckets([]histogramBucket{
+			{lower: 0, upper: 0.5, count: 1},
+			{lower: 0.5, upper: 1, count: 2},
+		})
+		require.NoError(t, err)
+		h.Sum = 0
+		require.Equal(t, &histogram.FloatHistogram{
+			Schema:          0,
+			Count:           3,
+			ZeroThreshold:   0.5,
+			ZeroCount:       1,
+			PositiveSpans:   []histogram.Span{{Offset: 0, Length: 1}},
+			PositiveBuckets: []float64{2},
+		}, h)
+	})
+
+	t.Run("error if zero bucket overlaps positive buckets", func(t *testing.T) {
+		_, err := histogramFromBuckets([]histogramBucket{
+			{lower: 0, upper: 1, count: 1},
+			{lower: 0.5, upper: 1, count: 2},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("error if zero bucket overlaps negative buckets", func(t *testing.T) {
+		_, err := histogramFromBuckets([]histogramBucket{
+			{lower: -1, upper: -0.5, count: 2},
+			{lower: -1, upper: 1, count: 1},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("error if buckets overlap", func(t *testing.T) {
+		_, err := histogramFromBuckets([]histogramBucket{
+			{lower: 0.1, upper: 0.5, count: 1},
+			{lower: 0.25, upper: 1, count: 1},
+		})
+		require.Error(t, err)
+	})
+}
@@ -5,10 +5,12 @@ import (
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
 )
 
 type NoopWriter struct{}
 
-func (w NoopWriter) Write(ctx context.Context, name string, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
+func (w NoopWriter) WriteDatasource(ctx context.Context, dsUID string, name string, metadata ngmodels.RecordMetadata, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
 	return nil
 }
@@ -12,9 +12,11 @@ import (
 	"github.com/benbjohnson/clock"
 	"github.com/grafana/dataplane/sdata/numeric"
 	"github.com/m3db/prometheus_remote_client_golang/promremote"
+	"github.com/prometheus/prometheus/model/histogram"
 
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
 	"github.com/grafana/grafana/pkg/setting"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
@@ -52,6 +54,8 @@ var DuplicateTimestampErrors = [...]string{
 type Metric struct {
 	T time.Time
 	V float64
+	// H is the value of a native histogram. If set, V is ignored.
+	H *histogram.FloatHistogram
 }
 
 // Point is a logical representation of a single point in time for a Prometheus time series.
@@ -59,9 +63,17 @@ type Point struct {
 	Name   string
 	Labels map[string]string
 	Metric Metric
+	// Metadata describes the metric of the point. It is written along with the series.
+	Metadata ngmodels.RecordMetadata
 }
 
+// PointsFromFrames returns a point for every series of the frames. Heatmap cells frames, which Prometheus data
+// sources return for native histograms, are read as native histograms, and other frames as numeric values.
 func PointsFromFrames(name string, t time.Time, frames data.Frames, extraLabels map[string]string) ([]Point, error) {
+	if isHistogramFrames(frames) {
+		return histogramPointsFromFrames(name, t, frames, extraLabels)
+	}
+
 	cr, err := numeric.CollectionReaderFromFrames(frames)
 	if err != nil {
 		return nil, err
@@ -90,18 +102,9 @@ func PointsFromFrames(name string, t time.Time, frames data.Frames, extraLabels
 			V: *fp,
 		}
 
-		labels := ref.GetLabels().Copy()
-		if labels == nil {
-			labels = data.Labels{}
-		}
-		delete(labels, "__name__")
-		for k, v := range extraLabels {
-			labels[k] = v
-		}
-
 		points = append(points, Point{
 			Name:   name,
-			Labels: labels,
+			Labels: pointLabels(ref.GetLabels(), extraLabels),
 			Metric: metric,
 		})
 	}
@@ -109,6 +112,18 @@ func PointsFromFrames(name string, t time.Time, frames data.Frames, extraLabels
 	return points, nil
 }
 
+func pointLabels(lbls data.Labels, extraLabels map[string]string) map[string]string {
+	labels := lbls.Copy()
+	if labels == nil {
+		labels = data.Labels{}
+	}
+	delete(labels, "__name__")
+	for k, v := range extraLabels {
+		labels[k] = v
+	}
+	return labels
+}
+
 type HttpClientProvider interface {
 	New(options ...httpclient.Options) (*http.Client, error)
 }
@@ -118,6 +133,12 @@ type PrometheusWriter struct {
 	clock   clock.Clock
 	logger  log.Logger
 	metrics *metrics.RemoteWriter
+	// clientV2 writes with the remote write 2.0 protocol. If nil, client is used.
+	clientV2 *remoteWriteV2Client
+	// wal buffers batches that failed to be written. If nil, failed batches are not retried.
+	wal *WAL
+	// batcher coalesces writes of many rules into fewer requests. If nil, every write is a request.
+	batcher *batcher
 }
 
 func NewPrometheusWriter(
@@ -144,24 +165,39 @@ func NewPrometheusWriter(
 		return nil, err
 	}
 
-	clientCfg := promremote.NewConfig(
-		promremote.UserAgent("grafana-recording-rule"),
-		promremote.WriteURLOption(settings.URL),
-		promremote.HTTPClientTimeoutOption(settings.Timeout),
-		promremote.HTTPClientOption(cl),
-	)
-
-	client, err := promremote.NewClient(clientCfg)
+	client, err := newRemoteWriteClient(settings.URL, settings.Timeout, cl)
 	if err != nil {
 		return nil, err
 	}
 
-	return &PrometheusWriter{
+	w := &PrometheusWriter{
 		client:  client,
 		clock:   clock,
 		logger:  l,
 		metrics: metrics,
-	}, nil
+	}
+	if settings.RemoteWriteProtocol == RemoteWriteProtocolV2 {
+		w.clientV2 = newRemoteWriteV2Client(settings.URL, settings.Timeout, cl)
+	}
+	return w, nil
+}
+
+// enableBatching makes the writer coalesce writes of many rules into fewer requests.
+func (w *PrometheusWriter) enableBatching(cfg BatchConfig) {
+	// The batcher writes through the writer as it is when the batch is written, e.g. with its write-ahead log.
+	w.batcher = newBatcher(cfg, func(ctx context.Context, points []Point, orgID int64) error {
+		return w.writePoints(ctx, points, orgID)
+	}, w.clock, w.logger, w.metrics)
+}
+
+func newRemoteWriteClient(writeURL string, timeout time.Duration, cl *http.Client) (promremote.Client, error) {
+	clientCfg := promremote.NewConfig(
+		promremote.UserAgent("grafana-recording-rule"),
+		promremote.WriteURLOption(writeURL),
+		promremote.HTTPClientTimeoutOption(timeout),
+		promremote.HTTPClientOption(cl),
+	)
+	return promremote.NewClient(clientCfg)
 }
 
 func validateSettings(settings setting.RecordingRuleSettings) error {
@@ -177,6 +213,12 @@ func validateSettings(settings setting.RecordingRuleSettings) error {
 		return fmt.Errorf("timeout must be greater than 0")
 	}
 
+	switch settings.RemoteWriteProtocol {
+	case "", RemoteWriteProtocolV1, RemoteWriteProtocolV2:
+	default:
+		return fmt.Errorf("unsupported remote write protocol %q, must be %s or %s", settings.RemoteWriteProtocol, RemoteWriteProtocolV1, RemoteWriteProtocolV2)
+	}
+
 	return nil
 }
 
@@ -194,28 +236,60 @@ func createAuthOpts(username, password string) *httpclient.BasicAuthOptions {
 
 // Write writes the given frames to the Prometheus remote write endpoint.
 func (w PrometheusWriter) Write(ctx context.Context, name string, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
-	l := w.logger.FromContext(ctx)
-	lvs := []string{fmt.Sprint(orgID), backendType}
-
 	points, err := PointsFromFrames(name, t, frames, extraLabels)
 	if err != nil {
 		return errors.Join(ErrBadFrame, err)
 	}
 
-	series := make([]promremote.TimeSeries, 0, len(points))
-	for _, p := range points {
-		series = append(series, promremote.TimeSeries{
-			Labels: promremoteLabelsFromPoint(p),
-			Datapoint: promremote.Datapoint{
-				Timestamp: p.Metric.T,
-				Value:     p.Metric.V,
-			},
-		})
+	w.logger.FromContext(ctx).Debug("Writing metric", "name", name)
+	return w.WritePoints(ctx, points, orgID)
+}
+
+// WritePoints writes the given points to the Prometheus remote write endpoint. Points can belong to different metrics.
+// If the writer batches writes, the points are written together with points of other rules.
+func (w PrometheusWriter) WritePoints(ctx context.Context, points []Point, orgID int64) error {
+	if w.batcher != nil {
+		return w.batcher.WritePoints(ctx, points, orgID)
+	}
+	return w.writePoints(ctx, points, orgID)
+}
+
+// writePoints writes the points in one request. If the writer has a write-ahead log, points that fail to be written
+// because of an unexpected error are buffered in it and replayed later, and the write does not fail.
+func (w PrometheusWriter) writePoints(ctx context.Context, points []Point, orgID int64) error {
+	if w.wal == nil {
+		return w.send(ctx, points, orgID)
+	}
+
+	// Samples of a series must be written in order, so points are not written before the buffered ones.
+	if w.wal.Len() > 0 {
+		return w.wal.Append(orgID, points)
+	}
+	err := w.send(ctx, points, orgID)
+	if err == nil || !errors.Is(err, ErrUnexpectedWriteFailure) {
+		return err
 	}
+	if walErr := w.wal.Append(orgID, points); walErr != nil {
+		return errors.Join(err, walErr)
+	}
+	w.logger.FromContext(ctx).Warn("Failed to write points, buffered them in write-ahead log", "error", err, "points", len(points))
+	return nil
+}
+
+// replayWAL writes the points buffered in the write-ahead log of the writer, if any.
+func (w PrometheusWriter) replayWAL(ctx context.Context) {
+	if w.wal == nil {
+		return
+	}
+	w.wal.replay(ctx, w.send)
+}
+
+func (w PrometheusWriter) send(ctx context.Context, points []Point, orgID int64) error {
+	l := w.logger.FromContext(ctx)
+	lvs := []string{fmt.Sprint(orgID), backendType}
 
-	l.Debug("Writing metric", "name", name)
 	writeStart := w.clock.Now()
-	res, writeErr := w.client.WriteTimeSeries(ctx, series, promremote.WriteOptions{})
+	res, writeErr := w.writeSeries(ctx, points)
 	w.metrics.WriteDuration.WithLabelValues(lvs...).Observe(w.clock.Now().Sub(writeStart).Seconds())
 
 	lvs = append(lvs, fmt.Sprint(res.StatusCode))
@@ -232,6 +306,29 @@ func (w PrometheusWriter) Write(ctx context.Context, name string, t time.Time, f
 	return nil
 }
 
+// writeSeries writes the points with the protocol of the writer. With the classic protocol, points that have native
+// histograms or metadata are written as protobuf, since time series of the client can only have float samples.
+func (w PrometheusWriter) writeSeries(ctx context.Context, points []Point) (promremote.WriteResult, promremote.WriteError) {
+	if w.clientV2 != nil {
+		return w.clientV2.Write(ctx, writeRequestV2(points))
+	}
+	if needsProtobuf(points) {
+		return w.client.WriteProto(ctx, writeRequestV1(points), promremote.WriteOptions{})
+	}
+
+	series := make([]promremote.TimeSeries, 0, len(points))
+	for _, p := range points {
+		series = append(series, promremote.TimeSeries{
+			Labels: promremoteLabelsFromPoint(p),
+			Datapoint: promremote.Datapoint{
+				Timestamp: p.Metric.T,
+				Value:     p.Metric.V,
+			},
+		})
+	}
+	return w.client.WriteTimeSeries(ctx, series, promremote.WriteOptions{})
+}
+
 func promremoteLabelsFromPoint(point Point) []promremote.Label {
 	labels := make([]promremote.Label, 0, len(point.Labels))
 	labels = append(labels, promremote.Label{
@@ -268,18 +365,9 @@ func checkWriteError(writeErr promremote.WriteError) (err error, ignored bool) {
 			}
 		}
 
-		// Check for expected user errors.
-		switch {
-		case strings.Contains(msg, MimirInvalidLabelError),
-			strings.Contains(msg, MimirMaxSeriesPerUserError),
-			strings.Contains(msg, MimirMaxLabelNamesPerSeriesError),
-			strings.Contains(msg, MimirLabelValueTooLongError):
-			return errors.Join(ErrRejectedWrite, writeErr), false
-		}
-
-		// For now, all 400s that are not previously known are considered unexpected.
-		// TODO: Consider blanket-converting all 400s to be known errors. This should only be done once we are confident this is not a problem with this client.
-		return errors.Join(ErrUnexpectedWriteFailure, writeErr), false
+		// The target rejected the data, e.g. because of invalid labels, limits, or samples that are out of order or
+		// too old. Writing the same data again fails the same way, so it must not be retried or buffered.
+		return errors.Join(ErrRejectedWrite, writeErr), false
 	}
 
 	// All other errors which do not fit into the above categories are also unexpected.
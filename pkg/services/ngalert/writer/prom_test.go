@@ -190,6 +190,29 @@ func TestPrometheusWriter_Write(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("writes points of different metrics", func(t *testing.T) {
+		points := []Point{
+			{Name: "first", Labels: map[string]string{"foo": "1"}, Metric: Metric{T: now, V: 1}},
+			{Name: "second", Labels: map[string]string{"foo": "2"}, Metric: Metric{T: now, V: 2}},
+		}
+		client.writeSeriesFunc = func(ctx context.Context, tslist promremote.TSList, opts promremote.WriteOptions) (promremote.WriteResult, promremote.WriteError) {
+			require.Len(t, tslist, len(points))
+			for i, ts := range tslist {
+				expectedLabels := []promremote.Label{
+					{Name: "__name__", Value: points[i].Name},
+					{Name: "foo", Value: points[i].Labels["foo"]},
+				}
+				require.ElementsMatch(t, expectedLabels, ts.Labels)
+				require.Equal(t, now, ts.Datapoint.Timestamp)
+				require.Equal(t, points[i].Metric.V, ts.Datapoint.Value)
+			}
+			return promremote.WriteResult{}, nil
+		}
+
+		err := writer.WritePoints(ctx, points, 1)
+		require.NoError(t, err)
+	})
+
 	t.Run("ignores client error when status code is 400 and message contains duplicate timestamp error", func(t *testing.T) {
 		for _, msg := range DuplicateTimestampErrors {
 			t.Run(msg, func(t *testing.T) {
@@ -255,6 +278,28 @@ func TestPrometheusWriter_Write(t *testing.T) {
 		require.ErrorIs(t, err, ErrRejectedWrite)
 	})
 
+	t.Run("out of order samples fit under the client error category", func(t *testing.T) {
+		for _, msg := range []string{
+			"out of order sample",
+			"the sample has been rejected because another sample with a more recent timestamp has already been ingested and out-of-order samples are not allowed (err-mimir-sample-out-of-order)",
+			"unexpected error",
+		} {
+			clientErr := testClientWriteError{
+				statusCode: http.StatusBadRequest,
+				msg:        &msg,
+			}
+			client.writeSeriesFunc = func(ctx context.Context, ts promremote.TSList, opts promremote.WriteOptions) (promremote.WriteResult, promremote.WriteError) {
+				return promremote.WriteResult{}, clientErr
+			}
+
+			err := writer.Write(ctx, "test", now, frames, 1, map[string]string{"extra": "label"})
+
+			require.Error(t, err)
+			require.ErrorIs(t, err, ErrRejectedWrite)
+			require.NotErrorIs(t, err, ErrUnexpectedWriteFailure)
+		}
+	})
+
 	t.Run("too many labels fit under the client error category", func(t *testing.T) {
 		msg := "received a series whose number of labels exceeds the limit (actual: 50, limit: 40) series: 'some_series' (err-mimir-max-label-names-per-series). To adjust the related per-tenant limit, configure -validation.max-label-names-per-series, or contact your service administrator."
 		clientErr := testClientWriteError{
@@ -436,6 +481,7 @@ func frameGenMulti(t *testing.T, labelSet []map[string]string) data.Frames {
 
 type testClient struct {
 	writeSeriesFunc func(ctx context.Context, ts promremote.TSList, opts promremote.WriteOptions) (promremote.WriteResult, promremote.WriteError)
+	writeProtoFunc  func(ctx context.Context, req *prompb.WriteRequest, opts promremote.WriteOptions) (promremote.WriteResult, promremote.WriteError)
 }
 
 func (c *testClient) WriteProto(
@@ -443,6 +489,10 @@ func (c *testClient) WriteProto(
 	req *prompb.WriteRequest,
 	opts promremote.WriteOptions,
 ) (promremote.WriteResult, promremote.WriteError) {
+	if c.writeProtoFunc != nil {
+		return c.writeProtoFunc(ctx, req, opts)
+	}
+
 	return promremote.WriteResult{}, nil
 }
 
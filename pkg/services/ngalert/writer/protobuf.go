@@ -0,0 +1,174 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/m3db/prometheus_remote_client_golang/promremote"
+	"github.com/prometheus/prometheus/prompb"
+	writev2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
+)
+
+const (
+	// RemoteWriteProtocolV1 is the classic Prometheus remote write protocol. It is used if no other is configured.
+	RemoteWriteProtocolV1 = "1.0"
+	// RemoteWriteProtocolV2 is the Prometheus remote write 2.0 protocol, which writes metadata along with every series.
+	RemoteWriteProtocolV2 = "2.0"
+)
+
+// needsProtobuf returns true if the points cannot be written as float time series of the client.
+func needsProtobuf(points []Point) bool {
+	for _, p := range points {
+		if p.Metric.H != nil || !p.Metadata.IsEmpty() {
+			return true
+		}
+	}
+	return false
+}
+
+// writeRequestV1 returns a remote write request of the classic protocol. Metadata is sent once per metric.
+func writeRequestV1(points []Point) *prompb.WriteRequest {
+	req := &prompb.WriteRequest{Timeseries: make([]prompb.TimeSeries, 0, len(points))}
+	withMetadata := make(map[string]struct{})
+	for _, p := range points {
+		ts := prompb.TimeSeries{}
+		for _, l := range sortedLabels(p) {
+			ts.Labels = append(ts.Labels, prompb.Label{Name: l[0], Value: l[1]})
+		}
+		t := p.Metric.T.UnixMilli()
+		if p.Metric.H != nil {
+			ts.Histograms = []prompb.Histogram{prompb.FromFloatHistogram(t, p.Metric.H)}
+		} else {
+			ts.Samples = []prompb.Sample{{Value: p.Metric.V, Timestamp: t}}
+		}
+		req.Timeseries = append(req.Timeseries, ts)
+
+		if _, ok := withMetadata[p.Name]; ok || p.Metadata.IsEmpty() {
+			continue
+		}
+		withMetadata[p.Name] = struct{}{}
+		req.Metadata = append(req.Metadata, prompb.MetricMetadata{
+			Type:             prompb.MetricMetadata_MetricType(prompb.MetricMetadata_MetricType_value[strings.ToUpper(p.Metadata.Type)]),
+			MetricFamilyName: p.Name,
+			Help:             p.Metadata.Help,
+			Unit:             p.Metadata.Unit,
+		})
+	}
+	return req
+}
+
+// writeRequestV2 returns a remote write request of the 2.0 protocol, in which strings are referenced from a symbol table.
+func writeRequestV2(points []Point) *writev2.Request {
+	symbols := writev2.NewSymbolTable()
+	series := make([]writev2.TimeSeries, 0, len(points))
+	for _, p := range points {
+		ts := writev2.TimeSeries{}
+		for _, l := range sortedLabels(p) {
+			ts.LabelsRefs = append(ts.LabelsRefs, symbols.Symbolize(l[0]), symbols.Symbolize(l[1]))
+		}
+		t := p.Metric.T.UnixMilli()
+		if p.Metric.H != nil {
+			ts.Histograms = []writev2.Histogram{writev2.FromFloatHistogram(t, p.Metric.H)}
+		} else {
+			ts.Samples = []writev2.Sample{{Value: p.Metric.V, Timestamp: t}}
+		}
+		ts.Metadata = writev2.Metadata{
+			Type:    writev2.Metadata_MetricType(writev2.Metadata_MetricType_value["METRIC_TYPE_"+strings.ToUpper(p.Metadata.Type)]),
+			HelpRef: symbols.Symbolize(p.Metadata.Help),
+			UnitRef: symbols.Symbolize(p.Metadata.Unit),
+		}
+		series = append(series, ts)
+	}
+	return &writev2.Request{Symbols: symbols.Symbols(), Timeseries: series}
+}
+
+// sortedLabels returns the name and value of the labels of the point including the metric name, sorted by name
+// as remote write requires.
+func sortedLabels(p Point) [][2]string {
+	labels := make([][2]string, 0, len(p.Labels)+1)
+	labels = append(labels, [2]string{"__name__", p.Name})
+	for k, v := range p.Labels {
+		labels = append(labels, [2]string{k, v})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i][0] < labels[j][0]
+	})
+	return labels
+}
+
+// remoteWriteV2Client writes requests of the remote write 2.0 protocol, which the client of the classic protocol
+// does not support.
+type remoteWriteV2Client struct {
+	url     string
+	timeout time.Duration
+	client  *http.Client
+}
+
+func newRemoteWriteV2Client(writeURL string, timeout time.Duration, cl *http.Client) *remoteWriteV2Client {
+	return &remoteWriteV2Client{
+		url:     writeURL,
+		timeout: timeout,
+		client:  cl,
+	}
+}
+
+func (c *remoteWriteV2Client) Write(ctx context.Context, req *writev2.Request) (promremote.WriteResult, promremote.WriteError) {
+	b, err := req.Marshal()
+	if err != nil {
+		return promremote.WriteResult{}, remoteWriteError{err: fmt.Errorf("failed to marshal request: %w", err)}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(snappy.Encode(nil, b)))
+	if err != nil {
+		return promremote.WriteResult{}, remoteWriteError{err: err}
+	}
+	httpReq.Header.Set("Content-Encoding", "snappy")
+	httpReq.Header.Set("Content-Type", "application/x-protobuf;proto=io.prometheus.write.v2.Request")
+	httpReq.Header.Set("User-Agent", "grafana-recording-rule")
+	httpReq.Header.Set("X-Prometheus-Remote-Write-Version", "2.0.0")
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return promremote.WriteResult{}, remoteWriteError{err: err}
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	result := promremote.WriteResult{StatusCode: resp.StatusCode}
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return result, nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return result, remoteWriteError{
+		statusCode: resp.StatusCode,
+		err:        fmt.Errorf("remote write failed with status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body))),
+	}
+}
+
+type remoteWriteError struct {
+	statusCode int
+	err        error
+}
+
+func (e remoteWriteError) StatusCode() int {
+	return e.statusCode
+}
+
+func (e remoteWriteError) Error() string {
+	return e.err.Error()
+}
+
+func (e remoteWriteError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,120 @@
+package writer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/golang/snappy"
+	"github.com/m3db/prometheus_remote_client_golang/promremote"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/prompb"
+	writev2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestPrometheusWriter_WriteProtobuf(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	metadata := ngmodels.RecordMetadata{Type: "histogram", Unit: "seconds", Help: "Request latency."}
+	h := &histogram.FloatHistogram{
+		Count:           3,
+		Sum:             1.5,
+		PositiveSpans:   []histogram.Span{{Offset: 0, Length: 2}},
+		PositiveBuckets: []float64{1, 2},
+	}
+	points := []Point{
+		{Name: "latency", Labels: map[string]string{"job": "api", "a": "b"}, Metric: Metric{T: now, H: h}, Metadata: metadata},
+		{Name: "latency", Labels: map[string]string{"job": "web"}, Metric: Metric{T: now, H: h}, Metadata: metadata},
+		{Name: "up", Labels: map[string]string{"job": "api"}, Metric: Metric{T: now, V: 1}},
+	}
+	ctx := context.Background()
+
+	t.Run("classic protocol", func(t *testing.T) {
+		var req *prompb.WriteRequest
+		client := &testClient{
+			writeSeriesFunc: func(context.Context, promremote.TSList, promremote.WriteOptions) (promremote.WriteResult, promremote.WriteError) {
+				require.Fail(t, "points with histograms must be written as protobuf")
+				return promremote.WriteResult{}, nil
+			},
+			writeProtoFunc: func(_ context.Context, r *prompb.WriteRequest, _ promremote.WriteOptions) (promremote.WriteResult, promremote.WriteError) {
+				req = r
+				return promremote.WriteResult{StatusCode: http.StatusOK}, nil
+			},
+		}
+		writer := &PrometheusWriter{
+			client:  client,
+			clock:   clock.New(),
+			logger:  log.NewNopLogger(),
+			metrics: metrics.NewRemoteWriterMetrics(prometheus.NewRegistry()),
+		}
+
+		require.NoError(t, writer.WritePoints(ctx, points, 1))
+		require.Len(t, req.Timeseries, 3)
+		require.Equal(t, []prompb.Label{{Name: "__name__", Value: "latency"}, {Name: "a", Value: "b"}, {Name: "job", Value: "api"}}, req.Timeseries[0].Labels)
+		require.Equal(t, []prompb.Histogram{prompb.FromFloatHistogram(now.UnixMilli(), h)}, req.Timeseries[0].Histograms)
+		require.Equal(t, []prompb.Sample{{Value: 1, Timestamp: now.UnixMilli()}}, req.Timeseries[2].Samples)
+		// Metadata is sent once per metric.
+		require.Equal(t, []prompb.MetricMetadata{{
+			Type:             prompb.MetricMetadata_HISTOGRAM,
+			MetricFamilyName: "latency",
+			Help:             "Request latency.",
+			Unit:             "seconds",
+		}}, req.Metadata)
+	})
+
+	t.Run("remote write 2.0", func(t *testing.T) {
+		var req writev2.Request
+		statusCode := http.StatusNoContent
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "2.0.0", r.Header.Get("X-Prometheus-Remote-Write-Version"))
+			require.Equal(t, "application/x-protobuf;proto=io.prometheus.write.v2.Request", r.Header.Get("Content-Type"))
+			require.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+			compressed, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			b, err := snappy.Decode(nil, compressed)
+			require.NoError(t, err)
+			require.NoError(t, req.Unmarshal(b))
+			w.WriteHeader(statusCode)
+			_, _ = w.Write([]byte("out of order sample"))
+		}))
+		defer srv.Close()
+
+		writer := &PrometheusWriter{
+			clientV2: newRemoteWriteV2Client(srv.URL+RemoteWriteEndpoint, time.Second, srv.Client()),
+			clock:    clock.New(),
+			logger:   log.NewNopLogger(),
+			metrics:  metrics.NewRemoteWriterMetrics(prometheus.NewRegistry()),
+		}
+
+		require.NoError(t, writer.WritePoints(ctx, points, 1))
+		require.Len(t, req.Timeseries, 3)
+		labels := func(ts writev2.TimeSeries) []string {
+			result := make([]string, 0, len(ts.LabelsRefs))
+			for _, ref := range ts.LabelsRefs {
+				result = append(result, req.Symbols[ref])
+			}
+			return result
+		}
+		require.Equal(t, []string{"__name__", "latency", "a", "b", "job", "api"}, labels(req.Timeseries[0]))
+		require.Equal(t, []writev2.Histogram{writev2.FromFloatHistogram(now.UnixMilli(), h)}, req.Timeseries[0].Histograms)
+		require.Equal(t, writev2.Metadata_METRIC_TYPE_HISTOGRAM, req.Timeseries[1].Metadata.Type)
+		require.Equal(t, "Request latency.", req.Symbols[req.Timeseries[1].Metadata.HelpRef])
+		require.Equal(t, "seconds", req.Symbols[req.Timeseries[1].Metadata.UnitRef])
+		require.Equal(t, []writev2.Sample{{Value: 1, Timestamp: now.UnixMilli()}}, req.Timeseries[2].Samples)
+		require.Equal(t, writev2.Metadata_METRIC_TYPE_UNSPECIFIED, req.Timeseries[2].Metadata.Type)
+
+		statusCode = http.StatusInternalServerError
+		err := writer.WritePoints(ctx, points, 1)
+		require.ErrorIs(t, err, ErrUnexpectedWriteFailure)
+		require.ErrorContains(t, err, "out of order sample")
+	})
+}
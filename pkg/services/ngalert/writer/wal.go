@@ -0,0 +1,383 @@
+package writer
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+)
+
+const (
+	// DefaultWALMaxAge is the maximum age of buffered samples if no other is configured.
+	// Prometheus and Mimir reject samples older than their head block by default, which covers about an hour.
+	DefaultWALMaxAge = time.Hour
+	// DefaultWALMaxSize is the maximum size in bytes of the write-ahead log of a target if no other is configured.
+	DefaultWALMaxSize = 256 << 20
+
+	walMinBackoff     = 5 * time.Second
+	walMaxBackoff     = 5 * time.Minute
+	walReplayInterval = 5 * time.Second
+
+	walBatchExt = ".batch"
+	walTmpExt   = ".tmp"
+
+	walDropReasonCorrupted = "corrupted"
+	walDropReasonFull      = "full"
+	walDropReasonRejected  = "rejected"
+	walDropReasonTooOld    = "too_old"
+)
+
+// ErrWALFull is returned when a batch does not fit into the write-ahead log.
+var ErrWALFull = errors.New("write-ahead log is full")
+
+// WALConfig configures the write-ahead log of the remote writers.
+type WALConfig struct {
+	// Dir is the directory that contains the write-ahead logs of all targets.
+	Dir string
+	// MaxAge is the maximum age of samples that are replayed. Older samples would be rejected by the target as out of
+	// bounds, so batches that contain them are dropped.
+	MaxAge time.Duration
+	// MaxSize is the maximum size in bytes of the write-ahead log of a target. Batches that do not fit are not buffered.
+	MaxSize int64
+}
+
+func (cfg WALConfig) withDefaults() WALConfig {
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = DefaultWALMaxAge
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = DefaultWALMaxSize
+	}
+	return cfg
+}
+
+// walBatchHeader is stored in front of the points of a batch, so the log can be opened without decoding all points.
+type walBatchHeader struct {
+	OrgID  int64
+	Oldest time.Time
+}
+
+type walBatch struct {
+	walBatchHeader
+	Points []Point
+}
+
+type walEntry struct {
+	seq    uint64
+	size   int64
+	oldest time.Time
+}
+
+// WAL is an on-disk queue of batches of points that failed to be written to a target. Every batch is stored in its
+// own file named after its sequence number, and batches are replayed in the order they were appended.
+// Batches can be appended concurrently, but only one goroutine may replay them.
+type WAL struct {
+	dir     string
+	target  string
+	maxAge  time.Duration
+	maxSize int64
+	clock   clock.Clock
+	logger  log.Logger
+	metrics *metrics.RemoteWriter
+
+	mtx        sync.Mutex
+	entries    []walEntry
+	size       int64
+	nextSeq    uint64
+	backoff    time.Duration
+	nextReplay time.Time
+}
+
+// OpenWAL opens the write-ahead log of the target in dir, creating the directory if it does not exist.
+// Batches left by a previous run are kept and replayed first.
+func OpenWAL(dir string, target string, cfg WALConfig, clock clock.Clock, l log.Logger, metrics *metrics.RemoteWriter) (*WAL, error) {
+	cfg = cfg.withDefaults()
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create write-ahead log directory: %w", err)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read write-ahead log directory: %w", err)
+	}
+
+	w := &WAL{
+		dir:     dir,
+		target:  target,
+		maxAge:  cfg.MaxAge,
+		maxSize: cfg.MaxSize,
+		clock:   clock,
+		logger:  l.New("target", target),
+		metrics: metrics,
+	}
+	for _, f := range files {
+		name := f.Name()
+		switch filepath.Ext(name) {
+		case walTmpExt:
+			// A batch that was not completely written before Grafana stopped.
+			_ = os.Remove(filepath.Join(dir, name))
+			continue
+		case walBatchExt:
+		default:
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, walBatchExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		entry, err := w.readEntry(seq)
+		if err != nil {
+			w.logger.Warn("Dropping corrupted batch from write-ahead log", "file", name, "error", err)
+			_ = os.Remove(w.path(seq))
+			w.metrics.WALDroppedBatchesTotal.WithLabelValues(w.target, walDropReasonCorrupted).Inc()
+			continue
+		}
+		w.entries = append(w.entries, entry)
+		w.size += entry.size
+	}
+	slices.SortFunc(w.entries, func(a, b walEntry) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+	if len(w.entries) > 0 {
+		w.nextSeq = w.entries[len(w.entries)-1].seq + 1
+		w.logger.Info("Opened write-ahead log with pending batches", "batches", len(w.entries), "size", w.size)
+	}
+	w.updateMetrics()
+	return w, nil
+}
+
+// Len returns the number of batches waiting to be replayed.
+func (w *WAL) Len() int {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return len(w.entries)
+}
+
+// Append stores the points at the end of the log. It returns ErrWALFull if the batch exceeds the maximum size of the log.
+func (w *WAL) Append(orgID int64, points []Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+	header := walBatchHeader{OrgID: orgID, Oldest: points[0].Metric.T}
+	for _, p := range points[1:] {
+		if p.Metric.T.Before(header.Oldest) {
+			header.Oldest = p.Metric.T
+		}
+	}
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	if err := enc.Encode(header); err != nil {
+		return fmt.Errorf("failed to encode batch: %w", err)
+	}
+	if err := enc.Encode(points); err != nil {
+		return fmt.Errorf("failed to encode batch: %w", err)
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	size := int64(buf.Len())
+	if w.size+size > w.maxSize {
+		w.metrics.WALDroppedBatchesTotal.WithLabelValues(w.target, walDropReasonFull).Inc()
+		return ErrWALFull
+	}
+	seq := w.nextSeq
+	if err := writeFileAtomic(w.path(seq), buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write batch to write-ahead log: %w", err)
+	}
+	w.nextSeq++
+	w.entries = append(w.entries, walEntry{seq: seq, size: size, oldest: header.Oldest})
+	w.size += size
+	w.updateMetrics()
+	return nil
+}
+
+// replay writes the buffered batches in order with send until the log is empty or a write fails with an error that
+// can be retried, in which case the next replay is delayed with exponential backoff.
+// Batches that are rejected by the target or contain samples older than the maximum age are dropped.
+func (w *WAL) replay(ctx context.Context, send func(ctx context.Context, points []Point, orgID int64) error) {
+	now := w.clock.Now()
+	w.mtx.Lock()
+	w.updateMetrics()
+	pending := len(w.entries) > 0 && !now.Before(w.nextReplay)
+	w.mtx.Unlock()
+	if !pending {
+		return
+	}
+
+	err := w.flush(ctx, send)
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if err == nil {
+		w.backoff = 0
+		w.nextReplay = time.Time{}
+		w.updateMetrics()
+		return
+	}
+	w.backoff = min(max(2*w.backoff, walMinBackoff), walMaxBackoff)
+	w.nextReplay = now.Add(w.backoff)
+	w.logger.Warn("Failed to replay write-ahead log", "error", err, "batches", len(w.entries), "backoff", w.backoff)
+	w.updateMetrics()
+}
+
+func (w *WAL) flush(ctx context.Context, send func(ctx context.Context, points []Point, orgID int64) error) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch, err := w.head()
+		if err != nil || batch == nil {
+			return err
+		}
+
+		if age := w.clock.Now().Sub(batch.Oldest); age > w.maxAge {
+			w.logger.Warn("Dropping batch with samples older than the maximum age", "age", age, "max_age", w.maxAge, "points", len(batch.Points))
+			w.removeHead(walDropReasonTooOld)
+			continue
+		}
+
+		if err := send(ctx, batch.Points, batch.OrgID); err != nil {
+			if errors.Is(err, ErrUnexpectedWriteFailure) {
+				return err
+			}
+			// Rejected batches fail the same way every time they are written.
+			w.logger.Error("Dropping batch rejected by the target", "error", err, "points", len(batch.Points))
+			w.removeHead(walDropReasonRejected)
+			continue
+		}
+		w.removeHead("")
+		w.metrics.WALReplayedBatchesTotal.WithLabelValues(w.target).Inc()
+	}
+}
+
+// head returns the first batch of the log, or nil if the log is empty. Corrupted batches are dropped.
+func (w *WAL) head() (*walBatch, error) {
+	for {
+		w.mtx.Lock()
+		if len(w.entries) == 0 {
+			w.mtx.Unlock()
+			return nil, nil
+		}
+		seq := w.entries[0].seq
+		w.mtx.Unlock()
+
+		batch, err := w.readBatch(seq)
+		if err == nil {
+			return batch, nil
+		}
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrPermission) {
+			return nil, fmt.Errorf("failed to read batch from write-ahead log: %w", err)
+		}
+		w.logger.Warn("Dropping corrupted batch from write-ahead log", "seq", seq, "error", err)
+		w.removeHead(walDropReasonCorrupted)
+	}
+}
+
+// removeHead deletes the first batch of the log. If reason is not empty, the batch is counted as dropped.
+func (w *WAL) removeHead(reason string) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if len(w.entries) == 0 {
+		return
+	}
+	entry := w.entries[0]
+	if err := os.Remove(w.path(entry.seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		w.logger.Error("Failed to delete batch from write-ahead log", "seq", entry.seq, "error", err)
+	}
+	w.entries = w.entries[1:]
+	w.size -= entry.size
+	if reason != "" {
+		w.metrics.WALDroppedBatchesTotal.WithLabelValues(w.target, reason).Inc()
+	}
+	w.updateMetrics()
+}
+
+// updateMetrics must be called with the lock held.
+func (w *WAL) updateMetrics() {
+	w.metrics.WALBatches.WithLabelValues(w.target).Set(float64(len(w.entries)))
+	w.metrics.WALSizeBytes.WithLabelValues(w.target).Set(float64(w.size))
+	var age float64
+	if len(w.entries) > 0 {
+		age = w.clock.Now().Sub(w.entries[0].oldest).Seconds()
+	}
+	w.metrics.WALOldestSampleAge.WithLabelValues(w.target).Set(age)
+}
+
+func (w *WAL) path(seq uint64) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%020d%s", seq, walBatchExt))
+}
+
+func (w *WAL) readEntry(seq uint64) (walEntry, error) {
+	f, err := os.Open(w.path(seq))
+	if err != nil {
+		return walEntry{}, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	info, err := f.Stat()
+	if err != nil {
+		return walEntry{}, err
+	}
+	var header walBatchHeader
+	if err := gob.NewDecoder(f).Decode(&header); err != nil {
+		return walEntry{}, err
+	}
+	return walEntry{seq: seq, size: info.Size(), oldest: header.Oldest}, nil
+}
+
+func (w *WAL) readBatch(seq uint64) (*walBatch, error) {
+	f, err := os.Open(w.path(seq))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	var batch walBatch
+	dec := gob.NewDecoder(f)
+	if err := dec.Decode(&batch.walBatchHeader); err != nil {
+		return nil, err
+	}
+	if err := dec.Decode(&batch.Points); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// writeFileAtomic writes the file through a temporary file, so a partially written batch is never read.
+func writeFileAtomic(path string, b []byte) error {
+	tmp := path + walTmpExt
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
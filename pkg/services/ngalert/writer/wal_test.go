@@ -0,0 +1,182 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/m3db/prometheus_remote_client_golang/promremote"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+)
+
+func TestWAL(t *testing.T) {
+	clk := clock.NewMock()
+	clk.Set(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	m := metrics.NewRemoteWriterMetrics(prometheus.NewRegistry())
+	point := func(name string, v float64) Point {
+		return Point{Name: name, Labels: map[string]string{"foo": "bar"}, Metric: Metric{T: clk.Now(), V: v}}
+	}
+
+	t.Run("keeps batches in order across restarts", func(t *testing.T) {
+		dir := t.TempDir()
+		wal, err := OpenWAL(dir, "test", WALConfig{}, clk, log.NewNopLogger(), m)
+		require.NoError(t, err)
+		require.NoError(t, wal.Append(1, []Point{point("first", 1)}))
+		require.NoError(t, wal.Append(2, []Point{point("second", math.NaN())}))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "00000000000000000002.batch.tmp"), []byte("partial"), 0o640))
+
+		wal, err = OpenWAL(dir, "test", WALConfig{}, clk, log.NewNopLogger(), m)
+		require.NoError(t, err)
+		require.Equal(t, 2, wal.Len())
+		require.NoFileExists(t, filepath.Join(dir, "00000000000000000002.batch.tmp"))
+		require.Equal(t, 2.0, testutil.ToFloat64(m.WALBatches.WithLabelValues("test")))
+
+		var written []Point
+		var orgs []int64
+		wal.replay(context.Background(), func(_ context.Context, points []Point, orgID int64) error {
+			written = append(written, points...)
+			orgs = append(orgs, orgID)
+			return nil
+		})
+		require.Equal(t, []int64{1, 2}, orgs)
+		require.Len(t, written, 2)
+		require.Equal(t, "first", written[0].Name)
+		require.Equal(t, map[string]string{"foo": "bar"}, written[0].Labels)
+		require.True(t, written[0].Metric.T.Equal(clk.Now()))
+		require.True(t, math.IsNaN(written[1].Metric.V))
+		require.Zero(t, wal.Len())
+		require.Equal(t, 2.0, testutil.ToFloat64(m.WALReplayedBatchesTotal.WithLabelValues("test")))
+	})
+
+	t.Run("does not buffer batches exceeding the maximum size", func(t *testing.T) {
+		wal, err := OpenWAL(t.TempDir(), "full", WALConfig{MaxSize: 1}, clk, log.NewNopLogger(), m)
+		require.NoError(t, err)
+
+		err = wal.Append(1, []Point{point("test", 1)})
+		require.ErrorIs(t, err, ErrWALFull)
+		require.Zero(t, wal.Len())
+	})
+
+	t.Run("backs off after unexpected write failures", func(t *testing.T) {
+		wal, err := OpenWAL(t.TempDir(), "backoff", WALConfig{}, clk, log.NewNopLogger(), m)
+		require.NoError(t, err)
+		require.NoError(t, wal.Append(1, []Point{point("test", 1)}))
+
+		calls := 0
+		failing := func(context.Context, []Point, int64) error {
+			calls++
+			return errors.Join(ErrUnexpectedWriteFailure, errors.New("unavailable"))
+		}
+		wal.replay(context.Background(), failing)
+		wal.replay(context.Background(), failing)
+		require.Equal(t, 1, calls)
+		require.Equal(t, 1, wal.Len())
+
+		clk.Add(walMinBackoff)
+		wal.replay(context.Background(), failing)
+		require.Equal(t, 2, calls)
+
+		// The backoff doubled.
+		clk.Add(walMinBackoff)
+		wal.replay(context.Background(), failing)
+		require.Equal(t, 2, calls)
+		require.Equal(t, 10.0, testutil.ToFloat64(m.WALOldestSampleAge.WithLabelValues("backoff")))
+	})
+
+	t.Run("drops rejected batches and batches with too old samples", func(t *testing.T) {
+		wal, err := OpenWAL(t.TempDir(), "drop", WALConfig{MaxAge: time.Minute}, clk, log.NewNopLogger(), m)
+		require.NoError(t, err)
+		require.NoError(t, wal.Append(1, []Point{point("old", 1)}))
+		clk.Add(2 * time.Minute)
+		require.NoError(t, wal.Append(1, []Point{point("rejected", 1)}))
+		require.NoError(t, wal.Append(1, []Point{point("ok", 1)}))
+
+		var written []string
+		wal.replay(context.Background(), func(_ context.Context, points []Point, _ int64) error {
+			if points[0].Name == "rejected" {
+				return errors.Join(ErrRejectedWrite, errors.New("invalid label"))
+			}
+			written = append(written, points[0].Name)
+			return nil
+		})
+		require.Equal(t, []string{"ok"}, written)
+		require.Zero(t, wal.Len())
+		require.Equal(t, 1.0, testutil.ToFloat64(m.WALDroppedBatchesTotal.WithLabelValues("drop", walDropReasonTooOld)))
+		require.Equal(t, 1.0, testutil.ToFloat64(m.WALDroppedBatchesTotal.WithLabelValues("drop", walDropReasonRejected)))
+	})
+}
+
+func TestPrometheusWriter_WritePointsWithWAL(t *testing.T) {
+	clk := clock.NewMock()
+	wal, err := OpenWAL(t.TempDir(), "test", WALConfig{}, clk, log.NewNopLogger(), metrics.NewRemoteWriterMetrics(prometheus.NewRegistry()))
+	require.NoError(t, err)
+
+	var written []string
+	var writeErr promremote.WriteError
+	client := &testClient{
+		writeSeriesFunc: func(_ context.Context, ts promremote.TSList, _ promremote.WriteOptions) (promremote.WriteResult, promremote.WriteError) {
+			if writeErr != nil {
+				return promremote.WriteResult{StatusCode: writeErr.StatusCode()}, writeErr
+			}
+			written = append(written, ts[0].Labels[0].Value)
+			return promremote.WriteResult{StatusCode: http.StatusOK}, nil
+		},
+	}
+	writer := &PrometheusWriter{
+		client:  client,
+		clock:   clk,
+		logger:  log.NewNopLogger(),
+		metrics: metrics.NewRemoteWriterMetrics(prometheus.NewRegistry()),
+		wal:     wal,
+	}
+	ctx := context.Background()
+	points := func(name string) []Point {
+		return []Point{{Name: name, Labels: map[string]string{}, Metric: Metric{T: clk.Now(), V: 1}}}
+	}
+
+	t.Run("buffers failed writes and writes behind them until they are replayed", func(t *testing.T) {
+		writeErr = testClientWriteError{statusCode: http.StatusServiceUnavailable}
+		require.NoError(t, writer.WritePoints(ctx, points("first"), 1))
+		require.Equal(t, 1, wal.Len())
+
+		// The target recovered, but points are not written before the buffered ones.
+		writeErr = nil
+		require.NoError(t, writer.WritePoints(ctx, points("second"), 1))
+		require.Equal(t, 2, wal.Len())
+		require.Empty(t, written)
+
+		writer.replayWAL(ctx)
+		require.Zero(t, wal.Len())
+		require.NoError(t, writer.WritePoints(ctx, points("third"), 1))
+		require.Equal(t, []string{"first", "second", "third"}, written)
+	})
+
+	t.Run("does not buffer rejected writes", func(t *testing.T) {
+		msg := MimirInvalidLabelError
+		writeErr = testClientWriteError{statusCode: http.StatusBadRequest, msg: &msg}
+
+		err := writer.WritePoints(ctx, points("rejected"), 1)
+		require.ErrorIs(t, err, ErrRejectedWrite)
+		require.Zero(t, wal.Len())
+	})
+
+	t.Run("does not buffer out of order samples", func(t *testing.T) {
+		msg := "out of order sample"
+		writeErr = testClientWriteError{statusCode: http.StatusBadRequest, msg: &msg}
+
+		err := writer.WritePoints(ctx, points("out-of-order"), 1)
+		require.ErrorIs(t, err, ErrRejectedWrite)
+		require.Zero(t, wal.Len())
+	})
+}
@@ -0,0 +1,23 @@
+package ualert
+
+import (
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+// AddAlertRuleKeepFiringForMigrations adds the keep_firing_for column to alert_rule and alert_rule_version. It stores
+// the duration in nanoseconds for which an alert keeps firing after its condition stops being met.
+func AddAlertRuleKeepFiringForMigrations(mg *migrator.Migrator) {
+	mg.AddMigration("add keep_firing_for column to alert_rule", migrator.NewAddColumnMigration(migrator.Table{Name: "alert_rule"}, &migrator.Column{
+		Name:     "keep_firing_for",
+		Type:     migrator.DB_BigInt,
+		Nullable: false,
+		Default:  "0",
+	}))
+
+	mg.AddMigration("add keep_firing_for column to alert_rule_version", migrator.NewAddColumnMigration(migrator.Table{Name: "alert_rule_version"}, &migrator.Column{
+		Name:     "keep_firing_for",
+		Type:     migrator.DB_BigInt,
+		Nullable: false,
+		Default:  "0",
+	}))
+}
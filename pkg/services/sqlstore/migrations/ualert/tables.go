@@ -0,0 +1,14 @@
+package ualert
+
+import (
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+// UIDMaxLength is the maximum length of the UIDs of rules, folders and dashboards stored by unified alerting.
+const UIDMaxLength = 40
+
+// AddTablesMigrations adds the migrations of the tables and columns of unified alerting.
+func AddTablesMigrations(mg *migrator.Migrator) {
+	AddAlertRuleKeepFiringForMigrations(mg)
+	AddStateHistoryMigrations(mg)
+}